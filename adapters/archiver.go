@@ -0,0 +1,269 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	DefaultArchiveTimeout = 30 * time.Second
+	DefaultMaxArchiveSize = 10 << 20
+	// the assets of a page together, beyond which they are left linked rather than inlined
+	DefaultMaxAssetsSize = 20 << 20
+)
+
+// HTTPPageArchiver implements PageArchiver by downloading pages over HTTP. The copies are self-contained HTML: they
+// get a <base> of the page URL so that what isn't inlined still loads from the site, and their scripts are removed.
+type HTTPPageArchiver struct {
+	client        *http.Client
+	maxPageSize   int64
+	maxAssetsSize int64
+}
+
+func NewHTTPPageArchiver(timeout time.Duration, maxPageSize int64, maxAssetsSize int64) *HTTPPageArchiver {
+	return &HTTPPageArchiver{client: &http.Client{Timeout: timeout}, maxPageSize: maxPageSize, maxAssetsSize: maxAssetsSize}
+}
+
+func (a *HTTPPageArchiver) Archive(ctx context.Context, link model.URL, inlineAssets bool) (model.ArchivedPage, error) {
+	body, contentType, pageURL, err := a.download(ctx, string(link), a.maxPageSize)
+	if err != nil {
+		return model.ArchivedPage{}, err
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return model.ArchivedPage{}, fmt.Errorf("%s is not a web page (%s)", link, mediaType)
+	}
+	utf8Body, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return model.ArchivedPage{}, fmt.Errorf("error reading %s: %v", link, err)
+	}
+	doc, err := html.Parse(utf8Body)
+	if err != nil {
+		return model.ArchivedPage{}, fmt.Errorf("error parsing %s: %v", link, err)
+	}
+
+	head := findElement(doc, atom.Head)
+	base := prepareHead(head, pageURL)
+	removeElements(doc, atom.Script)
+	if inlineAssets {
+		budget := a.maxAssetsSize
+		a.inlineAssets(ctx, doc, base, &budget)
+	}
+
+	var out bytes.Buffer
+	if err := html.Render(&out, doc); err != nil {
+		return model.ArchivedPage{}, fmt.Errorf("error rendering %s: %v", link, err)
+	}
+	archivedURL, err := model.NewURL(pageURL.String())
+	if err != nil {
+		archivedURL = link
+	}
+	return model.ArchivedPage{HTML: out.Bytes(), URL: archivedURL, InlinedAssets: inlineAssets}, nil
+}
+
+// Downloads the resource at the URL, up to maxSize bytes, returning its content type and URL after redirects
+func (a *HTTPPageArchiver) download(ctx context.Context, resource string, maxSize int64) ([]byte, string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resource, nil)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error requesting %s: %v", resource, err)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error requesting %s: %v", resource, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", nil, fmt.Errorf("error requesting %s: %s", resource, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error reading %s: %v", resource, err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, "", nil, fmt.Errorf("%s is larger than %d bytes", resource, maxSize)
+	}
+	return data, resp.Header.Get("Content-Type"), resp.Request.URL, nil
+}
+
+/*
+Prepares the head of the copy and returns the URL relative links resolve against:
+  - the content is UTF-8 after decoding, so the charset declarations of the page are replaced by one for UTF-8
+  - the <base> of the page, if any, is resolved against the page URL, otherwise it's the page URL
+*/
+func prepareHead(head *html.Node, pageURL *url.URL) *url.URL {
+	base := pageURL
+	for node := head.FirstChild; node != nil; {
+		next := node.NextSibling
+		switch {
+		case node.DataAtom == atom.Base:
+			if href, ok := attr(node, "href"); ok {
+				if resolved, err := pageURL.Parse(href); err == nil {
+					base = resolved
+				}
+			}
+			head.RemoveChild(node)
+		case node.DataAtom == atom.Meta && (hasAttr(node, "charset") || strings.EqualFold(attrOrEmpty(node, "http-equiv"), "content-type")):
+			head.RemoveChild(node)
+		}
+		node = next
+	}
+	baseNode := &html.Node{Type: html.ElementNode, DataAtom: atom.Base, Data: "base", Attr: []html.Attribute{{Key: "href", Val: base.String()}}}
+	charsetNode := &html.Node{Type: html.ElementNode, DataAtom: atom.Meta, Data: "meta", Attr: []html.Attribute{{Key: "charset", Val: "utf-8"}}}
+	head.InsertBefore(baseNode, head.FirstChild)
+	head.InsertBefore(charsetNode, baseNode)
+	return base
+}
+
+// Replaces stylesheet links with the stylesheets and image sources with data URIs, as long as the budget lasts.
+// Assets that fail to load are left linked.
+func (a *HTTPPageArchiver) inlineAssets(ctx context.Context, node *html.Node, base *url.URL, budget *int64) {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		switch {
+		case child.DataAtom == atom.Link && isStylesheet(child):
+			if css, ok := a.fetchAsset(ctx, base, attrOrEmpty(child, "href"), "text/css", budget); ok {
+				style := &html.Node{Type: html.ElementNode, DataAtom: atom.Style, Data: "style"}
+				if media, ok := attr(child, "media"); ok {
+					style.Attr = append(style.Attr, html.Attribute{Key: "media", Val: media})
+				}
+				style.AppendChild(&html.Node{Type: html.TextNode, Data: css})
+				node.InsertBefore(style, child)
+				node.RemoveChild(child)
+			}
+		case child.DataAtom == atom.Img:
+			if dataURI, ok := a.fetchAsset(ctx, base, attrOrEmpty(child, "src"), "image/", budget); ok {
+				setAttr(child, "src", dataURI)
+				// the other sources would still load from the site
+				removeAttr(child, "srcset")
+			}
+		default:
+			a.inlineAssets(ctx, child, base, budget)
+		}
+		child = next
+	}
+}
+
+var cssURLs = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)`)
+
+// Fetches an asset of the expected type (a prefix of its media type), returning stylesheets as text, with their URLs
+// made absolute, and everything else as a data URI
+func (a *HTTPPageArchiver) fetchAsset(ctx context.Context, base *url.URL, ref string, expected string, budget *int64) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "data:") || *budget <= 0 {
+		return "", false
+	}
+	assetURL, err := base.Parse(ref)
+	if err != nil || (assetURL.Scheme != "http" && assetURL.Scheme != "https") {
+		return "", false
+	}
+	data, contentType, finalURL, err := a.download(ctx, assetURL.String(), *budget)
+	if err != nil {
+		return "", false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if !strings.HasPrefix(mediaType, expected) {
+		return "", false
+	}
+	*budget -= int64(len(data))
+
+	if expected == "text/css" {
+		css := cssURLs.ReplaceAllStringFunc(string(data), func(match string) string {
+			parts := cssURLs.FindStringSubmatch(match)
+			if resolved, err := finalURL.Parse(parts[2]); err == nil && !strings.HasPrefix(parts[2], "data:") {
+				return "url(" + parts[1] + resolved.String() + parts[3] + ")"
+			}
+			return match
+		})
+		// the stylesheet can't end the <style> element early
+		return strings.ReplaceAll(css, "</style", `<\/style`), true
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data), true
+}
+
+func isStylesheet(node *html.Node) bool {
+	for _, rel := range strings.Fields(strings.ToLower(attrOrEmpty(node, "rel"))) {
+		if rel == "stylesheet" {
+			return true
+		}
+	}
+	return false
+}
+
+func findElement(node *html.Node, a atom.Atom) *html.Node {
+	if node.Type == html.ElementNode && node.DataAtom == a {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func removeElements(node *html.Node, a atom.Atom) {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.ElementNode && child.DataAtom == a {
+			node.RemoveChild(child)
+		} else {
+			removeElements(child, a)
+		}
+		child = next
+	}
+}
+
+func attr(node *html.Node, key string) (string, bool) {
+	for _, a := range node.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attrOrEmpty(node *html.Node, key string) string {
+	val, _ := attr(node, key)
+	return val
+}
+
+func hasAttr(node *html.Node, key string) bool {
+	_, ok := attr(node, key)
+	return ok
+}
+
+func setAttr(node *html.Node, key, val string) {
+	for i, a := range node.Attr {
+		if strings.EqualFold(a.Key, key) {
+			node.Attr[i].Val = val
+			return
+		}
+	}
+	node.Attr = append(node.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func removeAttr(node *html.Node, key string) {
+	attrs := node.Attr[:0]
+	for _, a := range node.Attr {
+		if !strings.EqualFold(a.Key, key) {
+			attrs = append(attrs, a)
+		}
+	}
+	node.Attr = attrs
+}
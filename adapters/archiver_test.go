@@ -0,0 +1,108 @@
+package adapters
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func setupArchiveServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blog/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		w.Write([]byte(`<html><head><meta charset="iso-8859-1"><title>Caf` + "\xe9" + `</title>
+			<link rel="stylesheet" href="../static/style.css" media="screen">
+			<script src="/static/app.js"></script>
+			</head><body><p>Post</p><img src="/static/photo.png" srcset="/static/photo-2x.png 2x"><img src="/static/missing.png">
+			<script>alert("hi")</script></body></html>`))
+	})
+	mux.HandleFunc("/static/style.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(`body { background: url("img/bg.png") } p::after { content: "</style>" }`))
+	})
+	mux.HandleFunc("/static/photo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(testPNG)
+	})
+	mux.HandleFunc("/based", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><base href="/docs/"></head><body><img src="photo.png"></body></html>`))
+	})
+	mux.HandleFunc("/paper.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestArchivePage(t *testing.T) {
+	server := setupArchiveServer(t)
+	archiver := NewHTTPPageArchiver(time.Second, DefaultMaxArchiveSize, DefaultMaxAssetsSize)
+
+	page, err := archiver.Archive(context.Background(), model.URL(server.URL+"/blog/post"), false)
+	require.NoError(t, err)
+	require.Equal(t, model.URL(server.URL+"/blog/post"), page.URL)
+	require.False(t, page.InlinedAssets)
+	archived := string(page.HTML)
+	require.Contains(t, archived, `<head><meta charset="utf-8"/><base href="`+server.URL+`/blog/post"/>`)
+	require.NotContains(t, archived, "iso-8859-1")
+	require.Contains(t, archived, "<title>Café</title>")
+	require.NotContains(t, archived, "<script")
+	// the assets are still linked, relative to the base
+	require.Contains(t, archived, `<link rel="stylesheet" href="../static/style.css" media="screen"/>`)
+	require.Contains(t, archived, `<img src="/static/photo.png" srcset="/static/photo-2x.png 2x"/>`)
+}
+
+func TestArchivePageWithInlinedAssets(t *testing.T) {
+	server := setupArchiveServer(t)
+	archiver := NewHTTPPageArchiver(time.Second, DefaultMaxArchiveSize, DefaultMaxAssetsSize)
+
+	page, err := archiver.Archive(context.Background(), model.URL(server.URL+"/blog/post"), true)
+	require.NoError(t, err)
+	require.True(t, page.InlinedAssets)
+	archived := string(page.HTML)
+	require.Contains(t, archived, `<style media="screen">body { background: url("`+server.URL+`/static/img/bg.png") } p::after { content: "<\/style>" }</style>`)
+	require.NotContains(t, archived, "<link")
+	require.Contains(t, archived, `<img src="data:image/png;base64,`+base64.StdEncoding.EncodeToString(testPNG)+`"/>`)
+	// assets that fail to load are left linked
+	require.Contains(t, archived, `<img src="/static/missing.png"/>`)
+
+	// the <base> of the page is kept, resolved against the page URL
+	page, err = archiver.Archive(context.Background(), model.URL(server.URL+"/based"), true)
+	require.NoError(t, err)
+	require.Contains(t, string(page.HTML), `<base href="`+server.URL+`/docs/"/>`)
+	require.Equal(t, 1, strings.Count(string(page.HTML), "<base"))
+}
+
+func TestArchivePageLimits(t *testing.T) {
+	server := setupArchiveServer(t)
+
+	// once the assets budget is spent, the other assets are left linked
+	page, err := NewHTTPPageArchiver(time.Second, DefaultMaxArchiveSize, 80).Archive(context.Background(), model.URL(server.URL+"/blog/post"), true)
+	require.NoError(t, err)
+	require.Contains(t, string(page.HTML), "<style")
+	require.Contains(t, string(page.HTML), `<img src="/static/photo.png"`)
+
+	_, err = NewHTTPPageArchiver(time.Second, 100, DefaultMaxAssetsSize).Archive(context.Background(), model.URL(server.URL+"/blog/post"), false)
+	require.ErrorContains(t, err, "is larger than 100 bytes")
+}
+
+func TestArchivePageFailures(t *testing.T) {
+	server := setupArchiveServer(t)
+	archiver := NewHTTPPageArchiver(time.Second, DefaultMaxArchiveSize, DefaultMaxAssetsSize)
+
+	_, err := archiver.Archive(context.Background(), model.URL(server.URL+"/gone"), false)
+	require.ErrorContains(t, err, "404 Not Found")
+	_, err = archiver.Archive(context.Background(), model.URL(server.URL+"/paper.pdf"), false)
+	require.ErrorContains(t, err, "is not a web page (application/pdf)")
+}
@@ -0,0 +1,161 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+type SQLiteAttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteAttachmentRepository(db *sql.DB) *SQLiteAttachmentRepository {
+	return &SQLiteAttachmentRepository{db: db}
+}
+
+func (r *SQLiteAttachmentRepository) GetAttachments(referenceId model.Id) ([]model.Attachment, error) {
+	return r.queryAttachments(`
+		SELECT id, reference_id, file_name, media_type, size, content_hash, added_at
+		FROM attachments
+		WHERE reference_id = ?
+		ORDER BY added_at, id`, int64(referenceId))
+}
+
+func (r *SQLiteAttachmentRepository) GetAllAttachments() ([]model.Attachment, error) {
+	return r.queryAttachments(`
+		SELECT id, reference_id, file_name, media_type, size, content_hash, added_at
+		FROM attachments
+		ORDER BY id`)
+}
+
+func (r *SQLiteAttachmentRepository) queryAttachments(query string, args ...any) ([]model.Attachment, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attachments: %v", err)
+	}
+	defer rows.Close()
+
+	attachments := []model.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning attachment: %v", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attachments: %v", err)
+	}
+	return attachments, nil
+}
+
+func (r *SQLiteAttachmentRepository) GetAttachmentById(id model.Id) (*model.Attachment, error) {
+	row := r.db.QueryRow(`
+		SELECT id, reference_id, file_name, media_type, size, content_hash, added_at
+		FROM attachments WHERE id = ?`, int64(id))
+	attachment, err := scanAttachment(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("attachment with id %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying attachment: %v", err)
+	}
+	return &attachment, nil
+}
+
+func (r *SQLiteAttachmentRepository) AddAttachment(attachment model.Attachment) (model.Id, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO attachments (reference_id, file_name, media_type, size, content_hash, added_at)
+		SELECT ?, ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM base_references WHERE id = ?)`,
+		int64(attachment.ReferenceId), string(attachment.FileName), attachment.MediaType, attachment.Size,
+		string(attachment.ContentHash), attachment.AddedAt.Unix(), int64(attachment.ReferenceId))
+	if err != nil {
+		return 0, fmt.Errorf("error inserting attachment: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return 0, fmt.Errorf("no reference found with id %d", attachment.ReferenceId)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting attachment id: %v", err)
+	}
+	return model.Id(id), nil
+}
+
+func (r *SQLiteAttachmentRepository) DeleteAttachment(id model.Id) error {
+	result, err := r.db.Exec(`DELETE FROM attachments WHERE id = ?`, int64(id))
+	if err != nil {
+		return fmt.Errorf("error deleting attachment: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("attachment with id %d not found", id)
+	}
+	return nil
+}
+
+func scanAttachment(scanner rowScanner) (model.Attachment, error) {
+	var id, referenceId, addedAt int64
+	var fileName, hash string
+	var attachment model.Attachment
+	if err := scanner.Scan(&id, &referenceId, &fileName, &attachment.MediaType, &attachment.Size, &hash, &addedAt); err != nil {
+		return model.Attachment{}, err
+	}
+	attachment.Id, attachment.ReferenceId = model.Id(id), model.Id(referenceId)
+	attachment.FileName, attachment.ContentHash = model.FileName(fileName), model.ContentHash(hash)
+	attachment.AddedAt = time.Unix(addedAt, 0)
+	return attachment, nil
+}
+
+type SQLiteOrphanedBlobRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteOrphanedBlobRepository(db *sql.DB) *SQLiteOrphanedBlobRepository {
+	return &SQLiteOrphanedBlobRepository{db: db}
+}
+
+func (r *SQLiteOrphanedBlobRepository) GetOrphanedBlobs() ([]model.ContentHash, error) {
+	rows, err := r.db.Query(`SELECT hash FROM orphaned_blobs ORDER BY hash`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying orphaned blobs: %v", err)
+	}
+	defer rows.Close()
+
+	hashes := []model.ContentHash{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("error scanning orphaned blob: %v", err)
+		}
+		hashes = append(hashes, model.ContentHash(hash))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating orphaned blobs: %v", err)
+	}
+	return hashes, nil
+}
+
+func (r *SQLiteOrphanedBlobRepository) ClaimOrphanedBlob(hash model.ContentHash) (bool, error) {
+	// storing the content again takes it off the queue, so it's only claimed if it's still orphaned
+	result, err := r.db.Exec(`DELETE FROM orphaned_blobs WHERE hash = ?`, string(hash))
+	if err != nil {
+		return false, fmt.Errorf("error claiming orphaned blob: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
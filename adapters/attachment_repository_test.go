@@ -0,0 +1,146 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/testutils"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAndGetAttachments(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteAttachmentRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book", "", "", false)
+	noteId := testutils.CreateTestNoteReference(t, db, catId, "Note", "text", false)
+
+	pdf := model.Attachment{ReferenceId: bookId, FileName: "book.pdf", MediaType: "application/pdf", Size: 3, ContentHash: model.HashContent([]byte("pdf")), AddedAt: time.Unix(1760000000, 0)}
+	epub := model.Attachment{ReferenceId: bookId, FileName: "book.epub", MediaType: "application/epub+zip", Size: 4, ContentHash: model.HashContent([]byte("epub")), AddedAt: time.Unix(1760003600, 0)}
+	notes := model.Attachment{ReferenceId: noteId, FileName: "notes.md", MediaType: "text/markdown; charset=utf-8", Size: 5, ContentHash: model.HashContent([]byte("notes")), AddedAt: time.Unix(1760000000, 0)}
+	var err error
+	pdf.Id, err = repo.AddAttachment(pdf)
+	require.NoError(t, err)
+	epub.Id, err = repo.AddAttachment(epub)
+	require.NoError(t, err)
+	notes.Id, err = repo.AddAttachment(notes)
+	require.NoError(t, err)
+
+	attachments, err := repo.GetAttachments(bookId)
+	require.NoError(t, err)
+	require.Equal(t, []model.Attachment{pdf, epub}, attachments)
+
+	all, err := repo.GetAllAttachments()
+	require.NoError(t, err)
+	require.Equal(t, []model.Attachment{pdf, epub, notes}, all)
+
+	loaded, err := repo.GetAttachmentById(notes.Id)
+	require.NoError(t, err)
+	require.Equal(t, notes, *loaded)
+	_, err = repo.GetAttachmentById(999)
+	require.Error(t, err)
+
+	_, err = repo.AddAttachment(model.Attachment{ReferenceId: 999, FileName: "x.pdf", MediaType: "application/pdf", ContentHash: model.HashContent(nil), AddedAt: time.Now()})
+	require.Error(t, err)
+
+	require.NoError(t, repo.DeleteAttachment(pdf.Id))
+	require.Error(t, repo.DeleteAttachment(pdf.Id))
+	attachments, err = repo.GetAttachments(bookId)
+	require.NoError(t, err)
+	require.Equal(t, []model.Attachment{epub}, attachments)
+}
+
+func TestContentIsOrphanedWhenItsLastRowIsDeleted(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	attachmentRepo := NewSQLiteAttachmentRepository(db)
+	snapshotRepo := NewSQLiteSnapshotRepository(db)
+	orphanRepo := NewSQLiteOrphanedBlobRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link", "https://example.com/paper", "", false)
+
+	// the same content, attached and archived
+	hash := model.HashContent([]byte("same content"))
+	attachmentId, err := attachmentRepo.AddAttachment(model.Attachment{ReferenceId: linkId, FileName: "paper.txt", MediaType: "text/plain; charset=utf-8", ContentHash: hash, AddedAt: time.Now()})
+	require.NoError(t, err)
+	snapshotId, err := snapshotRepo.AddSnapshot(model.Snapshot{ReferenceId: linkId, URL: "https://example.com/paper", ContentHash: hash, CapturedAt: time.Now()})
+	require.NoError(t, err)
+
+	require.NoError(t, attachmentRepo.DeleteAttachment(attachmentId))
+	orphans, err := orphanRepo.GetOrphanedBlobs()
+	require.NoError(t, err)
+	require.Empty(t, orphans)
+
+	require.NoError(t, snapshotRepo.DeleteSnapshot(snapshotId))
+	orphans, err = orphanRepo.GetOrphanedBlobs()
+	require.NoError(t, err)
+	require.Equal(t, []model.ContentHash{hash}, orphans)
+
+	claimed, err := orphanRepo.ClaimOrphanedBlob(hash)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = orphanRepo.ClaimOrphanedBlob(hash)
+	require.NoError(t, err)
+	require.False(t, claimed)
+}
+
+func TestContentStoredAgainIsNoLongerOrphaned(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteAttachmentRepository(db)
+	orphanRepo := NewSQLiteOrphanedBlobRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book", "", "", false)
+
+	attachment := model.Attachment{ReferenceId: bookId, FileName: "book.pdf", MediaType: "application/pdf", ContentHash: model.HashContent([]byte("pdf")), AddedAt: time.Now()}
+	id, err := repo.AddAttachment(attachment)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteAttachment(id))
+	_, err = repo.AddAttachment(attachment)
+	require.NoError(t, err)
+
+	claimed, err := orphanRepo.ClaimOrphanedBlob(attachment.ContentHash)
+	require.NoError(t, err)
+	require.False(t, claimed)
+}
+
+func TestRemoveReferenceOrphansTheContentOfItsAttachments(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	categoryRepo := NewSQLiteCategoryRepository(db)
+	attachmentRepo := NewSQLiteAttachmentRepository(db)
+	orphanRepo := NewSQLiteOrphanedBlobRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	first := testutils.CreateTestBookReference(t, db, catId, "First", "", "", false)
+	second := testutils.CreateTestBookReference(t, db, catId, "Second", "", "", false)
+
+	shared := model.HashContent([]byte("shared"))
+	own := model.HashContent([]byte("own"))
+	for _, attachment := range []model.Attachment{
+		{ReferenceId: first, FileName: "shared.pdf", MediaType: "application/pdf", ContentHash: shared, AddedAt: time.Now()},
+		{ReferenceId: first, FileName: "own.pdf", MediaType: "application/pdf", ContentHash: own, AddedAt: time.Now()},
+		{ReferenceId: second, FileName: "shared.pdf", MediaType: "application/pdf", ContentHash: shared, AddedAt: time.Now()},
+	} {
+		_, err := attachmentRepo.AddAttachment(attachment)
+		require.NoError(t, err)
+	}
+
+	cat, err := categoryRepo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.NoError(t, categoryRepo.RemoveReference(catId, first, cat.Version))
+
+	attachments, err := attachmentRepo.GetAllAttachments()
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	orphans, err := orphanRepo.GetOrphanedBlobs()
+	require.NoError(t, err)
+	require.Equal(t, []model.ContentHash{own}, orphans)
+}
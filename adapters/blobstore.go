@@ -0,0 +1,107 @@
+package adapters
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// SQLiteBlobStore implements BlobStore with the blobs table, which keeps the whole library in one file
+type SQLiteBlobStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteBlobStore(db *sql.DB) *SQLiteBlobStore {
+	return &SQLiteBlobStore{db: db}
+}
+
+func (s *SQLiteBlobStore) Put(data []byte) (model.ContentHash, error) {
+	hash := model.HashContent(data)
+	if _, err := s.db.Exec(`INSERT OR IGNORE INTO blobs (hash, data) VALUES (?, ?)`, string(hash), data); err != nil {
+		return "", fmt.Errorf("error storing blob: %v", err)
+	}
+	return hash, nil
+}
+
+func (s *SQLiteBlobStore) Get(hash model.ContentHash) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM blobs WHERE hash = ?`, string(hash)).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying blob: %v", err)
+	}
+	return data, nil
+}
+
+func (s *SQLiteBlobStore) Delete(hash model.ContentHash) error {
+	if _, err := s.db.Exec(`DELETE FROM blobs WHERE hash = ?`, string(hash)); err != nil {
+		return fmt.Errorf("error deleting blob: %v", err)
+	}
+	return nil
+}
+
+// FileBlobStore implements BlobStore with a file per blob, named by its hash and spread over subdirectories by its
+// first two digits (like git objects), so that large blobs don't bloat the database
+type FileBlobStore struct {
+	dir string
+}
+
+func NewFileBlobStore(dir string) *FileBlobStore {
+	return &FileBlobStore{dir: dir}
+}
+
+func (s *FileBlobStore) path(hash model.ContentHash) string {
+	return filepath.Join(s.dir, string(hash[:2]), string(hash[2:]))
+}
+
+func (s *FileBlobStore) Put(data []byte) (model.ContentHash, error) {
+	hash := model.HashContent(data)
+	path := s.path(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("error creating blob directory: %v", err)
+	}
+	// written to a temporary file first, so that a blob is either complete or missing
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("error storing blob: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("error storing blob: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("error storing blob: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("error storing blob: %v", err)
+	}
+	return hash, nil
+}
+
+func (s *FileBlobStore) Get(hash model.ContentHash) ([]byte, error) {
+	data, err := os.ReadFile(s.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, model.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading blob: %v", err)
+	}
+	return data, nil
+}
+
+func (s *FileBlobStore) Delete(hash model.ContentHash) error {
+	if err := os.Remove(s.path(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error deleting blob: %v", err)
+	}
+	return nil
+}
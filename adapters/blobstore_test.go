@@ -0,0 +1,67 @@
+package adapters
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/testutils"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/require"
+)
+
+type blobStore interface {
+	Put(data []byte) (model.ContentHash, error)
+	Get(hash model.ContentHash) ([]byte, error)
+	Delete(hash model.ContentHash) error
+}
+
+func testBlobStore(t *testing.T, store blobStore) {
+	hash, err := store.Put([]byte("<html>archived</html>"))
+	require.NoError(t, err)
+	require.Equal(t, model.HashContent([]byte("<html>archived</html>")), hash)
+
+	// storing the same content again is a no-op
+	again, err := store.Put([]byte("<html>archived</html>"))
+	require.NoError(t, err)
+	require.Equal(t, hash, again)
+
+	data, err := store.Get(hash)
+	require.NoError(t, err)
+	require.Equal(t, "<html>archived</html>", string(data))
+
+	empty, err := store.Put([]byte{})
+	require.NoError(t, err)
+	data, err = store.Get(empty)
+	require.NoError(t, err)
+	require.Empty(t, data)
+
+	require.NoError(t, store.Delete(hash))
+	_, err = store.Get(hash)
+	require.True(t, errors.Is(err, model.ErrBlobNotFound))
+	require.NoError(t, store.Delete(hash))
+}
+
+func TestSQLiteBlobStore(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	testBlobStore(t, NewSQLiteBlobStore(db))
+}
+
+func TestFileBlobStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileBlobStore(dir)
+	testBlobStore(t, store)
+
+	hash, err := store.Put([]byte("kept"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, string(hash[:2]), string(hash[2:])))
+	require.NoError(t, err)
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Join(dir, string(hash[:2])))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
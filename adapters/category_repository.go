@@ -3,6 +3,7 @@ package adapters
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/VladMinzatu/reference-manager/domain/model"
 )
@@ -16,36 +17,18 @@ func NewSQLiteCategoryRepository(db *sql.DB) *SQLiteCategoryRepository {
 }
 
 func (r *SQLiteCategoryRepository) GetCategoryById(id model.Id) (*model.Category, error) {
-	const (
-		BOOK_TYPE = "book"
-		LINK_TYPE = "link"
-		NOTE_TYPE = "note"
-	)
-
 	// Single query to get category and all references atomically
-	query := `
+	query := fmt.Sprintf(`
 		SELECT 
 			c.id, c.name, c.version,
-			br.id as ref_id, br.title as ref_title, br.position as ref_position, br.is_starred,
-			CASE 
-				WHEN bk.reference_id IS NOT NULL THEN ?
-				WHEN l.reference_id IS NOT NULL THEN ?
-				WHEN n.reference_id IS NOT NULL THEN ?
-			END as ref_type,
-			COALESCE(bk.isbn, '') as isbn,
-			COALESCE(bk.description, '') as book_description,
-			COALESCE(l.url, '') as url,
-			COALESCE(l.description, '') as link_description,
-			COALESCE(n.text, '') as text
+			%s
 		FROM categories c
 		LEFT JOIN base_references br ON c.id = br.category_id
-		LEFT JOIN book_references bk ON br.id = bk.reference_id
-		LEFT JOIN link_references l ON br.id = l.reference_id
-		LEFT JOIN note_references n ON br.id = n.reference_id
+		%s
 		WHERE c.id = ?
-		ORDER BY br.position`
+		ORDER BY br.sort_key`, referenceColumns, referenceJoins)
 
-	rows, err := r.db.Query(query, BOOK_TYPE, LINK_TYPE, NOTE_TYPE, id)
+	rows, err := r.db.Query(query, id)
 	if err != nil {
 		return nil, fmt.Errorf("error querying category: %v", err)
 	}
@@ -58,18 +41,9 @@ func (r *SQLiteCategoryRepository) GetCategoryById(id model.Id) (*model.Category
 		var catId int64
 		var catName string
 		var catVersion int64
-		var refId sql.NullInt64
-		var refTitle sql.NullString
-		var refPosition sql.NullInt64
-		var refStarred sql.NullBool
-		var refType sql.NullString
-		var isbn, bookDescription, url, linkDescription, text string
-
-		err := rows.Scan(
-			&catId, &catName, &catVersion,
-			&refId, &refTitle, &refPosition, &refStarred,
-			&refType, &isbn, &bookDescription, &url, &linkDescription, &text,
-		)
+		var row referenceRow
+
+		err := rows.Scan(append([]interface{}{&catId, &catName, &catVersion}, row.scanDest()...)...)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning row: %v", err)
 		}
@@ -86,23 +60,8 @@ func (r *SQLiteCategoryRepository) GetCategoryById(id model.Id) (*model.Category
 		}
 
 		// Next, add reference if it exists (refId will be NULL if category has no references)
-
-		// I need to add a note here for the curious reader: yes, this is a switch statement, but because it's coming from the persistence, it's not a switch on type
-		// and cannot be removed via double dispatch. Since it's the only place where it happens, I think adding an abstract Factory here is overkill, as it would require
-		// the same kind of update when adding a new reference type.
-		// On the plus side, the impact of forgetting to add support for a new type here is not big - the new references wold just not show up.
-		// Almost certainly something that will not cause more than 5 min of head scratching during development at worst.
-		if refId.Valid {
-			var ref model.Reference
-			switch refType.String {
-			case BOOK_TYPE:
-				ref = buildBookReference(refId, refTitle, refStarred, isbn, bookDescription)
-			case LINK_TYPE:
-				ref = buildLinkReference(refId, refTitle, refStarred, url, linkDescription)
-			case NOTE_TYPE:
-				ref = buildNoteReference(refId, refTitle, refStarred, text)
-			}
-			if ref != nil {
+		if row.id.Valid {
+			if ref := row.build(); ref != nil {
 				references = append(references, ref)
 			}
 		}
@@ -120,26 +79,6 @@ func (r *SQLiteCategoryRepository) GetCategoryById(id model.Id) (*model.Category
 	return category, nil
 }
 
-func buildBookReference(refId sql.NullInt64, refTitle sql.NullString, refStarred sql.NullBool, isbn, bookDescription string) model.Reference {
-	bookId, _ := model.NewId(refId.Int64)
-	bookTitle, _ := model.NewTitle(refTitle.String)
-	bookISBN, _ := model.NewISBN(isbn)
-	return model.NewBookReference(bookId, bookTitle, bookISBN, bookDescription, refStarred.Bool)
-}
-
-func buildLinkReference(refId sql.NullInt64, refTitle sql.NullString, refStarred sql.NullBool, url, linkDescription string) model.Reference {
-	linkId, _ := model.NewId(refId.Int64)
-	linkTitle, _ := model.NewTitle(refTitle.String)
-	linkURL, _ := model.NewURL(url)
-	return model.NewLinkReference(linkId, linkTitle, linkURL, linkDescription, refStarred.Bool)
-}
-
-func buildNoteReference(refId sql.NullInt64, refTitle sql.NullString, refStarred sql.NullBool, text string) model.Reference {
-	noteId, _ := model.NewId(refId.Int64)
-	noteTitle, _ := model.NewTitle(refTitle.String)
-	return model.NewNoteReference(noteId, noteTitle, text, refStarred.Bool)
-}
-
 func (r *SQLiteCategoryRepository) UpdateTitle(id model.Id, title model.Title, version model.Version) error {
 	tx, err := r.db.Begin()
 	if err != nil {
@@ -178,37 +117,29 @@ func (r *SQLiteCategoryRepository) ReorderReferences(id model.Id, positions map[
 		return fmt.Errorf("no references to reorder")
 	}
 
-	// Step 1: Set all positions to negative values to avoid unique constraint violation
-	negPositions := make(map[model.Id]int, len(positions))
-	for refId, pos := range positions {
-		negPositions[refId] = -pos - 1 // -1 to avoid unique constraint violation for position=0
-	}
-	negQuery, negArgs := buildUpdateReferencePositionsQuery(id, negPositions, version)
-	result, err := tx.Exec(negQuery, negArgs...)
-	if err != nil {
-		return fmt.Errorf("error setting negative positions: %v", err)
-	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("error getting rows affected (neg): %v", err)
-	}
-	expectedUpdates := int64(len(positions))
-	if rowsAffected < expectedUpdates {
-		return fmt.Errorf("expected to update %d references, but only updated %d; category with id %d may not exist or version was out of date", expectedUpdates, rowsAffected, id)
+	// Only the references that actually move get new sort keys. The version check at the end guards against concurrent changes
+	// to the list: the update fails (and the transaction is rolled back) if the category was modified in the meantime.
+	if err := categoryReferencesGroup(id).reorder(tx, positions); err != nil {
+		return err
 	}
 
-	// Step 2: Set positions to their intended positive values
-	posQuery, posArgs := buildUpdateReferencePositionsQuery(id, positions, version)
-	result, err = tx.Exec(posQuery, posArgs...)
+	err = r.updateCategoryVersion(tx, id, version)
 	if err != nil {
-		return fmt.Errorf("error updating reference positions: %v", err)
+		return err
 	}
-	rowsAffected, err = result.RowsAffected()
+
+	return tx.Commit()
+}
+
+func (r *SQLiteCategoryRepository) MoveReferenceToPosition(id model.Id, referenceId model.Id, position int, version model.Version) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("error getting rows affected (pos): %v", err)
+		return fmt.Errorf("error beginning transaction: %v", err)
 	}
-	if rowsAffected < expectedUpdates {
-		return fmt.Errorf("expected to update %d references, but only updated %d; category with id %d may not exist or version was out of date", expectedUpdates, rowsAffected, id)
+	defer tx.Rollback()
+
+	if err := categoryReferencesGroup(id).move(tx, referenceId, position); err != nil {
+		return fmt.Errorf("error moving reference %d in category %d: %v", referenceId, id, err)
 	}
 
 	err = r.updateCategoryVersion(tx, id, version)
@@ -219,79 +150,69 @@ func (r *SQLiteCategoryRepository) ReorderReferences(id model.Id, positions map[
 	return tx.Commit()
 }
 
-// buildUpdateReferencePositionsQuery builds a single SQL update statement for reference positions using CASE WHEN.
-func buildUpdateReferencePositionsQuery(categoryId model.Id, positions map[model.Id]int, version model.Version) (string, []interface{}) {
-	caseStmt := ""
-	var caseArgs []interface{}
-	var refIds []interface{}
-	for refId, position := range positions {
-		caseStmt += " WHEN ? THEN ?"
-		caseArgs = append(caseArgs, int64(refId), position)
-		refIds = append(refIds, int64(refId))
-	}
-
-	inClause := ""
-	for i := range refIds {
-		if i > 0 {
-			inClause += ","
-		}
-		inClause += "?"
+func (r *SQLiteCategoryRepository) AddReference(id model.Id, reference model.Reference, version model.Version) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
 	}
+	defer tx.Rollback()
 
-	query := fmt.Sprintf(`
-		UPDATE base_references
-		SET position = CASE id %s END
-		WHERE category_id = ?
-		AND id IN (%s)
-		AND EXISTS (
-			SELECT 1 FROM categories
-			WHERE id = ? AND version = ?
-		)`, caseStmt, inClause)
+	if err := insertReference(tx, id, reference, version); err != nil {
+		return err
+	}
 
-	args := append(caseArgs, int64(categoryId))
-	args = append(args, refIds...)
-	args = append(args, int64(categoryId), int64(version))
-	return query, args
+	err = r.updateCategoryVersion(tx, id, version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *SQLiteCategoryRepository) AddReference(id model.Id, reference model.Reference, version model.Version) error {
-	tx, err := r.db.Begin()
+// Inserts the reference (with its tags) at the end of the category, if the category is still at the version.
+// The version of the category is left to the caller to bump.
+func insertReference(tx *sql.Tx, id model.Id, reference model.Reference, version model.Version) error {
+	sortKey, err := categoryReferencesGroup(id).nextKey(tx)
 	if err != nil {
-		return fmt.Errorf("error beginning transaction: %v", err)
+		return err
 	}
-	defer tx.Rollback()
 
 	query := `
-		INSERT INTO base_references (category_id, title, position, is_starred)
-		SELECT ?, ?, COALESCE(MAX(position) + 1, 0), 0
-		FROM base_references
-		WHERE category_id = ?
-		AND EXISTS (
+		INSERT INTO base_references (category_id, title, sort_key, is_starred)
+		SELECT ?, ?, ?, ?
+		WHERE EXISTS (
 			SELECT 1 FROM categories WHERE id = ? AND version = ?
 		)`
 
-	result, err := tx.Exec(query, id, string(reference.Title()), id, id, version)
+	result, err := tx.Exec(query, id, string(reference.Title()), sortKey, reference.Starred(), id, version)
 	if err != nil {
 		return fmt.Errorf("error inserting base reference: %v", err)
 	}
 
-	refId, err := result.LastInsertId()
-	if err != nil || refId == 0 {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
 		return fmt.Errorf("category with id %d not found or version was out of date", id)
 	}
 
-	persistor := NewSQLiteReferenceAddPersistor(id, version, tx, refId)
-	err = reference.Persist(persistor)
+	refId, err := result.LastInsertId()
 	if err != nil {
-		return err
+		return fmt.Errorf("error getting last insert id: %v", err)
 	}
 
-	err = r.updateCategoryVersion(tx, id, version)
+	persistor := NewSQLiteReferenceAddPersistor(id, version, tx, refId)
+	err = reference.Persist(persistor)
 	if err != nil {
 		return err
 	}
-
-	return tx.Commit()
+	for _, tag := range reference.Tags() {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO reference_tags (reference_id, tag) VALUES (?, ?)`, refId, string(tag)); err != nil {
+			return fmt.Errorf("error inserting tag: %v", err)
+		}
+	}
+	return nil
 }
 
 func (r *SQLiteCategoryRepository) RemoveReference(id model.Id, referenceId model.Id, version model.Version) error {
@@ -323,19 +244,144 @@ func (r *SQLiteCategoryRepository) RemoveReference(id model.Id, referenceId mode
 		return fmt.Errorf("category with id %d not found or version was out of date", id)
 	}
 
-	// Reorder remaining references to close any gaps
-	_, err = tx.Exec(`
-		WITH ranked AS (
-			SELECT id, ROW_NUMBER() OVER (ORDER BY position) - 1 as new_pos
-			FROM base_references
-			WHERE category_id = ?
-		)
-		UPDATE base_references
-		SET position = ranked.new_pos
-		FROM ranked
-		WHERE base_references.id = ranked.id AND base_references.category_id = ?`, id, id)
+	// The remaining references keep their sort keys, so there are no gaps to close
+	err = r.updateCategoryVersion(tx, id, version)
 	if err != nil {
-		return fmt.Errorf("error reordering remaining references: %v", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteCategoryRepository) SetReferencesStarred(id model.Id, referenceIds []model.Id, starred bool, version model.Version) error {
+	return r.bulkUpdate(id, referenceIds, version, func(tx *sql.Tx, inClause string, args []interface{}) error {
+		query := fmt.Sprintf(`UPDATE base_references SET is_starred = ? WHERE id IN (%s)`, inClause)
+		if _, err := tx.Exec(query, append([]interface{}{starred}, args...)...); err != nil {
+			return fmt.Errorf("error updating starred references: %v", err)
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteCategoryRepository) RemoveReferences(id model.Id, referenceIds []model.Id, version model.Version) error {
+	// As with RemoveReference, the remaining references keep their sort keys
+	return r.bulkUpdate(id, referenceIds, version, func(tx *sql.Tx, inClause string, args []interface{}) error {
+		query := fmt.Sprintf(`DELETE FROM base_references WHERE id IN (%s)`, inClause)
+		if _, err := tx.Exec(query, args...); err != nil {
+			return fmt.Errorf("error deleting references: %v", err)
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteCategoryRepository) MoveReferences(id model.Id, referenceIds []model.Id, targetCategoryId model.Id, version model.Version) error {
+	if targetCategoryId == id {
+		return fmt.Errorf("references are already in category %d", id)
+	}
+	return r.bulkUpdate(id, referenceIds, version, func(tx *sql.Tx, inClause string, args []interface{}) error {
+		// The target category is modified too, so its version is bumped to make concurrent writers to it retry
+		result, err := tx.Exec(`UPDATE categories SET version = version + 1 WHERE id = ?`, int64(targetCategoryId))
+		if err != nil {
+			return fmt.Errorf("error updating target category version: %v", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error getting rows affected: %v", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("target category with id %d not found", targetCategoryId)
+		}
+
+		rows, err := tx.Query(fmt.Sprintf(`SELECT id FROM base_references WHERE id IN (%s) ORDER BY sort_key`, inClause), args...)
+		if err != nil {
+			return fmt.Errorf("error fetching references: %v", err)
+		}
+		var ordered []int64
+		for rows.Next() {
+			var refId int64
+			if err := rows.Scan(&refId); err != nil {
+				rows.Close()
+				return fmt.Errorf("error scanning reference id: %v", err)
+			}
+			ordered = append(ordered, refId)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating references: %v", err)
+		}
+
+		target := categoryReferencesGroup(targetCategoryId)
+		for _, refId := range ordered {
+			sortKey, err := target.nextKey(tx)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`UPDATE base_references SET category_id = ?, sort_key = ? WHERE id = ?`, int64(targetCategoryId), sortKey, refId)
+			if err != nil {
+				return fmt.Errorf("error moving reference: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteCategoryRepository) TagReferences(id model.Id, referenceIds []model.Id, tag model.Tag, version model.Version) error {
+	return r.bulkUpdate(id, referenceIds, version, func(tx *sql.Tx, inClause string, args []interface{}) error {
+		query := fmt.Sprintf(`INSERT OR IGNORE INTO reference_tags (reference_id, tag) SELECT id, ? FROM base_references WHERE id IN (%s)`, inClause)
+		if _, err := tx.Exec(query, append([]interface{}{string(tag)}, args...)...); err != nil {
+			return fmt.Errorf("error tagging references: %v", err)
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteCategoryRepository) UntagReferences(id model.Id, referenceIds []model.Id, tag model.Tag, version model.Version) error {
+	return r.bulkUpdate(id, referenceIds, version, func(tx *sql.Tx, inClause string, args []interface{}) error {
+		query := fmt.Sprintf(`DELETE FROM reference_tags WHERE tag = ? AND reference_id IN (%s)`, inClause)
+		if _, err := tx.Exec(query, append([]interface{}{string(tag)}, args...)...); err != nil {
+			return fmt.Errorf("error untagging references: %v", err)
+		}
+		return nil
+	})
+}
+
+// Runs a bulk operation in a single transaction, after checking that all the references belong to the category,
+// and finishes with the optimistic locking version check and bump.
+func (r *SQLiteCategoryRepository) bulkUpdate(id model.Id, referenceIds []model.Id, version model.Version, update func(tx *sql.Tx, inClause string, args []interface{}) error) error {
+	if len(referenceIds) == 0 {
+		return fmt.Errorf("no references selected")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	unique := make(map[model.Id]bool, len(referenceIds))
+	placeholders := make([]string, 0, len(referenceIds))
+	args := make([]interface{}, 0, len(referenceIds))
+	for _, refId := range referenceIds {
+		if unique[refId] {
+			continue
+		}
+		unique[refId] = true
+		placeholders = append(placeholders, "?")
+		args = append(args, int64(refId))
+	}
+	inClause := strings.Join(placeholders, ",")
+
+	var count int
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM base_references WHERE category_id = ? AND id IN (%s)`, inClause)
+	if err := tx.QueryRow(query, append([]interface{}{int64(id)}, args...)...).Scan(&count); err != nil {
+		return fmt.Errorf("error checking references: %v", err)
+	}
+	if count != len(args) {
+		return fmt.Errorf("%d of the selected references were not found in category %d", len(args)-count, id)
+	}
+
+	if err := update(tx, inClause, args); err != nil {
+		return err
 	}
 
 	err = r.updateCategoryVersion(tx, id, version)
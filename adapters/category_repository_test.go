@@ -245,6 +245,83 @@ func TestReorderReferences_FailsWithNonExistentReference(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestMoveReferenceToPosition_MovesSingleReference(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "TestCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link 1", "http://1", "desc2", false)
+	noteId := testutils.CreateTestNoteReference(t, db, catId, "Note 1", "content1", false)
+
+	cat, err := repo.GetCategoryById(catId)
+	require.NoError(t, err)
+
+	// Move the note to the front
+	err = repo.MoveReferenceToPosition(catId, noteId, 0, cat.Version)
+	require.NoError(t, err)
+
+	cat2, err := repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.Equal(t, []model.Id{noteId, bookId, linkId}, []model.Id{cat2.References[0].GetId(), cat2.References[1].GetId(), cat2.References[2].GetId()})
+	require.Equal(t, cat.Version+1, cat2.Version)
+
+	// Move the note to the end
+	err = repo.MoveReferenceToPosition(catId, noteId, 2, cat2.Version)
+	require.NoError(t, err)
+
+	cat3, err := repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.Equal(t, []model.Id{bookId, linkId, noteId}, []model.Id{cat3.References[0].GetId(), cat3.References[1].GetId(), cat3.References[2].GetId()})
+}
+
+func TestMoveReferenceToPosition_FailsWithWrongVersion(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "TestCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+	testutils.CreateTestBookReference(t, db, catId, "Book 2", "222", "desc2", false)
+
+	err := repo.MoveReferenceToPosition(catId, bookId, 1, 999) // Wrong version
+	require.Error(t, err)
+
+	cat, err := repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.Equal(t, bookId, cat.References[0].GetId())
+}
+
+func TestMoveReferenceToPosition_FailsWithInvalidPosition(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, version := testutils.CreateTestCategory(t, db, "TestCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+
+	err := repo.MoveReferenceToPosition(catId, bookId, 1, version)
+	require.Error(t, err)
+	err = repo.MoveReferenceToPosition(catId, bookId, -1, version)
+	require.Error(t, err)
+}
+
+func TestMoveReferenceToPosition_FailsWithReferenceFromOtherCategory(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, version := testutils.CreateTestCategory(t, db, "TestCat")
+	otherId, _ := testutils.CreateTestCategory(t, db, "OtherCat")
+	testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+	otherBookId := testutils.CreateTestBookReference(t, db, otherId, "Book 2", "222", "desc2", false)
+
+	err := repo.MoveReferenceToPosition(catId, otherBookId, 0, version)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not found")
+}
+
 func TestAddBookReference(t *testing.T) {
 	db, cleanup := testutils.SetupTestDB(t)
 	defer cleanup()
@@ -266,6 +343,7 @@ func TestAddBookReference(t *testing.T) {
 	require.Equal(t, "New Book", string(addedBook.Title()))
 	require.Equal(t, "123-456", string(addedBook.ISBN))
 	require.Equal(t, "Test description", addedBook.Description)
+	require.True(t, addedBook.Starred())
 }
 
 func TestAddLinkReference(t *testing.T) {
@@ -312,6 +390,25 @@ func TestAddNoteReference(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, "New Note", string(addedNote.Title()))
 	require.Equal(t, "Test note content", addedNote.Text)
+	require.True(t, addedNote.Starred())
+}
+
+func TestAddReferenceSavesTags(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, version := testutils.CreateTestCategory(t, db, "TestCat")
+	book := model.NewBookReference(0, "Tagged Book", "9781985086593", "", false)
+	book.SetTags([]model.Tag{"to-read", "favorites"})
+
+	err := repo.AddReference(catId, book, version)
+	require.NoError(t, err)
+
+	cat, err := repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.Len(t, cat.References, 1)
+	require.Equal(t, []model.Tag{"favorites", "to-read"}, cat.References[0].Tags())
 }
 
 func TestAddReferenceAssignsSequentialPositions(t *testing.T) {
@@ -462,3 +559,172 @@ func TestRemoveReference_FailsWithNonExistentReference(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "not found")
 }
+
+func TestSetReferencesStarred_StarsAndUnstarsSelectedReferences(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, version := testutils.CreateTestCategory(t, db, "TestCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link 1", "http://1", "desc2", false)
+	noteId := testutils.CreateTestNoteReference(t, db, catId, "Note 1", "content1", false)
+
+	err := repo.SetReferencesStarred(catId, []model.Id{bookId, noteId}, true, version)
+	require.NoError(t, err)
+
+	cat, err := repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.True(t, cat.References[0].Starred())
+	require.False(t, cat.References[1].Starred())
+	require.True(t, cat.References[2].Starred())
+	require.Equal(t, version+1, cat.Version)
+
+	err = repo.SetReferencesStarred(catId, []model.Id{bookId, linkId}, false, cat.Version)
+	require.NoError(t, err)
+
+	cat, err = repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.False(t, cat.References[0].Starred())
+	require.False(t, cat.References[1].Starred())
+	require.True(t, cat.References[2].Starred())
+}
+
+func TestSetReferencesStarred_FailsWithWrongVersion(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "TestCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+
+	err := repo.SetReferencesStarred(catId, []model.Id{bookId}, true, 999) // Wrong version
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "version")
+
+	cat, err := repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.False(t, cat.References[0].Starred())
+}
+
+func TestBulkOperations_FailWithReferenceFromOtherCategory(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, version := testutils.CreateTestCategory(t, db, "TestCat")
+	otherId, _ := testutils.CreateTestCategory(t, db, "OtherCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+	otherBookId := testutils.CreateTestBookReference(t, db, otherId, "Book 2", "222", "desc2", false)
+
+	err := repo.RemoveReferences(catId, []model.Id{bookId, otherBookId}, version)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not found")
+
+	// nothing was deleted
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM base_references`).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	err = repo.SetReferencesStarred(catId, nil, true, version)
+	require.Error(t, err)
+}
+
+func TestRemoveReferences_RemovesSelectedReferences(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, version := testutils.CreateTestCategory(t, db, "TestCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link 1", "http://1", "desc2", false)
+	noteId := testutils.CreateTestNoteReference(t, db, catId, "Note 1", "content1", false)
+
+	err := repo.RemoveReferences(catId, []model.Id{bookId, noteId}, version)
+	require.NoError(t, err)
+
+	cat, err := repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.Len(t, cat.References, 1)
+	require.Equal(t, linkId, cat.References[0].GetId())
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM book_references WHERE reference_id = ?`, bookId).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
+
+func TestMoveReferences_AppendsToTargetCategoryInOrder(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, version := testutils.CreateTestCategory(t, db, "TestCat")
+	targetId, targetVersion := testutils.CreateTestCategory(t, db, "TargetCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link 1", "http://1", "desc2", false)
+	noteId := testutils.CreateTestNoteReference(t, db, catId, "Note 1", "content1", false)
+	existingId := testutils.CreateTestNoteReference(t, db, targetId, "Existing", "content2", false)
+
+	// the order of the ids doesn't matter, the references keep their relative order
+	err := repo.MoveReferences(catId, []model.Id{noteId, bookId}, targetId, version)
+	require.NoError(t, err)
+
+	source, err := repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.Len(t, source.References, 1)
+	require.Equal(t, linkId, source.References[0].GetId())
+
+	target, err := repo.GetCategoryById(targetId)
+	require.NoError(t, err)
+	require.Equal(t, []model.Id{existingId, bookId, noteId}, []model.Id{target.References[0].GetId(), target.References[1].GetId(), target.References[2].GetId()})
+	require.Equal(t, targetVersion+1, target.Version)
+}
+
+func TestMoveReferences_FailsForInvalidTarget(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, version := testutils.CreateTestCategory(t, db, "TestCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+
+	err := repo.MoveReferences(catId, []model.Id{bookId}, model.Id(999), version)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not found")
+
+	err = repo.MoveReferences(catId, []model.Id{bookId}, catId, version)
+	require.Error(t, err)
+}
+
+func TestTagReferences_AddsAndRemovesTags(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryRepository(db)
+
+	catId, version := testutils.CreateTestCategory(t, db, "TestCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book 1", "111", "desc1", false)
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link 1", "http://1", "desc2", false)
+
+	err := repo.TagReferences(catId, []model.Id{bookId, linkId}, "to-read", version)
+	require.NoError(t, err)
+	err = repo.TagReferences(catId, []model.Id{bookId}, "classic", version+1)
+	require.NoError(t, err)
+	// tagging again is a no-op for the tags
+	err = repo.TagReferences(catId, []model.Id{bookId}, "classic", version+2)
+	require.NoError(t, err)
+
+	cat, err := repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.Equal(t, []model.Tag{"classic", "to-read"}, cat.References[0].Tags())
+	require.Equal(t, []model.Tag{"to-read"}, cat.References[1].Tags())
+
+	err = repo.UntagReferences(catId, []model.Id{bookId, linkId}, "to-read", cat.Version)
+	require.NoError(t, err)
+
+	cat, err = repo.GetCategoryById(catId)
+	require.NoError(t, err)
+	require.Equal(t, []model.Tag{"classic"}, cat.References[0].Tags())
+	require.Empty(t, cat.References[1].Tags())
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/VladMinzatu/reference-manager/domain/model"
-	"github.com/VladMinzatu/reference-manager/domain/util"
 )
 
 type SQLiteCategoryListRepository struct {
@@ -17,7 +16,19 @@ func NewSQLiteCategoryListRepository(db *sql.DB) *SQLiteCategoryListRepository {
 }
 
 func (r *SQLiteCategoryListRepository) GetAllCategoryRefs() ([]model.CategoryRef, error) {
-	rows, err := r.db.Query(`SELECT id, name FROM categories ORDER BY position`)
+	// The path is built from the sort keys, which never contain '/' (it sorts before all key digits),
+	// so ordering by it yields a depth-first traversal of the tree
+	rows, err := r.db.Query(`
+		WITH RECURSIVE tree(id, name, parent_id, path) AS (
+			SELECT id, name, parent_id, sort_key
+			FROM categories
+			WHERE parent_id IS NULL
+			UNION ALL
+			SELECT c.id, c.name, c.parent_id, tree.path || '/' || c.sort_key
+			FROM categories c
+			JOIN tree ON c.parent_id = tree.id
+		)
+		SELECT id, name, parent_id FROM tree ORDER BY path`)
 	if err != nil {
 		return nil, fmt.Errorf("error querying categories: %v", err)
 	}
@@ -27,7 +38,8 @@ func (r *SQLiteCategoryListRepository) GetAllCategoryRefs() ([]model.CategoryRef
 	for rows.Next() {
 		var id int64
 		var name string
-		if err := rows.Scan(&id, &name); err != nil {
+		var parentId sql.NullInt64
+		if err := rows.Scan(&id, &name, &parentId); err != nil {
 			return nil, fmt.Errorf("error scanning category: %v", err)
 		}
 		catId, err := model.NewId(id)
@@ -38,44 +50,94 @@ func (r *SQLiteCategoryListRepository) GetAllCategoryRefs() ([]model.CategoryRef
 		if err != nil {
 			return nil, fmt.Errorf("invalid title: %v", err)
 		}
-		refs = append(refs, model.CategoryRef{Id: catId, Name: title})
+		refs = append(refs, model.CategoryRef{Id: catId, Name: title, ParentId: model.Id(parentId.Int64)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating categories: %v", err)
 	}
 	return refs, nil
 }
 
 func (r *SQLiteCategoryListRepository) AddNewCategory(name model.Title) (model.Category, error) {
+	return r.addCategory(0, name)
+}
+
+func (r *SQLiteCategoryListRepository) AddNewSubcategory(parentId model.Id, name model.Title) (model.Category, error) {
+	if _, err := model.NewId(int64(parentId)); err != nil {
+		return model.Category{}, fmt.Errorf("invalid parent id: %v", err)
+	}
+	return r.addCategory(parentId, name)
+}
+
+func (r *SQLiteCategoryListRepository) addCategory(parentId model.Id, name model.Title) (model.Category, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return model.Category{}, fmt.Errorf("error beginning transaction: %v", err)
 	}
 	defer tx.Rollback()
 
+	catId, err := insertCategory(tx, parentId, name)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.Category{}, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return model.Category{Id: catId, Name: name}, nil
+}
+
+// Inserts the category as the last child of its parent (or as the last top-level category, for a zero parentId)
+func insertCategory(tx *sql.Tx, parentId model.Id, name model.Title) (model.Id, error) {
 	if _, err := model.NewTitle(string(name)); err != nil {
-		return model.Category{}, fmt.Errorf("invalid title: %v", err)
+		return 0, fmt.Errorf("invalid title: %v", err)
 	}
 
 	// Note: This logic is safe in SQLite because all writers are serialized.
 	// In e.g. Postgres, we would need row/table-level locking via SELECT...FOR UPDATE prior to this statement
 	// (sequences or separate table with table-level locking are also options, but with sqlite, we can keep it simple)
-	result, err := tx.Exec(`INSERT INTO categories (name, position) SELECT ?, COALESCE(MAX(position) + 1, 0) FROM categories`, string(name))
+	sortKey, err := categoryChildrenGroup(parentId).nextKey(tx)
 	if err != nil {
-		return model.Category{}, fmt.Errorf("error inserting category: %v", err)
+		return 0, err
 	}
-
-	id, err := result.LastInsertId()
+	result, err := tx.Exec(`
+		INSERT INTO categories (name, parent_id, sort_key)
+		SELECT ?, ?, ?
+		WHERE ? IS NULL OR EXISTS (SELECT 1 FROM categories WHERE id = ?)`,
+		string(name), parentParam(parentId), sortKey, parentParam(parentId), parentParam(parentId))
 	if err != nil {
-		return model.Category{}, fmt.Errorf("error getting last insert id: %v", err)
+		return 0, fmt.Errorf("error inserting category: %v", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return model.Category{}, fmt.Errorf("error committing transaction: %v", err)
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return 0, fmt.Errorf("parent category with id %d not found", parentId)
 	}
 
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting last insert id: %v", err)
+	}
 	catId, _ := model.NewId(id)
-	return model.Category{Id: catId, Name: name}, nil
+	return catId, nil
 }
 
 func (r *SQLiteCategoryListRepository) ReorderCategories(positions map[model.Id]int) error {
+	return r.reorderChildren(0, positions)
+}
+
+func (r *SQLiteCategoryListRepository) ReorderSubcategories(parentId model.Id, positions map[model.Id]int) error {
+	if _, err := model.NewId(int64(parentId)); err != nil {
+		return fmt.Errorf("invalid parent id: %v", err)
+	}
+	return r.reorderChildren(parentId, positions)
+}
+
+func (r *SQLiteCategoryListRepository) reorderChildren(parentId model.Id, positions map[model.Id]int) error {
 	// This is the one place in the code where I violate my pledge to design with fine granularity of locking and concurrency in mind (see more details in README)
 	// If we were using e.g. Postgres, we would start off the transaction with a SELECT...FOR UPDATE on our categories and that would be sufficient, even in case of new categories being added concurrently (due to how the reordering logic works).
 	// Of course, even with SQLite, there are other options as well - we could use a separate table to lock or version our categories list for example.
@@ -87,69 +149,125 @@ func (r *SQLiteCategoryListRepository) ReorderCategories(positions map[model.Id]
 	}
 	defer tx.Rollback()
 
-	rows, err := tx.Query(`SELECT id FROM categories`)
+	if err := categoryChildrenGroup(parentId).reorder(tx, positions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteCategoryListRepository) MoveCategoryToPosition(id model.Id, position int) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("error fetching category ids: %v", err)
+		return fmt.Errorf("error beginning transaction: %v", err)
 	}
-	var ids []model.Id
-	for rows.Next() {
-		var id int64
-		if err := rows.Scan(&id); err != nil {
-			return fmt.Errorf("error scanning id: %v", err)
-		}
-		ids = append(ids, model.Id(id))
+	defer tx.Rollback()
+
+	var parentId sql.NullInt64
+	err = tx.QueryRow(`SELECT parent_id FROM categories WHERE id = ?`, int64(id)).Scan(&parentId)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("category with id %d not found", id)
 	}
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating ids: %v", err)
+	if err != nil {
+		return fmt.Errorf("error fetching category: %v", err)
 	}
 
-	if err := util.ValidatePositions(ids, positions); err != nil {
-		return err
+	// Only the moved category gets a new sort key, so categories added concurrently don't make the move fail
+	if err := categoryChildrenGroup(model.Id(parentId.Int64)).move(tx, id, position); err != nil {
+		return fmt.Errorf("error moving category %d: %v", id, err)
 	}
 
-	// Helper function to build the UPDATE statement for category positions
-	buildUpdatePositionsQuery := func(positions map[model.Id]int) (string, []interface{}) {
-		query := `UPDATE categories SET position = CASE id`
-		args := []interface{}{}
-		for id, pos := range positions {
-			query += " WHEN ? THEN ?"
-			args = append(args, int64(id), pos)
-		}
-		query += " END WHERE id IN ("
-		first := true
-		for id := range positions {
-			if !first {
-				query += ","
-			}
-			query += "?"
-			args = append(args, int64(id))
-			first = false
-		}
-		query += ")"
-		return query, args
+	return tx.Commit()
+}
+
+func (r *SQLiteCategoryListRepository) ReparentCategory(id model.Id, newParentId model.Id) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
 	}
+	defer tx.Rollback()
 
-	// Step 1: Set all positions to negative values to avoid unique constraint violation
-	negPositions := make(map[model.Id]int, len(positions))
-	for id, pos := range positions {
-		negPositions[id] = -pos - 1 // -1 to avoid unique constraint violation for position=0
+	if err := reparentCategory(tx, id, newParentId); err != nil {
+		return err
 	}
-	negQuery, negArgs := buildUpdatePositionsQuery(negPositions)
-	_, err = tx.Exec(negQuery, negArgs...)
+
+	return tx.Commit()
+}
+
+func (r *SQLiteCategoryListRepository) RenameAndReparentCategory(id model.Id, name model.Title, newParentId model.Id) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("error setting negative positions: %v", err)
+		return fmt.Errorf("error beginning transaction: %v", err)
 	}
+	defer tx.Rollback()
 
-	// Step 2: Set positions to their intended positive values
-	posQuery, posArgs := buildUpdatePositionsQuery(positions)
-	_, err = tx.Exec(posQuery, posArgs...)
+	result, err := tx.Exec(`UPDATE categories SET name = ?, version = version + 1 WHERE id = ?`, name, int64(id))
 	if err != nil {
-		return fmt.Errorf("error updating category positions: %v", err)
+		return fmt.Errorf("error updating category title: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("category with id %d not found", id)
+	}
+	if err := reparentCategory(tx, id, newParentId); err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
 
+func reparentCategory(tx *sql.Tx, id model.Id, newParentId model.Id) error {
+	var oldParentId sql.NullInt64
+	err := tx.QueryRow(`SELECT parent_id FROM categories WHERE id = ?`, int64(id)).Scan(&oldParentId)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("category with id %d not found", id)
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching category: %v", err)
+	}
+	if model.Id(oldParentId.Int64) == newParentId {
+		return nil
+	}
+
+	if newParentId != 0 {
+		// The new parent must exist and must not be the category itself or one of its descendants
+		var exists, isDescendant bool
+		err = tx.QueryRow(`
+			WITH RECURSIVE subtree(id) AS (
+				SELECT ?
+				UNION ALL
+				SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+			)
+			SELECT
+				EXISTS (SELECT 1 FROM categories WHERE id = ?),
+				EXISTS (SELECT 1 FROM subtree WHERE id = ?)`,
+			int64(id), int64(newParentId), int64(newParentId)).Scan(&exists, &isDescendant)
+		if err != nil {
+			return fmt.Errorf("error checking new parent category: %v", err)
+		}
+		if !exists {
+			return fmt.Errorf("parent category with id %d not found", newParentId)
+		}
+		if isDescendant {
+			return model.ErrCategoryCycle
+		}
+	}
+
+	// The category is appended to its new siblings; the old siblings keep their keys
+	sortKey, err := categoryChildrenGroup(newParentId).nextKey(tx)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE categories SET parent_id = ?, sort_key = ? WHERE id = ?`, parentParam(newParentId), sortKey, int64(id))
+	if err != nil {
+		return fmt.Errorf("error moving category: %v", err)
+	}
+	return nil
+}
+
 func (r *SQLiteCategoryListRepository) DeleteCategory(id model.Id) error {
 	tx, err := r.db.Begin()
 	if err != nil {
@@ -157,7 +275,16 @@ func (r *SQLiteCategoryListRepository) DeleteCategory(id model.Id) error {
 	}
 	defer tx.Rollback()
 
-	result, err := tx.Exec(`DELETE FROM categories WHERE id = ?`, int64(id))
+	// the remaining siblings keep their sort keys, so there is nothing to renumber.
+	// the whole subtree is deleted here rather than left to ON DELETE CASCADE, so no subcategory can be left
+	// pointing at a deleted parent. references are removed by the ON DELETE CASCADE constraints
+	result, err := tx.Exec(`
+		WITH RECURSIVE subtree(id) AS (
+			SELECT ?
+			UNION ALL
+			SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+		)
+		DELETE FROM categories WHERE id IN (SELECT id FROM subtree)`, int64(id))
 	if err != nil {
 		return fmt.Errorf("error deleting category: %v", err)
 	}
@@ -169,21 +296,13 @@ func (r *SQLiteCategoryListRepository) DeleteCategory(id model.Id) error {
 		return fmt.Errorf("category with id %d not found", id)
 	}
 
-	// Reorder remaining categories to close any gaps
-	// again, as in the other methods, we're taking a shortcut here afforded by sqlite
-	// we'd need to use e.g. row-level locking for this if we were using e.g. Postgres.
-	_, err = tx.Exec(`
-		WITH ranked AS (
-			SELECT id, ROW_NUMBER() OVER (ORDER BY position) - 1 as new_pos
-			FROM categories
-		)
-		UPDATE categories
-		SET position = ranked.new_pos
-		FROM ranked
-		WHERE categories.id = ranked.id`)
-	if err != nil {
-		return fmt.Errorf("error reordering remaining categories: %v", err)
-	}
-
 	return tx.Commit()
 }
+
+// parentParam maps the zero Id used for top-level categories to NULL
+func parentParam(parentId model.Id) interface{} {
+	if parentId == 0 {
+		return nil
+	}
+	return int64(parentId)
+}
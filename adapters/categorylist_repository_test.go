@@ -1,9 +1,11 @@
 package adapters
 
 import (
+	"database/sql"
 	"testing"
 
 	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/util"
 	"github.com/VladMinzatu/reference-manager/testutils"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/require"
@@ -20,7 +22,7 @@ func TestGetAllCategoryRefs(t *testing.T) {
 		require.Empty(t, refs)
 	})
 
-	t.Run("returns all categories ordered by position", func(t *testing.T) {
+	t.Run("returns all categories in order", func(t *testing.T) {
 		cat1, err := repo.AddNewCategory("Cat1")
 		require.NoError(t, err)
 		cat2, err := repo.AddNewCategory("Cat2")
@@ -41,21 +43,20 @@ func TestAddNewCategory(t *testing.T) {
 	defer cleanup()
 	repo := NewSQLiteCategoryListRepository(db)
 
-	t.Run("creates category with correct name and position", func(t *testing.T) {
+	t.Run("creates category with correct name and sort key", func(t *testing.T) {
 		cat, err := repo.AddNewCategory("Test Category")
 		require.NoError(t, err)
 		require.Equal(t, "Test Category", string(cat.Name))
 		require.NotZero(t, cat.Id)
 
-		var name string
-		var position int
-		err = db.QueryRow(`SELECT name, position FROM categories WHERE id = ?`, cat.Id).Scan(&name, &position)
+		var name, sortKey string
+		err = db.QueryRow(`SELECT name, sort_key FROM categories WHERE id = ?`, cat.Id).Scan(&name, &sortKey)
 		require.NoError(t, err)
 		require.Equal(t, "Test Category", name)
-		require.Equal(t, 0, position)
+		require.NotEmpty(t, sortKey)
 	})
 
-	t.Run("assigns sequential positions to multiple categories", func(t *testing.T) {
+	t.Run("assigns increasing sort keys to multiple categories", func(t *testing.T) {
 		cat1, err := repo.AddNewCategory("First")
 		require.NoError(t, err)
 		cat2, err := repo.AddNewCategory("Second")
@@ -63,18 +64,12 @@ func TestAddNewCategory(t *testing.T) {
 		cat3, err := repo.AddNewCategory("Third")
 		require.NoError(t, err)
 
-		// Check positions in database
-		var pos1, pos2, pos3 int
-		err = db.QueryRow(`SELECT position FROM categories WHERE id = ?`, cat1.Id).Scan(&pos1)
-		require.NoError(t, err)
-		err = db.QueryRow(`SELECT position FROM categories WHERE id = ?`, cat2.Id).Scan(&pos2)
-		require.NoError(t, err)
-		err = db.QueryRow(`SELECT position FROM categories WHERE id = ?`, cat3.Id).Scan(&pos3)
-		require.NoError(t, err)
-
-		require.Equal(t, 1, pos1)
-		require.Equal(t, 2, pos2)
-		require.Equal(t, 3, pos3)
+		// Check sort keys in database
+		key1 := categorySortKey(t, db, cat1.Id)
+		key2 := categorySortKey(t, db, cat2.Id)
+		key3 := categorySortKey(t, db, cat3.Id)
+		require.Less(t, key1, key2)
+		require.Less(t, key2, key3)
 	})
 
 	t.Run("handles empty category name", func(t *testing.T) {
@@ -112,7 +107,7 @@ func TestReorderCategoriesCorrectly(t *testing.T) {
 	err := repo.ReorderCategories(positions)
 	require.NoError(t, err)
 
-	rows, err := db.Query(`SELECT name FROM categories ORDER BY position`)
+	rows, err := db.Query(`SELECT name FROM categories ORDER BY sort_key`)
 	require.NoError(t, err)
 	defer rows.Close()
 
@@ -218,6 +213,8 @@ func TestDeleteMiddleCategory(t *testing.T) {
 	cat2, _ := repo.AddNewCategory("Second")
 	cat3, _ := repo.AddNewCategory("Third")
 
+	key1, key3 := categorySortKey(t, db, cat1.Id), categorySortKey(t, db, cat3.Id)
+
 	// Delete the middle category
 	err := repo.DeleteCategory(cat2.Id)
 	require.NoError(t, err)
@@ -227,8 +224,8 @@ func TestDeleteMiddleCategory(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 0, count)
 
-	// Verify remaining categories are reordered
-	rows, err := db.Query(`SELECT name FROM categories ORDER BY position`)
+	// Verify remaining categories keep their order
+	rows, err := db.Query(`SELECT name FROM categories ORDER BY sort_key`)
 	require.NoError(t, err)
 	defer rows.Close()
 
@@ -240,14 +237,9 @@ func TestDeleteMiddleCategory(t *testing.T) {
 	}
 	require.Equal(t, []string{"First", "Third"}, names)
 
-	// Verify positions are sequential
-	var pos1, pos3 int
-	err = db.QueryRow(`SELECT position FROM categories WHERE id = ?`, cat1.Id).Scan(&pos1)
-	require.NoError(t, err)
-	err = db.QueryRow(`SELECT position FROM categories WHERE id = ?`, cat3.Id).Scan(&pos3)
-	require.NoError(t, err)
-	require.Equal(t, 0, pos1)
-	require.Equal(t, 1, pos3)
+	// Verify the remaining categories were not renumbered
+	require.Equal(t, key1, categorySortKey(t, db, cat1.Id))
+	require.Equal(t, key3, categorySortKey(t, db, cat3.Id))
 }
 
 func TestDeleteLastCategory(t *testing.T) {
@@ -257,11 +249,12 @@ func TestDeleteLastCategory(t *testing.T) {
 
 	cat1, _ := repo.AddNewCategory("First")
 	cat2, _ := repo.AddNewCategory("Second")
+	key1 := categorySortKey(t, db, cat1.Id)
 
 	err := repo.DeleteCategory(cat2.Id)
 	require.NoError(t, err)
 
-	rows, err := db.Query(`SELECT name FROM categories ORDER BY position`)
+	rows, err := db.Query(`SELECT name FROM categories ORDER BY sort_key`)
 	require.NoError(t, err)
 	defer rows.Close()
 
@@ -273,10 +266,7 @@ func TestDeleteLastCategory(t *testing.T) {
 	}
 	require.Equal(t, []string{"First"}, names)
 
-	var pos int
-	err = db.QueryRow(`SELECT position FROM categories WHERE id = ?`, cat1.Id).Scan(&pos)
-	require.NoError(t, err)
-	require.Equal(t, 0, pos)
+	require.Equal(t, key1, categorySortKey(t, db, cat1.Id))
 }
 
 func TestDeleteCategoryWithReferences(t *testing.T) {
@@ -300,3 +290,341 @@ func TestDeleteCategoryWithReferences(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 0, count)
 }
+
+func TestAddNewSubcategory(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryListRepository(db)
+
+	parent, _ := repo.AddNewCategory("Databases")
+	other, _ := repo.AddNewCategory("Networking")
+
+	t.Run("assigns sort keys per parent", func(t *testing.T) {
+		sub1, err := repo.AddNewSubcategory(parent.Id, "Storage engines")
+		require.NoError(t, err)
+		sub2, err := repo.AddNewSubcategory(parent.Id, "Query optimisation")
+		require.NoError(t, err)
+
+		var parentId int64
+		err = db.QueryRow(`SELECT parent_id FROM categories WHERE id = ?`, sub1.Id).Scan(&parentId)
+		require.NoError(t, err)
+		require.Equal(t, int64(parent.Id), parentId)
+		require.Less(t, categorySortKey(t, db, sub1.Id), categorySortKey(t, db, sub2.Id))
+	})
+
+	t.Run("returns categories in depth-first order", func(t *testing.T) {
+		refs, err := repo.GetAllCategoryRefs()
+		require.NoError(t, err)
+		var names []string
+		for _, ref := range refs {
+			names = append(names, string(ref.Name))
+		}
+		require.Equal(t, []string{"Databases", "Storage engines", "Query optimisation", "Networking"}, names)
+		require.Equal(t, parent.Id, refs[1].ParentId)
+		require.True(t, refs[3].IsTopLevel())
+		require.Equal(t, other.Id, refs[3].Id)
+	})
+
+	t.Run("fails for non-existent parent", func(t *testing.T) {
+		_, err := repo.AddNewSubcategory(model.Id(999), "Orphan")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found")
+	})
+}
+
+func TestReorderSubcategories(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryListRepository(db)
+
+	parent, _ := repo.AddNewCategory("Parent")
+	sub1, _ := repo.AddNewSubcategory(parent.Id, "First")
+	sub2, _ := repo.AddNewSubcategory(parent.Id, "Second")
+
+	t.Run("top-level reorder only accepts top-level categories", func(t *testing.T) {
+		err := repo.ReorderCategories(map[model.Id]int{parent.Id: 0, sub1.Id: 1, sub2.Id: 2})
+		require.Error(t, err)
+	})
+
+	t.Run("reorders the children of a parent", func(t *testing.T) {
+		err := repo.ReorderSubcategories(parent.Id, map[model.Id]int{sub1.Id: 1, sub2.Id: 0})
+		require.NoError(t, err)
+
+		refs, err := repo.GetAllCategoryRefs()
+		require.NoError(t, err)
+		require.Equal(t, []model.Id{parent.Id, sub2.Id, sub1.Id}, []model.Id{refs[0].Id, refs[1].Id, refs[2].Id})
+	})
+}
+
+func TestReparentCategory(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryListRepository(db)
+
+	a, _ := repo.AddNewCategory("A")
+	b, _ := repo.AddNewCategory("B")
+	c, _ := repo.AddNewCategory("C")
+	aChild, _ := repo.AddNewSubcategory(a.Id, "A child")
+
+	t.Run("moves a category to the end of a new parent", func(t *testing.T) {
+		cKey := categorySortKey(t, db, c.Id)
+		err := repo.ReparentCategory(b.Id, a.Id)
+		require.NoError(t, err)
+
+		var parentId int64
+		err = db.QueryRow(`SELECT parent_id FROM categories WHERE id = ?`, b.Id).Scan(&parentId)
+		require.NoError(t, err)
+		require.Equal(t, int64(a.Id), parentId)
+		require.Less(t, categorySortKey(t, db, aChild.Id), categorySortKey(t, db, b.Id))
+
+		// the old siblings are left untouched
+		require.Equal(t, cKey, categorySortKey(t, db, c.Id))
+	})
+
+	t.Run("prevents moving a category under itself", func(t *testing.T) {
+		err := repo.ReparentCategory(a.Id, a.Id)
+		require.ErrorIs(t, err, model.ErrCategoryCycle)
+	})
+
+	t.Run("prevents moving a category under one of its descendants", func(t *testing.T) {
+		err := repo.ReparentCategory(a.Id, aChild.Id)
+		require.ErrorIs(t, err, model.ErrCategoryCycle)
+	})
+
+	t.Run("moves a subcategory back to the top level", func(t *testing.T) {
+		err := repo.ReparentCategory(b.Id, 0)
+		require.NoError(t, err)
+
+		refs, err := repo.GetAllCategoryRefs()
+		require.NoError(t, err)
+		require.Equal(t, []model.Id{a.Id, aChild.Id, c.Id, b.Id}, []model.Id{refs[0].Id, refs[1].Id, refs[2].Id, refs[3].Id})
+	})
+
+	t.Run("fails for non-existent parent", func(t *testing.T) {
+		err := repo.ReparentCategory(c.Id, model.Id(999))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found")
+	})
+}
+
+func TestRenameAndReparentCategory(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryListRepository(db)
+	categoryRepo := NewSQLiteCategoryRepository(db)
+
+	a, _ := repo.AddNewCategory("A")
+	b, _ := repo.AddNewCategory("B")
+	aChild, _ := repo.AddNewSubcategory(a.Id, "A child")
+	version := func(id model.Id) model.Version {
+		category, err := categoryRepo.GetCategoryById(id)
+		require.NoError(t, err)
+		return category.Version
+	}
+
+	t.Run("renames and moves a category", func(t *testing.T) {
+		bVersion := version(b.Id)
+		err := repo.RenameAndReparentCategory(b.Id, "Renamed", a.Id)
+		require.NoError(t, err)
+
+		category, err := categoryRepo.GetCategoryById(b.Id)
+		require.NoError(t, err)
+		require.Equal(t, model.Title("Renamed"), category.Name)
+		require.Equal(t, bVersion+1, category.Version)
+		var parentId int64
+		err = db.QueryRow(`SELECT parent_id FROM categories WHERE id = ?`, b.Id).Scan(&parentId)
+		require.NoError(t, err)
+		require.Equal(t, int64(a.Id), parentId)
+	})
+
+	t.Run("leaves the name unchanged if the move fails", func(t *testing.T) {
+		aVersion := version(a.Id)
+		err := repo.RenameAndReparentCategory(a.Id, "Not applied", aChild.Id)
+		require.ErrorIs(t, err, model.ErrCategoryCycle)
+
+		category, err := categoryRepo.GetCategoryById(a.Id)
+		require.NoError(t, err)
+		require.Equal(t, model.Title("A"), category.Name)
+		require.Equal(t, aVersion, category.Version)
+	})
+
+	t.Run("fails for non-existent category", func(t *testing.T) {
+		err := repo.RenameAndReparentCategory(model.Id(999), "Missing", 0)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found")
+	})
+}
+
+func TestDeleteCategoryWithSubcategories(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryListRepository(db)
+
+	parent, _ := repo.AddNewCategory("Parent")
+	sub, _ := repo.AddNewSubcategory(parent.Id, "Sub")
+	testutils.CreateTestBookReference(t, db, sub.Id, "Book1", "123", "desc", false)
+	other, _ := repo.AddNewCategory("Other")
+	otherKey := categorySortKey(t, db, other.Id)
+
+	err := repo.DeleteCategory(parent.Id)
+	require.NoError(t, err)
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM categories WHERE id IN (?, ?)`, parent.Id, sub.Id).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	err = db.QueryRow(`SELECT COUNT(*) FROM base_references WHERE category_id = ?`, sub.Id).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	require.Equal(t, otherKey, categorySortKey(t, db, other.Id))
+}
+
+func TestDeleteCategoryWithNestedSubcategoriesWithoutForeignKeys(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryListRepository(db)
+
+	parent, _ := repo.AddNewCategory("Parent")
+	sub, _ := repo.AddNewSubcategory(parent.Id, "Sub")
+	subsub, _ := repo.AddNewSubcategory(sub.Id, "SubSub")
+	other, _ := repo.AddNewCategory("Other")
+
+	// a single connection, so the delete runs on the one without foreign keys
+	db.SetMaxOpenConns(1)
+	_, err := db.Exec("PRAGMA foreign_keys = OFF")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteCategory(parent.Id))
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM categories WHERE id IN (?, ?, ?)`, parent.Id, sub.Id, subsub.Id).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	refs, err := repo.GetAllCategoryRefs()
+	require.NoError(t, err)
+	require.Len(t, refs, 1)
+	require.Equal(t, other.Id, refs[0].Id)
+}
+
+func TestMoveCategoryToPosition(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryListRepository(db)
+
+	a, _ := repo.AddNewCategory("A")
+	b, _ := repo.AddNewCategory("B")
+	c, _ := repo.AddNewCategory("C")
+	child1, _ := repo.AddNewSubcategory(a.Id, "Child 1")
+	child2, _ := repo.AddNewSubcategory(a.Id, "Child 2")
+
+	order := func() []model.Id {
+		refs, err := repo.GetAllCategoryRefs()
+		require.NoError(t, err)
+		var ids []model.Id
+		for _, ref := range refs {
+			ids = append(ids, ref.Id)
+		}
+		return ids
+	}
+
+	t.Run("moves a top-level category and only changes its sort key", func(t *testing.T) {
+		aKey, bKey := categorySortKey(t, db, a.Id), categorySortKey(t, db, b.Id)
+		err := repo.MoveCategoryToPosition(c.Id, 1)
+		require.NoError(t, err)
+		require.Equal(t, []model.Id{a.Id, child1.Id, child2.Id, c.Id, b.Id}, order())
+		require.Equal(t, aKey, categorySortKey(t, db, a.Id))
+		require.Equal(t, bKey, categorySortKey(t, db, b.Id))
+	})
+
+	t.Run("moves a subcategory within its parent", func(t *testing.T) {
+		err := repo.MoveCategoryToPosition(child2.Id, 0)
+		require.NoError(t, err)
+		require.Equal(t, []model.Id{a.Id, child2.Id, child1.Id, c.Id, b.Id}, order())
+	})
+
+	t.Run("moves a category with its subcategories", func(t *testing.T) {
+		err := repo.MoveCategoryToPosition(a.Id, 2)
+		require.NoError(t, err)
+		require.Equal(t, []model.Id{c.Id, b.Id, a.Id, child2.Id, child1.Id}, order())
+	})
+
+	t.Run("fails for a position outside the sibling list", func(t *testing.T) {
+		err := repo.MoveCategoryToPosition(child1.Id, 2)
+		require.Error(t, err)
+		err = repo.MoveCategoryToPosition(b.Id, -1)
+		require.Error(t, err)
+	})
+
+	t.Run("fails for non-existent category", func(t *testing.T) {
+		err := repo.MoveCategoryToPosition(model.Id(999), 0)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found")
+	})
+}
+
+func TestReorderCategoriesOnlyRewritesMovedKeys(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryListRepository(db)
+
+	var ids []model.Id
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		cat, err := repo.AddNewCategory(model.Title(name))
+		require.NoError(t, err)
+		ids = append(ids, cat.Id)
+	}
+	keysBefore := make(map[model.Id]string)
+	for _, id := range ids {
+		keysBefore[id] = categorySortKey(t, db, id)
+	}
+
+	// move E to the front
+	err := repo.ReorderCategories(map[model.Id]int{ids[4]: 0, ids[0]: 1, ids[1]: 2, ids[2]: 3, ids[3]: 4})
+	require.NoError(t, err)
+
+	refs, err := repo.GetAllCategoryRefs()
+	require.NoError(t, err)
+	require.Equal(t, []model.Id{ids[4], ids[0], ids[1], ids[2], ids[3]}, []model.Id{refs[0].Id, refs[1].Id, refs[2].Id, refs[3].Id, refs[4].Id})
+	for _, id := range ids[:4] {
+		require.Equal(t, keysBefore[id], categorySortKey(t, db, id))
+	}
+	require.NotEqual(t, keysBefore[ids[4]], categorySortKey(t, db, ids[4]))
+}
+
+func TestRepeatedMovesRebalanceSortKeys(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCategoryListRepository(db)
+
+	var ids []model.Id
+	for _, name := range []string{"A", "B", "C"} {
+		cat, err := repo.AddNewCategory(model.Title(name))
+		require.NoError(t, err)
+		ids = append(ids, cat.Id)
+	}
+
+	// repeatedly swapping the last two categories always squeezes a key right after the first one
+	for i := 0; i < 300; i++ {
+		ids[1], ids[2] = ids[2], ids[1]
+		err := repo.ReorderCategories(map[model.Id]int{ids[0]: 0, ids[1]: 1, ids[2]: 2})
+		require.NoError(t, err)
+
+		for _, id := range ids {
+			require.LessOrEqual(t, len(categorySortKey(t, db, id)), util.MaxSortKeyLength)
+		}
+	}
+
+	refs, err := repo.GetAllCategoryRefs()
+	require.NoError(t, err)
+	require.Equal(t, ids, []model.Id{refs[0].Id, refs[1].Id, refs[2].Id})
+}
+
+func categorySortKey(t *testing.T, db *sql.DB, id model.Id) string {
+	var key string
+	err := db.QueryRow(`SELECT sort_key FROM categories WHERE id = ?`, id).Scan(&key)
+	require.NoError(t, err)
+	return key
+}
@@ -0,0 +1,116 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+type SQLiteCoverRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteCoverRepository(db *sql.DB) *SQLiteCoverRepository {
+	return &SQLiteCoverRepository{db: db}
+}
+
+func (r *SQLiteCoverRepository) GetCover(referenceId model.Id) (*model.BookCover, error) {
+	row := r.db.QueryRow(`
+		SELECT reference_id, image_hash, media_type, width, height, size, thumbnail_hash, updated_at
+		FROM book_covers WHERE reference_id = ?`, int64(referenceId))
+	cover, err := scanCover(row)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNoCover
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying cover: %v", err)
+	}
+	return &cover, nil
+}
+
+func (r *SQLiteCoverRepository) GetCovers() (map[model.Id]model.BookCover, error) {
+	rows, err := r.db.Query(`
+		SELECT reference_id, image_hash, media_type, width, height, size, thumbnail_hash, updated_at
+		FROM book_covers`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying covers: %v", err)
+	}
+	defer rows.Close()
+
+	covers := make(map[model.Id]model.BookCover)
+	for rows.Next() {
+		cover, err := scanCover(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning cover: %v", err)
+		}
+		covers[cover.ReferenceId] = cover
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating covers: %v", err)
+	}
+	return covers, nil
+}
+
+// The old cover is deleted rather than updated, so that the triggers queue its content if nothing else uses it
+func (r *SQLiteCoverRepository) SetCover(cover model.BookCover) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM book_covers WHERE reference_id = ?`, int64(cover.ReferenceId)); err != nil {
+		return fmt.Errorf("error deleting old cover: %v", err)
+	}
+	// only books have covers
+	result, err := tx.Exec(`
+		INSERT INTO book_covers (reference_id, image_hash, media_type, width, height, size, thumbnail_hash, updated_at)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM book_references WHERE reference_id = ?)`,
+		int64(cover.ReferenceId), string(cover.ImageHash), cover.MediaType, cover.Width, cover.Height, cover.Size,
+		string(cover.ThumbnailHash), cover.UpdatedAt.Unix(), int64(cover.ReferenceId))
+	if err != nil {
+		return fmt.Errorf("error inserting cover: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("no book reference found with id %d", cover.ReferenceId)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+	return nil
+}
+
+func (r *SQLiteCoverRepository) RemoveCover(referenceId model.Id) error {
+	result, err := r.db.Exec(`DELETE FROM book_covers WHERE reference_id = ?`, int64(referenceId))
+	if err != nil {
+		return fmt.Errorf("error deleting cover: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return model.ErrNoCover
+	}
+	return nil
+}
+
+func scanCover(scanner rowScanner) (model.BookCover, error) {
+	var referenceId, updatedAt int64
+	var imageHash, thumbnailHash string
+	var cover model.BookCover
+	if err := scanner.Scan(&referenceId, &imageHash, &cover.MediaType, &cover.Width, &cover.Height, &cover.Size, &thumbnailHash, &updatedAt); err != nil {
+		return model.BookCover{}, err
+	}
+	cover.ReferenceId = model.Id(referenceId)
+	cover.ImageHash, cover.ThumbnailHash = model.ContentHash(imageHash), model.ContentHash(thumbnailHash)
+	cover.UpdatedAt = time.Unix(updatedAt, 0)
+	return cover, nil
+}
@@ -0,0 +1,87 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/testutils"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testCover(referenceId model.Id, image string) model.BookCover {
+	return model.BookCover{
+		ReferenceId:   referenceId,
+		ImageHash:     model.HashContent([]byte(image)),
+		MediaType:     "image/jpeg",
+		Width:         600,
+		Height:        900,
+		Size:          int64(len(image)),
+		ThumbnailHash: model.HashContent([]byte(image + " thumbnail")),
+		UpdatedAt:     time.Unix(1760000000, 0),
+	}
+}
+
+func TestSetAndGetCovers(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCoverRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	first := testutils.CreateTestBookReference(t, db, catId, "First", "", "", false)
+	second := testutils.CreateTestBookReference(t, db, catId, "Second", "", "", false)
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link", "https://example.com", "", false)
+
+	_, err := repo.GetCover(first)
+	require.ErrorIs(t, err, model.ErrNoCover)
+
+	require.NoError(t, repo.SetCover(testCover(first, "old")))
+	require.NoError(t, repo.SetCover(testCover(first, "new")))
+	require.NoError(t, repo.SetCover(testCover(second, "second")))
+	require.Error(t, repo.SetCover(testCover(linkId, "link")))
+
+	cover, err := repo.GetCover(first)
+	require.NoError(t, err)
+	require.Equal(t, testCover(first, "new"), *cover)
+
+	covers, err := repo.GetCovers()
+	require.NoError(t, err)
+	require.Equal(t, map[model.Id]model.BookCover{first: testCover(first, "new"), second: testCover(second, "second")}, covers)
+
+	require.NoError(t, repo.RemoveCover(second))
+	require.ErrorIs(t, repo.RemoveCover(second), model.ErrNoCover)
+}
+
+func TestReplacedAndRemovedCoversAreOrphaned(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteCoverRepository(db)
+	orphanRepo := NewSQLiteOrphanedBlobRepository(db)
+
+	catId, cv := testutils.CreateTestCategory(t, db, "Cat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book", "", "", false)
+	other := testutils.CreateTestBookReference(t, db, catId, "Other", "", "", false)
+
+	old, shared := testCover(bookId, "old"), testCover(other, "shared")
+	require.NoError(t, repo.SetCover(old))
+	require.NoError(t, repo.SetCover(shared))
+	shared.ReferenceId = bookId
+	require.NoError(t, repo.SetCover(shared))
+
+	// the old cover and its thumbnail are no longer used, the shared one still is
+	orphans, err := orphanRepo.GetOrphanedBlobs()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []model.ContentHash{old.ImageHash, old.ThumbnailHash}, orphans)
+
+	require.NoError(t, NewSQLiteCategoryRepository(db).RemoveReference(catId, bookId, cv))
+	orphans, err = orphanRepo.GetOrphanedBlobs()
+	require.NoError(t, err)
+	require.Len(t, orphans, 2)
+
+	require.NoError(t, repo.RemoveCover(other))
+	orphans, err = orphanRepo.GetOrphanedBlobs()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []model.ContentHash{old.ImageHash, old.ThumbnailHash, shared.ImageHash, shared.ThumbnailHash}, orphans)
+}
@@ -0,0 +1,56 @@
+package adapters
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// The Open Library covers by ISBN; default=false makes it answer 404 rather than a blank image for books without one
+const DefaultCoverURL = "https://covers.openlibrary.org/b/isbn/{isbn}-L.jpg?default=false"
+
+const DefaultCoverFetchTimeout = 15 * time.Second
+
+// HTTPCoverProvider implements CoverProvider by downloading covers from a URL template with an {isbn} placeholder,
+// so that any catalog serving covers by ISBN can be used
+type HTTPCoverProvider struct {
+	urlTemplate string
+	client      *http.Client
+	maxSize     int64
+}
+
+// Creates a provider for the covers at urlTemplate (e.g. DefaultCoverURL), which refuses covers larger than maxSize
+func NewHTTPCoverProvider(urlTemplate string, timeout time.Duration, maxSize int64) *HTTPCoverProvider {
+	return &HTTPCoverProvider{urlTemplate: urlTemplate, client: &http.Client{Timeout: timeout}, maxSize: maxSize}
+}
+
+func (p *HTTPCoverProvider) FetchCover(isbn model.ISBN) ([]byte, error) {
+	url := strings.ReplaceAll(p.urlTemplate, "{isbn}", string(isbn))
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, model.ErrMetadataNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error requesting %s: %s", url, resp.Status)
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("%s is not an image (%s)", url, mediaType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, p.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", url, err)
+	}
+	if int64(len(data)) > p.maxSize {
+		return nil, fmt.Errorf("the cover at %s is too large (max %d bytes)", url, p.maxSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,58 @@
+package adapters
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCoverServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/covers/9781449373320.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("jpeg data"))
+	})
+	mux.HandleFunc("/covers/9780131103627.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>not a cover</html>"))
+	})
+	mux.HandleFunc("/covers/9780262033848.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(make([]byte, 2000))
+	})
+	mux.HandleFunc("/covers/9780596517748.jpg", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchCover(t *testing.T) {
+	server := setupCoverServer(t)
+	provider := NewHTTPCoverProvider(server.URL+"/covers/{isbn}.jpg", time.Second, 1000)
+
+	data, err := provider.FetchCover("9781449373320")
+	require.NoError(t, err)
+	require.Equal(t, []byte("jpeg data"), data)
+}
+
+func TestFetchCoverFailures(t *testing.T) {
+	server := setupCoverServer(t)
+	provider := NewHTTPCoverProvider(server.URL+"/covers/{isbn}.jpg", time.Second, 1000)
+
+	_, err := provider.FetchCover("9780000000002")
+	require.True(t, errors.Is(err, model.ErrMetadataNotFound))
+
+	_, err = provider.FetchCover("9780131103627")
+	require.ErrorContains(t, err, "not an image")
+	_, err = provider.FetchCover("9780262033848")
+	require.ErrorContains(t, err, "too large")
+	_, err = provider.FetchCover("9780596517748")
+	require.ErrorContains(t, err, "503")
+}
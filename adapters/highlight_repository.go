@@ -0,0 +1,156 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+type SQLiteHighlightRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteHighlightRepository(db *sql.DB) *SQLiteHighlightRepository {
+	return &SQLiteHighlightRepository{db: db}
+}
+
+func (r *SQLiteHighlightRepository) GetHighlights(referenceId model.Id) ([]model.Highlight, error) {
+	rows, err := r.db.Query(`
+		SELECT id, reference_id, text, location, comment
+		FROM book_highlights
+		WHERE reference_id = ?
+		ORDER BY sort_key`, int64(referenceId))
+	if err != nil {
+		return nil, fmt.Errorf("error querying highlights: %v", err)
+	}
+	defer rows.Close()
+
+	highlights := []model.Highlight{}
+	for rows.Next() {
+		highlight, err := scanHighlight(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning highlight: %v", err)
+		}
+		highlights = append(highlights, highlight)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating highlights: %v", err)
+	}
+	return highlights, nil
+}
+
+func (r *SQLiteHighlightRepository) GetHighlightById(id model.Id) (*model.Highlight, error) {
+	row := r.db.QueryRow(`SELECT id, reference_id, text, location, comment FROM book_highlights WHERE id = ?`, int64(id))
+	highlight, err := scanHighlight(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("highlight with id %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying highlight: %v", err)
+	}
+	return &highlight, nil
+}
+
+func (r *SQLiteHighlightRepository) AddHighlight(highlight model.Highlight) (model.Id, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	sortKey, err := bookHighlightsGroup(highlight.ReferenceId).nextKey(tx)
+	if err != nil {
+		return 0, err
+	}
+	// only books have highlights
+	result, err := tx.Exec(`
+		INSERT INTO book_highlights (reference_id, text, location, comment, sort_key)
+		SELECT ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM book_references WHERE reference_id = ?)`,
+		int64(highlight.ReferenceId), highlight.Text, string(highlight.Location), highlight.Comment, sortKey, int64(highlight.ReferenceId))
+	if err != nil {
+		return 0, fmt.Errorf("error inserting highlight: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return 0, fmt.Errorf("no book reference found with id %d", highlight.ReferenceId)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting highlight id: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %v", err)
+	}
+	return model.Id(id), nil
+}
+
+func (r *SQLiteHighlightRepository) UpdateHighlight(highlight model.Highlight) error {
+	result, err := r.db.Exec(`UPDATE book_highlights SET text = ?, location = ?, comment = ? WHERE id = ?`,
+		highlight.Text, string(highlight.Location), highlight.Comment, int64(highlight.Id))
+	if err != nil {
+		return fmt.Errorf("error updating highlight: %v", err)
+	}
+	return checkHighlightAffected(result, highlight.Id)
+}
+
+func (r *SQLiteHighlightRepository) DeleteHighlight(id model.Id) error {
+	// The remaining highlights keep their sort keys, so there are no gaps to close
+	result, err := r.db.Exec(`DELETE FROM book_highlights WHERE id = ?`, int64(id))
+	if err != nil {
+		return fmt.Errorf("error deleting highlight: %v", err)
+	}
+	return checkHighlightAffected(result, id)
+}
+
+func (r *SQLiteHighlightRepository) MoveHighlight(id model.Id, position int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var referenceId int64
+	err = tx.QueryRow(`SELECT reference_id FROM book_highlights WHERE id = ?`, int64(id)).Scan(&referenceId)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("highlight with id %d not found", id)
+	}
+	if err != nil {
+		return fmt.Errorf("error querying highlight: %v", err)
+	}
+	if err := bookHighlightsGroup(model.Id(referenceId)).move(tx, id, position); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanHighlight(scanner rowScanner) (model.Highlight, error) {
+	var id, referenceId int64
+	var text, location, comment string
+	if err := scanner.Scan(&id, &referenceId, &text, &location, &comment); err != nil {
+		return model.Highlight{}, err
+	}
+	return model.Highlight{
+		Id:          model.Id(id),
+		ReferenceId: model.Id(referenceId),
+		Text:        text,
+		Location:    model.Location(location),
+		Comment:     comment,
+	}, nil
+}
+
+func checkHighlightAffected(result sql.Result, id model.Id) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("highlight with id %d not found", id)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/testutils"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/require"
+)
+
+func addTestHighlight(t *testing.T, repo *SQLiteHighlightRepository, referenceId model.Id, text string) model.Id {
+	highlight, err := model.NewHighlight(0, referenceId, text, "p. 1", "")
+	require.NoError(t, err)
+	id, err := repo.AddHighlight(highlight)
+	require.NoError(t, err)
+	return id
+}
+
+func highlightIds(t *testing.T, repo *SQLiteHighlightRepository, referenceId model.Id) []model.Id {
+	highlights, err := repo.GetHighlights(referenceId)
+	require.NoError(t, err)
+	ids := make([]model.Id, len(highlights))
+	for i, highlight := range highlights {
+		ids[i] = highlight.Id
+	}
+	return ids
+}
+
+func TestAddAndGetHighlights(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteHighlightRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book", "9781449373320", "", false)
+
+	highlight, err := model.NewHighlight(0, bookId, "a quote", "p. 42", "my comment")
+	require.NoError(t, err)
+	first, err := repo.AddHighlight(highlight)
+	require.NoError(t, err)
+	second := addTestHighlight(t, repo, bookId, "another quote")
+
+	highlights, err := repo.GetHighlights(bookId)
+	require.NoError(t, err)
+	require.Len(t, highlights, 2)
+	require.Equal(t, model.Highlight{Id: first, ReferenceId: bookId, Text: "a quote", Location: "p. 42", Comment: "my comment"}, highlights[0])
+	require.Equal(t, second, highlights[1].Id)
+
+	loaded, err := repo.GetHighlightById(first)
+	require.NoError(t, err)
+	require.Equal(t, highlights[0], *loaded)
+}
+
+func TestAddHighlightToNonBookFails(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteHighlightRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	noteId := testutils.CreateTestNoteReference(t, db, catId, "Note", "text", false)
+
+	highlight, err := model.NewHighlight(0, noteId, "a quote", "", "")
+	require.NoError(t, err)
+	_, err = repo.AddHighlight(highlight)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no book reference found")
+}
+
+func TestUpdateAndDeleteHighlight(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteHighlightRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book", "9781449373320", "", false)
+	id := addTestHighlight(t, repo, bookId, "a quote")
+
+	updated, err := model.NewHighlight(id, bookId, "the quote", "loc. 7", "comment")
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateHighlight(updated))
+	loaded, err := repo.GetHighlightById(id)
+	require.NoError(t, err)
+	require.Equal(t, updated, *loaded)
+
+	require.NoError(t, repo.DeleteHighlight(id))
+	_, err = repo.GetHighlightById(id)
+	require.Error(t, err)
+	require.Error(t, repo.DeleteHighlight(id))
+
+	updated.Id = model.Id(999)
+	require.Error(t, repo.UpdateHighlight(updated))
+}
+
+func TestMoveHighlight(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteHighlightRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book", "9781449373320", "", false)
+	a := addTestHighlight(t, repo, bookId, "a")
+	b := addTestHighlight(t, repo, bookId, "b")
+	c := addTestHighlight(t, repo, bookId, "c")
+
+	require.NoError(t, repo.MoveHighlight(c, 0))
+	require.Equal(t, []model.Id{c, a, b}, highlightIds(t, repo, bookId))
+
+	require.NoError(t, repo.MoveHighlight(c, 2))
+	require.Equal(t, []model.Id{a, b, c}, highlightIds(t, repo, bookId))
+
+	require.Error(t, repo.MoveHighlight(a, 3))
+	require.Error(t, repo.MoveHighlight(model.Id(999), 0))
+}
+
+func TestHighlightsAreDeletedWithTheBook(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteHighlightRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book", "9781449373320", "", false)
+	addTestHighlight(t, repo, bookId, "a")
+
+	_, err := db.Exec(`DELETE FROM base_references WHERE id = ?`, bookId)
+	require.NoError(t, err)
+	require.Empty(t, highlightIds(t, repo, bookId))
+}
+
+func TestMergedHighlightsMoveToTheKeptBook(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteHighlightRepository(db)
+	references := NewSQLiteReferencesRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	keep := testutils.CreateTestBookReference(t, db, catId, "Book", "9781449373320", "", false)
+	other := testutils.CreateTestBookReference(t, db, catId, "Same Book", "9781449373320", "", false)
+	a := addTestHighlight(t, repo, keep, "a")
+	b := addTestHighlight(t, repo, other, "b")
+	c := addTestHighlight(t, repo, other, "c")
+
+	merged := model.NewBookReference(keep, "Book", "9781449373320", "", false)
+	require.NoError(t, references.MergeReferences(merged, []model.Id{other}))
+	require.Equal(t, []model.Id{a, b, c}, highlightIds(t, repo, keep))
+}
@@ -0,0 +1,136 @@
+package adapters
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// Images larger than this are refused before they are decoded, as a small file can hold a huge image
+const DefaultMaxImagePixels = 25_000_000
+
+const thumbnailQuality = 85
+
+// StdlibImageProcessor implements ImageProcessor with the image codecs of the standard library (JPEG, PNG and GIF),
+// scaling images down by averaging the pixels that make up each pixel of the thumbnail
+type StdlibImageProcessor struct {
+	maxPixels int
+}
+
+func NewStdlibImageProcessor(maxPixels int) *StdlibImageProcessor {
+	return &StdlibImageProcessor{maxPixels: maxPixels}
+}
+
+func (p *StdlibImageProcessor) Inspect(data []byte) (model.ImageInfo, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return model.ImageInfo{}, errors.New("unsupported image format (supported: JPEG, PNG, GIF)")
+	}
+	if err != nil {
+		return model.ImageInfo{}, fmt.Errorf("error reading image: %v", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return model.ImageInfo{}, errors.New("image is empty")
+	}
+	if config.Width*config.Height > p.maxPixels {
+		return model.ImageInfo{}, fmt.Errorf("image is too large (%dx%d, max %d pixels)", config.Width, config.Height, p.maxPixels)
+	}
+	return model.ImageInfo{MediaType: "image/" + format, Width: config.Width, Height: config.Height}, nil
+}
+
+// Images are never scaled up, so a small image is only re-encoded. Transparent areas become white, as JPEGs have no
+// transparency.
+func (p *StdlibImageProcessor) Thumbnail(data []byte, maxWidth, maxHeight int) ([]byte, error) {
+	info, err := p.Inspect(data)
+	if err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding image: %v", err)
+	}
+	width, height := fitIn(info.Width, info.Height, maxWidth, maxHeight)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleDown(src, width, height), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, fmt.Errorf("error encoding thumbnail: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Returns the size of an image of width x height scaled to fit in maxWidth x maxHeight, keeping its shape
+func fitIn(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= maxWidth && height <= maxHeight {
+		return width, height
+	}
+	// compares the ratios without dividing, to keep the rounding to the last step
+	if width*maxHeight > height*maxWidth {
+		return maxWidth, max(1, (height*maxWidth+width/2)/width)
+	}
+	return max(1, (width*maxHeight+height/2)/height), maxHeight
+}
+
+// Scales the image to width x height (no larger than the image) with a box filter: each pixel of the result is the
+// average of the block of pixels it covers, weighted by how much of each pixel it covers
+func scaleDown(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	// the source is copied into RGBA first, to work on its pixels directly rather than through the color model
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	xSpans := boxSpans(bounds.Dx(), width)
+	ySpans := boxSpans(bounds.Dy(), height)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, ySpan := range ySpans {
+		for x, xSpan := range xSpans {
+			var sum [4]float64
+			var total float64
+			for _, sy := range ySpan {
+				for _, sx := range xSpan {
+					weight := sy.weight * sx.weight
+					i := rgba.PixOffset(sx.index, sy.index)
+					for c := 0; c < 4; c++ {
+						sum[c] += weight * float64(rgba.Pix[i+c])
+					}
+					total += weight
+				}
+			}
+			i := dst.PixOffset(x, y)
+			alpha := sum[3] / total
+			for c := 0; c < 3; c++ {
+				// the pixels are premultiplied by their alpha, so adding the missing alpha puts them over white
+				dst.Pix[i+c] = uint8(min(255, sum[c]/total+255-alpha+0.5))
+			}
+			dst.Pix[i+3] = 255
+		}
+	}
+	return dst
+}
+
+type weightedPixel struct {
+	index  int
+	weight float64
+}
+
+// Returns, for each of the n pixels a row (or column) of size pixels is scaled to, the source pixels it covers
+func boxSpans(size, n int) [][]weightedPixel {
+	scale := float64(size) / float64(n)
+	spans := make([][]weightedPixel, n)
+	for i := range spans {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for p := int(start); p < size && float64(p) < end; p++ {
+			covered := min(end, float64(p+1)) - max(start, float64(p))
+			if covered > 0 {
+				spans[i] = append(spans[i], weightedPixel{index: p, weight: covered})
+			}
+		}
+	}
+	return spans
+}
@@ -0,0 +1,134 @@
+package adapters
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func filledImage(width, height int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func decodeThumbnail(t *testing.T, data []byte) image.Image {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img
+}
+
+// JPEG is lossy, so colors are compared with some tolerance
+func requireColorNear(t *testing.T, want color.RGBA, got color.Color) {
+	r, g, b, _ := got.RGBA()
+	for i, pair := range [][2]int{{int(want.R), int(r >> 8)}, {int(want.G), int(g >> 8)}, {int(want.B), int(b >> 8)}} {
+		diff := pair[0] - pair[1]
+		require.LessOrEqual(t, diff*diff, 100, "channel %d: want %d, got %d", i, pair[0], pair[1])
+	}
+}
+
+func TestInspectImage(t *testing.T) {
+	processor := NewStdlibImageProcessor(DefaultMaxImagePixels)
+
+	info, err := processor.Inspect(encodePNG(t, filledImage(30, 45, color.White)))
+	require.NoError(t, err)
+	require.Equal(t, "image/png", info.MediaType)
+	require.Equal(t, 30, info.Width)
+	require.Equal(t, 45, info.Height)
+
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, filledImage(10, 10, color.Black), nil))
+	info, err = processor.Inspect(buf.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, "image/gif", info.MediaType)
+
+	_, err = processor.Inspect([]byte("%PDF-1.4"))
+	require.ErrorContains(t, err, "unsupported image format")
+	_, err = processor.Inspect(nil)
+	require.Error(t, err)
+}
+
+func TestInspectRefusesHugeImages(t *testing.T) {
+	processor := NewStdlibImageProcessor(100)
+	_, err := processor.Inspect(encodePNG(t, filledImage(20, 20, color.White)))
+	require.ErrorContains(t, err, "too large")
+}
+
+func TestThumbnailFitsTheBoxAndKeepsTheShape(t *testing.T) {
+	processor := NewStdlibImageProcessor(DefaultMaxImagePixels)
+
+	cases := []struct {
+		width, height         int
+		wantWidth, wantHeight int
+	}{
+		// a typical 2:3 cover fills the box
+		{600, 900, 160, 240},
+		// wider and taller images fit one side of it
+		{800, 400, 160, 80},
+		{100, 1000, 24, 240},
+		// small images aren't scaled up
+		{50, 70, 50, 70},
+	}
+	for _, c := range cases {
+		data, err := processor.Thumbnail(encodePNG(t, filledImage(c.width, c.height, color.RGBA{200, 30, 30, 255})), 160, 240)
+		require.NoError(t, err)
+		img := decodeThumbnail(t, data)
+		require.Equal(t, c.wantWidth, img.Bounds().Dx(), "width of %dx%d", c.width, c.height)
+		require.Equal(t, c.wantHeight, img.Bounds().Dy(), "height of %dx%d", c.width, c.height)
+		requireColorNear(t, color.RGBA{200, 30, 30, 255}, img.At(img.Bounds().Dx()/2, img.Bounds().Dy()/2))
+	}
+}
+
+func TestThumbnailAveragesPixels(t *testing.T) {
+	processor := NewStdlibImageProcessor(DefaultMaxImagePixels)
+
+	// fine black and white stripes average to grey, rather than picking one of the colors
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 400; x++ {
+			if x%2 == 0 {
+				img.Set(x, y, color.Black)
+			} else {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+	data, err := processor.Thumbnail(encodePNG(t, img), 100, 100)
+	require.NoError(t, err)
+	requireColorNear(t, color.RGBA{128, 128, 128, 255}, decodeThumbnail(t, data).At(50, 50))
+}
+
+func TestThumbnailPutsTransparencyOverWhite(t *testing.T) {
+	processor := NewStdlibImageProcessor(DefaultMaxImagePixels)
+
+	data, err := processor.Thumbnail(encodePNG(t, filledImage(300, 300, color.NRGBA{0, 0, 0, 0})), 160, 240)
+	require.NoError(t, err)
+	requireColorNear(t, color.RGBA{255, 255, 255, 255}, decodeThumbnail(t, data).At(80, 80))
+
+	// half transparent black is grey over white
+	data, err = processor.Thumbnail(encodePNG(t, filledImage(300, 300, color.NRGBA{0, 0, 0, 128})), 160, 240)
+	require.NoError(t, err)
+	requireColorNear(t, color.RGBA{127, 127, 127, 255}, decodeThumbnail(t, data).At(80, 80))
+}
+
+func TestThumbnailOfNonImageFails(t *testing.T) {
+	processor := NewStdlibImageProcessor(DefaultMaxImagePixels)
+	_, err := processor.Thumbnail([]byte("not an image"), 160, 240)
+	require.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+type SQLiteImportRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteImportRepository(db *sql.DB) *SQLiteImportRepository {
+	return &SQLiteImportRepository{db: db}
+}
+
+func (r *SQLiteImportRepository) Import(plan model.ImportPlan) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// the ids of the created categories, by their ids in the plan
+	created := make(map[model.Id]model.Id, len(plan.Categories))
+	resolve := func(id model.Id) model.Id {
+		if id < 0 {
+			return created[id]
+		}
+		return id
+	}
+	for _, category := range plan.Categories {
+		parentId := resolve(category.ParentId)
+		if category.ParentId < 0 && parentId == 0 {
+			return fmt.Errorf("the parent of category %s is created after it", category.Name)
+		}
+		id, err := insertCategory(tx, parentId, category.Name)
+		if err != nil {
+			return fmt.Errorf("error creating category %s: %v", category.Name, err)
+		}
+		created[category.Id] = id
+	}
+
+	// each category that gets references has its version bumped once, when they are all added
+	versions := make(map[model.Id]model.Version)
+	var updated []model.Id
+	for _, planned := range plan.References {
+		categoryId := resolve(planned.CategoryId)
+		version, ok := versions[categoryId]
+		if !ok {
+			err := tx.QueryRow(`SELECT version FROM categories WHERE id = ?`, int64(categoryId)).Scan(&version)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("category with id %d not found", planned.CategoryId)
+			}
+			if err != nil {
+				return fmt.Errorf("error fetching category: %v", err)
+			}
+			versions[categoryId] = version
+			updated = append(updated, categoryId)
+		}
+		if err := insertReference(tx, categoryId, planned.Reference, version); err != nil {
+			return fmt.Errorf("error importing %q: %v", planned.Reference.Title(), err)
+		}
+	}
+	for _, categoryId := range updated {
+		if _, err := tx.Exec(`UPDATE categories SET version = version + 1 WHERE id = ?`, int64(categoryId)); err != nil {
+			return fmt.Errorf("error updating category version: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
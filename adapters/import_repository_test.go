@@ -0,0 +1,81 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportCreatesCategoriesAndAddsReferences(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteImportRepository(db)
+	categoryRepo := NewSQLiteCategoryRepository(db)
+	categoryListRepo := NewSQLiteCategoryListRepository(db)
+
+	existing, version := testutils.CreateTestCategory(t, db, "Programming")
+	plan := model.ImportPlan{
+		Categories: []model.PlannedCategory{
+			{Id: -1, ParentId: existing, Name: "Go"},
+			{Id: -2, ParentId: -1, Name: "Concurrency"},
+		},
+		References: []model.PlannedReference{
+			{CategoryId: existing, Reference: model.NewNoteReference(0, "Reading list", "later", false)},
+			{CategoryId: -2, Reference: model.NewLinkReference(0, "Go memory model", "https://go.dev/ref/mem", "", false)},
+			{CategoryId: -2, Reference: model.NewBookReference(0, "Concurrency in Go", "9781491941195", "", true)},
+		},
+	}
+	require.NoError(t, repo.Import(plan))
+
+	categories, err := categoryListRepo.GetAllCategoryRefs()
+	require.NoError(t, err)
+	require.Len(t, categories, 3)
+	require.Equal(t, model.Title("Go"), categories[1].Name)
+	require.Equal(t, existing, categories[1].ParentId)
+	require.Equal(t, model.Title("Concurrency"), categories[2].Name)
+	require.Equal(t, categories[1].Id, categories[2].ParentId)
+
+	programming, err := categoryRepo.GetCategoryById(existing)
+	require.NoError(t, err)
+	require.Len(t, programming.References, 1)
+	require.Equal(t, version+1, programming.Version)
+
+	concurrency, err := categoryRepo.GetCategoryById(categories[2].Id)
+	require.NoError(t, err)
+	require.Len(t, concurrency.References, 2)
+	require.Equal(t, model.Title("Go memory model"), concurrency.References[0].Title())
+	require.Equal(t, model.Title("Concurrency in Go"), concurrency.References[1].Title())
+	require.True(t, concurrency.References[1].Starred())
+}
+
+func TestImportThatFailsChangesNothing(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteImportRepository(db)
+	categoryRepo := NewSQLiteCategoryRepository(db)
+	categoryListRepo := NewSQLiteCategoryListRepository(db)
+
+	existing, version := testutils.CreateTestCategory(t, db, "Programming")
+	plan := model.ImportPlan{
+		Categories: []model.PlannedCategory{{Id: -1, ParentId: existing, Name: "Go"}},
+		References: []model.PlannedReference{
+			{CategoryId: existing, Reference: model.NewNoteReference(0, "Reading list", "later", false)},
+			{CategoryId: -1, Reference: model.NewLinkReference(0, "Go memory model", "https://go.dev/ref/mem", "", false)},
+			// the category was deleted since the import was planned
+			{CategoryId: 999, Reference: model.NewNoteReference(0, "Lost", "", false)},
+		},
+	}
+	err := repo.Import(plan)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not found")
+
+	categories, err := categoryListRepo.GetAllCategoryRefs()
+	require.NoError(t, err)
+	require.Len(t, categories, 1)
+	programming, err := categoryRepo.GetCategoryById(existing)
+	require.NoError(t, err)
+	require.Empty(t, programming.References)
+	require.Equal(t, version, programming.Version)
+}
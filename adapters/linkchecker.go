@@ -0,0 +1,124 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+const (
+	DefaultLinkCheckTimeout = 15 * time.Second
+	// the minimum time between requests to the same host, so that checking many links to one site doesn't hammer it
+	DefaultHostInterval = time.Second
+)
+
+// HTTPLinkChecker implements LinkChecker with HEAD requests, falling back to GET for servers that don't support them.
+// Requests to the same host are spaced out by the host interval, however many checks run concurrently.
+type HTTPLinkChecker struct {
+	client       *http.Client
+	hostInterval time.Duration
+	mu           sync.Mutex
+	// the earliest time of the next request to each host
+	nextRequest map[string]time.Time
+}
+
+func NewHTTPLinkChecker(timeout time.Duration, hostInterval time.Duration) *HTTPLinkChecker {
+	checker := &HTTPLinkChecker{hostInterval: hostInterval, nextRequest: make(map[string]time.Time)}
+	checker.client = &http.Client{Timeout: timeout, CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return checker.waitForHost(req.Context(), req.URL.Host)
+	}}
+	return checker
+}
+
+func (c *HTTPLinkChecker) CheckLink(ctx context.Context, link model.URL) model.LinkHealth {
+	health := model.LinkHealth{URL: link}
+	resp, err := c.request(ctx, http.MethodHead, link)
+	// servers that don't support HEAD answer with all sorts of errors
+	if err == nil && resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone {
+		resp, err = c.request(ctx, http.MethodGet, link)
+	}
+	health.CheckedAt = time.Now()
+	if err != nil {
+		health.Error = linkError(err)
+		return health
+	}
+	health.StatusCode = resp.StatusCode
+	// the request of the response is the last one of the redirects, which has the redirect response that led to it
+	if resp.Request.Response != nil {
+		health.RedirectURL = model.URL(resp.Request.URL.String())
+	}
+	return health
+}
+
+func (c *HTTPLinkChecker) request(ctx context.Context, method string, link model.URL) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, string(link), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "reference-manager link checker")
+	if err := c.waitForHost(ctx, req.URL.Host); err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// only the status matters
+	resp.Body.Close()
+	return resp, nil
+}
+
+// Waits until the next request to the host is allowed and reserves the slot after it
+func (c *HTTPLinkChecker) waitForHost(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+	c.mu.Lock()
+	now := time.Now()
+	// hosts that can be requested again right away are forgotten, so only the recently checked ones are kept
+	for known, next := range c.nextRequest {
+		if !next.After(now) {
+			delete(c.nextRequest, known)
+		}
+	}
+	at := c.nextRequest[host]
+	if at.Before(now) {
+		at = now
+	}
+	c.nextRequest[host] = at.Add(c.hostInterval)
+	c.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Returns the cause of a failed request without the method and URL that *url.Error adds to it
+func linkError(err error) string {
+	// the domains of dead sites often expire
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return "host not found"
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if urlErr.Timeout() {
+			return "timed out"
+		}
+		err = urlErr.Err
+	}
+	return err.Error()
+}
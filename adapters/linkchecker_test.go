@@ -0,0 +1,127 @@
+package adapters
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLinkServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckLink(t *testing.T) {
+	server := setupLinkServer(t)
+	checker := NewHTTPLinkChecker(time.Second, 0)
+
+	health := checker.CheckLink(context.Background(), model.URL(server.URL+"/ok"))
+	require.Equal(t, 200, health.StatusCode)
+	require.Empty(t, health.RedirectURL)
+	require.Empty(t, health.Error)
+	require.Equal(t, model.URL(server.URL+"/ok"), health.URL)
+	require.WithinDuration(t, time.Now(), health.CheckedAt, time.Second)
+
+	health = checker.CheckLink(context.Background(), model.URL(server.URL+"/missing"))
+	require.Equal(t, 404, health.StatusCode)
+	require.True(t, health.Broken())
+
+	health = checker.CheckLink(context.Background(), model.URL(server.URL+"/moved"))
+	require.Equal(t, 200, health.StatusCode)
+	require.Equal(t, model.URL(server.URL+"/ok"), health.RedirectURL)
+
+	// servers that don't support HEAD are asked with GET
+	health = checker.CheckLink(context.Background(), model.URL(server.URL+"/no-head"))
+	require.Equal(t, 200, health.StatusCode)
+}
+
+func TestCheckLinkFailures(t *testing.T) {
+	server := setupLinkServer(t)
+	checker := NewHTTPLinkChecker(100*time.Millisecond, 0)
+
+	health := checker.CheckLink(context.Background(), model.URL(server.URL+"/slow"))
+	require.Equal(t, "timed out", health.Error)
+	require.True(t, health.Broken())
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	health = checker.CheckLink(context.Background(), model.URL(closed.URL+"/ok"))
+	require.NotEmpty(t, health.Error)
+	require.Zero(t, health.StatusCode)
+	require.False(t, health.CheckedAt.IsZero())
+}
+
+func TestCheckLinkRateLimitsRequestsPerHost(t *testing.T) {
+	server := setupLinkServer(t)
+	checker := NewHTTPLinkChecker(time.Second, 100*time.Millisecond)
+
+	var mu sync.Mutex
+	var times []time.Time
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			health := checker.CheckLink(context.Background(), model.URL(server.URL+"/ok"))
+			require.Equal(t, 200, health.StatusCode)
+			mu.Lock()
+			times = append(times, health.CheckedAt)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	first, last := times[0], times[0]
+	for _, at := range times {
+		if at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	// the three checks ran concurrently, but their requests were spaced out
+	require.GreaterOrEqual(t, last.Sub(first), 200*time.Millisecond)
+
+	// a cancelled check doesn't wait for its turn
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	health := checker.CheckLink(ctx, model.URL(server.URL+"/ok"))
+	require.NotEmpty(t, health.Error)
+}
+
+func TestCheckLinkForgetsHostsAfterTheirInterval(t *testing.T) {
+	server := setupLinkServer(t)
+	checker := NewHTTPLinkChecker(time.Second, 50*time.Millisecond)
+
+	require.Equal(t, 200, checker.CheckLink(context.Background(), model.URL(server.URL+"/ok")).StatusCode)
+	time.Sleep(60 * time.Millisecond)
+	// the same server by another name, so another host
+	other := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	require.Equal(t, 200, checker.CheckLink(context.Background(), model.URL(other+"/ok")).StatusCode)
+
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+	require.Len(t, checker.nextRequest, 1)
+	require.Contains(t, checker.nextRequest, strings.TrimPrefix(other, "http://"))
+}
@@ -0,0 +1,104 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+type SQLiteLinkHealthRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteLinkHealthRepository(db *sql.DB) *SQLiteLinkHealthRepository {
+	return &SQLiteLinkHealthRepository{db: db}
+}
+
+func (r *SQLiteLinkHealthRepository) GetLinksToCheck(checkedBefore time.Time) ([]model.LinkHealth, error) {
+	// links are checked in the order they were added, with the ones that were never checked first
+	rows, err := r.db.Query(`
+		SELECT l.reference_id, l.url, COALESCE(h.status_code, 0), COALESCE(h.redirect_url, ''), COALESCE(h.error, ''), COALESCE(h.checked_at, 0)
+		FROM link_references l
+		JOIN base_references br ON br.id = l.reference_id
+		LEFT JOIN link_health h ON h.reference_id = l.reference_id
+		WHERE h.reference_id IS NULL OR h.checked_at < ? OR h.url <> l.url
+		ORDER BY h.reference_id IS NOT NULL, l.reference_id`, checkedBefore.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("error querying links to check: %v", err)
+	}
+	defer rows.Close()
+
+	links := []model.LinkHealth{}
+	for rows.Next() {
+		health, err := scanLinkHealth(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning link: %v", err)
+		}
+		links = append(links, health)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating links: %v", err)
+	}
+	return links, nil
+}
+
+func (r *SQLiteLinkHealthRepository) GetLinkHealth() (map[model.Id]model.LinkHealth, error) {
+	rows, err := r.db.Query(`SELECT reference_id, url, status_code, redirect_url, error, checked_at FROM link_health`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying link health: %v", err)
+	}
+	defer rows.Close()
+
+	health := make(map[model.Id]model.LinkHealth)
+	for rows.Next() {
+		h, err := scanLinkHealth(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning link health: %v", err)
+		}
+		health[h.ReferenceId] = h
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating link health: %v", err)
+	}
+	return health, nil
+}
+
+func (r *SQLiteLinkHealthRepository) SaveLinkHealth(health model.LinkHealth) error {
+	// the link may have been deleted or converted to another type while it was being checked
+	result, err := r.db.Exec(`
+		INSERT INTO link_health (reference_id, url, status_code, redirect_url, error, checked_at)
+		SELECT ?, ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM link_references WHERE reference_id = ?)
+		ON CONFLICT (reference_id) DO UPDATE SET
+			url = excluded.url, status_code = excluded.status_code, redirect_url = excluded.redirect_url,
+			error = excluded.error, checked_at = excluded.checked_at`,
+		int64(health.ReferenceId), string(health.URL), health.StatusCode, string(health.RedirectURL), health.Error,
+		health.CheckedAt.Unix(), int64(health.ReferenceId))
+	if err != nil {
+		return fmt.Errorf("error saving link health: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("no link reference found with id %d", health.ReferenceId)
+	}
+	return nil
+}
+
+func scanLinkHealth(scanner rowScanner) (model.LinkHealth, error) {
+	var id, checkedAt int64
+	var url, redirectURL string
+	var health model.LinkHealth
+	if err := scanner.Scan(&id, &url, &health.StatusCode, &redirectURL, &health.Error, &checkedAt); err != nil {
+		return model.LinkHealth{}, err
+	}
+	health.ReferenceId, health.URL, health.RedirectURL = model.Id(id), model.URL(url), model.URL(redirectURL)
+	if checkedAt != 0 {
+		health.CheckedAt = time.Unix(checkedAt, 0)
+	}
+	return health, nil
+}
@@ -0,0 +1,125 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/testutils"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/require"
+)
+
+func linkIds(links []model.LinkHealth) []model.Id {
+	ids := make([]model.Id, len(links))
+	for i, link := range links {
+		ids[i] = link.ReferenceId
+	}
+	return ids
+}
+
+func TestSaveAndGetLinkHealth(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteLinkHealthRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link", "https://example.com/old", "", false)
+
+	checkedAt := time.Unix(1760000000, 0)
+	health := model.LinkHealth{ReferenceId: linkId, URL: "https://example.com/old", StatusCode: 200, RedirectURL: "https://example.com/new", CheckedAt: checkedAt}
+	require.NoError(t, repo.SaveLinkHealth(health))
+	all, err := repo.GetLinkHealth()
+	require.NoError(t, err)
+	require.Equal(t, map[model.Id]model.LinkHealth{linkId: health}, all)
+
+	// a later check replaces the earlier one
+	health = model.LinkHealth{ReferenceId: linkId, URL: "https://example.com/old", Error: "no such host", CheckedAt: checkedAt.Add(time.Hour)}
+	require.NoError(t, repo.SaveLinkHealth(health))
+	all, err = repo.GetLinkHealth()
+	require.NoError(t, err)
+	require.Equal(t, map[model.Id]model.LinkHealth{linkId: health}, all)
+}
+
+func TestSaveLinkHealthOfNonLinkFails(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteLinkHealthRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book", "9781449373320", "", false)
+
+	err := repo.SaveLinkHealth(model.LinkHealth{ReferenceId: bookId, URL: "https://example.com", StatusCode: 200, CheckedAt: time.Now()})
+	require.Error(t, err)
+	err = repo.SaveLinkHealth(model.LinkHealth{ReferenceId: 999, URL: "https://example.com", StatusCode: 200, CheckedAt: time.Now()})
+	require.Error(t, err)
+}
+
+func TestGetLinksToCheck(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteLinkHealthRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	stale := testutils.CreateTestLinkReference(t, db, catId, "Stale", "https://example.com/stale", "", false)
+	fresh := testutils.CreateTestLinkReference(t, db, catId, "Fresh", "https://example.com/fresh", "", false)
+	edited := testutils.CreateTestLinkReference(t, db, catId, "Edited", "https://example.com/edited", "", false)
+	unchecked := testutils.CreateTestLinkReference(t, db, catId, "Unchecked", "https://example.com/unchecked", "", false)
+	testutils.CreateTestBookReference(t, db, catId, "Book", "9781449373320", "", false)
+
+	now := time.Unix(1760000000, 0)
+	require.NoError(t, repo.SaveLinkHealth(model.LinkHealth{ReferenceId: stale, URL: "https://example.com/stale", StatusCode: 404, CheckedAt: now.Add(-48 * time.Hour)}))
+	require.NoError(t, repo.SaveLinkHealth(model.LinkHealth{ReferenceId: fresh, URL: "https://example.com/fresh", StatusCode: 200, CheckedAt: now}))
+	require.NoError(t, repo.SaveLinkHealth(model.LinkHealth{ReferenceId: edited, URL: "https://example.com/before-edit", StatusCode: 200, CheckedAt: now}))
+
+	links, err := repo.GetLinksToCheck(now.Add(-24 * time.Hour))
+	require.NoError(t, err)
+	// links that were never checked come first
+	require.Equal(t, []model.Id{unchecked, stale, edited}, linkIds(links))
+	require.Equal(t, model.LinkHealth{ReferenceId: unchecked, URL: "https://example.com/unchecked"}, links[0])
+	// the current URL is returned, along with the last check of the old one
+	require.Equal(t, model.URL("https://example.com/edited"), links[2].URL)
+	require.Equal(t, 200, links[2].StatusCode)
+
+	links, err = repo.GetLinksToCheck(now.Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, []model.Id{unchecked, stale, fresh, edited}, linkIds(links))
+}
+
+func TestLinkHealthIsDeletedWithTheLink(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteLinkHealthRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link", "https://example.com", "", false)
+	require.NoError(t, repo.SaveLinkHealth(model.LinkHealth{ReferenceId: linkId, URL: "https://example.com", StatusCode: 200, CheckedAt: time.Now()}))
+
+	_, err := db.Exec("DELETE FROM base_references WHERE id = ?", int64(linkId))
+	require.NoError(t, err)
+	all, err := repo.GetLinkHealth()
+	require.NoError(t, err)
+	require.Empty(t, all)
+}
+
+func TestLinkHealthIsDeletedWithTheLinkWhileTheCheckerHoldsAConnection(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteLinkHealthRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link", "https://example.com", "", false)
+	require.NoError(t, repo.SaveLinkHealth(model.LinkHealth{ReferenceId: linkId, URL: "https://example.com", StatusCode: 200, CheckedAt: time.Now()}))
+
+	// the background checker holds a connection, so the delete runs on another one from the pool
+	checkerConn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer checkerConn.Close()
+	_, err = db.Exec("DELETE FROM base_references WHERE id = ?", int64(linkId))
+	require.NoError(t, err)
+	all, err := repo.GetLinkHealth()
+	require.NoError(t, err)
+	require.Empty(t, all)
+}
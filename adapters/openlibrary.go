@@ -0,0 +1,129 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+const DefaultOpenLibraryURL = "https://openlibrary.org"
+
+// the documents of editions, works and authors are small, so anything larger than this isn't one of them
+const maxOpenLibraryDocumentSize = 1 << 20
+
+// OpenLibraryProvider implements MetadataProvider with the Open Library API: the edition of an ISBN, then its authors
+// and, for the description if the edition has none, its work
+type OpenLibraryProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// Creates a provider for the Open Library API at baseURL (DefaultOpenLibraryURL, or a stand-in in tests)
+func NewOpenLibraryProvider(baseURL string) *OpenLibraryProvider {
+	return &OpenLibraryProvider{baseURL: strings.TrimRight(baseURL, "/"), client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type openLibraryKey struct {
+	Key string `json:"key"`
+}
+
+// Open Library texts are either strings or objects like {"type": "/type/text", "value": "..."}
+type openLibraryText string
+
+func (t *openLibraryText) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*t = openLibraryText(text)
+		return nil
+	}
+	var typed struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return err
+	}
+	*t = openLibraryText(typed.Value)
+	return nil
+}
+
+type openLibraryEdition struct {
+	Title       string           `json:"title"`
+	Subtitle    string           `json:"subtitle"`
+	Authors     []openLibraryKey `json:"authors"`
+	Works       []openLibraryKey `json:"works"`
+	Description openLibraryText  `json:"description"`
+}
+
+type openLibraryWork struct {
+	Authors []struct {
+		Author openLibraryKey `json:"author"`
+	} `json:"authors"`
+	Description openLibraryText `json:"description"`
+}
+
+type openLibraryAuthor struct {
+	Name string `json:"name"`
+}
+
+func (p *OpenLibraryProvider) LookupBook(isbn model.ISBN) (model.BookMetadata, error) {
+	var edition openLibraryEdition
+	if err := p.get("/isbn/"+string(isbn)+".json", &edition); err != nil {
+		return model.BookMetadata{}, err
+	}
+	metadata := model.BookMetadata{ISBN: isbn, Title: strings.TrimSpace(edition.Title), Description: strings.TrimSpace(string(edition.Description))}
+	if subtitle := strings.TrimSpace(edition.Subtitle); subtitle != "" {
+		metadata.Title += ": " + subtitle
+	}
+
+	authorKeys := edition.Authors
+	if len(edition.Works) > 0 && (metadata.Description == "" || len(authorKeys) == 0) {
+		var work openLibraryWork
+		// the catalog isn't always consistent, so missing works and authors are left out
+		if err := p.get(edition.Works[0].Key+".json", &work); err != nil && !errors.Is(err, model.ErrMetadataNotFound) {
+			return model.BookMetadata{}, err
+		}
+		if metadata.Description == "" {
+			metadata.Description = strings.TrimSpace(string(work.Description))
+		}
+		if len(authorKeys) == 0 {
+			for _, author := range work.Authors {
+				authorKeys = append(authorKeys, author.Author)
+			}
+		}
+	}
+	for _, key := range authorKeys {
+		var author openLibraryAuthor
+		if err := p.get(key.Key+".json", &author); err != nil && !errors.Is(err, model.ErrMetadataNotFound) {
+			return model.BookMetadata{}, err
+		}
+		if name := strings.TrimSpace(author.Name); name != "" {
+			metadata.Authors = append(metadata.Authors, name)
+		}
+	}
+	return metadata, nil
+}
+
+// Gets the JSON document at the path, returning model.ErrMetadataNotFound for missing documents
+func (p *OpenLibraryProvider) get(path string, document any) error {
+	resp, err := p.client.Get(p.baseURL + path)
+	if err != nil {
+		return fmt.Errorf("error requesting %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return model.ErrMetadataNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error requesting %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOpenLibraryDocumentSize)).Decode(document); err != nil {
+		return fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package adapters
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func setupOpenLibraryStandIn(t *testing.T) *OpenLibraryProvider {
+	documents := map[string]string{
+		// the edition has no description, so it's taken from the work
+		"/books/OL1M.json":   `{"title": "Code", "subtitle": "The Hidden Language of Computer Hardware and Software", "authors": [{"key": "/authors/OL1A"}], "works": [{"key": "/works/OL1W"}]}`,
+		"/works/OL1W.json":   `{"description": {"type": "/type/text", "value": "What computers do, from the ground up."}}`,
+		"/authors/OL1A.json": `{"name": "Charles Petzold"}`,
+		"/books/OL2M.json":   `{"title": "Operating Systems", "description": "The OS book", "works": [{"key": "/works/OL2W"}]}`,
+		"/works/OL2W.json":   `{"authors": [{"author": {"key": "/authors/OL2A"}}, {"author": {"key": "/authors/OL3A"}}]}`,
+		"/authors/OL2A.json": `{"name": "Remzi H. Arpaci-Dusseau"}`,
+		"/authors/OL3A.json": `{"name": "Andrea C. Arpaci-Dusseau"}`,
+		"/books/OL3M.json":   `not json`,
+		"/books/OL4M.json":   `{"title": "` + strings.Repeat("a", maxOpenLibraryDocumentSize) + `"}`,
+	}
+	isbns := map[string]string{"9780735611313": "/books/OL1M.json", "9781985086593": "/books/OL2M.json", "9781449373320": "/books/OL3M.json", "9780262033848": "/books/OL4M.json"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isbn, ok := strings.CutPrefix(r.URL.Path, "/isbn/"); ok {
+			if location, ok := isbns[strings.TrimSuffix(isbn, ".json")]; ok {
+				// like Open Library, redirect to the edition
+				http.Redirect(w, r, location, http.StatusFound)
+				return
+			}
+		}
+		document, ok := documents[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(document))
+	}))
+	t.Cleanup(server.Close)
+	return NewOpenLibraryProvider(server.URL + "/")
+}
+
+func TestOpenLibraryLookupBook(t *testing.T) {
+	provider := setupOpenLibraryStandIn(t)
+
+	metadata, err := provider.LookupBook("9780735611313")
+	require.NoError(t, err)
+	require.Equal(t, model.ISBN("9780735611313"), metadata.ISBN)
+	require.Equal(t, "Code: The Hidden Language of Computer Hardware and Software", metadata.Title)
+	require.Equal(t, []string{"Charles Petzold"}, metadata.Authors)
+	require.Equal(t, "What computers do, from the ground up.", metadata.Description)
+}
+
+func TestOpenLibraryLookupBookTakesAuthorsFromWork(t *testing.T) {
+	provider := setupOpenLibraryStandIn(t)
+
+	metadata, err := provider.LookupBook("9781985086593")
+	require.NoError(t, err)
+	require.Equal(t, "The OS book", metadata.Description)
+	require.Equal(t, []string{"Remzi H. Arpaci-Dusseau", "Andrea C. Arpaci-Dusseau"}, metadata.Authors)
+	require.Equal(t, model.Title("Operating Systems (by Remzi H. Arpaci-Dusseau and Andrea C. Arpaci-Dusseau)"), metadata.FullTitle())
+}
+
+func TestOpenLibraryLookupBookNotFound(t *testing.T) {
+	provider := setupOpenLibraryStandIn(t)
+
+	_, err := provider.LookupBook("9780262510875")
+	require.True(t, errors.Is(err, model.ErrMetadataNotFound))
+}
+
+func TestOpenLibraryLookupBookFailsOnInvalidResponse(t *testing.T) {
+	provider := setupOpenLibraryStandIn(t)
+
+	_, err := provider.LookupBook("9781449373320")
+	require.Error(t, err)
+	require.False(t, errors.Is(err, model.ErrMetadataNotFound))
+	require.Contains(t, err.Error(), "error decoding")
+}
+
+func TestOpenLibraryLookupBookFailsOnOversizedResponse(t *testing.T) {
+	provider := setupOpenLibraryStandIn(t)
+
+	_, err := provider.LookupBook("9780262033848")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error decoding")
+}
@@ -0,0 +1,141 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	DefaultPageFetchTimeout = 10 * time.Second
+	// the metadata is in the head of the page, so there's no need to download more than this of it
+	DefaultMaxPageSize = 1 << 20
+)
+
+// HTTPPageFetcher implements PageFetcher by downloading pages over HTTP and reading the <title>, the OpenGraph tags
+// and the meta description of the head
+type HTTPPageFetcher struct {
+	client      *http.Client
+	maxPageSize int64
+}
+
+// Creates a fetcher that gives up on pages after the timeout and reads no more than maxPageSize bytes of them
+func NewHTTPPageFetcher(timeout time.Duration, maxPageSize int64) *HTTPPageFetcher {
+	return &HTTPPageFetcher{client: &http.Client{Timeout: timeout}, maxPageSize: maxPageSize}
+}
+
+func (f *HTTPPageFetcher) FetchPage(url model.URL) (model.PageMetadata, error) {
+	req, err := http.NewRequest(http.MethodGet, string(url), nil)
+	if err != nil {
+		return model.PageMetadata{}, fmt.Errorf("error requesting %s: %v", url, err)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return model.PageMetadata{}, fmt.Errorf("error requesting %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return model.PageMetadata{}, model.ErrMetadataNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return model.PageMetadata{}, fmt.Errorf("error requesting %s: %s", url, resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return model.PageMetadata{}, fmt.Errorf("%s is not a web page (%s)", url, mediaType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.maxPageSize), contentType)
+	if err != nil {
+		return model.PageMetadata{}, fmt.Errorf("error reading %s: %v", url, err)
+	}
+	metadata, err := parsePageMetadata(body)
+	if err != nil {
+		return model.PageMetadata{}, fmt.Errorf("error reading %s: %v", url, err)
+	}
+	metadata.URL = url
+	return metadata, nil
+}
+
+/*
+Reads the metadata from the head of a page, preferring what the page says for sharing:
+  - the title is the og:title, or else the <title>
+  - the description is the og:description, or else the meta description
+*/
+func parsePageMetadata(r io.Reader) (model.PageMetadata, error) {
+	var title strings.Builder
+	// by name or OpenGraph property, the first of each
+	metas := map[string]string{}
+	inTitle := false
+	tokenizer := html.NewTokenizer(r)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// a page cut off at the size limit still has its head
+			if err := tokenizer.Err(); err != io.EOF && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return model.PageMetadata{}, err
+			}
+			return pageMetadata(title.String(), metas), nil
+		case html.TextToken:
+			if inTitle {
+				title.Write(tokenizer.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Title:
+				inTitle = title.Len() == 0
+			case atom.Meta:
+				if name, content := metaTag(token); metas[name] == "" {
+					metas[name] = content
+				}
+			case atom.Body:
+				return pageMetadata(title.String(), metas), nil
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); atom.Lookup(name) == atom.Title {
+				inTitle = false
+			}
+		}
+	}
+}
+
+// Returns the name (or OpenGraph property) of a meta tag and its content
+func metaTag(token html.Token) (string, string) {
+	var name, content string
+	for _, attr := range token.Attr {
+		switch strings.ToLower(attr.Key) {
+		case "name", "property":
+			if name == "" {
+				name = strings.ToLower(strings.TrimSpace(attr.Val))
+			}
+		case "content":
+			content = attr.Val
+		}
+	}
+	return name, content
+}
+
+func pageMetadata(title string, metas map[string]string) model.PageMetadata {
+	return model.PageMetadata{Title: firstText(metas["og:title"], title), Description: firstText(metas["og:description"], metas["description"])}
+}
+
+// Returns the first of the texts that isn't blank, with its whitespace collapsed
+func firstText(texts ...string) string {
+	for _, text := range texts {
+		if text = strings.Join(strings.Fields(text), " "); text != "" {
+			return text
+		}
+	}
+	return ""
+}
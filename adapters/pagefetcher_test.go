@@ -0,0 +1,109 @@
+package adapters
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPageServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/opengraph", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html><html><head>
+			<title>Effective Go - The Go Programming Language</title>
+			<meta name="description" content="The plain description">
+			<meta property="og:title" content="Effective Go">
+			<meta property="og:description" content="Tips for writing clear, idiomatic Go code.">
+			<meta property="og:title" content="A second title">
+		</head><body><h1>Effective Go</h1></body></html>`))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>\n  Tom &amp; Jerry\n  </title><META NAME=\"Description\" CONTENT=\"  A cat\n and a mouse \"></head><body><title>Not this</title></body></html>"))
+	})
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		w.Write([]byte("<html><head><title>Caf\xe9</title></head></html>"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>Large page</title></head><body>"))
+		w.Write([]byte(strings.Repeat("<p>filler</p>", 100000)))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/plain", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/paper.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4"))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		w.Write([]byte("<title>Too late</title>"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchPagePrefersOpenGraphTags(t *testing.T) {
+	server := setupPageServer(t)
+	fetcher := NewHTTPPageFetcher(time.Second, DefaultMaxPageSize)
+
+	metadata, err := fetcher.FetchPage(model.URL(server.URL + "/opengraph"))
+	require.NoError(t, err)
+	require.Equal(t, model.PageMetadata{URL: model.URL(server.URL + "/opengraph"), Title: "Effective Go", Description: "Tips for writing clear, idiomatic Go code."}, metadata)
+}
+
+func TestFetchPageReadsTitleAndMetaDescription(t *testing.T) {
+	server := setupPageServer(t)
+	fetcher := NewHTTPPageFetcher(time.Second, DefaultMaxPageSize)
+
+	for _, path := range []string{"/plain", "/moved"} {
+		metadata, err := fetcher.FetchPage(model.URL(server.URL + path))
+		require.NoError(t, err)
+		require.Equal(t, "Tom & Jerry", metadata.Title)
+		require.Equal(t, "A cat and a mouse", metadata.Description)
+	}
+
+	metadata, err := fetcher.FetchPage(model.URL(server.URL + "/latin1"))
+	require.NoError(t, err)
+	require.Equal(t, "Café", metadata.Title)
+	require.Empty(t, metadata.Description)
+}
+
+func TestFetchPageReadsOnlyUpToTheSizeLimit(t *testing.T) {
+	server := setupPageServer(t)
+	fetcher := NewHTTPPageFetcher(time.Second, 1024)
+
+	metadata, err := fetcher.FetchPage(model.URL(server.URL + "/large"))
+	require.NoError(t, err)
+	require.Equal(t, "Large page", metadata.Title)
+
+	// the head of the opengraph page is past the limit
+	metadata, err = NewHTTPPageFetcher(time.Second, 20).FetchPage(model.URL(server.URL + "/opengraph"))
+	require.NoError(t, err)
+	require.Empty(t, metadata.Title)
+}
+
+func TestFetchPageFailures(t *testing.T) {
+	server := setupPageServer(t)
+	fetcher := NewHTTPPageFetcher(100*time.Millisecond, DefaultMaxPageSize)
+
+	_, err := fetcher.FetchPage(model.URL(server.URL + "/missing"))
+	require.True(t, errors.Is(err, model.ErrMetadataNotFound))
+
+	_, err = fetcher.FetchPage(model.URL(server.URL + "/paper.pdf"))
+	require.ErrorContains(t, err, "is not a web page (application/pdf)")
+
+	_, err = fetcher.FetchPage(model.URL(server.URL + "/slow"))
+	require.Error(t, err)
+	require.False(t, errors.Is(err, model.ErrMetadataNotFound))
+}
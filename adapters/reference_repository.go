@@ -3,6 +3,7 @@ package adapters
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/VladMinzatu/reference-manager/domain/model"
 )
@@ -15,6 +16,103 @@ func NewSQLiteReferencesRepository(db *sql.DB) *SQLiteReferencesRepository {
 	return &SQLiteReferencesRepository{db: db}
 }
 
+func (r *SQLiteReferencesRepository) GetReferenceById(id model.Id) (*model.CategorizedReference, error) {
+	query := fmt.Sprintf(`%s WHERE br.id = ?`, categorizedReferenceQuery)
+	ref, err := scanCategorizedReference(r.db.QueryRow(query, int64(id)))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("reference with id %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying reference: %v", err)
+	}
+	if ref.Reference == nil {
+		return nil, fmt.Errorf("reference with id %d has an unknown type", id)
+	}
+	return ref, nil
+}
+
+// Returns the links across all categories that point to the same page as the given (canonical) URL.
+// Links added before URLs were canonicalized may be stored in a different form, and SQLite can't canonicalize them,
+// so all links are loaded and compared after canonicalizing them here. That's fine for the size of a personal library.
+func (r *SQLiteReferencesRepository) FindLinksByURL(url model.URL) ([]model.CategorizedReference, error) {
+	query := fmt.Sprintf(`%s WHERE l.reference_id IS NOT NULL ORDER BY br.id`, categorizedReferenceQuery)
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying links: %v", err)
+	}
+	defer rows.Close()
+
+	var matches []model.CategorizedReference
+	for rows.Next() {
+		ref, err := scanCategorizedReference(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning link: %v", err)
+		}
+		link, ok := ref.Reference.(model.LinkReference)
+		if !ok {
+			continue
+		}
+		if canonical, err := model.NewURL(string(link.URL)); link.URL == url || (err == nil && canonical == url) {
+			matches = append(matches, *ref)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating links: %v", err)
+	}
+	return matches, nil
+}
+
+// Returns the references of all categories, category by category, in their order within the category
+func (r *SQLiteReferencesRepository) GetAllReferences() ([]model.CategorizedReference, error) {
+	query := fmt.Sprintf(`%s ORDER BY c.id, br.sort_key`, categorizedReferenceQuery)
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying references: %v", err)
+	}
+	defer rows.Close()
+
+	var refs []model.CategorizedReference
+	for rows.Next() {
+		ref, err := scanCategorizedReference(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning reference: %v", err)
+		}
+		if ref.Reference != nil {
+			refs = append(refs, *ref)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating references: %v", err)
+	}
+	return refs, nil
+}
+
+var categorizedReferenceQuery = fmt.Sprintf(`
+	SELECT
+		c.id, c.name, c.parent_id,
+		%s
+	FROM base_references br
+	JOIN categories c ON c.id = br.category_id
+	%s`, referenceColumns, referenceJoins)
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCategorizedReference(scanner rowScanner) (*model.CategorizedReference, error) {
+	var catId int64
+	var catName string
+	var parentId sql.NullInt64
+	var row referenceRow
+	if err := scanner.Scan(append([]interface{}{&catId, &catName, &parentId}, row.scanDest()...)...); err != nil {
+		return nil, err
+	}
+	return &model.CategorizedReference{
+		Category:  model.CategoryRef{Id: model.Id(catId), Name: model.Title(catName), ParentId: model.Id(parentId.Int64)},
+		Reference: row.build(),
+	}, nil
+}
+
 func (r *SQLiteReferencesRepository) UpdateReference(id model.Id, reference model.Reference) error {
 	tx, err := r.db.Begin()
 	if err != nil {
@@ -43,3 +141,224 @@ func (r *SQLiteReferencesRepository) UpdateReference(id model.Id, reference mode
 
 	return tx.Commit()
 }
+
+// Replaces the reference with one of another type. The base reference is updated in place, so the id, category,
+// position and tags are preserved.
+func (r *SQLiteReferencesRepository) ConvertReference(id model.Id, reference model.Reference) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE base_references SET title = ?, is_starred = ? WHERE id = ?`, string(reference.Title()), reference.Starred(), int64(id))
+	if err != nil {
+		return fmt.Errorf("error updating base reference: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("reference with id %d not found", id)
+	}
+
+	persistor := NewSQLiteReferenceConvertPersistor(tx, int64(id))
+	if err := reference.Persist(persistor); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Saves the merged reference (including its tags) and removes the references that were merged into it, in one transaction.
+// The removed references may belong to any category. The remaining references keep their sort keys, so the order of
+// each category stays as it was, and the versions of the categories that lost references are bumped, so that
+// operations based on a stale view of them fail.
+func (r *SQLiteReferencesRepository) MergeReferences(merged model.Reference, mergedIds []model.Id) error {
+	if len(mergedIds) == 0 {
+		return fmt.Errorf("no references to merge")
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	id := merged.GetId()
+	result, err := tx.Exec(`UPDATE base_references SET title = ?, is_starred = ? WHERE id = ?`, string(merged.Title()), merged.Starred(), int64(id))
+	if err != nil {
+		return fmt.Errorf("error updating base reference: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("reference with id %d not found", id)
+	}
+	if err := merged.Persist(NewSQLiteReferenceUpdatePersistor(tx, int64(id))); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM reference_tags WHERE reference_id = ?`, int64(id)); err != nil {
+		return fmt.Errorf("error clearing tags: %v", err)
+	}
+	for _, tag := range merged.Tags() {
+		if _, err := tx.Exec(`INSERT INTO reference_tags (reference_id, tag) VALUES (?, ?)`, int64(id), string(tag)); err != nil {
+			return fmt.Errorf("error inserting tag: %v", err)
+		}
+	}
+
+	placeholders := make([]string, len(mergedIds))
+	args := make([]interface{}, len(mergedIds))
+	for i, mergedId := range mergedIds {
+		if mergedId == id {
+			return fmt.Errorf("cannot merge reference %d into itself", id)
+		}
+		placeholders[i] = "?"
+		args[i] = int64(mergedId)
+	}
+	inClause := strings.Join(placeholders, ", ")
+
+	if err := moveHighlights(tx, id, inClause, args); err != nil {
+		return err
+	}
+	if err := moveRelations(tx, id, mergedIds, inClause, args); err != nil {
+		return err
+	}
+	// attachments are files the user added, so they are kept too
+	query := fmt.Sprintf(`UPDATE attachments SET reference_id = ? WHERE reference_id IN (%s)`, inClause)
+	if _, err := tx.Exec(query, append([]interface{}{int64(id)}, args...)...); err != nil {
+		return fmt.Errorf("error moving attachments: %v", err)
+	}
+	// and so are the archived copies of merged links, which may be all that is left of a page
+	query = fmt.Sprintf(`UPDATE link_snapshots SET reference_id = ? WHERE reference_id IN (%s)`, inClause)
+	if _, err := tx.Exec(query, append([]interface{}{int64(id)}, args...)...); err != nil {
+		return fmt.Errorf("error moving snapshots: %v", err)
+	}
+	// a book without a cover takes the most recent cover of the merged books
+	query = fmt.Sprintf(`
+		INSERT OR IGNORE INTO book_covers (reference_id, image_hash, media_type, width, height, size, thumbnail_hash, updated_at)
+		SELECT ?, image_hash, media_type, width, height, size, thumbnail_hash, updated_at
+		FROM book_covers
+		WHERE reference_id IN (%s) AND EXISTS (SELECT 1 FROM book_references WHERE reference_id = ?)
+		ORDER BY updated_at DESC
+		LIMIT 1`, inClause)
+	if _, err := tx.Exec(query, append(append([]interface{}{int64(id)}, args...), int64(id))...); err != nil {
+		return fmt.Errorf("error moving cover: %v", err)
+	}
+
+	query = fmt.Sprintf(`UPDATE categories SET version = version + 1 WHERE id IN (SELECT category_id FROM base_references WHERE id IN (%s))`, inClause)
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("error updating category versions: %v", err)
+	}
+	query = fmt.Sprintf(`DELETE FROM base_references WHERE id IN (%s)`, inClause)
+	result, err = tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("error deleting merged references: %v", err)
+	}
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected != int64(len(mergedIds)) {
+		return fmt.Errorf("%d of the merged references were not found", int64(len(mergedIds))-rowsAffected)
+	}
+
+	return tx.Commit()
+}
+
+// Appends the highlights of the merged references to the highlights of the kept one, so that they aren't deleted with them.
+// This is done even if the kept reference isn't a book, for the same reason that highlights survive converting a book.
+func moveHighlights(tx *sql.Tx, keepId model.Id, inClause string, args []interface{}) error {
+	query := fmt.Sprintf(`
+		SELECT h.id FROM book_highlights h
+		JOIN base_references br ON br.id = h.reference_id
+		WHERE h.reference_id IN (%s)
+		ORDER BY br.category_id, br.sort_key, h.sort_key`, inClause)
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("error querying highlights: %v", err)
+	}
+	var highlightIds []int64
+	for rows.Next() {
+		var highlightId int64
+		if err := rows.Scan(&highlightId); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning highlight: %v", err)
+		}
+		highlightIds = append(highlightIds, highlightId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating highlights: %v", err)
+	}
+
+	group := bookHighlightsGroup(keepId)
+	for _, highlightId := range highlightIds {
+		key, err := group.nextKey(tx)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE book_highlights SET reference_id = ?, sort_key = ? WHERE id = ?`, int64(keepId), key, highlightId); err != nil {
+			return fmt.Errorf("error moving highlight: %v", err)
+		}
+	}
+	return nil
+}
+
+// Moves the relations of the merged references to the kept one. Relations between the merged references (and the kept one)
+// would relate the kept reference to itself, so they are dropped, as are prerequisites that would now form a cycle.
+func moveRelations(tx *sql.Tx, keepId model.Id, mergedIds []model.Id, inClause string, args []interface{}) error {
+	query := fmt.Sprintf(`SELECT from_id, to_id, relation_type FROM reference_relations WHERE from_id IN (%s) OR to_id IN (%s)`, inClause, inClause)
+	rows, err := tx.Query(query, append(args, args...)...)
+	if err != nil {
+		return fmt.Errorf("error querying relations: %v", err)
+	}
+	moved, err := collectRelations(rows)
+	if err != nil {
+		return err
+	}
+	query = fmt.Sprintf(`DELETE FROM reference_relations WHERE from_id IN (%s) OR to_id IN (%s)`, inClause, inClause)
+	if _, err := tx.Exec(query, append(args, args...)...); err != nil {
+		return fmt.Errorf("error deleting relations: %v", err)
+	}
+
+	rows, err = tx.Query(`SELECT from_id, to_id, relation_type FROM reference_relations WHERE relation_type = ?`, string(model.PrerequisiteRelation))
+	if err != nil {
+		return fmt.Errorf("error querying relations: %v", err)
+	}
+	prerequisites, err := collectRelations(rows)
+	if err != nil {
+		return err
+	}
+
+	isMerged := make(map[model.Id]bool, len(mergedIds))
+	for _, mergedId := range mergedIds {
+		isMerged[mergedId] = true
+	}
+	remap := func(id model.Id) model.Id {
+		if isMerged[id] {
+			return keepId
+		}
+		return id
+	}
+	for _, relation := range moved {
+		remapped, err := model.NewRelation(remap(relation.From), remap(relation.To), relation.Type)
+		if err != nil {
+			continue
+		}
+		if model.CheckPrerequisiteCycle(prerequisites, remapped) != nil {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO reference_relations (from_id, to_id, relation_type) VALUES (?, ?, ?)`,
+			int64(remapped.From), int64(remapped.To), string(remapped.Type)); err != nil {
+			return fmt.Errorf("error moving relation: %v", err)
+		}
+		if remapped.Type == model.PrerequisiteRelation {
+			prerequisites = append(prerequisites, remapped)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package adapters
 
 import (
 	"testing"
+	"time"
 
 	"github.com/VladMinzatu/reference-manager/domain/model"
 	"github.com/VladMinzatu/reference-manager/testutils"
@@ -109,3 +110,273 @@ func TestUpdatingReferenceWithWrongType(t *testing.T) {
 	require.Equal(t, "desc", desc)
 	require.False(t, starred)
 }
+
+func TestGetReferenceById(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteReferencesRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "TestCat")
+	bookId := testutils.CreateTestBookReference(t, db, catId, "Book", "111-111", "book desc", true)
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link", "https://example.com/link", "link desc", false)
+	noteId := testutils.CreateTestNoteReference(t, db, catId, "Note", "a long note text", false)
+
+	t.Run("returns a book", func(t *testing.T) {
+		ref, err := repo.GetReferenceById(bookId)
+		require.NoError(t, err)
+		require.Equal(t, catId, ref.Category.Id)
+		require.Equal(t, model.Title("TestCat"), ref.Category.Name)
+		book, ok := ref.Reference.(model.BookReference)
+		require.True(t, ok)
+		require.Equal(t, model.Title("Book"), book.Title())
+		require.Equal(t, model.ISBN("111-111"), book.ISBN)
+		require.Equal(t, "book desc", book.Description)
+		require.True(t, book.Starred())
+	})
+
+	t.Run("returns a link", func(t *testing.T) {
+		ref, err := repo.GetReferenceById(linkId)
+		require.NoError(t, err)
+		link, ok := ref.Reference.(model.LinkReference)
+		require.True(t, ok)
+		require.Equal(t, model.URL("https://example.com/link"), link.URL)
+		require.Equal(t, "link desc", link.Description)
+	})
+
+	t.Run("returns a note with its tags", func(t *testing.T) {
+		_, err := db.Exec(`INSERT INTO reference_tags (reference_id, tag) VALUES (?, 'b'), (?, 'a')`, noteId, noteId)
+		require.NoError(t, err)
+
+		ref, err := repo.GetReferenceById(noteId)
+		require.NoError(t, err)
+		note, ok := ref.Reference.(model.NoteReference)
+		require.True(t, ok)
+		require.Equal(t, "a long note text", note.Text)
+		require.Equal(t, []model.Tag{"a", "b"}, note.Tags())
+	})
+
+	t.Run("fails for non-existent reference", func(t *testing.T) {
+		_, err := repo.GetReferenceById(model.Id(999))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found")
+	})
+}
+
+func TestConvertReference(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteReferencesRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "TestCat")
+	testutils.CreateTestNoteReference(t, db, catId, "First", "first", false)
+	refId := testutils.CreateTestBookReference(t, db, catId, "Book", "111-111", "desc", true)
+	testutils.CreateTestNoteReference(t, db, catId, "Last", "last", false)
+	_, err := db.Exec(`INSERT INTO reference_tags (reference_id, tag) VALUES (?, 'kept')`, refId)
+	require.NoError(t, err)
+
+	var sortKey string
+	require.NoError(t, db.QueryRow(`SELECT sort_key FROM base_references WHERE id = ?`, refId).Scan(&sortKey))
+
+	link := model.NewLinkReference(refId, "Link", "https://example.com", "desc", true)
+	require.NoError(t, repo.ConvertReference(refId, link))
+
+	ref, err := repo.GetReferenceById(refId)
+	require.NoError(t, err)
+	converted, ok := ref.Reference.(model.LinkReference)
+	require.True(t, ok)
+	require.Equal(t, model.Title("Link"), converted.Title())
+	require.Equal(t, model.URL("https://example.com"), converted.URL)
+	require.True(t, converted.Starred())
+	require.Equal(t, []model.Tag{"kept"}, converted.Tags())
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM book_references WHERE reference_id = ?`, refId).Scan(&count))
+	require.Equal(t, 0, count)
+
+	var newSortKey string
+	require.NoError(t, db.QueryRow(`SELECT sort_key FROM base_references WHERE id = ?`, refId).Scan(&newSortKey))
+	require.Equal(t, sortKey, newSortKey)
+
+	note := model.NewNoteReference(refId, "Note", "text", false)
+	require.NoError(t, repo.ConvertReference(refId, note))
+	ref, err = repo.GetReferenceById(refId)
+	require.NoError(t, err)
+	_, ok = ref.Reference.(model.NoteReference)
+	require.True(t, ok)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM link_references WHERE reference_id = ?`, refId).Scan(&count))
+	require.Equal(t, 0, count)
+}
+
+func TestConvertNonExistentReference(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteReferencesRepository(db)
+
+	note := model.NewNoteReference(model.Id(999), "Note", "text", false)
+	err := repo.ConvertReference(model.Id(999), note)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "reference with id")
+}
+
+func TestFindLinksByURL(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteReferencesRepository(db)
+
+	cat1, _ := testutils.CreateTestCategory(t, db, "Cat1")
+	cat2, _ := testutils.CreateTestCategory(t, db, "Cat2")
+	canonicalId := testutils.CreateTestLinkReference(t, db, cat1, "Canonical", "https://example.com/article", "", false)
+	// stored before URLs were canonicalized
+	legacyId := testutils.CreateTestLinkReference(t, db, cat2, "Legacy", "HTTPS://Example.com/article/?utm_source=feed", "", false)
+	testutils.CreateTestLinkReference(t, db, cat2, "Other", "https://example.com/other", "", false)
+	testutils.CreateTestBookReference(t, db, cat1, "Book", "9781449373320", "https://example.com/article", false)
+
+	matches, err := repo.FindLinksByURL("https://example.com/article")
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	require.Equal(t, canonicalId, matches[0].Reference.GetId())
+	require.Equal(t, cat1, matches[0].Category.Id)
+	require.Equal(t, legacyId, matches[1].Reference.GetId())
+	require.Equal(t, model.Title("Cat2"), matches[1].Category.Name)
+
+	matches, err = repo.FindLinksByURL("https://example.com/missing")
+	require.NoError(t, err)
+	require.Empty(t, matches)
+}
+
+func TestGetAllReferences(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteReferencesRepository(db)
+
+	cat1, _ := testutils.CreateTestCategory(t, db, "Cat1")
+	cat2, _ := testutils.CreateTestCategory(t, db, "Cat2")
+	note := testutils.CreateTestNoteReference(t, db, cat2, "Note", "text", false)
+	book := testutils.CreateTestBookReference(t, db, cat1, "Book", "9781449373320", "desc", false)
+	link := testutils.CreateTestLinkReference(t, db, cat1, "Link", "https://example.com", "desc", false)
+
+	refs, err := repo.GetAllReferences()
+	require.NoError(t, err)
+	require.Len(t, refs, 3)
+	require.Equal(t, []model.Id{book, link, note}, []model.Id{refs[0].Reference.GetId(), refs[1].Reference.GetId(), refs[2].Reference.GetId()})
+	require.Equal(t, cat2, refs[2].Category.Id)
+}
+
+func TestMergeReferences(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteReferencesRepository(db)
+
+	cat1, v1 := testutils.CreateTestCategory(t, db, "Cat1")
+	cat2, v2 := testutils.CreateTestCategory(t, db, "Cat2")
+	first := testutils.CreateTestNoteReference(t, db, cat1, "First", "", false)
+	keep := testutils.CreateTestBookReference(t, db, cat1, "Book", "9781449373320", "desc", false)
+	last := testutils.CreateTestNoteReference(t, db, cat1, "Last", "", false)
+	other := testutils.CreateTestBookReference(t, db, cat2, "Same Book", "9781449373320", "other desc", true)
+	_, err := db.Exec(`INSERT INTO reference_tags (reference_id, tag) VALUES (?, 'old'), (?, 'other')`, keep, other)
+	require.NoError(t, err)
+
+	merged := model.NewBookReference(keep, "Book", "9781449373320", "desc\n\nother desc", true)
+	merged.SetTags([]model.Tag{"old", "other"})
+	require.NoError(t, repo.MergeReferences(merged, []model.Id{other}))
+
+	ref, err := repo.GetReferenceById(keep)
+	require.NoError(t, err)
+	book := ref.Reference.(model.BookReference)
+	require.Equal(t, "desc\n\nother desc", book.Description)
+	require.True(t, book.Starred())
+	require.Equal(t, []model.Tag{"old", "other"}, book.Tags())
+
+	_, err = repo.GetReferenceById(other)
+	require.Error(t, err)
+
+	// the kept reference stays in place and only the category that lost a reference gets a new version
+	rows, err := db.Query(`SELECT id FROM base_references WHERE category_id = ? ORDER BY sort_key`, cat1)
+	require.NoError(t, err)
+	var ids []model.Id
+	for rows.Next() {
+		var id model.Id
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	rows.Close()
+	require.Equal(t, []model.Id{first, keep, last}, ids)
+
+	var version1, version2 model.Version
+	require.NoError(t, db.QueryRow(`SELECT version FROM categories WHERE id = ?`, cat1).Scan(&version1))
+	require.NoError(t, db.QueryRow(`SELECT version FROM categories WHERE id = ?`, cat2).Scan(&version2))
+	require.Equal(t, v1, version1)
+	require.Equal(t, v2+1, version2)
+}
+
+func TestMergeReferencesKeepsAttachmentsAndCover(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteReferencesRepository(db)
+	attachmentRepo := NewSQLiteAttachmentRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	keep := testutils.CreateTestBookReference(t, db, catId, "Book", "9781449373320", "", false)
+	other := testutils.CreateTestBookReference(t, db, catId, "Same Book", "9781449373320", "", false)
+	_, err := attachmentRepo.AddAttachment(model.Attachment{ReferenceId: other, FileName: "book.pdf", MediaType: "application/pdf", ContentHash: model.HashContent([]byte("pdf")), AddedAt: time.Now()})
+	require.NoError(t, err)
+	coverRepo := NewSQLiteCoverRepository(db)
+	require.NoError(t, coverRepo.SetCover(testCover(other, "cover")))
+
+	require.NoError(t, repo.MergeReferences(model.NewBookReference(keep, "Book", "9781449373320", "", false), []model.Id{other}))
+
+	// the kept book had no cover, so it takes the merged one's
+	cover, err := coverRepo.GetCover(keep)
+	require.NoError(t, err)
+	require.Equal(t, testCover(other, "cover").ImageHash, cover.ImageHash)
+
+	attachments, err := attachmentRepo.GetAttachments(keep)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	require.Equal(t, model.FileName("book.pdf"), attachments[0].FileName)
+}
+
+func TestMergeReferencesKeepsSnapshots(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteReferencesRepository(db)
+	snapshotRepo := NewSQLiteSnapshotRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	keep := testutils.CreateTestLinkReference(t, db, catId, "Go", "https://go.dev", "", false)
+	other := testutils.CreateTestLinkReference(t, db, catId, "Go", "https://go.dev/", "", false)
+	keptSnapshot, err := snapshotRepo.AddSnapshot(model.Snapshot{ReferenceId: keep, URL: "https://go.dev", ContentHash: model.HashContent([]byte("new")), Size: 3, CapturedAt: time.Now()})
+	require.NoError(t, err)
+	mergedSnapshot, err := snapshotRepo.AddSnapshot(model.Snapshot{ReferenceId: other, URL: "https://go.dev/", ContentHash: model.HashContent([]byte("old")), Size: 3, CapturedAt: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.MergeReferences(model.NewLinkReference(keep, "Go", "https://go.dev", "", false), []model.Id{other}))
+
+	snapshots, err := snapshotRepo.GetSnapshots(keep)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	require.Equal(t, keptSnapshot, snapshots[0].Id)
+	require.Equal(t, mergedSnapshot, snapshots[1].Id)
+
+	var orphaned int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM orphaned_blobs`).Scan(&orphaned))
+	require.Zero(t, orphaned)
+}
+
+func TestMergeReferencesFailsForMissingReference(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteReferencesRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	keep := testutils.CreateTestNoteReference(t, db, catId, "Note", "text", false)
+
+	merged := model.NewNoteReference(keep, "Note", "changed", false)
+	err := repo.MergeReferences(merged, []model.Id{model.Id(999)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not found")
+
+	ref, err := repo.GetReferenceById(keep)
+	require.NoError(t, err)
+	require.Equal(t, "text", ref.Reference.(model.NoteReference).Text)
+}
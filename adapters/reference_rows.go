@@ -0,0 +1,113 @@
+package adapters
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+/*
+Reading references is shared between the repositories: the base_references row (aliased br) is joined with all the type-specific
+tables and the type of the reference is resolved from whichever of them has a matching row.
+*/
+
+const (
+	BOOK_TYPE = "book"
+	LINK_TYPE = "link"
+	NOTE_TYPE = "note"
+)
+
+// The columns scanned by referenceRow, in order
+const referenceColumns = `
+			br.id as ref_id, br.title as ref_title, br.is_starred,
+			CASE 
+				WHEN bk.reference_id IS NOT NULL THEN '` + BOOK_TYPE + `'
+				WHEN l.reference_id IS NOT NULL THEN '` + LINK_TYPE + `'
+				WHEN n.reference_id IS NOT NULL THEN '` + NOTE_TYPE + `'
+			END as ref_type,
+			COALESCE(bk.isbn, '') as isbn,
+			COALESCE(bk.description, '') as book_description,
+			COALESCE(l.url, '') as url,
+			COALESCE(l.description, '') as link_description,
+			COALESCE(n.text, '') as text,
+			COALESCE((SELECT GROUP_CONCAT(tag, ',') FROM (SELECT tag FROM reference_tags WHERE reference_id = br.id ORDER BY tag)), '') as tags`
+
+const referenceJoins = `
+		LEFT JOIN book_references bk ON br.id = bk.reference_id
+		LEFT JOIN link_references l ON br.id = l.reference_id
+		LEFT JOIN note_references n ON br.id = n.reference_id`
+
+// referenceRow holds the referenceColumns of a single row. The id is NULL when a category without references is LEFT JOINed.
+type referenceRow struct {
+	id                                                sql.NullInt64
+	title                                             sql.NullString
+	starred                                           sql.NullBool
+	refType                                           sql.NullString
+	isbn, bookDescription, url, linkDescription, text string
+	tags                                              string
+}
+
+func (row *referenceRow) scanDest() []interface{} {
+	return []interface{}{
+		&row.id, &row.title, &row.starred,
+		&row.refType, &row.isbn, &row.bookDescription, &row.url, &row.linkDescription, &row.text, &row.tags,
+	}
+}
+
+// I need to add a note here for the curious reader: yes, this is a switch statement, but because it's coming from the persistence, it's not a switch on type
+// and cannot be removed via double dispatch. Since it's the only place where it happens, I think adding an abstract Factory here is overkill, as it would require
+// the same kind of update when adding a new reference type.
+// On the plus side, the impact of forgetting to add support for a new type here is not big - the new references wold just not show up.
+// Almost certainly something that will not cause more than 5 min of head scratching during development at worst.
+func (row *referenceRow) build() model.Reference {
+	switch row.refType.String {
+	case BOOK_TYPE:
+		return buildBookReference(row.id, row.title, row.starred, row.isbn, row.bookDescription, row.tags)
+	case LINK_TYPE:
+		return buildLinkReference(row.id, row.title, row.starred, row.url, row.linkDescription, row.tags)
+	case NOTE_TYPE:
+		return buildNoteReference(row.id, row.title, row.starred, row.text, row.tags)
+	}
+	return nil
+}
+
+func buildBookReference(refId sql.NullInt64, refTitle sql.NullString, refStarred sql.NullBool, isbn, bookDescription, tags string) model.Reference {
+	bookId, _ := model.NewId(refId.Int64)
+	bookTitle, _ := model.NewTitle(refTitle.String)
+	// not re-validated: rows that the ISBN normalization migration couldn't fix are kept as they are, so that they still show up and can be corrected
+	bookISBN := model.ISBN(isbn)
+	book := model.NewBookReference(bookId, bookTitle, bookISBN, bookDescription, refStarred.Bool)
+	book.SetTags(parseTags(tags))
+	return book
+}
+
+func buildLinkReference(refId sql.NullInt64, refTitle sql.NullString, refStarred sql.NullBool, url, linkDescription, tags string) model.Reference {
+	linkId, _ := model.NewId(refId.Int64)
+	linkTitle, _ := model.NewTitle(refTitle.String)
+	// not re-validated, for the same reason as the ISBNs: links added before URLs were canonicalized are kept as they are
+	linkURL := model.URL(url)
+	link := model.NewLinkReference(linkId, linkTitle, linkURL, linkDescription, refStarred.Bool)
+	link.SetTags(parseTags(tags))
+	return link
+}
+
+func buildNoteReference(refId sql.NullInt64, refTitle sql.NullString, refStarred sql.NullBool, text, tags string) model.Reference {
+	noteId, _ := model.NewId(refId.Int64)
+	noteTitle, _ := model.NewTitle(refTitle.String)
+	note := model.NewNoteReference(noteId, noteTitle, text, refStarred.Bool)
+	note.SetTags(parseTags(tags))
+	return note
+}
+
+// Tags are loaded as a single comma separated column (tags can't contain commas)
+func parseTags(tags string) []model.Tag {
+	if tags == "" {
+		return nil
+	}
+	var result []model.Tag
+	for _, tag := range strings.Split(tags, ",") {
+		result = append(result, model.Tag(tag))
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+type SQLiteRelationRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteRelationRepository(db *sql.DB) *SQLiteRelationRepository {
+	return &SQLiteRelationRepository{db: db}
+}
+
+func (r *SQLiteRelationRepository) GetRelations(referenceId model.Id) ([]model.Relation, error) {
+	rows, err := r.db.Query(`
+		SELECT from_id, to_id, relation_type
+		FROM reference_relations
+		WHERE from_id = ? OR to_id = ?
+		ORDER BY relation_type, from_id, to_id`, int64(referenceId), int64(referenceId))
+	if err != nil {
+		return nil, fmt.Errorf("error querying relations: %v", err)
+	}
+	return collectRelations(rows)
+}
+
+func (r *SQLiteRelationRepository) GetAllRelations() ([]model.Relation, error) {
+	rows, err := r.db.Query(`
+		SELECT from_id, to_id, relation_type
+		FROM reference_relations
+		ORDER BY relation_type, from_id, to_id`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying relations: %v", err)
+	}
+	return collectRelations(rows)
+}
+
+func (r *SQLiteRelationRepository) GetRelationsOfType(relType model.RelationType) ([]model.Relation, error) {
+	rows, err := r.db.Query(`
+		SELECT from_id, to_id, relation_type
+		FROM reference_relations
+		WHERE relation_type = ?
+		ORDER BY from_id, to_id`, string(relType))
+	if err != nil {
+		return nil, fmt.Errorf("error querying relations: %v", err)
+	}
+	return collectRelations(rows)
+}
+
+func (r *SQLiteRelationRepository) AddRelation(relation model.Relation) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range []model.Id{relation.From, relation.To} {
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM base_references WHERE id = ?)`, int64(id)).Scan(&exists); err != nil {
+			return fmt.Errorf("error querying reference: %v", err)
+		}
+		if !exists {
+			return fmt.Errorf("reference with id %d not found", id)
+		}
+	}
+
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM reference_relations WHERE from_id = ? AND to_id = ? AND relation_type = ?)`,
+		int64(relation.From), int64(relation.To), string(relation.Type)).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error querying relation: %v", err)
+	}
+	if exists {
+		return fmt.Errorf("relation already exists")
+	}
+
+	if relation.Type == model.PrerequisiteRelation {
+		rows, err := tx.Query(`SELECT from_id, to_id, relation_type FROM reference_relations WHERE relation_type = ?`, string(model.PrerequisiteRelation))
+		if err != nil {
+			return fmt.Errorf("error querying relations: %v", err)
+		}
+		prerequisites, err := collectRelations(rows)
+		if err != nil {
+			return err
+		}
+		if err := model.CheckPrerequisiteCycle(prerequisites, relation); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT INTO reference_relations (from_id, to_id, relation_type) VALUES (?, ?, ?)`,
+		int64(relation.From), int64(relation.To), string(relation.Type)); err != nil {
+		return fmt.Errorf("error inserting relation: %v", err)
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteRelationRepository) RemoveRelation(relation model.Relation) error {
+	result, err := r.db.Exec(`DELETE FROM reference_relations WHERE from_id = ? AND to_id = ? AND relation_type = ?`,
+		int64(relation.From), int64(relation.To), string(relation.Type))
+	if err != nil {
+		return fmt.Errorf("error deleting relation: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("relation not found")
+	}
+	return nil
+}
+
+// Reads the relations from the rows and closes them
+func collectRelations(rows *sql.Rows) ([]model.Relation, error) {
+	defer rows.Close()
+	relations := []model.Relation{}
+	for rows.Next() {
+		var from, to int64
+		var relType string
+		if err := rows.Scan(&from, &to, &relType); err != nil {
+			return nil, fmt.Errorf("error scanning relation: %v", err)
+		}
+		relations = append(relations, model.Relation{From: model.Id(from), To: model.Id(to), Type: model.RelationType(relType)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating relations: %v", err)
+	}
+	return relations, nil
+}
@@ -0,0 +1,146 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/testutils"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/require"
+)
+
+func addTestRelation(t *testing.T, repo *SQLiteRelationRepository, from, to model.Id, relType model.RelationType) model.Relation {
+	relation, err := model.NewRelation(from, to, relType)
+	require.NoError(t, err)
+	require.NoError(t, repo.AddRelation(relation))
+	return relation
+}
+
+func TestAddAndGetRelationsAcrossCategories(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteRelationRepository(db)
+
+	cat1, _ := testutils.CreateTestCategory(t, db, "Cat1")
+	cat2, _ := testutils.CreateTestCategory(t, db, "Cat2")
+	ostep := testutils.CreateTestBookReference(t, db, cat1, "OSTEP", "9781985086593", "", false)
+	ddia := testutils.CreateTestBookReference(t, db, cat2, "DDIA", "9781449373320", "", false)
+	note := testutils.CreateTestNoteReference(t, db, cat2, "Note", "text", false)
+
+	prerequisite := addTestRelation(t, repo, ostep, ddia, model.PrerequisiteRelation)
+	related := addTestRelation(t, repo, note, ddia, model.RelatedRelation)
+
+	relations, err := repo.GetRelations(ddia)
+	require.NoError(t, err)
+	require.Equal(t, []model.Relation{prerequisite, related}, relations)
+
+	relations, err = repo.GetRelations(ostep)
+	require.NoError(t, err)
+	require.Equal(t, []model.Relation{prerequisite}, relations)
+
+	relations, err = repo.GetRelationsOfType(model.RelatedRelation)
+	require.NoError(t, err)
+	require.Equal(t, []model.Relation{related}, relations)
+
+	relations, err = repo.GetAllRelations()
+	require.NoError(t, err)
+	require.Equal(t, []model.Relation{prerequisite, related}, relations)
+}
+
+func TestAddRelationFailsForDuplicateOrMissingReference(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteRelationRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	a := testutils.CreateTestNoteReference(t, db, catId, "A", "a", false)
+	b := testutils.CreateTestNoteReference(t, db, catId, "B", "b", false)
+	addTestRelation(t, repo, a, b, model.RelatedRelation)
+
+	// the same related relation, written the other way around
+	relation, err := model.NewRelation(b, a, model.RelatedRelation)
+	require.NoError(t, err)
+	require.ErrorContains(t, repo.AddRelation(relation), "already exists")
+
+	relation, err = model.NewRelation(a, 999, model.SupersedesRelation)
+	require.NoError(t, err)
+	require.ErrorContains(t, repo.AddRelation(relation), "not found")
+}
+
+func TestAddPrerequisiteRelationRejectsCycles(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteRelationRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	a := testutils.CreateTestNoteReference(t, db, catId, "A", "a", false)
+	b := testutils.CreateTestNoteReference(t, db, catId, "B", "b", false)
+	c := testutils.CreateTestNoteReference(t, db, catId, "C", "c", false)
+	addTestRelation(t, repo, a, b, model.PrerequisiteRelation)
+	addTestRelation(t, repo, b, c, model.PrerequisiteRelation)
+
+	relation, err := model.NewRelation(c, a, model.PrerequisiteRelation)
+	require.NoError(t, err)
+	require.ErrorContains(t, repo.AddRelation(relation), "cycle")
+
+	// supersedes isn't checked for cycles
+	addTestRelation(t, repo, c, a, model.SupersedesRelation)
+
+	relations, err := repo.GetRelationsOfType(model.PrerequisiteRelation)
+	require.NoError(t, err)
+	require.Len(t, relations, 2)
+}
+
+func TestRemoveRelationAndCascadeOnReferenceDelete(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteRelationRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	a := testutils.CreateTestNoteReference(t, db, catId, "A", "a", false)
+	b := testutils.CreateTestNoteReference(t, db, catId, "B", "b", false)
+	c := testutils.CreateTestNoteReference(t, db, catId, "C", "c", false)
+	related := addTestRelation(t, repo, a, b, model.RelatedRelation)
+	addTestRelation(t, repo, a, c, model.PrerequisiteRelation)
+
+	require.NoError(t, repo.RemoveRelation(related))
+	require.ErrorContains(t, repo.RemoveRelation(related), "not found")
+
+	_, err := db.Exec(`DELETE FROM base_references WHERE id = ?`, int64(c))
+	require.NoError(t, err)
+	relations, err := repo.GetRelations(a)
+	require.NoError(t, err)
+	require.Empty(t, relations)
+}
+
+func TestMergeReferencesMovesRelations(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteRelationRepository(db)
+	referenceRepo := NewSQLiteReferencesRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	keep := testutils.CreateTestNoteReference(t, db, catId, "Keep", "k", false)
+	other := testutils.CreateTestNoteReference(t, db, catId, "Other", "o", false)
+	a := testutils.CreateTestNoteReference(t, db, catId, "A", "a", false)
+	b := testutils.CreateTestNoteReference(t, db, catId, "B", "b", false)
+	prerequisite := addTestRelation(t, repo, a, keep, model.PrerequisiteRelation)
+	// would make keep a prerequisite of a, which is a prerequisite of keep
+	addTestRelation(t, repo, other, a, model.PrerequisiteRelation)
+	// would relate keep to itself
+	addTestRelation(t, repo, other, keep, model.RelatedRelation)
+	addTestRelation(t, repo, other, b, model.SupersedesRelation)
+	addTestRelation(t, repo, b, other, model.RelatedRelation)
+
+	merged := model.NewNoteReference(keep, "Keep", "k\n\no", false)
+	require.NoError(t, referenceRepo.MergeReferences(merged, []model.Id{other}))
+
+	relations, err := repo.GetRelations(keep)
+	require.NoError(t, err)
+	require.Equal(t, []model.Relation{
+		prerequisite,
+		{From: keep, To: b, Type: model.RelatedRelation},
+		{From: keep, To: b, Type: model.SupersedesRelation},
+	}, relations)
+}
@@ -0,0 +1,108 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+type SQLiteSnapshotRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteSnapshotRepository(db *sql.DB) *SQLiteSnapshotRepository {
+	return &SQLiteSnapshotRepository{db: db}
+}
+
+func (r *SQLiteSnapshotRepository) GetSnapshots(referenceId model.Id) ([]model.Snapshot, error) {
+	rows, err := r.db.Query(`
+		SELECT id, reference_id, url, content_hash, size, inlined_assets, captured_at
+		FROM link_snapshots
+		WHERE reference_id = ?
+		ORDER BY captured_at DESC, id DESC`, int64(referenceId))
+	if err != nil {
+		return nil, fmt.Errorf("error querying snapshots: %v", err)
+	}
+	defer rows.Close()
+
+	snapshots := []model.Snapshot{}
+	for rows.Next() {
+		snapshot, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning snapshot: %v", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating snapshots: %v", err)
+	}
+	return snapshots, nil
+}
+
+func (r *SQLiteSnapshotRepository) GetSnapshotById(id model.Id) (*model.Snapshot, error) {
+	row := r.db.QueryRow(`
+		SELECT id, reference_id, url, content_hash, size, inlined_assets, captured_at
+		FROM link_snapshots WHERE id = ?`, int64(id))
+	snapshot, err := scanSnapshot(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("snapshot with id %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying snapshot: %v", err)
+	}
+	return &snapshot, nil
+}
+
+func (r *SQLiteSnapshotRepository) AddSnapshot(snapshot model.Snapshot) (model.Id, error) {
+	// only links are archived
+	result, err := r.db.Exec(`
+		INSERT INTO link_snapshots (reference_id, url, content_hash, size, inlined_assets, captured_at)
+		SELECT ?, ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM link_references WHERE reference_id = ?)`,
+		int64(snapshot.ReferenceId), string(snapshot.URL), string(snapshot.ContentHash), snapshot.Size,
+		snapshot.InlinedAssets, snapshot.CapturedAt.Unix(), int64(snapshot.ReferenceId))
+	if err != nil {
+		return 0, fmt.Errorf("error inserting snapshot: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return 0, fmt.Errorf("no link reference found with id %d", snapshot.ReferenceId)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting snapshot id: %v", err)
+	}
+	return model.Id(id), nil
+}
+
+func (r *SQLiteSnapshotRepository) DeleteSnapshot(id model.Id) error {
+	result, err := r.db.Exec(`DELETE FROM link_snapshots WHERE id = ?`, int64(id))
+	if err != nil {
+		return fmt.Errorf("error deleting snapshot: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("snapshot with id %d not found", id)
+	}
+	return nil
+}
+
+func scanSnapshot(scanner rowScanner) (model.Snapshot, error) {
+	var id, referenceId, capturedAt int64
+	var url, hash string
+	var snapshot model.Snapshot
+	if err := scanner.Scan(&id, &referenceId, &url, &hash, &snapshot.Size, &snapshot.InlinedAssets, &capturedAt); err != nil {
+		return model.Snapshot{}, err
+	}
+	snapshot.Id, snapshot.ReferenceId, snapshot.URL, snapshot.ContentHash = model.Id(id), model.Id(referenceId), model.URL(url), model.ContentHash(hash)
+	snapshot.CapturedAt = time.Unix(capturedAt, 0)
+	return snapshot, nil
+}
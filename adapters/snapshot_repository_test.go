@@ -0,0 +1,80 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/testutils"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAndGetSnapshots(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteSnapshotRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	linkId := testutils.CreateTestLinkReference(t, db, catId, "Link", "https://example.com/post", "", false)
+
+	older := model.Snapshot{ReferenceId: linkId, URL: "https://example.com/post", ContentHash: model.HashContent([]byte("v1")), Size: 2, CapturedAt: time.Unix(1760000000, 0)}
+	newer := model.Snapshot{ReferenceId: linkId, URL: "https://example.com/post", ContentHash: model.HashContent([]byte("v2")), Size: 2, InlinedAssets: true, CapturedAt: time.Unix(1760003600, 0)}
+	olderId, err := repo.AddSnapshot(older)
+	require.NoError(t, err)
+	newerId, err := repo.AddSnapshot(newer)
+	require.NoError(t, err)
+	older.Id, newer.Id = olderId, newerId
+
+	snapshots, err := repo.GetSnapshots(linkId)
+	require.NoError(t, err)
+	require.Equal(t, []model.Snapshot{newer, older}, snapshots)
+
+	loaded, err := repo.GetSnapshotById(olderId)
+	require.NoError(t, err)
+	require.Equal(t, older, *loaded)
+	_, err = repo.GetSnapshotById(999)
+	require.Error(t, err)
+}
+
+func TestAddSnapshotOfNonLinkFails(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteSnapshotRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	noteId := testutils.CreateTestNoteReference(t, db, catId, "Note", "text", false)
+
+	_, err := repo.AddSnapshot(model.Snapshot{ReferenceId: noteId, URL: "https://example.com", ContentHash: model.HashContent(nil), CapturedAt: time.Now()})
+	require.Error(t, err)
+}
+
+func TestDeleteSnapshotOrphansContentNoLongerInUse(t *testing.T) {
+	db, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	repo := NewSQLiteSnapshotRepository(db)
+	orphanRepo := NewSQLiteOrphanedBlobRepository(db)
+
+	catId, _ := testutils.CreateTestCategory(t, db, "Cat")
+	first := testutils.CreateTestLinkReference(t, db, catId, "First", "https://example.com/a", "", false)
+	second := testutils.CreateTestLinkReference(t, db, catId, "Second", "https://example.com/b", "", false)
+
+	// identical pages share their content
+	hash := model.HashContent([]byte("same page"))
+	firstSnapshot, err := repo.AddSnapshot(model.Snapshot{ReferenceId: first, URL: "https://example.com/a", ContentHash: hash, Size: 9, CapturedAt: time.Now()})
+	require.NoError(t, err)
+	secondSnapshot, err := repo.AddSnapshot(model.Snapshot{ReferenceId: second, URL: "https://example.com/b", ContentHash: hash, Size: 9, CapturedAt: time.Now()})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteSnapshot(firstSnapshot))
+	orphans, err := orphanRepo.GetOrphanedBlobs()
+	require.NoError(t, err)
+	require.Empty(t, orphans)
+	require.NoError(t, repo.DeleteSnapshot(secondSnapshot))
+	orphans, err = orphanRepo.GetOrphanedBlobs()
+	require.NoError(t, err)
+	require.Equal(t, []model.ContentHash{hash}, orphans)
+
+	require.Error(t, repo.DeleteSnapshot(secondSnapshot))
+}
@@ -0,0 +1,170 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/util"
+)
+
+// sortKeyGroup is a list of sibling rows ordered by their sort_key: the children of a parent category (or the top-level
+// categories), the references of a category or the highlights of a book. See domain/util/sortkeys.go for how the keys work.
+type sortKeyGroup struct {
+	table  string
+	filter string
+	args   []interface{}
+}
+
+func categoryChildrenGroup(parentId model.Id) sortKeyGroup {
+	return sortKeyGroup{table: "categories", filter: "parent_id IS ?", args: []interface{}{parentParam(parentId)}}
+}
+
+func categoryReferencesGroup(categoryId model.Id) sortKeyGroup {
+	return sortKeyGroup{table: "base_references", filter: "category_id = ?", args: []interface{}{int64(categoryId)}}
+}
+
+func bookHighlightsGroup(referenceId model.Id) sortKeyGroup {
+	return sortKeyGroup{table: "book_highlights", filter: "reference_id = ?", args: []interface{}{int64(referenceId)}}
+}
+
+// Loads the ids and keys of the group in order
+func (g sortKeyGroup) load(tx *sql.Tx) ([]util.KeyedItem, error) {
+	rows, err := tx.Query(fmt.Sprintf(`SELECT id, sort_key FROM %s WHERE %s ORDER BY sort_key`, g.table, g.filter), g.args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching sort keys: %v", err)
+	}
+	defer rows.Close()
+
+	var items []util.KeyedItem
+	for rows.Next() {
+		var id int64
+		var key string
+		if err := rows.Scan(&id, &key); err != nil {
+			return nil, fmt.Errorf("error scanning sort key: %v", err)
+		}
+		items = append(items, util.KeyedItem{Id: model.Id(id), Key: key})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sort keys: %v", err)
+	}
+	return items, nil
+}
+
+// Returns a key that places a new row at the end of the group, rebalancing the group first if the keys have grown too long.
+// Note: like the MAX(position) + 1 approach it replaces, this is only safe because SQLite serializes writers.
+func (g sortKeyGroup) nextKey(tx *sql.Tx) (string, error) {
+	var last string
+	err := tx.QueryRow(fmt.Sprintf(`SELECT COALESCE(MAX(sort_key), '') FROM %s WHERE %s`, g.table, g.filter), g.args...).Scan(&last)
+	if err != nil {
+		return "", fmt.Errorf("error fetching last sort key: %v", err)
+	}
+	key, err := util.SortKeyBetween(last, "")
+	if err != nil {
+		return "", err
+	}
+	if !util.NeedsRebalancing(key) {
+		return key, nil
+	}
+
+	items, err := g.load(tx)
+	if err != nil {
+		return "", err
+	}
+	update := util.RebalanceSortKeys(items)
+	if err := g.apply(tx, update); err != nil {
+		return "", err
+	}
+	return util.SortKeyBetween(update.Keys[items[len(items)-1].Id], "")
+}
+
+// Returns the key that moves a row of the group to the given index, as counted among its siblings.
+// Only the moved row needs the new key, which is what makes single-item moves cheap.
+func (g sortKeyGroup) keyForMove(tx *sql.Tx, id model.Id, index int) (string, error) {
+	items, err := g.load(tx)
+	if err != nil {
+		return "", err
+	}
+	others := make([]util.KeyedItem, 0, len(items))
+	for _, item := range items {
+		if item.Id != id {
+			others = append(others, item)
+		}
+	}
+	if len(others) == len(items) {
+		return "", fmt.Errorf("row with id %d not found", id)
+	}
+	if index < 0 || index > len(others) {
+		return "", fmt.Errorf("invalid position %d (must be between 0 and %d)", index, len(others))
+	}
+
+	before, after := "", ""
+	if index > 0 {
+		before = others[index-1].Key
+	}
+	if index < len(others) {
+		after = others[index].Key
+	}
+	key, err := util.SortKeyBetween(before, after)
+	if err != nil {
+		return "", err
+	}
+	if !util.NeedsRebalancing(key) {
+		return key, nil
+	}
+
+	// rebalance and retry; the fresh keys are short, so the new key is too
+	if err := g.apply(tx, util.RebalanceSortKeys(items)); err != nil {
+		return "", err
+	}
+	return g.keyForMove(tx, id, index)
+}
+
+// Moves a row of the group to the given index, leaving the other rows untouched (unless the group had to be rebalanced)
+func (g sortKeyGroup) move(tx *sql.Tx, id model.Id, index int) error {
+	key, err := g.keyForMove(tx, id, index)
+	if err != nil {
+		return err
+	}
+	return g.apply(tx, util.SortKeyUpdate{Keys: map[model.Id]string{id: key}})
+}
+
+// Reorders the whole group according to a permutation of its ids, only rewriting the keys that need to change
+func (g sortKeyGroup) reorder(tx *sql.Tx, positions map[model.Id]int) error {
+	items, err := g.load(tx)
+	if err != nil {
+		return err
+	}
+	update, err := util.ReassignSortKeys(items, positions)
+	if err != nil {
+		return err
+	}
+	return g.apply(tx, update)
+}
+
+func (g sortKeyGroup) apply(tx *sql.Tx, update util.SortKeyUpdate) error {
+	if update.Rebalanced {
+		// Fresh keys may collide with the old keys of other rows, so first move the whole group out of the way.
+		// '~' sorts after all key digits and the id makes the temporary keys unique. This is the only case where rows are written twice.
+		query := fmt.Sprintf(`UPDATE %s SET sort_key = '~' || id WHERE %s`, g.table, g.filter)
+		if _, err := tx.Exec(query, g.args...); err != nil {
+			return fmt.Errorf("error clearing sort keys for rebalancing: %v", err)
+		}
+	}
+	query := fmt.Sprintf(`UPDATE %s SET sort_key = ? WHERE id = ? AND %s`, g.table, g.filter)
+	for id, key := range update.Keys {
+		args := append([]interface{}{key, int64(id)}, g.args...)
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return fmt.Errorf("error updating sort key: %v", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error getting rows affected: %v", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("row with id %d not found", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// SQLiteReferenceConvertPersistor implements ReferencePersistor for changing the type of an existing reference in SQLite:
+// the row of the old type-specific table is dropped and one is inserted into the table of the new type, while the
+// base_references row (and with it the id, position and tags) is kept.
+type SQLiteReferenceConvertPersistor struct {
+	tx    *sql.Tx
+	refId int64
+}
+
+func NewSQLiteReferenceConvertPersistor(tx *sql.Tx, refId int64) *SQLiteReferenceConvertPersistor {
+	return &SQLiteReferenceConvertPersistor{
+		tx:    tx,
+		refId: refId,
+	}
+}
+
+func (p *SQLiteReferenceConvertPersistor) PersistBook(reference model.BookReference) error {
+	if err := p.clear(); err != nil {
+		return err
+	}
+	_, err := p.tx.Exec(`INSERT INTO book_references (reference_id, isbn, description) VALUES (?, ?, ?)`, p.refId, reference.ISBN, reference.Description)
+	if err != nil {
+		return fmt.Errorf("error inserting book reference: %v", err)
+	}
+	return nil
+}
+
+func (p *SQLiteReferenceConvertPersistor) PersistLink(reference model.LinkReference) error {
+	if err := p.clear(); err != nil {
+		return err
+	}
+	_, err := p.tx.Exec(`INSERT INTO link_references (reference_id, url, description) VALUES (?, ?, ?)`, p.refId, reference.URL, reference.Description)
+	if err != nil {
+		return fmt.Errorf("error inserting link reference: %v", err)
+	}
+	return nil
+}
+
+func (p *SQLiteReferenceConvertPersistor) PersistNote(reference model.NoteReference) error {
+	if err := p.clear(); err != nil {
+		return err
+	}
+	_, err := p.tx.Exec(`INSERT INTO note_references (reference_id, text) VALUES (?, ?)`, p.refId, reference.Text)
+	if err != nil {
+		return fmt.Errorf("error inserting note reference: %v", err)
+	}
+	return nil
+}
+
+func (p *SQLiteReferenceConvertPersistor) clear() error {
+	for _, table := range []string{"book_references", "link_references", "note_references"} {
+		if _, err := p.tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE reference_id = ?`, table), p.refId); err != nil {
+			return fmt.Errorf("error deleting from %s: %v", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package citation formats books and links as plain-text citations in the APA, MLA and Chicago styles.
+package citation
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+type Style string
+
+const (
+	APA     Style = "apa"
+	MLA     Style = "mla"
+	Chicago Style = "chicago"
+)
+
+func NewStyle(val string) (Style, error) {
+	switch s := Style(strings.ToLower(strings.TrimSpace(val))); s {
+	case APA, MLA, Chicago:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown citation style %q (must be one of apa, mla, chicago)", val)
+}
+
+// Formats the reference in the given style. Links are cited as accessed at the given time.
+func Cite(ref model.Reference, style Style, accessed time.Time) (string, error) {
+	renderer := NewRenderer(style, accessed)
+	ref.Render(renderer)
+	return renderer.Citation()
+}
+
+/*
+Renderer formats the references it renders as citations. References only have a title and an ISBN or URL, so:
+  - the author of a book is taken from its title when it follows the "<title> (by <author>)" convention, and
+    the citation starts with the title otherwise
+  - there is no publisher or year, so APA citations use "n.d." (no date) and the others leave them out
+  - books are identified by their ISBN, links by their URL and the date they were accessed
+  - notes aren't published anywhere, so they can't be cited
+*/
+type Renderer struct {
+	style    Style
+	accessed time.Time
+	citation string
+	err      error
+}
+
+func NewRenderer(style Style, accessed time.Time) *Renderer {
+	return &Renderer{style: style, accessed: accessed}
+}
+
+// Returns the citation of the last rendered reference
+func (r *Renderer) Citation() (string, error) {
+	return r.citation, r.err
+}
+
+func (r *Renderer) RenderBook(ref model.BookReference) {
+	title, authors := model.SplitAuthors(ref.Title())
+	isbn := "ISBN " + ref.ISBN.Hyphenated()
+	r.err = nil
+	switch r.style {
+	case APA:
+		if len(authors) == 0 {
+			r.citation = fmt.Sprintf("%s (n.d.). %s.", sentence(title), isbn)
+		} else {
+			r.citation = fmt.Sprintf("%s (n.d.). %s %s.", apaAuthors(authors), sentence(title), isbn)
+		}
+	case MLA, Chicago:
+		if len(authors) == 0 {
+			r.citation = fmt.Sprintf("%s %s.", sentence(title), isbn)
+		} else {
+			r.citation = fmt.Sprintf("%s %s %s.", sentence(fullAuthors(authors, r.style)), sentence(title), isbn)
+		}
+	default:
+		r.citation, r.err = "", fmt.Errorf("unknown citation style %q", r.style)
+	}
+}
+
+func (r *Renderer) RenderLink(ref model.LinkReference) {
+	title := string(ref.Title())
+	link := string(ref.URL)
+	site := siteName(link)
+	r.err = nil
+	switch r.style {
+	case APA:
+		r.citation = fmt.Sprintf("%s (n.d.). %s. Retrieved %s, from %s", sentence(title), site, r.accessed.Format("January 2, 2006"), link)
+	case MLA:
+		r.citation = fmt.Sprintf("%s %s, %s. Accessed %s.", quoted(title), site, strings.TrimPrefix(strings.TrimPrefix(link, "https://"), "http://"), r.accessed.Format("2 Jan. 2006"))
+	case Chicago:
+		r.citation = fmt.Sprintf("%s %s. Accessed %s. %s.", quoted(title), site, r.accessed.Format("January 2, 2006"), link)
+	default:
+		r.citation, r.err = "", fmt.Errorf("unknown citation style %q", r.style)
+	}
+}
+
+func (r *Renderer) RenderNote(ref model.NoteReference) {
+	r.citation, r.err = "", errors.New("notes can't be cited, only books and links")
+}
+
+// "Petzold, C." for each author, the last one preceded by "&"
+func apaAuthors(authors []string) string {
+	formatted := make([]string, len(authors))
+	for i, author := range authors {
+		given, surname := model.SplitName(author)
+		var initials []string
+		for _, word := range strings.FieldsFunc(given, func(r rune) bool { return r == ' ' || r == '-' }) {
+			initials = append(initials, string([]rune(word)[0])+".")
+		}
+		formatted[i] = surname
+		if len(initials) > 0 {
+			formatted[i] += ", " + strings.Join(initials, " ")
+		}
+	}
+	if len(formatted) == 1 {
+		return formatted[0]
+	}
+	return strings.Join(formatted[:len(formatted)-1], ", ") + ", & " + formatted[len(formatted)-1]
+}
+
+// "Petzold, Charles" for the first author and "Charles Petzold" for the others. MLA abbreviates three or more authors to "et al.".
+func fullAuthors(authors []string, style Style) string {
+	given, surname := model.SplitName(authors[0])
+	first := surname
+	if given != "" {
+		first += ", " + given
+	}
+	switch {
+	case len(authors) == 1:
+		return first
+	case style == MLA && len(authors) > 2:
+		return first + ", et al."
+	case len(authors) == 2:
+		return first + ", and " + authors[1]
+	}
+	return first + ", " + strings.Join(authors[1:len(authors)-1], ", ") + ", and " + authors[len(authors)-1]
+}
+
+// Returns the host of the URL without the "www." prefix, as the name of the site
+func siteName(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return link
+	}
+	return strings.TrimPrefix(parsed.Hostname(), "www.")
+}
+
+// Ends the text with a period, unless it already ends with punctuation
+func sentence(text string) string {
+	if strings.HasSuffix(text, ".") || strings.HasSuffix(text, "?") || strings.HasSuffix(text, "!") {
+		return text
+	}
+	return text + "."
+}
+
+// Quotes a title, with the period inside the quotes as both MLA and Chicago have it
+func quoted(title string) string {
+	return `"` + sentence(title) + `"`
+}
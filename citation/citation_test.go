@@ -0,0 +1,87 @@
+package citation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+var accessed = time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+func TestNewStyle(t *testing.T) {
+	style, err := NewStyle(" APA ")
+	if err != nil || style != APA {
+		t.Errorf("expected apa, got %v, err=%v", style, err)
+	}
+	if _, err := NewStyle("ieee"); err == nil {
+		t.Error("expected error for unknown style")
+	}
+}
+
+func TestCiteBook(t *testing.T) {
+	withAuthor := model.NewBookReference(1, "Code: The Hidden Language of Computer Hardware and Software (by Charles Petzold)", "9780735611313", "", false)
+	withoutAuthor := model.NewBookReference(2, "Designing Data-Intensive Applications", "9781449373320", "", false)
+	tests := []struct {
+		ref      model.Reference
+		style    Style
+		expected string
+	}{
+		{withAuthor, APA, "Petzold, C. (n.d.). Code: The Hidden Language of Computer Hardware and Software. ISBN 978-0-7356-1131-3."},
+		{withAuthor, MLA, "Petzold, Charles. Code: The Hidden Language of Computer Hardware and Software. ISBN 978-0-7356-1131-3."},
+		{withAuthor, Chicago, "Petzold, Charles. Code: The Hidden Language of Computer Hardware and Software. ISBN 978-0-7356-1131-3."},
+		{withoutAuthor, APA, "Designing Data-Intensive Applications. (n.d.). ISBN 978-1-4493-7332-0."},
+		{withoutAuthor, MLA, "Designing Data-Intensive Applications. ISBN 978-1-4493-7332-0."},
+	}
+	for _, test := range tests {
+		citation, err := Cite(test.ref, test.style, accessed)
+		if err != nil || citation != test.expected {
+			t.Errorf("expected %s citation %q, got %q, err=%v", test.style, test.expected, citation, err)
+		}
+	}
+}
+
+func TestCiteBookWithSeveralAuthors(t *testing.T) {
+	two := model.NewBookReference(1, "Operating Systems: Three Easy Pieces (by Remzi H. Arpaci-Dusseau and Andrea C. Arpaci-Dusseau)", "9781985086593", "", false)
+	three := model.NewBookReference(2, "Structure and Interpretation of Computer Programs (by Harold Abelson, Gerald Jay Sussman & Julie Sussman)", "9780262510875", "", false)
+	tests := []struct {
+		ref      model.Reference
+		style    Style
+		expected string
+	}{
+		{two, APA, "Arpaci-Dusseau, R. H., & Arpaci-Dusseau, A. C. (n.d.). Operating Systems: Three Easy Pieces."},
+		{two, MLA, "Arpaci-Dusseau, Remzi H., and Andrea C. Arpaci-Dusseau. Operating Systems: Three Easy Pieces."},
+		{three, MLA, "Abelson, Harold, et al. Structure and Interpretation of Computer Programs."},
+		{three, Chicago, "Abelson, Harold, Gerald Jay Sussman, and Julie Sussman. Structure and Interpretation of Computer Programs."},
+	}
+	for _, test := range tests {
+		citation, err := Cite(test.ref, test.style, accessed)
+		if err != nil || len(citation) < len(test.expected) || citation[:len(test.expected)] != test.expected {
+			t.Errorf("expected %s citation starting with %q, got %q, err=%v", test.style, test.expected, citation, err)
+		}
+	}
+}
+
+func TestCiteLink(t *testing.T) {
+	link := model.NewLinkReference(1, "In Search of an Understandable Consensus Algorithm", "https://www.raft.github.io/raft.pdf", "", false)
+	tests := []struct {
+		style    Style
+		expected string
+	}{
+		{APA, "In Search of an Understandable Consensus Algorithm. (n.d.). raft.github.io. Retrieved March 5, 2026, from https://www.raft.github.io/raft.pdf"},
+		{MLA, `"In Search of an Understandable Consensus Algorithm." raft.github.io, www.raft.github.io/raft.pdf. Accessed 5 Mar. 2026.`},
+		{Chicago, `"In Search of an Understandable Consensus Algorithm." raft.github.io. Accessed March 5, 2026. https://www.raft.github.io/raft.pdf.`},
+	}
+	for _, test := range tests {
+		citation, err := Cite(link, test.style, accessed)
+		if err != nil || citation != test.expected {
+			t.Errorf("expected %s citation %q, got %q, err=%v", test.style, test.expected, citation, err)
+		}
+	}
+}
+
+func TestCiteNoteFails(t *testing.T) {
+	if _, err := Cite(model.NewNoteReference(1, "Note", "text", false), APA, accessed); err == nil {
+		t.Error("expected error for citing a note")
+	}
+}
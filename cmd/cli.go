@@ -3,13 +3,23 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/VladMinzatu/reference-manager/adapters"
+	"github.com/VladMinzatu/reference-manager/citation"
 	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/port"
 	"github.com/VladMinzatu/reference-manager/domain/service"
+	"github.com/VladMinzatu/reference-manager/export"
+	"github.com/VladMinzatu/reference-manager/importer"
+	"github.com/VladMinzatu/reference-manager/markdown"
+	"github.com/VladMinzatu/reference-manager/web"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/spf13/cobra"
 )
@@ -21,22 +31,41 @@ var rootCmd = &cobra.Command{
 }
 
 func main() {
-	db, err := sql.Open("sqlite3", "db/references.db")
+	// foreign keys are enabled in the DSN, so that every pooled connection enforces them, not just the first
+	db, err := sql.Open("sqlite3", "db/references.db?_foreign_keys=on")
 	if err != nil {
 		log.Fatalf("error opening database: %v", err)
 	}
 	defer db.Close()
 
-	// Enable foreign key constraints
-	_, err = db.Exec("PRAGMA foreign_keys = ON")
-	if err != nil {
-		log.Fatalf("error enabling foreign keys: %v", err)
-	}
+	// the blob store is picked by the --blob-dir flag, so it's only known once the flags are parsed
+	var blobDir string
+	rootCmd.PersistentFlags().StringVar(&blobDir, "blob-dir", "", "directory to keep archived pages and attachments in (by default they are kept in the database)")
+	blobStore := &flagBlobStore{db: db, dir: &blobDir}
+	blobCollector := service.NewBlobCollector(adapters.NewSQLiteOrphanedBlobRepository(db), blobStore)
 
 	categoryRepo := adapters.NewSQLiteCategoryRepository(db)
-	categoryService := service.NewCategoryService(categoryRepo)
+	categoryService := service.NewCategoryService(categoryRepo, blobCollector)
 	categoryListRepository := adapters.NewSQLiteCategoryListRepository(db)
 	referenceRepo := adapters.NewSQLiteReferencesRepository(db)
+	referenceService := service.NewReferenceService(referenceRepo, blobCollector)
+	highlightService := service.NewHighlightService(adapters.NewSQLiteHighlightRepository(db))
+	relationRepo := adapters.NewSQLiteRelationRepository(db)
+	relationService := service.NewRelationService(relationRepo, referenceRepo, categoryRepo)
+	libraryService := service.NewLibraryService(categoryListRepository, referenceRepo, relationRepo)
+	importService := service.NewImportService(categoryListRepository, referenceRepo, adapters.NewSQLiteImportRepository(db))
+	linkHealthRepo := adapters.NewSQLiteLinkHealthRepository(db)
+	archiver := adapters.NewHTTPPageArchiver(adapters.DefaultArchiveTimeout, adapters.DefaultMaxArchiveSize, adapters.DefaultMaxAssetsSize)
+	archiveService := service.NewArchiveService(referenceRepo, adapters.NewSQLiteSnapshotRepository(db), blobStore, blobCollector, archiver)
+	attachmentRepo := adapters.NewSQLiteAttachmentRepository(db)
+	coverService := func(coverURL string) *service.CoverService {
+		coverProvider := adapters.NewHTTPCoverProvider(coverURL, adapters.DefaultCoverFetchTimeout, model.MaxCoverSize)
+		return service.NewCoverService(referenceRepo, adapters.NewSQLiteCoverRepository(db), blobStore, blobCollector, adapters.NewStdlibImageProcessor(adapters.DefaultMaxImagePixels), coverProvider)
+	}
+	pageFetcher := adapters.NewHTTPPageFetcher(adapters.DefaultPageFetchTimeout, adapters.DefaultMaxPageSize)
+	metadataService := func(openLibraryURL string) *service.MetadataService {
+		return service.NewMetadataService(adapters.NewOpenLibraryProvider(openLibraryURL), pageFetcher)
+	}
 
 	// Category commands
 	var categoryCmd = &cobra.Command{
@@ -46,18 +75,49 @@ func main() {
 
 	var addCategoryCmd = &cobra.Command{
 		Use:   "add [name]",
-		Short: "Add a new category",
+		Short: "Add a new category, optionally as a subcategory of --parent",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			title, err := model.NewTitle(args[0])
 			if err != nil {
 				return fmt.Errorf("invalid category name: %v", err)
 			}
-			cat, err := categoryListRepository.AddNewCategory(title)
+			parent, _ := cmd.Flags().GetInt64("parent")
+			var cat model.Category
+			if parent != 0 {
+				parentId, err := model.NewId(parent)
+				if err != nil {
+					return fmt.Errorf("invalid parent category id: %v", err)
+				}
+				cat, err = categoryListRepository.AddNewSubcategory(parentId, title)
+				if err != nil {
+					return err
+				}
+			} else {
+				cat, err = categoryListRepository.AddNewCategory(title)
+				if err != nil {
+					return err
+				}
+			}
+			fmt.Printf("Added category: %s (id: %d)\n", cat.Name, cat.Id)
+			return nil
+		},
+	}
+	addCategoryCmd.Flags().Int64("parent", 0, "id of the parent category")
+
+	var categoryTreeCmd = &cobra.Command{
+		Use:   "tree",
+		Short: "Show categories as a tree",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			categories, err := categoryListRepository.GetAllCategoryRefs()
 			if err != nil {
 				return err
 			}
-			fmt.Printf("Added category: %s (id: %d)\n", cat.Name, cat.Id)
+			for _, root := range model.BuildCategoryTree(categories) {
+				root.Walk(func(node *model.CategoryNode, depth int) {
+					fmt.Printf("%s%d: %s\n", strings.Repeat("    ", depth), node.Id, node.Name)
+				})
+			}
 			return nil
 		},
 	}
@@ -118,6 +178,7 @@ func main() {
 			if err := categoryListRepository.DeleteCategory(modelId); err != nil {
 				return err
 			}
+			blobCollector.CollectAfterRemoval()
 			fmt.Printf("Deleted category with id: %d\n", id)
 			return nil
 		},
@@ -125,7 +186,7 @@ func main() {
 
 	var reorderCategoriesCmd = &cobra.Command{
 		Use:   "reorder [id1] [id2] ...",
-		Short: "Reorder categories by specifying their ids in the desired order",
+		Short: "Reorder top-level categories (or the subcategories of --parent) by specifying their ids in the desired order",
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			positions := make(map[model.Id]int)
@@ -140,13 +201,73 @@ func main() {
 				}
 				positions[modelId] = pos
 			}
-			if err := categoryListRepository.ReorderCategories(positions); err != nil {
+			parent, _ := cmd.Flags().GetInt64("parent")
+			if parent != 0 {
+				parentId, err := model.NewId(parent)
+				if err != nil {
+					return fmt.Errorf("invalid parent category id: %v", err)
+				}
+				if err := categoryListRepository.ReorderSubcategories(parentId, positions); err != nil {
+					return err
+				}
+			} else if err := categoryListRepository.ReorderCategories(positions); err != nil {
 				return err
 			}
 			fmt.Println("Categories reordered successfully.")
 			return nil
 		},
 	}
+	reorderCategoriesCmd.Flags().Int64("parent", 0, "id of the parent category whose subcategories are reordered")
+
+	var moveCategoryCmd = &cobra.Command{
+		Use:   "move [id] [position]",
+		Short: "Move a category to the given 0-based position among its siblings",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid category id format (must be integer): %v", err)
+			}
+			modelId, err := model.NewId(id)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			position, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid position (must be integer): %v", err)
+			}
+			if err := categoryListRepository.MoveCategoryToPosition(modelId, position); err != nil {
+				return err
+			}
+			fmt.Printf("Moved category %d to position %d\n", id, position)
+			return nil
+		},
+	}
+
+	var reparentCategoryCmd = &cobra.Command{
+		Use:   "reparent [id] [parentId]",
+		Short: "Move a category (with its subcategories) under another category; a parentId of 0 makes it top-level",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid category id format (must be integer): %v", err)
+			}
+			catId, err := model.NewId(id)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			parent, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil || parent < 0 {
+				return fmt.Errorf("invalid parent category id: %s", args[1])
+			}
+			if err := categoryListRepository.ReparentCategory(catId, model.Id(parent)); err != nil {
+				return err
+			}
+			fmt.Printf("Moved category %d under parent %d\n", id, parent)
+			return nil
+		},
+	}
 
 	// Reference commands
 	var referenceCmd = &cobra.Command{
@@ -182,7 +303,15 @@ func main() {
 	var addBookCmd = &cobra.Command{
 		Use:   "add-book [categoryId] [title] [isbn] [description]",
 		Short: "Add a book reference",
-		Args:  cobra.ExactArgs(4),
+		Long: `Add a book reference. With --lookup, only the category id and the ISBN are given:
+  refman reference add-book --lookup [categoryId] [isbn]
+and the title, authors and description are filled in from Open Library.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if lookup, _ := cmd.Flags().GetBool("lookup"); lookup {
+				return cobra.ExactArgs(2)(cmd, args)
+			}
+			return cobra.ExactArgs(4)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			categoryId, err := strconv.ParseInt(args[0], 10, 64)
 			if err != nil {
@@ -192,15 +321,27 @@ func main() {
 			if err != nil {
 				return fmt.Errorf("invalid category id: %v", err)
 			}
-			title, err := model.NewTitle(args[1])
-			if err != nil {
-				return fmt.Errorf("invalid title: %v", err)
-			}
-			isbn, err := model.NewISBN(args[2])
-			if err != nil {
-				return fmt.Errorf("invalid ISBN: %v", err)
+			var title model.Title
+			var isbn model.ISBN
+			var description string
+			if lookup, _ := cmd.Flags().GetBool("lookup"); lookup {
+				openLibraryURL, _ := cmd.Flags().GetString("openlibrary-url")
+				metadata, err := metadataService(openLibraryURL).LookupBook(args[1])
+				if err != nil {
+					return err
+				}
+				title, isbn, description = metadata.FullTitle(), metadata.ISBN, metadata.Description
+			} else {
+				title, err = model.NewTitle(args[1])
+				if err != nil {
+					return fmt.Errorf("invalid title: %v", err)
+				}
+				isbn, err = model.NewISBN(args[2])
+				if err != nil {
+					return fmt.Errorf("invalid ISBN: %v", err)
+				}
+				description = args[3]
 			}
-			description := args[3]
 			// Book id will be assigned by the system, so we use a placeholder zero value for id here
 			book := model.NewBookReference(0, title, isbn, description, false)
 			category, err := categoryService.AddReference(catId, book)
@@ -213,6 +354,9 @@ func main() {
 		},
 	}
 
+	addBookCmd.Flags().Bool("lookup", false, "fill in the title, authors and description from Open Library by the ISBN")
+	addBookCmd.Flags().String("openlibrary-url", adapters.DefaultOpenLibraryURL, "base URL of the Open Library API to look up the ISBN with")
+
 	var updateBookCmd = &cobra.Command{
 		Use:   "update-book [id] [title] [isbn] [description] [starred]",
 		Short: "Update a book reference",
@@ -251,7 +395,15 @@ func main() {
 	var addLinkCmd = &cobra.Command{
 		Use:   "add-link [categoryId] [title] [url] [description]",
 		Short: "Add a link reference",
-		Args:  cobra.ExactArgs(4),
+		Long: `Add a link reference. With --fetch, only the category id and the URL are given:
+  refman reference add-link --fetch [categoryId] [url]
+and the title and description are read from the page.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if fetch, _ := cmd.Flags().GetBool("fetch"); fetch {
+				return cobra.ExactArgs(2)(cmd, args)
+			}
+			return cobra.ExactArgs(4)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			catIdInt, err := strconv.ParseInt(args[0], 10, 64)
 			if err != nil {
@@ -261,15 +413,44 @@ func main() {
 			if err != nil {
 				return fmt.Errorf("invalid category id: %v", err)
 			}
-			title, err := model.NewTitle(args[1])
-			if err != nil {
-				return fmt.Errorf("invalid title: %v", err)
+			var title model.Title
+			var url model.URL
+			var description string
+			if fetch, _ := cmd.Flags().GetBool("fetch"); fetch {
+				page, err := metadataService(adapters.DefaultOpenLibraryURL).FetchLink(args[1])
+				if err != nil {
+					return err
+				}
+				title, err = model.NewTitle(model.TruncateTitle(page.Title))
+				if err != nil {
+					return fmt.Errorf("%s has no title, add the link with one instead", page.URL)
+				}
+				url, description = page.URL, page.Description
+			} else {
+				title, err = model.NewTitle(args[1])
+				if err != nil {
+					return fmt.Errorf("invalid title: %v", err)
+				}
+				url, err = model.NewURL(args[2])
+				if err != nil {
+					return fmt.Errorf("invalid URL: %v", err)
+				}
+				description = args[3]
 			}
-			url, err := model.NewURL(args[2])
-			if err != nil {
-				return fmt.Errorf("invalid URL: %v", err)
+			allowDuplicate, _ := cmd.Flags().GetBool("allow-duplicate")
+			if !allowDuplicate {
+				duplicates, err := referenceService.FindDuplicateLinks(url)
+				if err != nil {
+					return err
+				}
+				if len(duplicates) > 0 {
+					fmt.Printf("%s is already in the library:\n", url)
+					for _, duplicate := range duplicates {
+						fmt.Printf("\t%d: %s (category %d: %s)\n", duplicate.Reference.GetId(), duplicate.Reference.Title(), duplicate.Category.Id, duplicate.Category.Name)
+					}
+					return fmt.Errorf("duplicate link (use --allow-duplicate to add it anyway)")
+				}
 			}
-			description := args[3]
 			// Link id will be assigned by the system, so we use a placeholder zero value for id here
 			link := model.NewLinkReference(0, title, url, description, false)
 			category, err := categoryService.AddReference(catId, link)
@@ -282,6 +463,9 @@ func main() {
 		},
 	}
 
+	addLinkCmd.Flags().Bool("allow-duplicate", false, "add the link even if the same URL is already in the library")
+	addLinkCmd.Flags().Bool("fetch", false, "fill in the title and description from the page at the URL")
+
 	var updateLinkCmd = &cobra.Command{
 		Use:   "update-link [id] [title] [url] [description] [starred]",
 		Short: "Update a link reference",
@@ -380,8 +564,8 @@ func main() {
 
 	var deleteReferenceCmd = &cobra.Command{
 		Use:   "delete [category_id] [reference_id]",
-		Short: "Delete a reference from a category",
-		Args:  cobra.ExactArgs(2),
+		Short: "Delete a reference from a category, or several at once with --ids",
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			categoryIdInt, err := strconv.ParseInt(args[0], 10, 64)
 			if err != nil {
@@ -391,6 +575,23 @@ func main() {
 			if err != nil {
 				return fmt.Errorf("invalid category id: %v", err)
 			}
+			if cmd.Flags().Changed("ids") {
+				if len(args) > 1 {
+					return fmt.Errorf("pass either a reference id or --ids, not both")
+				}
+				refIds, err := idsFlag(cmd)
+				if err != nil {
+					return err
+				}
+				if _, err := categoryService.RemoveReferences(catId, refIds); err != nil {
+					return err
+				}
+				fmt.Printf("Deleted %d references from category: %d\n", len(refIds), categoryIdInt)
+				return nil
+			}
+			if len(args) < 2 {
+				return fmt.Errorf("missing reference id (or --ids)")
+			}
 			refIdInt, err := strconv.ParseInt(args[1], 10, 64)
 			if err != nil {
 				return fmt.Errorf("invalid reference id: %v", err)
@@ -406,6 +607,115 @@ func main() {
 			return nil
 		},
 	}
+	deleteReferenceCmd.Flags().Int64Slice("ids", nil, "comma separated ids of the references to delete")
+
+	var starReferencesCmd = &cobra.Command{
+		Use:   "star [categoryId]",
+		Short: "Star (or with --unstar, unstar) the references given by --ids",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			categoryIdInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			catId, err := model.NewId(categoryIdInt)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			refIds, err := idsFlag(cmd)
+			if err != nil {
+				return err
+			}
+			unstar, _ := cmd.Flags().GetBool("unstar")
+			if _, err := categoryService.SetReferencesStarred(catId, refIds, !unstar); err != nil {
+				return err
+			}
+			if unstar {
+				fmt.Printf("Unstarred %d references in category %d\n", len(refIds), categoryIdInt)
+			} else {
+				fmt.Printf("Starred %d references in category %d\n", len(refIds), categoryIdInt)
+			}
+			return nil
+		},
+	}
+	starReferencesCmd.Flags().Int64Slice("ids", nil, "comma separated ids of the references to star")
+	starReferencesCmd.Flags().Bool("unstar", false, "unstar the references instead")
+	starReferencesCmd.MarkFlagRequired("ids")
+
+	var moveReferencesToCategoryCmd = &cobra.Command{
+		Use:   "move-to [categoryId] [targetCategoryId]",
+		Short: "Move the references given by --ids to the end of another category",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			categoryIdInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			catId, err := model.NewId(categoryIdInt)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			targetIdInt, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid target category id: %v", err)
+			}
+			targetId, err := model.NewId(targetIdInt)
+			if err != nil {
+				return fmt.Errorf("invalid target category id: %v", err)
+			}
+			refIds, err := idsFlag(cmd)
+			if err != nil {
+				return err
+			}
+			if _, err := categoryService.MoveReferences(catId, refIds, targetId); err != nil {
+				return err
+			}
+			fmt.Printf("Moved %d references from category %d to category %d\n", len(refIds), categoryIdInt, targetIdInt)
+			return nil
+		},
+	}
+	moveReferencesToCategoryCmd.Flags().Int64Slice("ids", nil, "comma separated ids of the references to move")
+	moveReferencesToCategoryCmd.MarkFlagRequired("ids")
+
+	var tagReferencesCmd = &cobra.Command{
+		Use:   "tag [categoryId] [tag]",
+		Short: "Add a tag to (or with --remove, remove it from) the references given by --ids",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			categoryIdInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			catId, err := model.NewId(categoryIdInt)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			tag, err := model.NewTag(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid tag: %v", err)
+			}
+			refIds, err := idsFlag(cmd)
+			if err != nil {
+				return err
+			}
+			remove, _ := cmd.Flags().GetBool("remove")
+			if remove {
+				if _, err := categoryService.UntagReferences(catId, refIds, tag); err != nil {
+					return err
+				}
+				fmt.Printf("Removed tag %q from %d references in category %d\n", tag, len(refIds), categoryIdInt)
+				return nil
+			}
+			if _, err := categoryService.TagReferences(catId, refIds, tag); err != nil {
+				return err
+			}
+			fmt.Printf("Tagged %d references in category %d with %q\n", len(refIds), categoryIdInt, tag)
+			return nil
+		},
+	}
+	tagReferencesCmd.Flags().Int64Slice("ids", nil, "comma separated ids of the references to tag")
+	tagReferencesCmd.Flags().Bool("remove", false, "remove the tag instead")
+	tagReferencesCmd.MarkFlagRequired("ids")
 
 	var reorderReferencesCmd = &cobra.Command{
 		Use:   "reorder [categoryId] [id1] [id2] ...",
@@ -441,33 +751,1205 @@ func main() {
 		},
 	}
 
-	categoryCmd.AddCommand(addCategoryCmd, listCategoriesCmd, updateCategoryCmd, deleteCategoryCmd, reorderCategoriesCmd)
-	referenceCmd.AddCommand(listReferencesCmd, addBookCmd, updateBookCmd, addLinkCmd, updateLinkCmd, addNoteCmd, updateNoteCmd, deleteReferenceCmd, reorderReferencesCmd)
-	rootCmd.AddCommand(categoryCmd, referenceCmd)
-
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	var moveReferenceCmd = &cobra.Command{
+		Use:   "move [categoryId] [refId] [position]",
+		Short: "Move a reference to the given 0-based position within its category",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			categoryIdInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			categoryId, err := model.NewId(categoryIdInt)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			refIdInt, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid reference id: %v", err)
+			}
+			refId, err := model.NewId(refIdInt)
+			if err != nil {
+				return fmt.Errorf("invalid reference id: %v", err)
+			}
+			position, err := strconv.Atoi(args[2])
+			if err != nil {
+				return fmt.Errorf("invalid position (must be integer): %v", err)
+			}
+			if _, err := categoryService.MoveReferenceToPosition(categoryId, refId, position); err != nil {
+				return err
+			}
+			fmt.Printf("Moved reference %d to position %d in category %d\n", refIdInt, position, categoryIdInt)
+			return nil
+		},
 	}
-}
 
-type CLIReferenceRenderer struct{}
+	var convertReferenceCmd = &cobra.Command{
+		Use:   "convert [refId] [book|link|note]",
+		Short: "Change the type of a reference, keeping its id, position, title, star and tags",
+		Long: `Change the type of a reference, keeping its id, position, title, star and tags.
+Descriptions and note texts carry over. An ISBN or URL that the new type has no field for is appended to the description/text.
+Converting to a book requires --isbn and converting to a link requires --url.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			refIdInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid reference id: %v", err)
+			}
+			refId, err := model.NewId(refIdInt)
+			if err != nil {
+				return fmt.Errorf("invalid reference id: %v", err)
+			}
+			target, err := model.NewReferenceType(args[1])
+			if err != nil {
+				return err
+			}
+			isbn, _ := cmd.Flags().GetString("isbn")
+			url, _ := cmd.Flags().GetString("url")
 
-func (r *CLIReferenceRenderer) RenderBook(ref model.BookReference) {
-	fmt.Printf("%d: %s [Book] %s\n", ref.GetId(), r.StarChar(ref.Starred()), ref.Title())
-	fmt.Printf("\t\t\tISBN: %s\n", ref.ISBN)
-	fmt.Printf("\t\t\tDescription: %s\n", ref.Description)
-}
+			ref, err := referenceService.ConvertReference(refId, target, model.ConversionInput{ISBN: model.ISBN(isbn), URL: model.URL(url)})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Converted reference %d to a %s:\n", refIdInt, target)
+			ref.Reference.Render(&CLIReferenceRenderer{})
+			return nil
+		},
+	}
+	convertReferenceCmd.Flags().String("isbn", "", "ISBN of the book (when converting to a book)")
+	convertReferenceCmd.Flags().String("url", "", "URL of the link (when converting to a link)")
 
-func (r *CLIReferenceRenderer) RenderLink(ref model.LinkReference) {
-	fmt.Printf("%d: %s [Link] %s\n", ref.GetId(), r.StarChar(ref.Starred()), ref.Title())
-	fmt.Printf("\t\t\tURL: %s\n", ref.URL)
-	fmt.Printf("\t\t\tDescription: %s\n", ref.Description)
-}
+	var readingOrderCmd = &cobra.Command{
+		Use:   "reading-order [id]",
+		Short: "List the references of a category in an order that puts prerequisites first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			id, err := model.NewId(idInt)
+			if err != nil {
+				return fmt.Errorf("invalid category id: %v", err)
+			}
+			category, ordered, err := relationService.ReadingOrder(id)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Reading order for %s:\n", category.Name)
+			for _, ref := range ordered {
+				ref.Render(&CLIReferenceRenderer{})
+			}
+			return nil
+		},
+	}
+	categoryCmd.AddCommand(addCategoryCmd, listCategoriesCmd, categoryTreeCmd, updateCategoryCmd, deleteCategoryCmd, reorderCategoriesCmd, moveCategoryCmd, reparentCategoryCmd, readingOrderCmd)
+	referenceCmd.AddCommand(listReferencesCmd, addBookCmd, updateBookCmd, addLinkCmd, updateLinkCmd, addNoteCmd, updateNoteCmd, deleteReferenceCmd, reorderReferencesCmd, moveReferenceCmd, starReferencesCmd, moveReferencesToCategoryCmd, tagReferencesCmd, convertReferenceCmd)
+	// Dedupe commands
+	var dedupeCmd = &cobra.Command{
+		Use:   "dedupe",
+		Short: "List likely duplicates across the library (same ISBN, same URL or similar titles)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			groups, err := referenceService.FindDuplicates()
+			if err != nil {
+				return err
+			}
+			if len(groups) == 0 {
+				fmt.Println("No likely duplicates found")
+				return nil
+			}
+			for i, group := range groups {
+				reasons := make([]string, 0, len(group.Reasons))
+				for _, reason := range group.Reasons {
+					reasons = append(reasons, string(reason))
+				}
+				fmt.Printf("Group %d (%s):\n", i+1, strings.Join(reasons, ", "))
+				for _, ref := range group.References {
+					fmt.Printf("\tin category %d: %s\n", ref.Category.Id, ref.Category.Name)
+					ref.Reference.Render(&CLIReferenceRenderer{})
+				}
+				fmt.Println()
+			}
+			fmt.Println("Use 'dedupe merge [keepId] [otherIds...]' to merge a group")
+			return nil
+		},
+	}
 
-func (r *CLIReferenceRenderer) RenderNote(ref model.NoteReference) {
+	var mergeReferencesCmd = &cobra.Command{
+		Use:   "merge [keepId] [otherIds...]",
+		Short: "Merge references into the one that is kept, combining descriptions, stars and tags, and remove them",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ids []model.Id
+			for _, arg := range args {
+				idInt, err := strconv.ParseInt(arg, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid reference id: %v", err)
+				}
+				id, err := model.NewId(idInt)
+				if err != nil {
+					return fmt.Errorf("invalid reference id: %v", err)
+				}
+				ids = append(ids, id)
+			}
+			merged, err := referenceService.MergeReferences(ids[0], ids[1:])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Merged %d references into %d in category %d: %s\n", len(ids)-1, ids[0], merged.Category.Id, merged.Category.Name)
+			merged.Reference.Render(&CLIReferenceRenderer{})
+			return nil
+		},
+	}
+
+	// Highlight commands
+	var highlightCmd = &cobra.Command{
+		Use:   "highlight",
+		Short: "Manage the highlights and quotes of books",
+	}
+
+	var addHighlightCmd = &cobra.Command{
+		Use:   "add [bookId] [text]",
+		Short: "Add a highlight at the end of the highlights of a book",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookIdInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid book id: %v", err)
+			}
+			bookId, err := model.NewId(bookIdInt)
+			if err != nil {
+				return fmt.Errorf("invalid book id: %v", err)
+			}
+			location, comment, err := highlightFlags(cmd)
+			if err != nil {
+				return err
+			}
+			highlight, err := highlightService.AddHighlight(bookId, args[1], location, comment)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Added highlight (id: %d)\n", highlight.Id)
+			return nil
+		},
+	}
+	addHighlightCmd.Flags().String("location", "", "where the highlight is found, e.g. 'p. 42'")
+	addHighlightCmd.Flags().String("comment", "", "a comment on the highlight")
+
+	var listHighlightsCmd = &cobra.Command{
+		Use:   "list [bookId]",
+		Short: "List the highlights of a book",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookIdInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid book id: %v", err)
+			}
+			bookId, err := model.NewId(bookIdInt)
+			if err != nil {
+				return fmt.Errorf("invalid book id: %v", err)
+			}
+			highlights, err := highlightService.GetHighlights(bookId)
+			if err != nil {
+				return err
+			}
+			for i, highlight := range highlights {
+				printHighlight(i, highlight)
+			}
+			return nil
+		},
+	}
+
+	var updateHighlightCmd = &cobra.Command{
+		Use:   "update [id] [text]",
+		Short: "Update the text, location and comment of a highlight",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid highlight id: %v", err)
+			}
+			id, err := model.NewId(idInt)
+			if err != nil {
+				return fmt.Errorf("invalid highlight id: %v", err)
+			}
+			location, comment, err := highlightFlags(cmd)
+			if err != nil {
+				return err
+			}
+			if _, err := highlightService.UpdateHighlight(id, args[1], location, comment); err != nil {
+				return err
+			}
+			fmt.Printf("Updated highlight %d\n", idInt)
+			return nil
+		},
+	}
+	updateHighlightCmd.Flags().String("location", "", "where the highlight is found, e.g. 'p. 42'")
+	updateHighlightCmd.Flags().String("comment", "", "a comment on the highlight")
+
+	var deleteHighlightCmd = &cobra.Command{
+		Use:   "delete [id]",
+		Short: "Delete a highlight",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid highlight id: %v", err)
+			}
+			id, err := model.NewId(idInt)
+			if err != nil {
+				return fmt.Errorf("invalid highlight id: %v", err)
+			}
+			if _, err := highlightService.DeleteHighlight(id); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted highlight %d\n", idInt)
+			return nil
+		},
+	}
+
+	var moveHighlightCmd = &cobra.Command{
+		Use:   "move [id] [position]",
+		Short: "Move a highlight to the given 0-based position among the highlights of its book",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid highlight id: %v", err)
+			}
+			id, err := model.NewId(idInt)
+			if err != nil {
+				return fmt.Errorf("invalid highlight id: %v", err)
+			}
+			position, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid position (must be integer): %v", err)
+			}
+			if _, err := highlightService.MoveHighlight(id, position); err != nil {
+				return err
+			}
+			fmt.Printf("Moved highlight %d to position %d\n", idInt, position)
+			return nil
+		},
+	}
+
+	// Relation commands
+	var relationCmd = &cobra.Command{
+		Use:   "relation",
+		Short: "Manage the relations between references",
+	}
+
+	var addRelationCmd = &cobra.Command{
+		Use:   "add [fromId] [related|prerequisite|supersedes] [toId]",
+		Short: "Relate two references, e.g. 'add 3 prerequisite 7' to read 3 before 7, or 'add 5 supersedes 2'",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, relType, to, err := relationArgs(args)
+			if err != nil {
+				return err
+			}
+			if _, err := relationService.AddRelation(from, to, relType); err != nil {
+				return err
+			}
+			fmt.Printf("Added relation: %d %s %d\n", from, relType, to)
+			return nil
+		},
+	}
+
+	var removeRelationCmd = &cobra.Command{
+		Use:   "remove [fromId] [related|prerequisite|supersedes] [toId]",
+		Short: "Remove a relation between two references",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, relType, to, err := relationArgs(args)
+			if err != nil {
+				return err
+			}
+			if err := relationService.RemoveRelation(from, to, relType); err != nil {
+				return err
+			}
+			fmt.Printf("Removed relation: %d %s %d\n", from, relType, to)
+			return nil
+		},
+	}
+
+	var listRelationsCmd = &cobra.Command{
+		Use:   "list [refId]",
+		Short: "List the references related to a reference",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid reference id: %v", err)
+			}
+			id, err := model.NewId(idInt)
+			if err != nil {
+				return fmt.Errorf("invalid reference id: %v", err)
+			}
+			related, err := relationService.GetRelatedReferences(id)
+			if err != nil {
+				return err
+			}
+			if len(related) == 0 {
+				fmt.Println("No related references")
+				return nil
+			}
+			for _, rel := range related {
+				fmt.Printf("%s (in category %d: %s):\n", rel.Relation.Describe(id), rel.Reference.Category.Id, rel.Reference.Category.Name)
+				rel.Reference.Reference.Render(&CLIReferenceRenderer{})
+			}
+			return nil
+		},
+	}
+
+	var citeCmd = &cobra.Command{
+		Use:   "cite [refId]",
+		Short: "Print the citation of a book or link in the APA, MLA or Chicago style",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idInt, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid reference id: %v", err)
+			}
+			id, err := model.NewId(idInt)
+			if err != nil {
+				return fmt.Errorf("invalid reference id: %v", err)
+			}
+			rawStyle, _ := cmd.Flags().GetString("style")
+			style, err := citation.NewStyle(rawStyle)
+			if err != nil {
+				return err
+			}
+			ref, err := referenceService.GetReferenceById(id)
+			if err != nil {
+				return err
+			}
+			text, err := citation.Cite(ref.Reference, style, time.Now())
+			if err != nil {
+				return err
+			}
+			fmt.Println(text)
+			return nil
+		},
+	}
+	citeCmd.Flags().String("style", "apa", "citation style: apa, mla or chicago")
+
+	// Export commands
+	var exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the library",
+	}
+
+	var exportGraphCmd = &cobra.Command{
+		Use:   "graph",
+		Short: "Export the categories, references and their relations as a graph (Graphviz DOT or JSON)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			library, err := libraryService.GetLibrary()
+			if err != nil {
+				return err
+			}
+			return writeExport(cmd, func(w io.Writer) error {
+				return export.WriteGraph(w, export.BuildGraph(*library), format)
+			})
+		},
+	}
+	exportGraphCmd.Flags().String("format", "dot", "output format: dot or json")
+	exportGraphCmd.Flags().String("out", "", "file to write to (defaults to stdout)")
+
+	var exportCSVCmd = &cobra.Command{
+		Use:   "csv",
+		Short: "Export the references of the library or of a category (and its subcategories) as CSV",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			categoryInt, _ := cmd.Flags().GetInt64("category")
+			var categoryId model.Id
+			if categoryInt != 0 {
+				var err error
+				categoryId, err = model.NewId(categoryInt)
+				if err != nil {
+					return fmt.Errorf("invalid category id: %v", err)
+				}
+			}
+			library, err := libraryService.GetLibrary()
+			if err != nil {
+				return err
+			}
+			return writeExport(cmd, func(w io.Writer) error {
+				return export.WriteCSV(w, *library, categoryId)
+			})
+		},
+	}
+	exportCSVCmd.Flags().Int64("category", 0, "id of the category to export (defaults to the whole library)")
+	exportCSVCmd.Flags().String("out", "", "file to write to (defaults to stdout)")
+
+	newBibliographyExportCmd := func(use string, formatName string, write func(io.Writer, model.Library, model.Id) error) *cobra.Command {
+		bibliographyCmd := &cobra.Command{
+			Use:   use,
+			Short: fmt.Sprintf("Export the references of the library or of a category (and its subcategories) as %s, e.g. for Zotero", formatName),
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				categoryInt, _ := cmd.Flags().GetInt64("category")
+				var categoryId model.Id
+				if categoryInt != 0 {
+					var err error
+					categoryId, err = model.NewId(categoryInt)
+					if err != nil {
+						return fmt.Errorf("invalid category id: %v", err)
+					}
+				}
+				library, err := libraryService.GetLibrary()
+				if err != nil {
+					return err
+				}
+				return writeExport(cmd, func(w io.Writer) error {
+					return write(w, *library, categoryId)
+				})
+			},
+		}
+		bibliographyCmd.Flags().Int64("category", 0, "id of the category to export (defaults to the whole library)")
+		bibliographyCmd.Flags().String("out", "", "file to write to (defaults to stdout)")
+		return bibliographyCmd
+	}
+
+	var exportMarkdownCmd = &cobra.Command{
+		Use:   "markdown",
+		Short: "Export the library as one Markdown file per category, e.g. for a wiki",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, _ := cmd.Flags().GetString("out")
+			library, err := libraryService.GetLibrary()
+			if err != nil {
+				return err
+			}
+			files := export.MarkdownFiles(*library)
+			if err := export.WriteFiles(out, files); err != nil {
+				return err
+			}
+			fmt.Printf("Exported %d categories to %s\n", len(files), out)
+			return nil
+		},
+	}
+	exportMarkdownCmd.Flags().String("out", "", "directory to write the files to")
+	exportMarkdownCmd.MarkFlagRequired("out")
+
+	var exportSiteCmd = &cobra.Command{
+		Use:   "site",
+		Short: "Export the library as a static HTML site, with an index and a page per category",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, _ := cmd.Flags().GetString("out")
+			library, err := libraryService.GetLibrary()
+			if err != nil {
+				return err
+			}
+			files, err := web.SiteFiles(*library, time.Now())
+			if err != nil {
+				return err
+			}
+			if err := export.WriteFiles(out, files); err != nil {
+				return err
+			}
+			fmt.Printf("Exported the site to %s (open %s)\n", out, filepath.Join(out, "index.html"))
+			return nil
+		},
+	}
+	exportSiteCmd.Flags().String("out", "", "directory to write the site to")
+	exportSiteCmd.MarkFlagRequired("out")
+
+	// Import commands
+	var importCmd = &cobra.Command{
+		Use:   "import",
+		Short: "Import references from other tools",
+	}
+
+	var importBookmarksCmd = &cobra.Command{
+		Use:   "bookmarks [file]",
+		Short: "Import the links of a bookmarks file exported from a browser, with folders as categories",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("error opening %s: %v", args[0], err)
+			}
+			defer file.Close()
+			folders, err := importer.ParseBookmarks(file)
+			if err != nil {
+				return err
+			}
+			report, err := importService.Import(folders, dryRun)
+			if err != nil {
+				return err
+			}
+			printImportReport(*report)
+			return nil
+		},
+	}
+	importBookmarksCmd.Flags().Bool("dry-run", false, "only show what would be imported")
+
+	var importCSVCmd = &cobra.Command{
+		Use:   "csv [file]",
+		Short: "Import references from a CSV file, e.g. a reading list kept in a spreadsheet",
+		Long: `Import references from a CSV file with a header row. By default the columns are read by the names of the
+CSV export (type, title, isbn, url, description, starred, category); --map reads fields from other columns,
+e.g. --map "title=Name,url=Link". Rows that fail validation are skipped and listed with the reason.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			spec, _ := cmd.Flags().GetString("map")
+			mapping, err := importer.ParseColumnMapping(spec)
+			if err != nil {
+				return err
+			}
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("error opening %s: %v", args[0], err)
+			}
+			defer file.Close()
+			folders, err := importer.ParseCSV(file, mapping)
+			if err != nil {
+				return err
+			}
+			report, err := importService.Import(folders, dryRun)
+			if err != nil {
+				return err
+			}
+			printImportReport(*report)
+			return nil
+		},
+	}
+	importCSVCmd.Flags().Bool("dry-run", false, "only show what would be imported")
+	importCSVCmd.Flags().String("map", "", "columns to read fields from, as field=column pairs separated by commas")
+
+	newReadingLogCmd := func(use string, format importer.ReadingLogFormat) *cobra.Command {
+		readingLogCmd := &cobra.Command{
+			Use:   use + " [file]",
+			Short: fmt.Sprintf("Import the books of a %s export, with shelves as categories or tags", format.Name),
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				dryRun, _ := cmd.Flags().GetBool("dry-run")
+				shelvesFlag, _ := cmd.Flags().GetString("shelves")
+				threshold, _ := cmd.Flags().GetInt("star-threshold")
+				shelves, err := importer.NewShelfMapping(shelvesFlag)
+				if err != nil {
+					return err
+				}
+				if threshold < 0 || threshold > 5 {
+					return fmt.Errorf("invalid star threshold %d: expected 0 to 5", threshold)
+				}
+				file, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("error opening %s: %v", args[0], err)
+				}
+				defer file.Close()
+				folders, err := importer.ParseReadingLog(file, format, importer.ReadingLogOptions{Shelves: shelves, StarThreshold: threshold})
+				if err != nil {
+					return err
+				}
+				report, err := importService.Import(folders, dryRun)
+				if err != nil {
+					return err
+				}
+				printImportReport(*report)
+				return nil
+			},
+		}
+		readingLogCmd.Flags().Bool("dry-run", false, "only show what would be imported")
+		readingLogCmd.Flags().String("shelves", string(importer.ShelvesAsCategories), "import reading status shelves as categories or tags")
+		readingLogCmd.Flags().Int("star-threshold", 4, "star books rated with at least this many stars (0 to star none)")
+		return readingLogCmd
+	}
+
+	newBibliographyImportCmd := func(use string, formatName string, parse func(io.Reader, model.Title) ([]model.ImportFolder, error)) *cobra.Command {
+		bibliographyCmd := &cobra.Command{
+			Use:   use + " [file]",
+			Short: fmt.Sprintf("Import the books, web pages and notes of a %s file, e.g. exported from Zotero or Mendeley", formatName),
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				dryRun, _ := cmd.Flags().GetBool("dry-run")
+				categoryName, _ := cmd.Flags().GetString("category")
+				if categoryName == "" {
+					categoryName = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+				}
+				category, err := model.NewTitle(categoryName)
+				if err != nil {
+					return fmt.Errorf("invalid category name: %v", err)
+				}
+				file, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("error opening %s: %v", args[0], err)
+				}
+				defer file.Close()
+				folders, err := parse(file, category)
+				if err != nil {
+					return err
+				}
+				report, err := importService.Import(folders, dryRun)
+				if err != nil {
+					return err
+				}
+				printImportReport(*report)
+				return nil
+			},
+		}
+		bibliographyCmd.Flags().Bool("dry-run", false, "only show what would be imported")
+		bibliographyCmd.Flags().String("category", "", "name of the top-level category to import into (defaults to the file name)")
+		return bibliographyCmd
+	}
+
+	var linksCmd = &cobra.Command{
+		Use:   "links",
+		Short: "Check the health of the links and archive their pages",
+	}
+
+	var checkLinksCmd = &cobra.Command{
+		Use:   "check",
+		Short: "Check the links for broken pages and report the broken ones and the ones that moved to another site",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			concurrency, _ := cmd.Flags().GetInt("concurrency")
+			hostInterval, _ := cmd.Flags().GetDuration("host-interval")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			maxAge, _ := cmd.Flags().GetDuration("max-age")
+			if concurrency < 1 {
+				return fmt.Errorf("invalid concurrency %d: must be at least 1", concurrency)
+			}
+			linkCheckService := service.NewLinkCheckService(linkHealthRepo, adapters.NewHTTPLinkChecker(timeout, hostInterval))
+			printLink := func(label string, health model.LinkHealth) {
+				title := ""
+				if ref, err := referenceRepo.GetReferenceById(health.ReferenceId); err == nil {
+					title = string(ref.Reference.Title()) + ": "
+				}
+				fmt.Printf("%-6s %d: %s%s (%s)\n", label, health.ReferenceId, title, health.URL, health.Summary())
+			}
+			report, err := linkCheckService.CheckLinks(cmd.Context(), service.LinkCheckOptions{Concurrency: concurrency, MaxAge: maxAge}, func(health model.LinkHealth) {
+				if health.Broken() {
+					printLink("broken", health)
+				} else if health.MovedToOtherSite() {
+					printLink("moved", health)
+				}
+			})
+			if report != nil {
+				fmt.Printf("Checked %d links: %d broken, %d moved to another site\n", len(report.Checked), len(report.Broken()), len(report.MovedToOtherSite()))
+			}
+			return err
+		},
+	}
+	checkLinksCmd.Flags().Int("concurrency", 4, "number of links to check at the same time")
+	checkLinksCmd.Flags().Duration("host-interval", adapters.DefaultHostInterval, "minimum time between requests to the same host")
+	checkLinksCmd.Flags().Duration("timeout", adapters.DefaultLinkCheckTimeout, "time to wait for each link")
+	checkLinksCmd.Flags().Duration("max-age", 0, "skip links checked more recently than this (e.g. 168h), 0 checks all links")
+
+	var archiveLinkCmd = &cobra.Command{
+		Use:   "archive [linkId]",
+		Short: "Archive a copy of the page of a link",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			linkId, err := parseIdArg(args[0], "link")
+			if err != nil {
+				return err
+			}
+			inlineAssets, _ := cmd.Flags().GetBool("inline-assets")
+			snapshot, err := archiveService.ArchiveLink(cmd.Context(), linkId, inlineAssets)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Archived %s as snapshot %d (%d bytes)\n", snapshot.URL, snapshot.Id, snapshot.Size)
+			return nil
+		},
+	}
+	archiveLinkCmd.Flags().Bool("inline-assets", false, "include the stylesheets and images of the page in the copy")
+
+	var listSnapshotsCmd = &cobra.Command{
+		Use:   "snapshots [linkId]",
+		Short: "List the archived copies of a link, most recent first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			linkId, err := parseIdArg(args[0], "link")
+			if err != nil {
+				return err
+			}
+			snapshots, err := archiveService.GetSnapshots(linkId)
+			if err != nil {
+				return err
+			}
+			for _, snapshot := range snapshots {
+				assets := ""
+				if snapshot.InlinedAssets {
+					assets = ", with assets"
+				}
+				fmt.Printf("%d: %s %s (%d bytes%s)\n", snapshot.Id, snapshot.CapturedAt.Format("2006-01-02 15:04"), snapshot.URL, snapshot.Size, assets)
+			}
+			return nil
+		},
+	}
+
+	var showSnapshotCmd = &cobra.Command{
+		Use:   "snapshot [snapshotId]",
+		Short: "Write the archived HTML of a snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snapshotId, err := parseIdArg(args[0], "snapshot")
+			if err != nil {
+				return err
+			}
+			_, content, err := archiveService.GetSnapshotContent(snapshotId)
+			if err != nil {
+				return err
+			}
+			return writeExport(cmd, func(w io.Writer) error {
+				_, err := w.Write(content)
+				return err
+			})
+		},
+	}
+	showSnapshotCmd.Flags().String("out", "", "file to write the HTML to (defaults to stdout)")
+
+	var deleteSnapshotCmd = &cobra.Command{
+		Use:   "delete-snapshot [snapshotId]",
+		Short: "Delete an archived copy of a link",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snapshotId, err := parseIdArg(args[0], "snapshot")
+			if err != nil {
+				return err
+			}
+			if _, err := archiveService.DeleteSnapshot(snapshotId); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted snapshot %d\n", snapshotId)
+			return nil
+		},
+	}
+
+	var attachmentCmd = &cobra.Command{
+		Use:   "attachment",
+		Short: "Manage the files attached to references",
+	}
+
+	var addAttachmentCmd = &cobra.Command{
+		Use:   "add [referenceId] [file]",
+		Short: fmt.Sprintf("Attach a file to a reference (%s)", strings.Join(model.AttachmentExtensions(), ", ")),
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			refId, err := parseIdArg(args[0], "reference")
+			if err != nil {
+				return err
+			}
+			maxSize, _ := cmd.Flags().GetInt64("max-size")
+			info, err := os.Stat(args[1])
+			if err != nil {
+				return fmt.Errorf("error reading %s: %v", args[1], err)
+			}
+			// checked before reading, so large files aren't loaded only to be rejected
+			if info.Size() > maxSize {
+				return fmt.Errorf("%s is too large (max %d bytes)", args[1], maxSize)
+			}
+			data, err := os.ReadFile(args[1])
+			if err != nil {
+				return fmt.Errorf("error reading %s: %v", args[1], err)
+			}
+			attachmentService := service.NewAttachmentService(attachmentRepo, blobStore, blobCollector, maxSize)
+			attachment, err := attachmentService.AddAttachment(refId, args[1], data)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Attached %s as attachment %d (%s, %d bytes)\n", attachment.FileName, attachment.Id, attachment.MediaType, attachment.Size)
+			return nil
+		},
+	}
+	addAttachmentCmd.Flags().Int64("max-size", model.DefaultMaxAttachmentSize, "largest file that can be attached, in bytes")
+
+	attachmentService := service.NewAttachmentService(attachmentRepo, blobStore, blobCollector, model.DefaultMaxAttachmentSize)
+
+	var listAttachmentsCmd = &cobra.Command{
+		Use:   "list [referenceId]",
+		Short: "List the files attached to a reference",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			refId, err := parseIdArg(args[0], "reference")
+			if err != nil {
+				return err
+			}
+			attachments, err := attachmentService.GetAttachments(refId)
+			if err != nil {
+				return err
+			}
+			for _, attachment := range attachments {
+				fmt.Printf("%d: %s (%s, %d bytes, sha256 %s)\n", attachment.Id, attachment.FileName, attachment.MediaType, attachment.Size, attachment.ContentHash)
+			}
+			return nil
+		},
+	}
+
+	var getAttachmentCmd = &cobra.Command{
+		Use:   "get [attachmentId]",
+		Short: "Write the content of an attachment, after checking its integrity",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			attachmentId, err := parseIdArg(args[0], "attachment")
+			if err != nil {
+				return err
+			}
+			_, content, err := attachmentService.GetAttachmentContent(attachmentId)
+			if err != nil {
+				return err
+			}
+			return writeExport(cmd, func(w io.Writer) error {
+				_, err := w.Write(content)
+				return err
+			})
+		},
+	}
+	getAttachmentCmd.Flags().String("out", "", "file to write the content to (defaults to stdout)")
+
+	var deleteAttachmentCmd = &cobra.Command{
+		Use:   "delete [attachmentId]",
+		Short: "Delete an attachment",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			attachmentId, err := parseIdArg(args[0], "attachment")
+			if err != nil {
+				return err
+			}
+			attachment, err := attachmentService.DeleteAttachment(attachmentId)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Deleted attachment %d: %s\n", attachment.Id, attachment.FileName)
+			return nil
+		},
+	}
+
+	var verifyAttachmentsCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Check that the content of every attachment is in the blob store and matches its hash",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems, err := attachmentService.VerifyAttachments()
+			if err != nil {
+				return err
+			}
+			for _, problem := range problems {
+				fmt.Printf("%d: %s of reference %d: %v\n", problem.Attachment.Id, problem.Attachment.FileName, problem.Attachment.ReferenceId, problem.Err)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%d attachments are missing or corrupted", len(problems))
+			}
+			fmt.Println("All attachments are intact")
+			return nil
+		},
+	}
+
+	var coverCmd = &cobra.Command{
+		Use:   "cover",
+		Short: "Manage the cover images of books",
+	}
+
+	var setCoverCmd = &cobra.Command{
+		Use:   "set [bookId] [image]",
+		Short: "Set a JPEG, PNG or GIF image as the cover of a book",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookId, err := parseIdArg(args[0], "book")
+			if err != nil {
+				return err
+			}
+			info, err := os.Stat(args[1])
+			if err != nil {
+				return fmt.Errorf("error reading %s: %v", args[1], err)
+			}
+			if info.Size() > model.MaxCoverSize {
+				return fmt.Errorf("%s is too large (max %d bytes)", args[1], model.MaxCoverSize)
+			}
+			image, err := os.ReadFile(args[1])
+			if err != nil {
+				return fmt.Errorf("error reading %s: %v", args[1], err)
+			}
+			cover, err := coverService(adapters.DefaultCoverURL).SetCover(bookId, image)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Set the cover of book %d (%s, %dx%d)\n", bookId, cover.MediaType, cover.Width, cover.Height)
+			return nil
+		},
+	}
+
+	var fetchCoverCmd = &cobra.Command{
+		Use:   "fetch [bookId]",
+		Short: "Fetch the cover of a book by its ISBN",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookId, err := parseIdArg(args[0], "book")
+			if err != nil {
+				return err
+			}
+			coverURL, _ := cmd.Flags().GetString("cover-url")
+			cover, err := coverService(coverURL).FetchCover(bookId)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Fetched the cover of book %d (%s, %dx%d)\n", bookId, cover.MediaType, cover.Width, cover.Height)
+			return nil
+		},
+	}
+	fetchCoverCmd.Flags().String("cover-url", adapters.DefaultCoverURL, "URL of the covers of books by ISBN, with {isbn} in place of the ISBN")
+
+	var getCoverCmd = &cobra.Command{
+		Use:   "get [bookId]",
+		Short: "Write the cover image of a book, or its thumbnail",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookId, err := parseIdArg(args[0], "book")
+			if err != nil {
+				return err
+			}
+			thumbnail, _ := cmd.Flags().GetBool("thumbnail")
+			_, image, err := coverService(adapters.DefaultCoverURL).GetCoverImage(bookId, thumbnail)
+			if err != nil {
+				return err
+			}
+			return writeExport(cmd, func(w io.Writer) error {
+				_, err := w.Write(image)
+				return err
+			})
+		},
+	}
+	getCoverCmd.Flags().Bool("thumbnail", false, "write the thumbnail (a JPEG) rather than the cover")
+	getCoverCmd.Flags().String("out", "", "file to write the image to (defaults to stdout)")
+
+	var removeCoverCmd = &cobra.Command{
+		Use:   "remove [bookId]",
+		Short: "Remove the cover of a book",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookId, err := parseIdArg(args[0], "book")
+			if err != nil {
+				return err
+			}
+			if err := coverService(adapters.DefaultCoverURL).RemoveCover(bookId); err != nil {
+				return err
+			}
+			fmt.Printf("Removed the cover of book %d\n", bookId)
+			return nil
+		},
+	}
+
+	dedupeCmd.AddCommand(mergeReferencesCmd)
+	importCmd.AddCommand(importBookmarksCmd, importCSVCmd, newReadingLogCmd("goodreads", importer.Goodreads), newReadingLogCmd("openlibrary", importer.OpenLibrary),
+		newBibliographyImportCmd("ris", "RIS", importer.ParseRIS), newBibliographyImportCmd("csljson", "CSL-JSON", importer.ParseCSLJSON))
+	exportCmd.AddCommand(exportGraphCmd, exportCSVCmd, newBibliographyExportCmd("ris", "RIS", export.WriteRIS),
+		newBibliographyExportCmd("csljson", "CSL-JSON", export.WriteCSLJSON), exportMarkdownCmd, exportSiteCmd)
+	relationCmd.AddCommand(addRelationCmd, removeRelationCmd, listRelationsCmd)
+	highlightCmd.AddCommand(addHighlightCmd, listHighlightsCmd, updateHighlightCmd, deleteHighlightCmd, moveHighlightCmd)
+	linksCmd.AddCommand(checkLinksCmd, archiveLinkCmd, listSnapshotsCmd, showSnapshotCmd, deleteSnapshotCmd)
+	attachmentCmd.AddCommand(addAttachmentCmd, listAttachmentsCmd, getAttachmentCmd, deleteAttachmentCmd, verifyAttachmentsCmd)
+	coverCmd.AddCommand(setCoverCmd, fetchCoverCmd, getCoverCmd, removeCoverCmd)
+	rootCmd.AddCommand(categoryCmd, referenceCmd, dedupeCmd, highlightCmd, relationCmd, citeCmd, importCmd, exportCmd, linksCmd, attachmentCmd, coverCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// flagBlobStore is the blob store picked by the --blob-dir flag, which is only parsed after the services are made
+type flagBlobStore struct {
+	db  *sql.DB
+	dir *string
+}
+
+func (s *flagBlobStore) store() port.BlobStore {
+	if *s.dir != "" {
+		return adapters.NewFileBlobStore(*s.dir)
+	}
+	return adapters.NewSQLiteBlobStore(s.db)
+}
+
+func (s *flagBlobStore) Put(data []byte) (model.ContentHash, error) {
+	return s.store().Put(data)
+}
+
+func (s *flagBlobStore) Get(hash model.ContentHash) ([]byte, error) {
+	return s.store().Get(hash)
+}
+
+func (s *flagBlobStore) Delete(hash model.ContentHash) error {
+	return s.store().Delete(hash)
+}
+
+// Parses an id argument, named by what it identifies (e.g. "link") in errors
+func parseIdArg(arg string, name string) (model.Id, error) {
+	idInt, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s id: %v", name, err)
+	}
+	id, err := model.NewId(idInt)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s id: %v", name, err)
+	}
+	return id, nil
+}
+
+// Parses the --ids flag used by the bulk commands
+func idsFlag(cmd *cobra.Command) ([]model.Id, error) {
+	rawIds, err := cmd.Flags().GetInt64Slice("ids")
+	if err != nil {
+		return nil, fmt.Errorf("invalid --ids: %v", err)
+	}
+	if len(rawIds) == 0 {
+		return nil, fmt.Errorf("--ids must list at least one reference id")
+	}
+	ids := make([]model.Id, 0, len(rawIds))
+	for _, rawId := range rawIds {
+		id, err := model.NewId(rawId)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reference id %d: %v", rawId, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func printImportReport(report model.ImportReport) {
+	for _, path := range report.CreatedCategories {
+		fmt.Printf("new category: %s\n", path)
+	}
+	for _, item := range report.Items {
+		reason := ""
+		if item.Reason != "" {
+			reason = fmt.Sprintf(" (%s)", item.Reason)
+		}
+		fmt.Printf("%-9s %s: %s%s\n", item.Outcome, item.Category, item.Title, reason)
+	}
+	verb := "Imported"
+	if report.DryRun {
+		verb = "Dry run: would import"
+	}
+	fmt.Printf("%s %d references into %d new categories; skipped %d duplicates and %d invalid entries\n", verb,
+		report.Count(model.Imported), len(report.CreatedCategories), report.Count(model.SkippedDuplicate), report.Count(model.SkippedInvalid))
+}
+
+// Runs write against the file given by the --out flag, or stdout if there is none
+func writeExport(cmd *cobra.Command, write func(w io.Writer) error) error {
+	out, _ := cmd.Flags().GetString("out")
+	if out == "" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", out, err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %v", out, err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported to %s\n", out)
+	return nil
+}
+
+// Parses the [fromId] [type] [toId] arguments of the relation commands
+func relationArgs(args []string) (model.Id, model.RelationType, model.Id, error) {
+	ids := make([]model.Id, 0, 2)
+	for _, arg := range []string{args[0], args[2]} {
+		idInt, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return 0, "", 0, fmt.Errorf("invalid reference id: %v", err)
+		}
+		id, err := model.NewId(idInt)
+		if err != nil {
+			return 0, "", 0, fmt.Errorf("invalid reference id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	relType, err := model.NewRelationType(args[1])
+	if err != nil {
+		return 0, "", 0, err
+	}
+	return ids[0], relType, ids[1], nil
+}
+
+func highlightFlags(cmd *cobra.Command) (model.Location, string, error) {
+	rawLocation, _ := cmd.Flags().GetString("location")
+	location, err := model.NewLocation(rawLocation)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid location: %v", err)
+	}
+	comment, _ := cmd.Flags().GetString("comment")
+	return location, comment, nil
+}
+
+func printHighlight(position int, highlight model.Highlight) {
+	location := ""
+	if highlight.Location != "" {
+		location = fmt.Sprintf(" (%s)", highlight.Location)
+	}
+	fmt.Printf("%d. [id: %d]%s\n", position, highlight.Id, location)
+	for _, line := range strings.Split(highlight.Text, "\n") {
+		fmt.Printf("\t> %s\n", line)
+	}
+	if highlight.Comment != "" {
+		for _, line := range strings.Split(markdown.ToPlainText(highlight.Comment), "\n") {
+			fmt.Printf("\t%s\n", line)
+		}
+	}
+}
+
+type CLIReferenceRenderer struct{}
+
+func (r *CLIReferenceRenderer) RenderBook(ref model.BookReference) {
+	fmt.Printf("%d: %s [Book] %s\n", ref.GetId(), r.StarChar(ref.Starred()), ref.Title())
+	if isbn10 := ref.ISBN.ISBN10(); isbn10 != "" {
+		fmt.Printf("\t\t\tISBN: %s (ISBN-10: %s)\n", ref.ISBN.Hyphenated(), isbn10)
+	} else {
+		fmt.Printf("\t\t\tISBN: %s\n", ref.ISBN.Hyphenated())
+	}
+	r.printMarkdown("Description", ref.Description)
+	r.printTags(ref.Tags())
+}
+
+func (r *CLIReferenceRenderer) RenderLink(ref model.LinkReference) {
+	fmt.Printf("%d: %s [Link] %s\n", ref.GetId(), r.StarChar(ref.Starred()), ref.Title())
+	fmt.Printf("\t\t\tURL: %s\n", ref.URL)
+	r.printMarkdown("Description", ref.Description)
+	r.printTags(ref.Tags())
+}
+
+func (r *CLIReferenceRenderer) RenderNote(ref model.NoteReference) {
 	fmt.Printf("%d: %s [Note] %s\n", ref.GetId(), r.StarChar(ref.Starred()), ref.Title())
-	fmt.Printf("\t\t\tText: %s\n", ref.Text)
+	r.printMarkdown("Text", ref.Text)
+	r.printTags(ref.Tags())
+}
+
+// Prints Markdown as plain text, with the lines after the first one aligned under it
+func (r *CLIReferenceRenderer) printMarkdown(label, source string) {
+	lines := strings.Split(markdown.ToPlainText(source), "\n")
+	fmt.Printf("\t\t\t%s: %s\n", label, lines[0])
+	indent := strings.Repeat(" ", len(label)+2)
+	for _, line := range lines[1:] {
+		if line == "" {
+			fmt.Println()
+		} else {
+			fmt.Printf("\t\t\t%s%s\n", indent, line)
+		}
+	}
+}
+
+func (r *CLIReferenceRenderer) printTags(tags []model.Tag) {
+	if len(tags) == 0 {
+		return
+	}
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = string(tag)
+	}
+	fmt.Printf("\t\t\tTags: %s\n", strings.Join(names, ", "))
 }
 
 func (r *CLIReferenceRenderer) StarChar(starred bool) string {
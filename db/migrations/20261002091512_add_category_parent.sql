@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE categories ADD COLUMN parent_id INTEGER REFERENCES categories(id) ON DELETE CASCADE;
+
+-- positions are now unique among the children of the same parent (top-level categories share the NULL parent)
+DROP INDEX IF EXISTS idx_categories_position_unique;
+CREATE UNIQUE INDEX idx_categories_parent_position_unique ON categories(COALESCE(parent_id, 0), position);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_categories_parent_position_unique;
+
+-- flatten the tree back into a single list before restoring the global uniqueness constraint
+WITH ranked AS (
+    SELECT id, ROW_NUMBER() OVER (ORDER BY COALESCE(parent_id, 0), position) - 1 as new_pos
+    FROM categories
+)
+UPDATE categories
+SET position = -ranked.new_pos - 1
+FROM ranked
+WHERE categories.id = ranked.id;
+UPDATE categories SET position = -position - 1;
+
+CREATE UNIQUE INDEX idx_categories_position_unique ON categories(position);
+ALTER TABLE categories DROP COLUMN parent_id;
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Positions are replaced with fractional sort keys (see domain/util/sortkeys.go), so that moving, adding or removing
+-- an item only touches that one row. Existing positions are turned into fixed-width keys that preserve the current order.
+DROP INDEX IF EXISTS idx_categories_parent_position_unique;
+ALTER TABLE categories ADD COLUMN sort_key TEXT NOT NULL DEFAULT '';
+UPDATE categories SET sort_key = printf('%06dV', position);
+ALTER TABLE categories DROP COLUMN position;
+CREATE UNIQUE INDEX idx_categories_parent_sort_key_unique ON categories(COALESCE(parent_id, 0), sort_key);
+
+DROP INDEX IF EXISTS idx_base_references_category_position_unique;
+ALTER TABLE base_references ADD COLUMN sort_key TEXT NOT NULL DEFAULT '';
+UPDATE base_references SET sort_key = printf('%06dV', position);
+ALTER TABLE base_references DROP COLUMN position;
+CREATE UNIQUE INDEX idx_base_references_category_sort_key_unique ON base_references(category_id, sort_key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_base_references_category_sort_key_unique;
+ALTER TABLE base_references ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+WITH ranked AS (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY category_id ORDER BY sort_key) - 1 as new_pos
+    FROM base_references
+)
+UPDATE base_references
+SET position = ranked.new_pos
+FROM ranked
+WHERE base_references.id = ranked.id;
+ALTER TABLE base_references DROP COLUMN sort_key;
+CREATE UNIQUE INDEX idx_base_references_category_position_unique ON base_references(category_id, position);
+
+DROP INDEX IF EXISTS idx_categories_parent_sort_key_unique;
+ALTER TABLE categories ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+WITH ranked AS (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY COALESCE(parent_id, 0) ORDER BY sort_key) - 1 as new_pos
+    FROM categories
+)
+UPDATE categories
+SET position = ranked.new_pos
+FROM ranked
+WHERE categories.id = ranked.id;
+ALTER TABLE categories DROP COLUMN sort_key;
+CREATE UNIQUE INDEX idx_categories_parent_position_unique ON categories(COALESCE(parent_id, 0), position);
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE reference_tags (
+    reference_id INTEGER NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (reference_id, tag),
+    FOREIGN KEY (reference_id) REFERENCES base_references(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_reference_tags_tag ON reference_tags(tag);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_reference_tags_tag;
+DROP TABLE reference_tags;
+-- +goose StatementEnd
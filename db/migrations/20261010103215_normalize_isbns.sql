@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+-- ISBNs are now stored as the 13 digits of the ISBN-13 (see domain/model/isbn.go). Existing rows are normalized here:
+-- hyphens and spaces are stripped, valid ISBN-13s are kept and valid ISBN-10s are converted. Rows that fail validation are
+-- left untouched and listed in isbn_normalization_report, so that they can be looked up and corrected:
+--   SELECT r.reference_id, br.title, r.original_isbn, r.reason FROM isbn_normalization_report r JOIN base_references br ON br.id = r.reference_id;
+CREATE TABLE isbn_normalization_report (
+    reference_id INTEGER PRIMARY KEY,
+    original_isbn TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    FOREIGN KEY (reference_id) REFERENCES base_references(id) ON DELETE CASCADE
+);
+
+CREATE TEMP TABLE isbn_normalization AS
+SELECT reference_id, isbn AS original, UPPER(REPLACE(REPLACE(isbn, '-', ''), ' ', '')) AS stripped, NULL AS canonical
+FROM book_references;
+
+-- ISBN-13: the digits weighted alternately 1 and 3 sum up to a multiple of 10
+UPDATE isbn_normalization SET canonical = stripped
+WHERE stripped GLOB '97[89][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]'
+  AND (substr(stripped, 1, 1) + 3 * substr(stripped, 2, 1) + substr(stripped, 3, 1) + 3 * substr(stripped, 4, 1)
+     + substr(stripped, 5, 1) + 3 * substr(stripped, 6, 1) + substr(stripped, 7, 1) + 3 * substr(stripped, 8, 1)
+     + substr(stripped, 9, 1) + 3 * substr(stripped, 10, 1) + substr(stripped, 11, 1) + 3 * substr(stripped, 12, 1)
+     + substr(stripped, 13, 1)) % 10 = 0;
+
+-- ISBN-10: the digits weighted 10 down to 1 (with X standing for 10) sum up to a multiple of 11.
+-- The ISBN-13 is 978 followed by the first 9 digits and a new check digit (978 contributes 9 + 7 * 3 + 8 = 38 to the sum).
+UPDATE isbn_normalization
+SET canonical = '978' || substr(stripped, 1, 9) || ((10 - (38
+     + 3 * substr(stripped, 1, 1) + substr(stripped, 2, 1) + 3 * substr(stripped, 3, 1) + substr(stripped, 4, 1)
+     + 3 * substr(stripped, 5, 1) + substr(stripped, 6, 1) + 3 * substr(stripped, 7, 1) + substr(stripped, 8, 1)
+     + 3 * substr(stripped, 9, 1)) % 10) % 10)
+WHERE stripped GLOB '[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9X]'
+  AND (10 * substr(stripped, 1, 1) + 9 * substr(stripped, 2, 1) + 8 * substr(stripped, 3, 1) + 7 * substr(stripped, 4, 1)
+     + 6 * substr(stripped, 5, 1) + 5 * substr(stripped, 6, 1) + 4 * substr(stripped, 7, 1) + 3 * substr(stripped, 8, 1)
+     + 2 * substr(stripped, 9, 1) + CASE substr(stripped, 10, 1) WHEN 'X' THEN 10 ELSE substr(stripped, 10, 1) END) % 11 = 0;
+
+INSERT INTO isbn_normalization_report (reference_id, original_isbn, reason)
+SELECT reference_id, original,
+    CASE WHEN length(stripped) NOT IN (10, 13) THEN 'must have 10 or 13 digits' ELSE 'wrong format or check digit' END
+FROM isbn_normalization
+WHERE canonical IS NULL;
+
+UPDATE book_references
+SET isbn = (SELECT canonical FROM isbn_normalization n WHERE n.reference_id = book_references.reference_id)
+WHERE reference_id IN (SELECT reference_id FROM isbn_normalization WHERE canonical IS NOT NULL);
+
+DROP TABLE isbn_normalization;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- the normalized ISBNs are valid input, so they are kept
+DROP TABLE isbn_normalization_report;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Highlights are only added to books, but they reference base_references rather than book_references,
+-- so that converting a book to another type and back doesn't lose them.
+CREATE TABLE book_highlights (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reference_id INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    location VARCHAR(50) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    sort_key TEXT NOT NULL,
+    FOREIGN KEY (reference_id) REFERENCES base_references(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_book_highlights_reference_sort_key_unique ON book_highlights(reference_id, sort_key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_book_highlights_reference_sort_key_unique;
+DROP TABLE book_highlights;
+-- +goose StatementEnd
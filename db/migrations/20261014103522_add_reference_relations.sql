@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Related relations have no direction and are stored once, with from_id < to_id.
+CREATE TABLE reference_relations (
+    from_id INTEGER NOT NULL,
+    to_id INTEGER NOT NULL,
+    relation_type VARCHAR(20) NOT NULL CHECK (relation_type IN ('related', 'prerequisite', 'supersedes')),
+    PRIMARY KEY (from_id, to_id, relation_type),
+    CHECK (from_id <> to_id),
+    FOREIGN KEY (from_id) REFERENCES base_references(id) ON DELETE CASCADE,
+    FOREIGN KEY (to_id) REFERENCES base_references(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_reference_relations_to_id ON reference_relations(to_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_reference_relations_to_id;
+DROP TABLE reference_relations;
+-- +goose StatementEnd
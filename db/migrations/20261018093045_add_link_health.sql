@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The outcome of the last check of each link. The URL that was checked is kept, so that checks of links that were
+-- edited since can be told apart. Times are in Unix seconds.
+CREATE TABLE link_health (
+    reference_id INTEGER PRIMARY KEY,
+    url TEXT NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    redirect_url TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    checked_at INTEGER NOT NULL,
+    FOREIGN KEY (reference_id) REFERENCES base_references(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE link_health;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The content-addressed blob store, for libraries that keep their blobs in the database rather than on disk.
+CREATE TABLE blobs (
+    hash CHAR(64) PRIMARY KEY,
+    data BLOB NOT NULL
+);
+
+-- Archived copies of links. Their content is in the blob store, where identical copies are stored once.
+CREATE TABLE link_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reference_id INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    content_hash CHAR(64) NOT NULL,
+    size INTEGER NOT NULL,
+    inlined_assets BOOLEAN NOT NULL DEFAULT FALSE,
+    captured_at INTEGER NOT NULL,
+    FOREIGN KEY (reference_id) REFERENCES base_references(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_link_snapshots_reference_id ON link_snapshots(reference_id);
+CREATE INDEX idx_link_snapshots_content_hash ON link_snapshots(content_hash);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_link_snapshots_content_hash;
+DROP INDEX IF EXISTS idx_link_snapshots_reference_id;
+DROP TABLE link_snapshots;
+DROP TABLE blobs;
+-- +goose StatementEnd
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Files stored with references. Like the snapshots of links, their content is in the blob store.
+CREATE TABLE attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reference_id INTEGER NOT NULL,
+    file_name TEXT NOT NULL,
+    media_type VARCHAR(100) NOT NULL,
+    size INTEGER NOT NULL,
+    content_hash CHAR(64) NOT NULL,
+    added_at INTEGER NOT NULL,
+    FOREIGN KEY (reference_id) REFERENCES base_references(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_attachments_reference_id ON attachments(reference_id);
+CREATE INDEX idx_attachments_content_hash ON attachments(content_hash);
+
+-- The blob store is outside the database's transactions, so the content no longer referred to by any attachment or
+-- snapshot is queued here, in the same transaction that removed its last row, to be deleted from the store afterwards.
+-- The triggers also catch the rows removed by the cascades when references or categories are removed.
+CREATE TABLE orphaned_blobs (
+    hash CHAR(64) PRIMARY KEY
+);
+
+CREATE TRIGGER attachments_orphan_blob AFTER DELETE ON attachments
+WHEN NOT EXISTS (SELECT 1 FROM attachments WHERE content_hash = OLD.content_hash)
+    AND NOT EXISTS (SELECT 1 FROM link_snapshots WHERE content_hash = OLD.content_hash)
+BEGIN
+    INSERT OR IGNORE INTO orphaned_blobs (hash) VALUES (OLD.content_hash);
+END;
+
+CREATE TRIGGER link_snapshots_orphan_blob AFTER DELETE ON link_snapshots
+WHEN NOT EXISTS (SELECT 1 FROM attachments WHERE content_hash = OLD.content_hash)
+    AND NOT EXISTS (SELECT 1 FROM link_snapshots WHERE content_hash = OLD.content_hash)
+BEGIN
+    INSERT OR IGNORE INTO orphaned_blobs (hash) VALUES (OLD.content_hash);
+END;
+
+-- content that is stored again before it was deleted is in use again
+CREATE TRIGGER attachments_adopt_blob AFTER INSERT ON attachments
+BEGIN
+    DELETE FROM orphaned_blobs WHERE hash = NEW.content_hash;
+END;
+
+CREATE TRIGGER link_snapshots_adopt_blob AFTER INSERT ON link_snapshots
+BEGIN
+    DELETE FROM orphaned_blobs WHERE hash = NEW.content_hash;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS link_snapshots_adopt_blob;
+DROP TRIGGER IF EXISTS attachments_adopt_blob;
+DROP TRIGGER IF EXISTS link_snapshots_orphan_blob;
+DROP TRIGGER IF EXISTS attachments_orphan_blob;
+DROP TABLE orphaned_blobs;
+DROP INDEX IF EXISTS idx_attachments_content_hash;
+DROP INDEX IF EXISTS idx_attachments_reference_id;
+DROP TABLE attachments;
+-- +goose StatementEnd
@@ -0,0 +1,80 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The cover of a book and its thumbnail, both in the blob store. Converting the book to another type removes its cover.
+CREATE TABLE book_covers (
+    reference_id INTEGER PRIMARY KEY,
+    image_hash CHAR(64) NOT NULL,
+    media_type VARCHAR(100) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size INTEGER NOT NULL,
+    thumbnail_hash CHAR(64) NOT NULL,
+    updated_at INTEGER NOT NULL,
+    FOREIGN KEY (reference_id) REFERENCES book_references(reference_id) ON DELETE CASCADE
+);
+CREATE INDEX idx_book_covers_image_hash ON book_covers(image_hash);
+CREATE INDEX idx_book_covers_thumbnail_hash ON book_covers(thumbnail_hash);
+
+-- Every use of content in the blob store, for the triggers that queue the content no longer in use
+CREATE VIEW blob_references AS
+    SELECT content_hash AS hash FROM attachments
+    UNION ALL SELECT content_hash FROM link_snapshots
+    UNION ALL SELECT image_hash FROM book_covers
+    UNION ALL SELECT thumbnail_hash FROM book_covers;
+
+DROP TRIGGER attachments_orphan_blob;
+DROP TRIGGER link_snapshots_orphan_blob;
+
+CREATE TRIGGER attachments_orphan_blob AFTER DELETE ON attachments
+WHEN NOT EXISTS (SELECT 1 FROM blob_references WHERE hash = OLD.content_hash)
+BEGIN
+    INSERT OR IGNORE INTO orphaned_blobs (hash) VALUES (OLD.content_hash);
+END;
+
+CREATE TRIGGER link_snapshots_orphan_blob AFTER DELETE ON link_snapshots
+WHEN NOT EXISTS (SELECT 1 FROM blob_references WHERE hash = OLD.content_hash)
+BEGIN
+    INSERT OR IGNORE INTO orphaned_blobs (hash) VALUES (OLD.content_hash);
+END;
+
+-- covers are replaced by deleting the old row, so there is no trigger for updates
+CREATE TRIGGER book_covers_orphan_blobs AFTER DELETE ON book_covers
+BEGIN
+    INSERT OR IGNORE INTO orphaned_blobs (hash)
+    SELECT OLD.image_hash WHERE NOT EXISTS (SELECT 1 FROM blob_references WHERE hash = OLD.image_hash);
+    INSERT OR IGNORE INTO orphaned_blobs (hash)
+    SELECT OLD.thumbnail_hash WHERE NOT EXISTS (SELECT 1 FROM blob_references WHERE hash = OLD.thumbnail_hash);
+END;
+
+CREATE TRIGGER book_covers_adopt_blobs AFTER INSERT ON book_covers
+BEGIN
+    DELETE FROM orphaned_blobs WHERE hash IN (NEW.image_hash, NEW.thumbnail_hash);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS book_covers_adopt_blobs;
+DROP TRIGGER IF EXISTS book_covers_orphan_blobs;
+DROP TRIGGER link_snapshots_orphan_blob;
+DROP TRIGGER attachments_orphan_blob;
+
+CREATE TRIGGER attachments_orphan_blob AFTER DELETE ON attachments
+WHEN NOT EXISTS (SELECT 1 FROM attachments WHERE content_hash = OLD.content_hash)
+    AND NOT EXISTS (SELECT 1 FROM link_snapshots WHERE content_hash = OLD.content_hash)
+BEGIN
+    INSERT OR IGNORE INTO orphaned_blobs (hash) VALUES (OLD.content_hash);
+END;
+
+CREATE TRIGGER link_snapshots_orphan_blob AFTER DELETE ON link_snapshots
+WHEN NOT EXISTS (SELECT 1 FROM attachments WHERE content_hash = OLD.content_hash)
+    AND NOT EXISTS (SELECT 1 FROM link_snapshots WHERE content_hash = OLD.content_hash)
+BEGIN
+    INSERT OR IGNORE INTO orphaned_blobs (hash) VALUES (OLD.content_hash);
+END;
+
+DROP VIEW IF EXISTS blob_references;
+DROP INDEX IF EXISTS idx_book_covers_thumbnail_hash;
+DROP INDEX IF EXISTS idx_book_covers_image_hash;
+DROP TABLE book_covers;
+-- +goose StatementEnd
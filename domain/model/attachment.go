@@ -0,0 +1,113 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Attachment records a file stored with a reference, like the PDF of a paper or the ebook of a book.
+// Its content is kept in the blob store by its hash, which also serves to check the file wasn't corrupted.
+type Attachment struct {
+	Id          Id
+	ReferenceId Id
+	FileName    FileName
+	MediaType   string
+	Size        int64
+	ContentHash ContentHash
+	AddedAt     time.Time
+}
+
+// AttachmentProblem is an attachment whose content is missing from the blob store or was corrupted
+type AttachmentProblem struct {
+	Attachment Attachment
+	Err        error
+}
+
+type FileName string
+
+const MaxFileNameLength = 255
+
+// Only the base name of the file is kept, as browsers may send the path it was uploaded from
+func NewFileName(val string) (FileName, error) {
+	val = strings.TrimSpace(path.Base(strings.ReplaceAll(val, "\\", "/")))
+	if val == "" || val == "." || val == "/" {
+		return "", errors.New("file name cannot be empty")
+	}
+	if len(val) > MaxFileNameLength {
+		return "", fmt.Errorf("file name too long (max %d)", MaxFileNameLength)
+	}
+	if !utf8.ValidString(val) || strings.ContainsFunc(val, unicode.IsControl) {
+		return "", errors.New("file name contains invalid characters")
+	}
+	return FileName(val), nil
+}
+
+func (n FileName) Extension() string {
+	return strings.ToLower(path.Ext(string(n)))
+}
+
+// The maximum size of attachments, unless configured otherwise
+const DefaultMaxAttachmentSize = 200 << 20
+
+type attachmentType struct {
+	mediaType string
+	name      string
+	// checks the content is really of the type, as the extension of a file is easily wrong
+	matches func(data []byte) bool
+}
+
+var attachmentTypes = map[string]attachmentType{
+	".pdf":  {"application/pdf", "PDF", hasPrefix("%PDF-")},
+	".epub": {"application/epub+zip", "EPUB", isEPUB},
+	".mobi": {"application/x-mobipocket-ebook", "MOBI", isMobipocket},
+	".azw3": {"application/vnd.amazon.ebook", "AZW3", isMobipocket},
+	".djvu": {"image/vnd.djvu", "DjVu", hasPrefix("AT&TFORM")},
+	".txt":  {"text/plain; charset=utf-8", "text", utf8.Valid},
+	".md":   {"text/markdown; charset=utf-8", "Markdown", utf8.Valid},
+}
+
+// Lists the extensions of the files that can be attached
+func AttachmentExtensions() []string {
+	return []string{".pdf", ".epub", ".mobi", ".azw3", ".djvu", ".txt", ".md"}
+}
+
+// Returns the media type of a file that can be attached, going by its extension and checking its content agrees
+func DetectAttachmentType(fileName FileName, data []byte) (string, error) {
+	ext := fileName.Extension()
+	t, ok := attachmentTypes[ext]
+	if ext == "" {
+		return "", fmt.Errorf("%s has no extension to tell its type (supported: %s)", fileName, strings.Join(AttachmentExtensions(), ", "))
+	}
+	if !ok {
+		return "", fmt.Errorf("unsupported file type %q (supported: %s)", ext, strings.Join(AttachmentExtensions(), ", "))
+	}
+	if !t.matches(data) {
+		return "", fmt.Errorf("%s is not a valid %s file", fileName, t.name)
+	}
+	return t.mediaType, nil
+}
+
+func hasPrefix(magic string) func(data []byte) bool {
+	return func(data []byte) bool {
+		return bytes.HasPrefix(data, []byte(magic))
+	}
+}
+
+// EPUBs are zip files whose first entry is an uncompressed "mimetype" file naming the format
+func isEPUB(data []byte) bool {
+	const header = 30
+	return bytes.HasPrefix(data, []byte("PK\x03\x04")) &&
+		bytes.HasPrefix(data[min(header, len(data)):], []byte("mimetypeapplication/epub+zip"))
+}
+
+// Mobipocket files (MOBI and Kindle's AZW3) are Palm databases with the type and creator at offset 60
+func isMobipocket(data []byte) bool {
+	const offset = 60
+	return len(data) >= offset+8 && string(data[offset:offset+8]) == "BOOKMOBI"
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewFileName(t *testing.T) {
+	cases := map[string]FileName{
+		"paper.pdf":                     "paper.pdf",
+		"  paper.pdf ":                  "paper.pdf",
+		"/home/me/papers/paper.pdf":     "paper.pdf",
+		`C:\Users\me\Desktop\paper.pdf`: "paper.pdf",
+	}
+	for in, want := range cases {
+		got, err := NewFileName(in)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", in, err)
+		} else if got != want {
+			t.Errorf("expected %q to become %q, got %q", in, want, got)
+		}
+	}
+	for _, invalid := range []string{"", "  ", "/", "a\nb.pdf", strings.Repeat("a", 252) + ".pdf"} {
+		if _, err := NewFileName(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestDetectAttachmentType(t *testing.T) {
+	mobi := append(make([]byte, 60), []byte("BOOKMOBI")...)
+	epub := []byte("PK\x03\x04" + strings.Repeat("\x00", 26) + "mimetypeapplication/epub+zip")
+	cases := []struct {
+		fileName FileName
+		data     []byte
+		want     string
+	}{
+		{"paper.pdf", []byte("%PDF-1.7\n..."), "application/pdf"},
+		{"Paper.PDF", []byte("%PDF-1.4"), "application/pdf"},
+		{"book.epub", epub, "application/epub+zip"},
+		{"book.mobi", mobi, "application/x-mobipocket-ebook"},
+		{"book.azw3", mobi, "application/vnd.amazon.ebook"},
+		{"scan.djvu", []byte("AT&TFORM\x00\x00"), "image/vnd.djvu"},
+		{"notes.md", []byte("# Notes"), "text/markdown; charset=utf-8"},
+	}
+	for _, c := range cases {
+		got, err := DetectAttachmentType(c.fileName, c.data)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", c.fileName, err)
+		} else if got != c.want {
+			t.Errorf("expected %s to be %s, got %s", c.fileName, c.want, got)
+		}
+	}
+
+	invalid := []struct {
+		fileName FileName
+		data     []byte
+	}{
+		{"setup.exe", []byte("MZ")},
+		{"no-extension", []byte("%PDF-1.7")},
+		{"fake.pdf", []byte("<html>")},
+		{"archive.epub", []byte("PK\x03\x04 not an epub")},
+		{"short.mobi", []byte("BOOKMOBI")},
+		{"binary.txt", []byte{0xff, 0xfe, 0x00}},
+	}
+	for _, c := range invalid {
+		if _, err := DetectAttachmentType(c.fileName, c.data); err == nil {
+			t.Errorf("expected %s to be rejected", c.fileName)
+		}
+	}
+}
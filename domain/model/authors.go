@@ -0,0 +1,50 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Splits a book title that follows the "<title> (by <author>[, <author>][ and <author>])" convention into the title and
+// the authors. Titles without the suffix are returned as they are, without authors.
+func SplitAuthors(title Title) (string, []string) {
+	val := strings.TrimSpace(string(title))
+	start := strings.LastIndex(val, "(by ")
+	if start <= 0 || !strings.HasSuffix(val, ")") {
+		return val, nil
+	}
+	names := val[start+len("(by ") : len(val)-1]
+	var authors []string
+	for _, part := range strings.Split(strings.ReplaceAll(strings.ReplaceAll(names, " & ", ", "), " and ", ", "), ",") {
+		if name := strings.TrimSpace(part); name != "" {
+			authors = append(authors, name)
+		}
+	}
+	return strings.TrimSpace(val[:start]), authors
+}
+
+// Appends the authors to a book title following the convention SplitAuthors reads, unless that makes the title too long
+func TitleWithAuthors(title string, authors []string) string {
+	title = strings.TrimSpace(title)
+	if len(authors) == 0 {
+		return title
+	}
+	names := authors[0]
+	if len(authors) > 1 {
+		names = strings.Join(authors[:len(authors)-1], ", ") + " and " + authors[len(authors)-1]
+	}
+	withAuthors := fmt.Sprintf("%s (by %s)", title, names)
+	if len(withAuthors) > MaxTitleLength {
+		return title
+	}
+	return withAuthors
+}
+
+// Splits an author's name into the given names and the surname, which is taken to be the last word
+func SplitName(name string) (string, string) {
+	words := strings.Fields(name)
+	if len(words) < 2 {
+		return "", name
+	}
+	return strings.Join(words[:len(words)-1], " "), words[len(words)-1]
+}
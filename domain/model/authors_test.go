@@ -0,0 +1,52 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitAuthors(t *testing.T) {
+	tests := []struct {
+		title    Title
+		expected string
+		authors  []string
+	}{
+		{"Code (by Charles Petzold)", "Code", []string{"Charles Petzold"}},
+		{"SICP (by Harold Abelson, Gerald Jay Sussman & Julie Sussman)", "SICP", []string{"Harold Abelson", "Gerald Jay Sussman", "Julie Sussman"}},
+		{"The Name of the Wind (Kingkiller #1)", "The Name of the Wind (Kingkiller #1)", nil},
+		{"(by Nobody)", "(by Nobody)", nil},
+	}
+	for _, test := range tests {
+		title, authors := SplitAuthors(test.title)
+		if title != test.expected || !reflect.DeepEqual(authors, test.authors) {
+			t.Errorf("expected %q and %v for %q, got %q and %v", test.expected, test.authors, test.title, title, authors)
+		}
+	}
+}
+
+func TestTitleWithAuthors(t *testing.T) {
+	title := TitleWithAuthors("OSTEP", []string{"Remzi Arpaci-Dusseau", "Andrea Arpaci-Dusseau", "Other"})
+	if title != "OSTEP (by Remzi Arpaci-Dusseau, Andrea Arpaci-Dusseau and Other)" {
+		t.Errorf("unexpected title %q", title)
+	}
+	if split, authors := SplitAuthors(Title(title)); split != "OSTEP" || len(authors) != 3 {
+		t.Errorf("expected the title to split back, got %q and %v", split, authors)
+	}
+	if title := TitleWithAuthors("Code", nil); title != "Code" {
+		t.Errorf("expected the title without authors, got %q", title)
+	}
+	long := strings.Repeat("a", MaxTitleLength-5)
+	if title := TitleWithAuthors(long, []string{"Someone"}); title != long {
+		t.Error("expected the authors to be left out of a title that would be too long")
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	if given, surname := SplitName("Remzi H. Arpaci-Dusseau"); given != "Remzi H." || surname != "Arpaci-Dusseau" {
+		t.Errorf("unexpected split %q, %q", given, surname)
+	}
+	if given, surname := SplitName("Plato"); given != "" || surname != "Plato" {
+		t.Errorf("unexpected split %q, %q", given, surname)
+	}
+}
@@ -1,9 +1,14 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrConcurrentCategoryUpdate = errors.New("concurrent update error on category")
 
+var ErrCategoryCycle = errors.New("a category cannot be moved under itself or one of its subcategories")
+
 type Category struct {
 	Id         Id
 	Name       Title
@@ -12,7 +17,80 @@ type Category struct {
 }
 
 // CategoryRef is a lightweight view of a category (id + title only) that *might* come in handy, wink, wink
+// ParentId is the zero Id for top-level categories.
 type CategoryRef struct {
-	Id   Id
-	Name Title
+	Id       Id
+	Name     Title
+	ParentId Id
+}
+
+func (c CategoryRef) IsTopLevel() bool {
+	return c.ParentId == 0
+}
+
+// CategoryNode is a category in the category tree, along with its ordered subcategories
+type CategoryNode struct {
+	CategoryRef
+	Children []*CategoryNode
+}
+
+// BuildCategoryTree assembles a flat list of category refs into a forest of top-level categories.
+// The relative order of siblings in refs is preserved. Categories whose parent is not in the list are treated as top-level.
+func BuildCategoryTree(refs []CategoryRef) []*CategoryNode {
+	nodes := make(map[Id]*CategoryNode, len(refs))
+	for _, ref := range refs {
+		nodes[ref.Id] = &CategoryNode{CategoryRef: ref}
+	}
+
+	var roots []*CategoryNode
+	for _, ref := range refs {
+		node := nodes[ref.Id]
+		parent, ok := nodes[ref.ParentId]
+		if ref.IsTopLevel() || !ok {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return roots
+}
+
+// Walk visits the node and all its descendants depth-first, passing along the depth relative to this node
+func (n *CategoryNode) Walk(visit func(node *CategoryNode, depth int)) {
+	n.walk(visit, 0)
+}
+
+func (n *CategoryNode) walk(visit func(node *CategoryNode, depth int), depth int) {
+	visit(n, depth)
+	for _, child := range n.Children {
+		child.walk(visit, depth+1)
+	}
+}
+
+// ValidateParent checks that the category can be moved under parentId (the zero Id for the top level): the parent must
+// exist and must not be the category itself or one of its subcategories
+func ValidateParent(refs []CategoryRef, id Id, parentId Id) error {
+	if parentId == 0 {
+		return nil
+	}
+	parents := make(map[Id]Id, len(refs))
+	for _, ref := range refs {
+		parents[ref.Id] = ref.ParentId
+	}
+	if _, ok := parents[parentId]; !ok {
+		return fmt.Errorf("parent category with id %d not found", parentId)
+	}
+	// walks up from the new parent; the category is only among its ancestors if the move would make a cycle
+	ancestor := parentId
+	for range refs {
+		if ancestor == id {
+			return ErrCategoryCycle
+		}
+		next, ok := parents[ancestor]
+		if !ok || next == 0 {
+			return nil
+		}
+		ancestor = next
+	}
+	return nil
 }
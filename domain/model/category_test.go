@@ -0,0 +1,71 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuildCategoryTree(t *testing.T) {
+	refs := []CategoryRef{
+		{Id: 1, Name: "Databases"},
+		{Id: 2, Name: "Storage engines", ParentId: 1},
+		{Id: 3, Name: "LSM trees", ParentId: 2},
+		{Id: 4, Name: "Query optimisation", ParentId: 1},
+		{Id: 5, Name: "Networking"},
+		{Id: 6, Name: "Orphan", ParentId: 99},
+	}
+
+	roots := BuildCategoryTree(refs)
+	if len(roots) != 3 {
+		t.Fatalf("expected 3 top-level categories, got %d", len(roots))
+	}
+	if roots[0].Id != 1 || roots[1].Id != 5 || roots[2].Id != 6 {
+		t.Errorf("unexpected root order: %v, %v, %v", roots[0].Id, roots[1].Id, roots[2].Id)
+	}
+	if len(roots[0].Children) != 2 || roots[0].Children[0].Id != 2 || roots[0].Children[1].Id != 4 {
+		t.Errorf("unexpected children of root 1: %v", roots[0].Children)
+	}
+
+	var visited []Id
+	var depths []int
+	roots[0].Walk(func(node *CategoryNode, depth int) {
+		visited = append(visited, node.Id)
+		depths = append(depths, depth)
+	})
+	expectedIds := []Id{1, 2, 3, 4}
+	expectedDepths := []int{0, 1, 2, 1}
+	for i := range expectedIds {
+		if visited[i] != expectedIds[i] || depths[i] != expectedDepths[i] {
+			t.Errorf("walk step %d: expected (%v, %d), got (%v, %d)", i, expectedIds[i], expectedDepths[i], visited[i], depths[i])
+		}
+	}
+}
+
+func TestBuildCategoryTreeEmpty(t *testing.T) {
+	if roots := BuildCategoryTree(nil); len(roots) != 0 {
+		t.Errorf("expected empty forest, got %v", roots)
+	}
+}
+
+func TestValidateParent(t *testing.T) {
+	refs := []CategoryRef{
+		{Id: 1, Name: "Databases"},
+		{Id: 2, Name: "Storage engines", ParentId: 1},
+		{Id: 3, Name: "LSM trees", ParentId: 2},
+		{Id: 4, Name: "Networking"},
+	}
+
+	for _, parentId := range []Id{0, 4, 1} {
+		if err := ValidateParent(refs, 2, parentId); err != nil {
+			t.Errorf("expected category 2 to be movable under %v, got %v", parentId, err)
+		}
+	}
+	for _, parentId := range []Id{2, 3} {
+		if err := ValidateParent(refs, 2, parentId); !errors.Is(err, ErrCategoryCycle) {
+			t.Errorf("expected moving category 2 under %v to be a cycle, got %v", parentId, err)
+		}
+	}
+	if err := ValidateParent(refs, 2, 99); err == nil || errors.Is(err, ErrCategoryCycle) {
+		t.Errorf("expected a missing parent to be rejected, got %v", err)
+	}
+}
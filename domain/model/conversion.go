@@ -0,0 +1,115 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type ReferenceType string
+
+const (
+	BookType ReferenceType = "book"
+	LinkType ReferenceType = "link"
+	NoteType ReferenceType = "note"
+)
+
+func NewReferenceType(val string) (ReferenceType, error) {
+	switch t := ReferenceType(strings.ToLower(val)); t {
+	case BookType, LinkType, NoteType:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown reference type %q (must be one of book, link, note)", val)
+}
+
+// Returns the type of the reference
+func TypeOf(ref Reference) ReferenceType {
+	source := &conversionSource{}
+	ref.Render(source)
+	return source.refType
+}
+
+// ConversionInput holds the fields that the target type requires but the source type doesn't have.
+// ISBN is required when converting to a book, URL when converting to a link.
+type ConversionInput struct {
+	ISBN ISBN
+	URL  URL
+}
+
+/*
+ConvertReference turns a reference into one of another type, keeping its id, title, starred flag and tags.
+The mapping rules are:
+  - a book's or link's description becomes a note's text and vice versa
+  - descriptions are kept between books and links
+  - the ISBN or URL of the source, which the target has no field for, is appended to the description/text as a
+    "ISBN: ..." or "URL: ..." line, so that converting never silently loses data
+*/
+func ConvertReference(ref Reference, target ReferenceType, input ConversionInput) (Reference, error) {
+	source := &conversionSource{}
+	ref.Render(source)
+	if source.refType == target {
+		return nil, fmt.Errorf("reference is already a %s", target)
+	}
+
+	text := source.text
+	if source.extra != "" {
+		if text != "" {
+			text += "\n\n"
+		}
+		text += source.extra
+	}
+
+	var converted Reference
+	switch target {
+	case BookType:
+		isbn, err := NewISBN(string(input.ISBN))
+		if err != nil {
+			return nil, fmt.Errorf("converting to a book requires an ISBN: %v", err)
+		}
+		book := NewBookReference(ref.GetId(), ref.Title(), isbn, text, ref.Starred())
+		book.SetTags(ref.Tags())
+		converted = book
+	case LinkType:
+		url, err := NewURL(string(input.URL))
+		if err != nil {
+			return nil, fmt.Errorf("converting to a link requires a URL: %v", err)
+		}
+		link := NewLinkReference(ref.GetId(), ref.Title(), url, text, ref.Starred())
+		link.SetTags(ref.Tags())
+		converted = link
+	case NoteType:
+		if text == "" {
+			return nil, errors.New("converting to a note requires a description to use as the text")
+		}
+		note := NewNoteReference(ref.GetId(), ref.Title(), text, ref.Starred())
+		note.SetTags(ref.Tags())
+		converted = note
+	default:
+		return nil, fmt.Errorf("unknown reference type %q", target)
+	}
+	return converted, nil
+}
+
+// conversionSource collects the type-specific fields of the reference being converted
+type conversionSource struct {
+	refType ReferenceType
+	text    string
+	extra   string
+}
+
+func (s *conversionSource) RenderBook(ref BookReference) {
+	s.refType = BookType
+	s.text = ref.Description
+	s.extra = "ISBN: " + ref.ISBN.Hyphenated()
+}
+
+func (s *conversionSource) RenderLink(ref LinkReference) {
+	s.refType = LinkType
+	s.text = ref.Description
+	s.extra = "URL: " + string(ref.URL)
+}
+
+func (s *conversionSource) RenderNote(ref NoteReference) {
+	s.refType = NoteType
+	s.text = ref.Text
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewReferenceType(t *testing.T) {
+	for _, val := range []string{"book", "Link", "NOTE"} {
+		if _, err := NewReferenceType(val); err != nil {
+			t.Errorf("expected %q to be valid, got err=%v", val, err)
+		}
+	}
+	if _, err := NewReferenceType("video"); err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
+
+func TestConvertBookToLink(t *testing.T) {
+	book := NewBookReference(1, "Title", "978-0", "desc", true)
+	book.SetTags([]Tag{"go"})
+
+	ref, err := ConvertReference(book, LinkType, ConversionInput{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	link, ok := ref.(LinkReference)
+	if !ok {
+		t.Fatalf("expected a LinkReference, got %T", ref)
+	}
+	if link.GetId() != 1 || link.Title() != "Title" || !link.Starred() {
+		t.Errorf("expected id, title and starred to be kept, got %v, %v, %v", link.GetId(), link.Title(), link.Starred())
+	}
+	if !reflect.DeepEqual(link.Tags(), []Tag{"go"}) {
+		t.Errorf("expected tags to be kept, got %v", link.Tags())
+	}
+	if link.URL != "https://example.com" {
+		t.Errorf("expected url to be set, got %v", link.URL)
+	}
+	if link.Description != "desc\n\nISBN: 978-0" {
+		t.Errorf("expected the ISBN to be appended to the description, got %q", link.Description)
+	}
+}
+
+func TestConvertLinkToNote(t *testing.T) {
+	link := NewLinkReference(2, "Title", "https://example.com", "", false)
+
+	ref, err := ConvertReference(link, NoteType, ConversionInput{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	note, ok := ref.(NoteReference)
+	if !ok {
+		t.Fatalf("expected a NoteReference, got %T", ref)
+	}
+	if note.Text != "URL: https://example.com" {
+		t.Errorf("expected the URL to become the text, got %q", note.Text)
+	}
+}
+
+func TestConvertNoteToBook(t *testing.T) {
+	note := NewNoteReference(3, "Title", "text", false)
+
+	if _, err := ConvertReference(note, BookType, ConversionInput{}); err == nil {
+		t.Error("expected error when the ISBN is missing")
+	}
+	ref, err := ConvertReference(note, BookType, ConversionInput{ISBN: "1-4493-7332-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	book, ok := ref.(BookReference)
+	if !ok {
+		t.Fatalf("expected a BookReference, got %T", ref)
+	}
+	if book.ISBN != "9781449373320" || book.Description != "text" {
+		t.Errorf("expected the canonical isbn and description=text, got %v, %q", book.ISBN, book.Description)
+	}
+}
+
+func TestConvertToSameType(t *testing.T) {
+	note := NewNoteReference(4, "Title", "text", false)
+	if _, err := ConvertReference(note, NoteType, ConversionInput{}); err == nil {
+		t.Error("expected error when converting to the same type")
+	}
+}
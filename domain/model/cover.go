@@ -0,0 +1,40 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// ImageInfo is what's read from the header of an image
+type ImageInfo struct {
+	MediaType string
+	Width     int
+	Height    int
+}
+
+// BookCover is the cover image of a book and the thumbnail made of it, both kept in the blob store by their hashes
+type BookCover struct {
+	ReferenceId   Id
+	ImageHash     ContentHash
+	MediaType     string
+	Width         int
+	Height        int
+	Size          int64
+	ThumbnailHash ContentHash
+	UpdatedAt     time.Time
+}
+
+// The largest cover image that can be uploaded or fetched, in bytes
+const MaxCoverSize = 10 << 20
+
+// Thumbnails fit in this box, which has the 2:3 shape of most book covers
+const (
+	ThumbnailWidth  = 160
+	ThumbnailHeight = 240
+)
+
+// Thumbnails are always JPEGs, whatever the format of the cover
+const ThumbnailMediaType = "image/jpeg"
+
+// ErrNoCover is returned for books that have no cover
+var ErrNoCover = errors.New("book has no cover")
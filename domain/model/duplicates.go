@@ -0,0 +1,296 @@
+package model
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type DuplicateReason string
+
+const (
+	SameISBN     DuplicateReason = "same ISBN"
+	SameURL      DuplicateReason = "same URL"
+	SimilarTitle DuplicateReason = "similar title"
+)
+
+// Titles of the same type that are at least this similar (1 - edit distance / length) are reported as likely duplicates
+const TitleSimilarityThreshold = 0.85
+
+// Main titles (without subtitle) shorter than this are too generic to match on their own
+const minMainTitleLength = 8
+
+// DuplicateGroup is a set of references that are likely to be the same, along with why they were matched
+type DuplicateGroup struct {
+	References []CategorizedReference
+	Reasons    []DuplicateReason
+}
+
+/*
+FindDuplicates groups the references that are likely duplicates of each other. References are matched when they are
+books with the same ISBN, links to the same URL (both compared in their canonical forms) or references of the same type
+with similar titles, ignoring case, punctuation and subtitles. Matches are transitive, so a group may contain references
+that only match through another one. Groups and the references within them keep the order of the input.
+*/
+func FindDuplicates(refs []CategorizedReference) []DuplicateGroup {
+	parent := make([]int, len(refs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	reasons := map[int]map[DuplicateReason]bool{}
+	union := func(i, j int, reason DuplicateReason) {
+		ri, rj := find(i), find(j)
+		if ri > rj {
+			ri, rj = rj, ri
+		}
+		if ri != rj {
+			parent[rj] = ri
+			if reasons[ri] == nil {
+				reasons[ri] = map[DuplicateReason]bool{}
+			}
+			for r := range reasons[rj] {
+				reasons[ri][r] = true
+			}
+			delete(reasons, rj)
+		}
+		if reasons[ri] == nil {
+			reasons[ri] = map[DuplicateReason]bool{}
+		}
+		reasons[ri][reason] = true
+	}
+
+	keys := make([]duplicateKeys, len(refs))
+	byIdentifier := map[string]int{}
+	for i, ref := range refs {
+		keys[i] = newDuplicateKeys(ref.Reference)
+		if keys[i].identifier == "" {
+			continue
+		}
+		if j, ok := byIdentifier[keys[i].identifier]; ok {
+			union(j, i, keys[i].reason)
+		} else {
+			byIdentifier[keys[i].identifier] = i
+		}
+	}
+	for i := range refs {
+		for j := i + 1; j < len(refs); j++ {
+			if keys[i].refType == keys[j].refType && similarTitles(keys[i], keys[j]) {
+				union(i, j, SimilarTitle)
+			}
+		}
+	}
+
+	var groups []DuplicateGroup
+	index := map[int]int{}
+	for i, ref := range refs {
+		root := find(i)
+		if reasons[root] == nil {
+			continue
+		}
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, DuplicateGroup{Reasons: sortedReasons(reasons[root])})
+		}
+		groups[g].References = append(groups[g].References, ref)
+	}
+	return groups
+}
+
+// Returns what the reference points to, i.e. its canonical ISBN or URL prefixed with "isbn:" or "url:", or "" for notes.
+// References with the same identifier are duplicates.
+func IdentifierOf(ref Reference) string {
+	return newDuplicateKeys(ref).identifier
+}
+
+type duplicateKeys struct {
+	refType    ReferenceType
+	identifier string
+	reason     DuplicateReason
+	title      string
+	mainTitle  string
+}
+
+func newDuplicateKeys(ref Reference) duplicateKeys {
+	keys := duplicateKeys{refType: TypeOf(ref)}
+	keys.title = normalizeTitle(string(ref.Title()))
+	if main, _, found := strings.Cut(string(ref.Title()), ":"); found {
+		keys.mainTitle = normalizeTitle(main)
+	}
+
+	switch r := ref.(type) {
+	case BookReference:
+		isbn := string(r.ISBN)
+		if canonical, err := NewISBN(isbn); err == nil {
+			isbn = string(canonical)
+		}
+		keys.identifier, keys.reason = "isbn:"+isbn, SameISBN
+	case LinkReference:
+		url := string(r.URL)
+		if canonical, err := NewURL(url); err == nil {
+			url = string(canonical)
+		}
+		keys.identifier, keys.reason = "url:"+url, SameURL
+	}
+	return keys
+}
+
+func similarTitles(a, b duplicateKeys) bool {
+	if titleSimilarity(a.title, b.title) >= TitleSimilarityThreshold {
+		return true
+	}
+	// "Title" and "Title: Subtitle" are the same book more often than not
+	aMain, bMain := a.mainTitle, b.mainTitle
+	if aMain == "" {
+		aMain = a.title
+	}
+	if bMain == "" {
+		bMain = b.title
+	}
+	if len(aMain) < minMainTitleLength || len(bMain) < minMainTitleLength || (a.mainTitle == "" && b.mainTitle == "") {
+		return false
+	}
+	return titleSimilarity(aMain, bMain) >= TitleSimilarityThreshold
+}
+
+// Lowercases the title and reduces everything that isn't a letter or digit to single spaces
+func normalizeTitle(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func titleSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(editDistance(ra, rb))/float64(longest)
+}
+
+// Levenshtein distance, keeping only two rows of the matrix
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func sortedReasons(set map[DuplicateReason]bool) []DuplicateReason {
+	var reasons []DuplicateReason
+	for _, r := range []DuplicateReason{SameISBN, SameURL, SimilarTitle} {
+		if set[r] {
+			reasons = append(reasons, r)
+		}
+	}
+	return reasons
+}
+
+/*
+MergeReferences folds the other references into the one that is kept, which keeps its id, type, title and position.
+It ends up starred if any of them was, with the union of their tags, and with the descriptions/texts of the others
+appended to its own (skipping the ones it already contains). Like with ConvertReference, an ISBN or URL of another
+reference that differs from the kept one's is appended too, so that merging doesn't lose data.
+*/
+func MergeReferences(keep Reference, others []Reference) (Reference, error) {
+	if len(others) == 0 {
+		return nil, errors.New("no references to merge")
+	}
+	kept := &conversionSource{}
+	keep.Render(kept)
+
+	text := kept.text
+	appendText := func(s string) {
+		if s == "" || strings.Contains(text, s) {
+			return
+		}
+		if text != "" {
+			text += "\n\n"
+		}
+		text += s
+	}
+	starred := keep.Starred()
+	tags := map[Tag]bool{}
+	for _, tag := range keep.Tags() {
+		tags[tag] = true
+	}
+
+	for _, other := range others {
+		if other.GetId() == keep.GetId() {
+			return nil, errors.New("cannot merge a reference into itself")
+		}
+		source := &conversionSource{}
+		other.Render(source)
+		appendText(source.text)
+		// compared in canonical form, so that the same ISBN stored as an ISBN-10 isn't appended
+		if newDuplicateKeys(other).identifier != newDuplicateKeys(keep).identifier {
+			appendText(source.extra)
+		}
+		starred = starred || other.Starred()
+		for _, tag := range other.Tags() {
+			tags[tag] = true
+		}
+	}
+
+	merged := make([]Tag, 0, len(tags))
+	for tag := range tags {
+		merged = append(merged, tag)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i] < merged[j] })
+
+	builder := &mergeBuilder{text: text, starred: starred, tags: merged}
+	keep.Render(builder)
+	return builder.result, nil
+}
+
+// mergeBuilder rebuilds the kept reference with the merged fields
+type mergeBuilder struct {
+	text    string
+	starred bool
+	tags    []Tag
+	result  Reference
+}
+
+func (b *mergeBuilder) RenderBook(ref BookReference) {
+	book := NewBookReference(ref.GetId(), ref.Title(), ref.ISBN, b.text, b.starred)
+	book.SetTags(b.tags)
+	b.result = book
+}
+
+func (b *mergeBuilder) RenderLink(ref LinkReference) {
+	link := NewLinkReference(ref.GetId(), ref.Title(), ref.URL, b.text, b.starred)
+	link.SetTags(b.tags)
+	b.result = link
+}
+
+func (b *mergeBuilder) RenderNote(ref NoteReference) {
+	note := NewNoteReference(ref.GetId(), ref.Title(), b.text, b.starred)
+	note.SetTags(b.tags)
+	b.result = note
+}
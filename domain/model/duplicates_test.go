@@ -0,0 +1,94 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func categorized(categoryId Id, ref Reference) CategorizedReference {
+	return CategorizedReference{Category: CategoryRef{Id: categoryId}, Reference: ref}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	refs := []CategorizedReference{
+		categorized(1, NewBookReference(1, "Designing Data-Intensive Applications", "9781449373320", "", false)),
+		categorized(1, NewBookReference(2, "Learning Go", "9781098139292", "", false)),
+		categorized(2, NewBookReference(3, "DDIA", "1-4493-7332-1", "", false)),
+		categorized(2, NewBookReference(4, "designing data intensive applications: the big ideas", "9780000000002", "", false)),
+		categorized(2, NewLinkReference(5, "An article", "https://example.com/a", "", false)),
+		categorized(3, NewLinkReference(6, "Same article", "HTTPS://example.com/a/?utm_source=feed", "", false)),
+		categorized(3, NewNoteReference(7, "Learning Go", "my notes on the book", false)),
+		categorized(3, NewNoteReference(8, "Go: tips", "", false)),
+		categorized(3, NewNoteReference(9, "Go: gotchas", "", false)),
+	}
+
+	groups := FindDuplicates(refs)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d: %v", len(groups), groups)
+	}
+
+	var ids []Id
+	for _, ref := range groups[0].References {
+		ids = append(ids, ref.Reference.GetId())
+	}
+	if !reflect.DeepEqual(ids, []Id{1, 3, 4}) {
+		t.Errorf("expected the books 1, 3 and 4 to be grouped, got %v", ids)
+	}
+	if !reflect.DeepEqual(groups[0].Reasons, []DuplicateReason{SameISBN, SimilarTitle}) {
+		t.Errorf("expected same ISBN and similar title as reasons, got %v", groups[0].Reasons)
+	}
+
+	ids = nil
+	for _, ref := range groups[1].References {
+		ids = append(ids, ref.Reference.GetId())
+	}
+	if !reflect.DeepEqual(ids, []Id{5, 6}) || !reflect.DeepEqual(groups[1].Reasons, []DuplicateReason{SameURL}) {
+		t.Errorf("expected the links 5 and 6 to be grouped by URL, got %v, %v", ids, groups[1].Reasons)
+	}
+}
+
+func TestFindDuplicatesWithoutDuplicates(t *testing.T) {
+	refs := []CategorizedReference{
+		categorized(1, NewNoteReference(1, "One", "", false)),
+		categorized(1, NewNoteReference(2, "Two", "", false)),
+	}
+	if groups := FindDuplicates(refs); len(groups) != 0 {
+		t.Errorf("expected no groups, got %v", groups)
+	}
+}
+
+func TestMergeReferences(t *testing.T) {
+	keep := NewBookReference(1, "DDIA", "9781449373320", "the book", false)
+	keep.SetTags([]Tag{"databases"})
+	sameISBN := NewBookReference(2, "Designing Data-Intensive Applications", "1449373321", "the book", true)
+	sameISBN.SetTags([]Tag{"distributed", "databases"})
+	link := NewLinkReference(3, "DDIA website", "https://dataintensive.net", "", false)
+
+	ref, err := MergeReferences(keep, []Reference{sameISBN, link})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	book, ok := ref.(BookReference)
+	if !ok {
+		t.Fatalf("expected a BookReference, got %T", ref)
+	}
+	if book.GetId() != 1 || book.Title() != "DDIA" || book.ISBN != "9781449373320" {
+		t.Errorf("expected the kept reference's id, title and ISBN, got %v, %v, %v", book.GetId(), book.Title(), book.ISBN)
+	}
+	if !book.Starred() {
+		t.Error("expected the merged reference to be starred")
+	}
+	if !reflect.DeepEqual(book.Tags(), []Tag{"databases", "distributed"}) {
+		t.Errorf("expected the union of the tags, got %v", book.Tags())
+	}
+	if book.Description != "the book\n\nURL: https://dataintensive.net" {
+		t.Errorf("expected the link's URL to be appended to the description only, got %q", book.Description)
+	}
+
+	if _, err := MergeReferences(keep, nil); err == nil {
+		t.Error("expected error when there is nothing to merge")
+	}
+	if _, err := MergeReferences(keep, []Reference{keep}); err == nil {
+		t.Error("expected error when merging a reference into itself")
+	}
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Location is where a highlight is found in a book, in whatever form fits the edition (e.g. "p. 42", "loc. 1234", "ch. 3")
+type Location string
+
+const MaxLocationLength = 50
+
+func NewLocation(val string) (Location, error) {
+	val = strings.TrimSpace(val)
+	if len(val) > MaxLocationLength {
+		return "", fmt.Errorf("location too long (max %d)", MaxLocationLength)
+	}
+	return Location(val), nil
+}
+
+// Highlight is a quote or highlighted passage of a book, with an optional location and comment.
+// The highlights of a book are ordered, like the references of a category.
+type Highlight struct {
+	Id          Id
+	ReferenceId Id
+	Text        string
+	Location    Location
+	Comment     string
+}
+
+func NewHighlight(id Id, referenceId Id, text string, location Location, comment string) (Highlight, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Highlight{}, errors.New("highlight text cannot be empty")
+	}
+	return Highlight{
+		Id:          id,
+		ReferenceId: referenceId,
+		Text:        text,
+		Location:    location,
+		Comment:     strings.TrimSpace(comment),
+	}, nil
+}
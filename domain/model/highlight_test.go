@@ -0,0 +1,32 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewLocation(t *testing.T) {
+	_, err := NewLocation(strings.Repeat("a", MaxLocationLength+1))
+	if err == nil {
+		t.Error("expected error for too long location")
+	}
+	location, err := NewLocation("  p. 42 ")
+	if err != nil || location != "p. 42" {
+		t.Errorf("expected location='p. 42', got %v, err=%v", location, err)
+	}
+	location, err = NewLocation("")
+	if err != nil || location != "" {
+		t.Errorf("expected empty location to be allowed, got %v, err=%v", location, err)
+	}
+}
+
+func TestNewHighlight(t *testing.T) {
+	_, err := NewHighlight(0, 1, "   ", "", "")
+	if err == nil {
+		t.Error("expected error for empty text")
+	}
+	highlight, err := NewHighlight(0, 1, " quote ", "p. 1", " comment ")
+	if err != nil || highlight.Text != "quote" || highlight.Comment != "comment" || highlight.Location != "p. 1" {
+		t.Errorf("expected trimmed text and comment, got %+v, err=%v", highlight, err)
+	}
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ImportFolder is a category to import references into, e.g. a folder of browser bookmarks. Folders nest like categories do.
+type ImportFolder struct {
+	Name       Title
+	References []Reference
+	// entries of the source that couldn't be turned into references, reported as skipped
+	Rejected []RejectedEntry
+	Folders  []ImportFolder
+}
+
+type RejectedEntry struct {
+	Title  string
+	Reason string
+}
+
+type ImportOutcome string
+
+const (
+	Imported         ImportOutcome = "imported"
+	SkippedDuplicate ImportOutcome = "duplicate"
+	SkippedInvalid   ImportOutcome = "invalid"
+)
+
+// ImportItem is what happened (or, in a dry run, would happen) to an entry of an import
+type ImportItem struct {
+	// the path of the category, e.g. "Programming / Go"
+	Category string
+	Title    string
+	Outcome  ImportOutcome
+	Reason   string
+}
+
+type ImportReport struct {
+	DryRun bool
+	// the paths of the categories that were (or would be) created
+	CreatedCategories []string
+	Items             []ImportItem
+}
+
+/*
+ImportPlan is what an import adds to the library, in order. The categories it creates have negative ids until they
+are created, by which their subcategories and references refer to them.
+*/
+type ImportPlan struct {
+	// each listed after its parent, if that is created too
+	Categories []PlannedCategory
+	References []PlannedReference
+}
+
+type PlannedCategory struct {
+	Id Id
+	// the zero Id for a top-level category
+	ParentId Id
+	Name     Title
+}
+
+type PlannedReference struct {
+	CategoryId Id
+	Reference  Reference
+}
+
+func (r ImportReport) Count(outcome ImportOutcome) int {
+	count := 0
+	for _, item := range r.Items {
+		if item.Outcome == outcome {
+			count++
+		}
+	}
+	return count
+}
+
+func CategoryPath(parentPath string, name Title) string {
+	if parentPath == "" {
+		return string(name)
+	}
+	return parentPath + " / " + string(name)
+}
+
+// Truncates a title that is too long, e.g. the title of a web page, to the maximum length, at a rune boundary
+func TruncateTitle(val string) string {
+	val = strings.TrimSpace(val)
+	if len(val) <= MaxTitleLength {
+		return val
+	}
+	const ellipsis = "…"
+	cut := MaxTitleLength - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(val[cut]) {
+		cut--
+	}
+	return strings.TrimSpace(val[:cut]) + ellipsis
+}
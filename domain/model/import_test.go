@@ -0,0 +1,42 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateTitle(t *testing.T) {
+	if title := TruncateTitle("  short  "); title != "short" {
+		t.Errorf("expected short, got %q", title)
+	}
+	long := strings.Repeat("é", MaxTitleLength)
+	title := TruncateTitle(long)
+	if len(title) > MaxTitleLength || !strings.HasSuffix(title, "…") || !strings.HasPrefix(title, "éé") {
+		t.Errorf("expected title truncated to %d bytes, got %d bytes: %q", MaxTitleLength, len(title), title)
+	}
+	if _, err := NewTitle(title); err != nil {
+		t.Errorf("expected truncated title to be valid, got %v", err)
+	}
+}
+
+func TestImportReportCount(t *testing.T) {
+	report := ImportReport{Items: []ImportItem{{Outcome: Imported}, {Outcome: SkippedDuplicate}, {Outcome: Imported}}}
+	if report.Count(Imported) != 2 || report.Count(SkippedDuplicate) != 1 || report.Count(SkippedInvalid) != 0 {
+		t.Errorf("unexpected counts for %+v", report)
+	}
+	if CategoryPath("", "Go") != "Go" || CategoryPath("Programming", "Go") != "Programming / Go" {
+		t.Error("unexpected category paths")
+	}
+}
+
+func TestIdentifierOf(t *testing.T) {
+	if id := IdentifierOf(NewBookReference(1, "Book", "0-596-52068-9", "", false)); id != "isbn:9780596520687" {
+		t.Errorf("expected canonical ISBN identifier, got %q", id)
+	}
+	if id := IdentifierOf(NewLinkReference(1, "Link", "https://Example.com/a/", "", false)); id != "url:https://example.com/a" {
+		t.Errorf("expected canonical URL identifier, got %q", id)
+	}
+	if id := IdentifierOf(NewNoteReference(1, "Note", "text", false)); id != "" {
+		t.Errorf("expected no identifier for notes, got %q", id)
+	}
+}
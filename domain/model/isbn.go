@@ -0,0 +1,149 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// ISBN is stored in its canonical form: the 13 digits of the ISBN-13, without hyphens.
+// ISBN-10s are converted on input and can be derived again for display (see ISBN10).
+type ISBN string
+
+// NewISBN accepts an ISBN-10 or ISBN-13, with or without hyphens and spaces, validates its check digit and returns the canonical ISBN-13
+func NewISBN(val string) (ISBN, error) {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(val))
+	if len(digits) == 0 {
+		return "", errors.New("ISBN cannot be empty")
+	}
+	switch len(digits) {
+	case 10:
+		if !isValidISBN10(digits) {
+			return "", errors.New("invalid ISBN-10 (wrong format or check digit)")
+		}
+		body := "978" + digits[:9]
+		return ISBN(body + string(isbn13CheckDigit(body))), nil
+	case 13:
+		if !isValidISBN13(digits) {
+			return "", errors.New("invalid ISBN-13 (wrong format or check digit)")
+		}
+		return ISBN(digits), nil
+	default:
+		return "", errors.New("ISBN must have 10 or 13 digits")
+	}
+}
+
+// Returns the ISBN-10 for ISBNs in the 978 prefix (the only ones that have one), hyphenated like Hyphenated (so only
+// for the English language groups), or "" otherwise
+func (i ISBN) ISBN10() string {
+	if !i.isCanonical() || !strings.HasPrefix(string(i), "978") {
+		return ""
+	}
+	body := string(i)[3:12]
+	return hyphenate("", body, string(isbn10CheckDigit(body)))
+}
+
+// Returns the ISBN-13 split into prefix, registration group, registrant, publication and check digit, e.g. 978-1-4493-7332-0.
+// Only the English language groups (978-0 and 978-1) are hyphenated. The ISBNs of the other groups are returned without
+// hyphens, since where they go depends on the range tables of the International ISBN Agency, and an ISBN hyphenated
+// in the wrong places reads as a different one.
+// Values that aren't canonical (e.g. rows the normalization migration couldn't fix) are returned as they are.
+func (i ISBN) Hyphenated() string {
+	if !i.isCanonical() {
+		return string(i)
+	}
+	return hyphenate(string(i)[:3], string(i)[3:12], string(i)[12:])
+}
+
+func (i ISBN) isCanonical() bool {
+	return len(i) == 13 && isValidISBN13(string(i))
+}
+
+// Registrant ranges of the English language groups, as 7 digit prefixes of what follows the group digit.
+// Each entry gives the length of the registrant element for the rest of the range up to the next entry.
+type registrantRange struct {
+	from   string
+	length int
+}
+
+var registrantRanges = map[byte][]registrantRange{
+	'0': {{"0000000", 2}, {"2000000", 3}, {"7000000", 4}, {"8500000", 5}, {"9000000", 6}, {"9500000", 7}},
+	'1': {{"0000000", 2}, {"1000000", 3}, {"4000000", 4}, {"5500000", 5}, {"8698000", 6}, {"9990000", 7}},
+}
+
+// hyphenate joins the (optional) prefix, the 9 digit body of group, registrant and publication, and the check digit,
+// or returns them unhyphenated if the group isn't one whose ranges are known
+func hyphenate(prefix, body, check string) string {
+	ranges, ok := registrantRanges[body[0]]
+	if !ok || (prefix != "" && prefix != "978") {
+		return prefix + body + check
+	}
+	rest := body[1:]
+	length := 0
+	for _, r := range ranges {
+		if rest[:7] >= r.from {
+			length = r.length
+		}
+	}
+	if length == 0 || length >= len(rest) {
+		return prefix + body + check
+	}
+	parts := []string{}
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	return strings.Join(append(parts, body[:1], rest[:length], rest[length:], check), "-")
+}
+
+func isValidISBN10(digits string) bool {
+	if len(digits) != 10 {
+		return false
+	}
+	for _, d := range digits[:9] {
+		if d < '0' || d > '9' {
+			return false
+		}
+	}
+	last := digits[9]
+	if last != 'X' && (last < '0' || last > '9') {
+		return false
+	}
+	return isbn10CheckDigit(digits[:9]) == last
+}
+
+func isValidISBN13(digits string) bool {
+	if len(digits) != 13 || !(strings.HasPrefix(digits, "978") || strings.HasPrefix(digits, "979")) {
+		return false
+	}
+	for _, d := range digits {
+		if d < '0' || d > '9' {
+			return false
+		}
+	}
+	return isbn13CheckDigit(digits[:12]) == digits[12]
+}
+
+// The check digit of an ISBN-10 makes the sum of the digits weighted 10 down to 1 divisible by 11 (10 is written as X)
+func isbn10CheckDigit(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// The check digit of an ISBN-13 makes the sum of the digits weighted alternately 1 and 3 divisible by 10
+func isbn13CheckDigit(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(body[i]-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
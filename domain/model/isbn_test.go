@@ -0,0 +1,62 @@
+package model
+
+import "testing"
+
+func TestNewISBN(t *testing.T) {
+	invalid := []string{"", "123", "97814493733201", "9781449373321", "1449373322", "14493733X1", "abcdefghij", "1234567890123"}
+	for _, val := range invalid {
+		if _, err := NewISBN(val); err == nil {
+			t.Errorf("expected error for ISBN %q", val)
+		}
+	}
+
+	valid := map[string]ISBN{
+		"9781449373320":     "9781449373320",
+		"978-1-4493-7332-0": "9781449373320",
+		"978 1 4493 7332 0": "9781449373320",
+		"1449373321":        "9781449373320",
+		"1-4493-7332-1":     "9781449373320",
+		"0-8044-2957-x":     "9780804429573",
+		"979-10-90636-07-1": "9791090636071",
+	}
+	for val, expected := range valid {
+		isbn, err := NewISBN(val)
+		if err != nil || isbn != expected {
+			t.Errorf("expected %q to normalize to %v, got %v, err=%v", val, expected, isbn, err)
+		}
+	}
+}
+
+func TestISBNHyphenated(t *testing.T) {
+	cases := map[ISBN]string{
+		"9781449373320": "978-1-4493-7332-0",
+		"9780306406157": "978-0-306-40615-7",
+		"9780804429573": "978-0-8044-2957-3",
+		// the German, French and Japanese groups aren't known, so they are left unhyphenated
+		"9783161484100": "9783161484100",
+		"9782070408504": "9782070408504",
+		"9784062938426": "9784062938426",
+		"9791090636071": "9791090636071",
+		"111-111":       "111-111",
+	}
+	for isbn, expected := range cases {
+		if got := isbn.Hyphenated(); got != expected {
+			t.Errorf("expected %v to be hyphenated as %q, got %q", isbn, expected, got)
+		}
+	}
+}
+
+func TestISBN10(t *testing.T) {
+	cases := map[ISBN]string{
+		"9781449373320": "1-4493-7332-1",
+		"9780804429573": "0-8044-2957-X",
+		"9783161484100": "316148410X",
+		"9791090636071": "",
+		"111-111":       "",
+	}
+	for isbn, expected := range cases {
+		if got := isbn.ISBN10(); got != expected {
+			t.Errorf("expected the ISBN-10 of %v to be %q, got %q", isbn, expected, got)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package model
+
+// Library is a snapshot of all the categories and references, e.g. for exporting them
+type Library struct {
+	// in depth-first tree order (each category is followed by its subcategories)
+	Categories []CategoryRef
+	// grouped by category and in order within each
+	References []CategorizedReference
+	Relations  []Relation
+}
+
+// Returns the references of a category, in order
+func (l Library) ReferencesOf(categoryId Id) []Reference {
+	var refs []Reference
+	for _, ref := range l.References {
+		if ref.Category.Id == categoryId {
+			refs = append(refs, ref.Reference)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// LinkHealth is the outcome of the last check of a link: the status code of its page, where it redirects to and any
+// error that kept the page from loading
+type LinkHealth struct {
+	ReferenceId Id
+	// the URL that was checked, which may have been edited since
+	URL        URL
+	StatusCode int
+	// the URL the link ended up at after following redirects, empty if it didn't redirect
+	RedirectURL URL
+	Error       string
+	CheckedAt   time.Time
+}
+
+// Returns whether the page failed to load or answered with an error status
+func (h LinkHealth) Broken() bool {
+	return h.Error != "" || h.StatusCode >= 400
+}
+
+// Returns whether the link redirects to another site, which is how expired domains usually end up parked
+func (h LinkHealth) MovedToOtherSite() bool {
+	if h.RedirectURL == "" {
+		return false
+	}
+	from, err := url.Parse(string(h.URL))
+	if err != nil {
+		return false
+	}
+	to, err := url.Parse(string(h.RedirectURL))
+	if err != nil {
+		return false
+	}
+	return siteOf(from.Hostname()) != siteOf(to.Hostname())
+}
+
+// Returns whether the check is about the given URL, since checks of a link that was edited since no longer apply
+func (h LinkHealth) AppliesTo(url URL) bool {
+	return !h.CheckedAt.IsZero() && h.URL == url
+}
+
+// Describes the status for people, e.g. "404 Not Found", "200 OK, redirects to https://..." or the error
+func (h LinkHealth) Summary() string {
+	status := h.Error
+	if status == "" {
+		status = strings.TrimSpace(fmt.Sprintf("%d %s", h.StatusCode, http.StatusText(h.StatusCode)))
+	}
+	if h.RedirectURL != "" {
+		status += ", redirects to " + string(h.RedirectURL)
+	}
+	return status
+}
+
+// LinkCheckReport is the outcome of checking a batch of links, in the order they were checked
+type LinkCheckReport struct {
+	Checked []LinkHealth
+}
+
+func (r LinkCheckReport) Broken() []LinkHealth {
+	var broken []LinkHealth
+	for _, health := range r.Checked {
+		if health.Broken() {
+			broken = append(broken, health)
+		}
+	}
+	return broken
+}
+
+// Returns the links that load but redirect to another site
+func (r LinkCheckReport) MovedToOtherSite() []LinkHealth {
+	var moved []LinkHealth
+	for _, health := range r.Checked {
+		if !health.Broken() && health.MovedToOtherSite() {
+			moved = append(moved, health)
+		}
+	}
+	return moved
+}
+
+// www.example.com and example.com are the same site
+func siteOf(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLinkHealthBroken(t *testing.T) {
+	tests := []struct {
+		health LinkHealth
+		want   bool
+	}{
+		{LinkHealth{StatusCode: 200}, false},
+		{LinkHealth{StatusCode: 301, RedirectURL: "https://example.com/new"}, false},
+		{LinkHealth{StatusCode: 404}, true},
+		{LinkHealth{StatusCode: 503}, true},
+		{LinkHealth{Error: "no such host"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.health.Broken(); got != tt.want {
+			t.Errorf("%+v: Broken() = %v, want %v", tt.health, got, tt.want)
+		}
+	}
+}
+
+func TestLinkHealthMovedToOtherSite(t *testing.T) {
+	tests := []struct {
+		url, redirect URL
+		want          bool
+	}{
+		{"https://example.com/a", "", false},
+		{"http://example.com/a", "https://www.example.com/a", false},
+		{"https://Example.com/a", "https://example.com/b", false},
+		{"https://blog.example.com/post", "https://parked-domains.net/?d=blog.example.com", true},
+	}
+	for _, tt := range tests {
+		health := LinkHealth{URL: tt.url, StatusCode: 200, RedirectURL: tt.redirect}
+		if got := health.MovedToOtherSite(); got != tt.want {
+			t.Errorf("%s -> %s: MovedToOtherSite() = %v, want %v", tt.url, tt.redirect, got, tt.want)
+		}
+	}
+}
+
+func TestLinkHealthSummary(t *testing.T) {
+	tests := []struct {
+		health LinkHealth
+		want   string
+	}{
+		{LinkHealth{StatusCode: 404}, "404 Not Found"},
+		{LinkHealth{StatusCode: 200, RedirectURL: "https://example.com/new"}, "200 OK, redirects to https://example.com/new"},
+		{LinkHealth{StatusCode: 599}, "599"},
+		{LinkHealth{Error: "timeout"}, "timeout"},
+	}
+	for _, tt := range tests {
+		if got := tt.health.Summary(); got != tt.want {
+			t.Errorf("Summary() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestLinkHealthAppliesTo(t *testing.T) {
+	health := LinkHealth{URL: "https://example.com/a", StatusCode: 404, CheckedAt: time.Now()}
+	if !health.AppliesTo("https://example.com/a") {
+		t.Errorf("expected the check to apply to the URL that was checked")
+	}
+	if health.AppliesTo("https://example.com/b") {
+		t.Errorf("expected the check not to apply to an edited URL")
+	}
+	if (LinkHealth{URL: "https://example.com/a"}).AppliesTo("https://example.com/a") {
+		t.Errorf("expected a link that was never checked not to have a status")
+	}
+}
+
+func TestLinkCheckReport(t *testing.T) {
+	report := LinkCheckReport{Checked: []LinkHealth{
+		{ReferenceId: 1, URL: "https://example.com", StatusCode: 200},
+		{ReferenceId: 2, URL: "https://example.com/gone", StatusCode: 410},
+		{ReferenceId: 3, URL: "https://old.org", StatusCode: 200, RedirectURL: "https://parking.net/old.org"},
+		{ReferenceId: 4, URL: "https://old.net", Error: "no such host"},
+	}}
+	if broken := report.Broken(); len(broken) != 2 || broken[0].ReferenceId != 2 || broken[1].ReferenceId != 4 {
+		t.Errorf("unexpected broken links: %+v", broken)
+	}
+	if moved := report.MovedToOtherSite(); len(moved) != 1 || moved[0].ReferenceId != 3 {
+		t.Errorf("unexpected moved links: %+v", moved)
+	}
+}
@@ -0,0 +1,26 @@
+package model
+
+import "errors"
+
+// ErrMetadataNotFound is returned by metadata providers that don't know the book or page
+var ErrMetadataNotFound = errors.New("no metadata found")
+
+// BookMetadata is what a metadata provider knows about a book
+type BookMetadata struct {
+	ISBN        ISBN
+	Title       string
+	Authors     []string
+	Description string
+}
+
+// Returns the title with the authors appended as "(by ...)", the way books are titled in the library
+func (m BookMetadata) FullTitle() Title {
+	return Title(TruncateTitle(TitleWithAuthors(m.Title, m.Authors)))
+}
+
+// PageMetadata is what a web page says about itself, in its title and meta tags
+type PageMetadata struct {
+	URL         URL
+	Title       string
+	Description string
+}
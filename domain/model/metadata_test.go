@@ -0,0 +1,17 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBookMetadataFullTitle(t *testing.T) {
+	metadata := BookMetadata{Title: "Code", Authors: []string{"Charles Petzold"}}
+	if title := metadata.FullTitle(); title != "Code (by Charles Petzold)" {
+		t.Errorf("unexpected title %q", title)
+	}
+	long := BookMetadata{Title: strings.Repeat("a", MaxTitleLength+10)}
+	if _, err := NewTitle(string(long.FullTitle())); err != nil {
+		t.Errorf("expected long titles to be truncated, got %v", err)
+	}
+}
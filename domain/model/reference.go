@@ -4,6 +4,7 @@ type Reference interface {
 	GetId() Id
 	Title() Title
 	Starred() bool
+	Tags() []Tag
 	Render(renderer Renderer)                   // this is a classic Visitor pattern
 	Persist(persistor ReferencePersistor) error // so is this
 }
@@ -24,6 +25,7 @@ type BaseReference struct {
 	id      Id
 	title   Title
 	starred bool
+	tags    []Tag
 }
 
 func (b BaseReference) GetId() Id {
@@ -38,6 +40,15 @@ func (b BaseReference) Starred() bool {
 	return b.starred
 }
 
+func (b BaseReference) Tags() []Tag {
+	return b.tags
+}
+
+// Tags are attached after construction because they are managed separately from the other fields (see the bulk tag operations)
+func (b *BaseReference) SetTags(tags []Tag) {
+	b.tags = tags
+}
+
 type BookReference struct {
 	BaseReference
 	ISBN        ISBN
@@ -113,3 +124,9 @@ func (n NoteReference) Render(renderer Renderer) {
 func (n NoteReference) Persist(persistor ReferencePersistor) error {
 	return persistor.PersistNote(n)
 }
+
+// CategorizedReference is a reference along with the category it belongs to, for when a reference is read on its own
+type CategorizedReference struct {
+	Category  CategoryRef
+	Reference Reference
+}
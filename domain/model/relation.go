@@ -0,0 +1,167 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type RelationType string
+
+const (
+	// Related references are worth looking at together. The relation has no direction.
+	RelatedRelation RelationType = "related"
+	// The From reference should be read before the To reference
+	PrerequisiteRelation RelationType = "prerequisite"
+	// The From reference is a newer version of, or replacement for, the To reference
+	SupersedesRelation RelationType = "supersedes"
+)
+
+func NewRelationType(val string) (RelationType, error) {
+	switch t := RelationType(strings.ToLower(strings.TrimSpace(val))); t {
+	case RelatedRelation, PrerequisiteRelation, SupersedesRelation:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown relation type %q (must be one of related, prerequisite, supersedes)", val)
+}
+
+// Relation is a typed link between two references, which may be in different categories
+type Relation struct {
+	From Id
+	To   Id
+	Type RelationType
+}
+
+// Related relations have no direction, so they are normalized to have From < To, making the two ways of writing one equal
+func NewRelation(from Id, to Id, relType RelationType) (Relation, error) {
+	if from == to {
+		return Relation{}, errors.New("a reference cannot be related to itself")
+	}
+	if relType == RelatedRelation && from > to {
+		from, to = to, from
+	}
+	return Relation{From: from, To: to, Type: relType}, nil
+}
+
+// Returns the reference at the other end of the relation from the given one
+func (r Relation) Other(id Id) Id {
+	if r.From == id {
+		return r.To
+	}
+	return r.From
+}
+
+// Describes what the other end of the relation is to the given reference, e.g. "prerequisite" when it should be read before it
+func (r Relation) Describe(id Id) string {
+	switch r.Type {
+	case PrerequisiteRelation:
+		if r.To == id {
+			return "prerequisite"
+		}
+		return "prerequisite for"
+	case SupersedesRelation:
+		if r.To == id {
+			return "superseded by"
+		}
+		return "supersedes"
+	}
+	return "related"
+}
+
+// RelatedReference is a relation of a reference, along with the reference at its other end
+type RelatedReference struct {
+	Relation  Relation
+	Reference CategorizedReference
+}
+
+// Returns an error if adding the candidate to the existing prerequisite relations would create a cycle, i.e. if the
+// candidate's To reference is already (transitively) a prerequisite of its From reference. Other relation types can't create cycles.
+func CheckPrerequisiteCycle(prerequisites []Relation, candidate Relation) error {
+	if candidate.Type != PrerequisiteRelation {
+		return nil
+	}
+	// the prerequisites of each reference
+	requires := prerequisiteGraph(prerequisites)
+
+	// search the prerequisites of From for To, remembering how each reference was reached to report the cycle
+	reachedFrom := map[Id]Id{candidate.From: candidate.From}
+	queue := []Id{candidate.From}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == candidate.To {
+			path := []string{fmt.Sprint(candidate.From)}
+			for step := id; step != candidate.From; step = reachedFrom[step] {
+				path = append(path, fmt.Sprint(step))
+			}
+			path = append(path, fmt.Sprint(candidate.From))
+			return fmt.Errorf("reference %d is already a prerequisite of reference %d, so this would create a cycle (%s)",
+				candidate.To, candidate.From, strings.Join(path, " -> "))
+		}
+		for _, prerequisite := range requires[id] {
+			if _, seen := reachedFrom[prerequisite]; !seen {
+				reachedFrom[prerequisite] = id
+				queue = append(queue, prerequisite)
+			}
+		}
+	}
+	return nil
+}
+
+/*
+ReadingOrder sorts the references (e.g. of a category) so that each comes after its prerequisites, while staying as close
+to the given order as possible: references are taken in order, each preceded by those of its prerequisites that haven't
+been placed yet. Prerequisites outside the given references aren't part of the result, but are still followed, so that
+"A before X before B" orders A before B even when X is elsewhere.
+*/
+func ReadingOrder(references []Reference, prerequisites []Relation) ([]Reference, error) {
+	requires := prerequisiteGraph(prerequisites)
+	byId := make(map[Id]Reference, len(references))
+	for _, ref := range references {
+		byId[ref.GetId()] = ref
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[Id]int{}
+	ordered := make([]Reference, 0, len(references))
+	var visit func(id Id) error
+	visit = func(id Id) error {
+		switch state[id] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("prerequisites of reference %d form a cycle", id)
+		}
+		state[id] = visiting
+		for _, prerequisite := range requires[id] {
+			if err := visit(prerequisite); err != nil {
+				return err
+			}
+		}
+		state[id] = done
+		if ref, ok := byId[id]; ok {
+			ordered = append(ordered, ref)
+		}
+		return nil
+	}
+	for _, ref := range references {
+		if err := visit(ref.GetId()); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// Maps each reference to its direct prerequisites, in the order the relations are given in
+func prerequisiteGraph(relations []Relation) map[Id][]Id {
+	requires := map[Id][]Id{}
+	for _, relation := range relations {
+		if relation.Type == PrerequisiteRelation {
+			requires[relation.To] = append(requires[relation.To], relation.From)
+		}
+	}
+	return requires
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewRelationType(t *testing.T) {
+	relType, err := NewRelationType(" Prerequisite ")
+	if err != nil || relType != PrerequisiteRelation {
+		t.Errorf("expected prerequisite, got %v, err=%v", relType, err)
+	}
+	if _, err := NewRelationType("cites"); err == nil {
+		t.Error("expected error for unknown relation type")
+	}
+}
+
+func TestNewRelation(t *testing.T) {
+	if _, err := NewRelation(1, 1, RelatedRelation); err == nil {
+		t.Error("expected error for self relation")
+	}
+	relation, err := NewRelation(5, 2, RelatedRelation)
+	if err != nil || relation.From != 2 || relation.To != 5 {
+		t.Errorf("expected related relation to be normalized to 2-5, got %+v, err=%v", relation, err)
+	}
+	relation, err = NewRelation(5, 2, PrerequisiteRelation)
+	if err != nil || relation.From != 5 || relation.To != 2 {
+		t.Errorf("expected prerequisite relation to keep its direction, got %+v, err=%v", relation, err)
+	}
+}
+
+func TestRelationDescribe(t *testing.T) {
+	prerequisite := Relation{From: 1, To: 2, Type: PrerequisiteRelation}
+	if prerequisite.Describe(2) != "prerequisite" || prerequisite.Other(2) != 1 {
+		t.Errorf("expected 1 to be a prerequisite of 2, got %q", prerequisite.Describe(2))
+	}
+	if prerequisite.Describe(1) != "prerequisite for" || prerequisite.Other(1) != 2 {
+		t.Errorf("expected 2 to require 1, got %q", prerequisite.Describe(1))
+	}
+	supersedes := Relation{From: 1, To: 2, Type: SupersedesRelation}
+	if supersedes.Describe(1) != "supersedes" || supersedes.Describe(2) != "superseded by" {
+		t.Errorf("unexpected descriptions %q, %q", supersedes.Describe(1), supersedes.Describe(2))
+	}
+}
+
+func TestCheckPrerequisiteCycle(t *testing.T) {
+	// 1 before 2 before 3
+	existing := []Relation{
+		{From: 1, To: 2, Type: PrerequisiteRelation},
+		{From: 2, To: 3, Type: PrerequisiteRelation},
+		{From: 3, To: 1, Type: RelatedRelation},
+	}
+	if err := CheckPrerequisiteCycle(existing, Relation{From: 1, To: 3, Type: PrerequisiteRelation}); err != nil {
+		t.Errorf("expected no cycle, got %v", err)
+	}
+	err := CheckPrerequisiteCycle(existing, Relation{From: 3, To: 1, Type: PrerequisiteRelation})
+	if err == nil || !strings.Contains(err.Error(), "3 -> 1 -> 2 -> 3") {
+		t.Errorf("expected cycle 3 -> 1 -> 2 -> 3, got %v", err)
+	}
+	if err := CheckPrerequisiteCycle(existing, Relation{From: 3, To: 1, Type: SupersedesRelation}); err != nil {
+		t.Errorf("expected only prerequisites to be checked, got %v", err)
+	}
+}
+
+func TestReadingOrder(t *testing.T) {
+	refs := []Reference{
+		NewNoteReference(1, "A", "a", false),
+		NewNoteReference(2, "B", "b", false),
+		NewNoteReference(3, "C", "c", false),
+		NewNoteReference(4, "D", "d", false),
+	}
+	prerequisites := []Relation{
+		// 4 before 2, and 3 before 99 (outside) before 1
+		{From: 4, To: 2, Type: PrerequisiteRelation},
+		{From: 3, To: 99, Type: PrerequisiteRelation},
+		{From: 99, To: 1, Type: PrerequisiteRelation},
+	}
+	ordered, err := ReadingOrder(refs, prerequisites)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []Id
+	for _, ref := range ordered {
+		ids = append(ids, ref.GetId())
+	}
+	expected := []Id{3, 1, 4, 2}
+	if len(ids) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, ids)
+		}
+	}
+
+	cyclic := append(prerequisites, Relation{From: 2, To: 4, Type: PrerequisiteRelation})
+	if _, err := ReadingOrder(refs, cyclic); err == nil {
+		t.Error("expected error for cyclic prerequisites")
+	}
+}
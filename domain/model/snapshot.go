@@ -0,0 +1,55 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ContentHash is the hex encoded SHA-256 hash of a blob of content, which identifies it in a content-addressed store
+type ContentHash string
+
+func HashContent(data []byte) ContentHash {
+	sum := sha256.Sum256(data)
+	return ContentHash(hex.EncodeToString(sum[:]))
+}
+
+func NewContentHash(val string) (ContentHash, error) {
+	val = strings.ToLower(strings.TrimSpace(val))
+	if len(val) != sha256.Size*2 {
+		return "", errors.New("content hash must have 64 hex digits")
+	}
+	if _, err := hex.DecodeString(val); err != nil {
+		return "", errors.New("content hash must have 64 hex digits")
+	}
+	return ContentHash(val), nil
+}
+
+// ErrBlobNotFound is returned by blob stores that don't have content with the requested hash
+var ErrBlobNotFound = errors.New("blob not found")
+
+// ErrCorruptContent is returned for content read from a blob store that doesn't match its hash
+var ErrCorruptContent = errors.New("content doesn't match its hash")
+
+// ArchivedPage is a copy of a web page, captured to be read after the page is gone
+type ArchivedPage struct {
+	// the HTML of the page, with its assets inlined if they were asked for
+	HTML []byte
+	// the URL the page was captured from, after redirects
+	URL           URL
+	InlinedAssets bool
+}
+
+// Snapshot records an archived copy of a link, whose HTML is kept in the blob store by its hash.
+// A link can be archived many times, and identical copies share their content.
+type Snapshot struct {
+	Id            Id
+	ReferenceId   Id
+	URL           URL
+	ContentHash   ContentHash
+	Size          int64
+	InlinedAssets bool
+	CapturedAt    time.Time
+}
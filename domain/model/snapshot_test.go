@@ -0,0 +1,29 @@
+package model
+
+import "testing"
+
+func TestHashContent(t *testing.T) {
+	// the SHA-256 of the empty string
+	if got := HashContent(nil); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("unexpected hash of no content: %s", got)
+	}
+	if HashContent([]byte("a")) == HashContent([]byte("b")) {
+		t.Errorf("expected different content to have different hashes")
+	}
+}
+
+func TestNewContentHash(t *testing.T) {
+	valid := "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855"
+	hash, err := NewContentHash(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hash != HashContent(nil) {
+		t.Errorf("expected the hash to be lowercased, got %s", hash)
+	}
+	for _, invalid := range []string{"", "e3b0c442", valid + "00", "g3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", "../../etc/passwd"} {
+		if _, err := NewContentHash(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
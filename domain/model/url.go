@@ -0,0 +1,81 @@
+package model
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// URL is stored in its canonical form (see NewURL), so that the same page added twice with small differences can be recognized
+type URL string
+
+/*
+NewURL validates an absolute http(s) URL and returns its canonical form:
+  - the scheme and host are lowercased
+  - default ports (80 for http, 443 for https) are dropped
+  - trailing slashes are removed from the path
+  - utm_* tracking parameters are removed from the query, which otherwise stays as given, as servers may depend on
+    the order of its parameters or on how they are written
+*/
+func NewURL(val string) (URL, error) {
+	val = strings.TrimSpace(val)
+	if len(val) == 0 {
+		return "", errors.New("URL cannot be empty")
+	}
+
+	u, err := url.Parse(val)
+	if err != nil {
+		return "", errors.New("invalid URL format")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", errors.New("URL must start with http:// or https://")
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return "", errors.New("URL must have a host")
+	}
+	if !strings.Contains(host, ".") && host != "localhost" && net.ParseIP(host) == nil {
+		return "", errors.New("invalid URL host")
+	}
+	u.Host = canonicalHost(u.Scheme, host, u.Port())
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+
+	u.RawQuery = stripTrackingParameters(u.RawQuery)
+	u.ForceQuery = false
+
+	return URL(u.String()), nil
+}
+
+func canonicalHost(scheme, host, port string) string {
+	if port == "" || (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		if strings.Contains(host, ":") {
+			// IPv6 literals keep their brackets
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Removes the utm_* parameters from the raw query, without decoding and encoding the others
+func stripTrackingParameters(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	params := strings.Split(rawQuery, "&")
+	kept := params[:0]
+	for _, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if !strings.HasPrefix(strings.ToLower(key), "utm_") {
+			kept = append(kept, param)
+		}
+	}
+	return strings.Join(kept, "&")
+}
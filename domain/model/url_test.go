@@ -0,0 +1,36 @@
+package model
+
+import "testing"
+
+func TestNewURL(t *testing.T) {
+	invalid := []string{"", "not-a-url", "example.com/path", "ftp://example.com", "https://", "http://link", "https://exa mple.com"}
+	for _, val := range invalid {
+		if _, err := NewURL(val); err == nil {
+			t.Errorf("expected error for URL %q", val)
+		}
+	}
+
+	valid := map[string]URL{
+		"https://example.com":                                     "https://example.com",
+		"http://example.com/path":                                 "http://example.com/path",
+		"  HTTPS://Example.COM/Path/  ":                           "https://example.com/Path",
+		"https://example.com/":                                    "https://example.com",
+		"https://example.com:443/a":                               "https://example.com/a",
+		"http://example.com:80/a":                                 "http://example.com/a",
+		"http://example.com:8080/a":                               "http://example.com:8080/a",
+		"https://example.com/a?utm_source=x&b=2&UTM_Medium=y&a=1": "https://example.com/a?b=2&a=1",
+		"https://example.com/a?b=1&a":                             "https://example.com/a?b=1&a",
+		"https://example.com/search?q=a%20b+c&utm%5Fterm=x":       "https://example.com/search?q=a%20b+c",
+		"https://example.com/a?utm_source=x":                      "https://example.com/a",
+		"https://example.com/a#section":                           "https://example.com/a#section",
+		"http://localhost:3000/":                                  "http://localhost:3000",
+		"http://[::1]:8080/":                                      "http://[::1]:8080",
+		"https://[2001:DB8::1]:443/a":                             "https://[2001:db8::1]/a",
+	}
+	for val, expected := range valid {
+		url, err := NewURL(val)
+		if err != nil || url != expected {
+			t.Errorf("expected %q to be canonicalized as %v, got %v, err=%v", val, expected, url, err)
+		}
+	}
+}
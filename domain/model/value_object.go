@@ -3,7 +3,7 @@ package model
 import (
 	"errors"
 	"fmt"
-	"regexp"
+	"strings"
 )
 
 // TODO: need better enforcement of value object constraints throughout the project(avoid bypassing by implicit conversion)
@@ -39,31 +39,21 @@ func NewTitle(val string) (Title, error) {
 	return Title(val), nil
 }
 
-type ISBN string
+type Tag string
 
-const MaxISBNLength = 50
+const MaxTagLength = 50
 
-func NewISBN(val string) (ISBN, error) {
+// Tags are case-insensitive labels, so they are stored trimmed and lowercased
+func NewTag(val string) (Tag, error) {
+	val = strings.ToLower(strings.TrimSpace(val))
 	if len(val) == 0 {
-		return "", errors.New("ISBN cannot be empty")
+		return "", errors.New("tag cannot be empty")
 	}
-	if len(val) > MaxISBNLength {
-		return "", fmt.Errorf("ISBN too long (max %d)", MaxISBNLength)
+	if len(val) > MaxTagLength {
+		return "", fmt.Errorf("tag too long (max %d)", MaxTagLength)
 	}
-	return ISBN(val), nil
-}
-
-type URL string
-
-var urlRegexp = regexp.MustCompile(`^(https?://)?[\w.-]+(\.[a-zA-Z]{2,})+.*$`)
-
-func NewURL(val string) (URL, error) {
-	if len(val) == 0 {
-		return "", errors.New("URL cannot be empty")
-	}
-
-	if !urlRegexp.MatchString(val) {
-		return "", errors.New("invalid URL format")
+	if strings.Contains(val, ",") {
+		return "", errors.New("tag cannot contain commas")
 	}
-	return URL(val), nil
+	return Tag(val), nil
 }
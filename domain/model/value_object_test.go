@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -53,40 +54,21 @@ func TestNewTitle(t *testing.T) {
 	}
 }
 
-func TestNewISBN(t *testing.T) {
-	_, err := NewISBN("")
+func TestNewTag(t *testing.T) {
+	_, err := NewTag("  ")
 	if err == nil {
-		t.Error("expected error for empty ISBN")
+		t.Error("expected error for empty tag")
 	}
-	long := ""
-	for i := 0; i < MaxISBNLength+1; i++ {
-		long += "1"
-	}
-	_, err = NewISBN(long)
-	if err == nil {
-		t.Error("expected error for too long ISBN")
-	}
-	isbn, err := NewISBN("1234567890")
-	if err != nil || isbn != "1234567890" {
-		t.Errorf("expected isbn='1234567890', got %v, err=%v", isbn, err)
-	}
-}
-
-func TestNewURL(t *testing.T) {
-	_, err := NewURL("")
+	_, err = NewTag("a,b")
 	if err == nil {
-		t.Error("expected error for empty URL")
+		t.Error("expected error for tag with comma")
 	}
-	_, err = NewURL("not-a-url")
+	_, err = NewTag(strings.Repeat("a", MaxTagLength+1))
 	if err == nil {
-		t.Error("expected error for invalid URL")
-	}
-	url, err := NewURL("https://example.com")
-	if err != nil || url != "https://example.com" {
-		t.Errorf("expected url='https://example.com', got %v, err=%v", url, err)
+		t.Error("expected error for too long tag")
 	}
-	url, err = NewURL("http://example.com/path")
-	if err != nil || url != "http://example.com/path" {
-		t.Errorf("expected url='http://example.com/path', got %v, err=%v", url, err)
+	tag, err := NewTag("  Distributed Systems ")
+	if err != nil || tag != "distributed systems" {
+		t.Errorf("expected tag='distributed systems', got %v, err=%v", tag, err)
 	}
 }
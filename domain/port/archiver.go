@@ -0,0 +1,14 @@
+package port
+
+import (
+	"context"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// PageArchiver captures copies of web pages
+type PageArchiver interface {
+	// Downloads the page at the URL, with its stylesheets and images inlined if inlineAssets is set, so that the copy
+	// reads the same without the site.
+	Archive(ctx context.Context, url model.URL, inlineAssets bool) (model.ArchivedPage, error)
+}
@@ -0,0 +1,13 @@
+package port
+
+import "github.com/VladMinzatu/reference-manager/domain/model"
+
+// BlobStore keeps blobs of content by their hash, so storing the same content twice keeps one copy of it
+type BlobStore interface {
+	// Stores the content, if it isn't stored already, and returns its hash
+	Put(data []byte) (model.ContentHash, error)
+	// Returns the content with the hash, or model.ErrBlobNotFound
+	Get(hash model.ContentHash) ([]byte, error)
+	// Removes the content with the hash. Removing content that isn't stored is not an error.
+	Delete(hash model.ContentHash) error
+}
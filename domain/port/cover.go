@@ -0,0 +1,17 @@
+package port
+
+import "github.com/VladMinzatu/reference-manager/domain/model"
+
+// CoverProvider fetches the cover images of books from an external catalog, e.g. Open Library
+type CoverProvider interface {
+	// Returns the cover image of the book, or model.ErrMetadataNotFound if the catalog has none
+	FetchCover(isbn model.ISBN) ([]byte, error)
+}
+
+// ImageProcessor reads cover images and scales them down to thumbnails
+type ImageProcessor interface {
+	// Returns the format and size of the image, or an error if it isn't an image that can be processed
+	Inspect(data []byte) (model.ImageInfo, error)
+	// Scales the image down to fit in maxWidth x maxHeight and encodes it as a JPEG (model.ThumbnailMediaType)
+	Thumbnail(data []byte, maxWidth, maxHeight int) ([]byte, error)
+}
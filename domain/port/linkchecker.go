@@ -0,0 +1,14 @@
+package port
+
+import (
+	"context"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// LinkChecker requests links to see whether their pages still load. It's safe for concurrent use.
+type LinkChecker interface {
+	// Returns the status code, the redirect target and the time of the check of the URL. Failures to load the page are
+	// recorded in the Error of the result, so only the URL, status and time are set by the checker.
+	CheckLink(ctx context.Context, url model.URL) model.LinkHealth
+}
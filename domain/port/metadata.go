@@ -0,0 +1,16 @@
+// Package port declares the interfaces through which the domain uses external services, like repository does for storage.
+package port
+
+import "github.com/VladMinzatu/reference-manager/domain/model"
+
+// MetadataProvider looks up books by ISBN in an external catalog, e.g. Open Library
+type MetadataProvider interface {
+	// Returns the metadata of the book, or model.ErrMetadataNotFound if the catalog doesn't have it
+	LookupBook(isbn model.ISBN) (model.BookMetadata, error)
+}
+
+// PageFetcher downloads web pages to read their metadata
+type PageFetcher interface {
+	// Returns the title and description of the page, or model.ErrMetadataNotFound if there is no page at the URL
+	FetchPage(url model.URL) (model.PageMetadata, error)
+}
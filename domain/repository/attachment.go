@@ -0,0 +1,15 @@
+package repository
+
+import "github.com/VladMinzatu/reference-manager/domain/model"
+
+// Like snapshots, attachments only record the files; their content is kept in the blob store
+type AttachmentRepository interface {
+	// Returns the attachments of a reference, in the order they were added
+	GetAttachments(referenceId model.Id) ([]model.Attachment, error)
+	GetAttachmentById(id model.Id) (*model.Attachment, error)
+	// Returns all attachments, to check the integrity of their content
+	GetAllAttachments() ([]model.Attachment, error)
+	// Records an attachment of a reference and returns its id
+	AddAttachment(attachment model.Attachment) (model.Id, error)
+	DeleteAttachment(id model.Id) error
+}
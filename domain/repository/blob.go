@@ -0,0 +1,15 @@
+package repository
+
+import "github.com/VladMinzatu/reference-manager/domain/model"
+
+/*
+The content of attachments, snapshots and covers is kept in a blob store, outside of the transactions of the repositories.
+When the last row referring to some content is removed, whether directly or along with its reference or category,
+the content is queued as orphaned in the same transaction, to be deleted from the store after.
+*/
+type OrphanedBlobRepository interface {
+	GetOrphanedBlobs() ([]model.ContentHash, error)
+	// Takes the content off the queue so it can be deleted from the store. Returns false if it's in use again, in
+	// which case it must be kept.
+	ClaimOrphanedBlob(hash model.ContentHash) (bool, error)
+}
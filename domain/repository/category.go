@@ -14,6 +14,16 @@ type CategoryRepository interface {
 
 	UpdateTitle(id model.Id, title model.Title, version model.Version) error
 	ReorderReferences(id model.Id, positions map[model.Id]int, version model.Version) error
+	// Moves a single reference to the given (0-based) position, shifting the references in between by one
+	MoveReferenceToPosition(id model.Id, referenceId model.Id, position int, version model.Version) error
 	AddReference(id model.Id, reference model.Reference, version model.Version) error
 	RemoveReference(id model.Id, referenceId model.Id, version model.Version) error
+
+	// Bulk operations: all the references must belong to the category and are changed in a single transaction, under a single version check.
+	SetReferencesStarred(id model.Id, referenceIds []model.Id, starred bool, version model.Version) error
+	RemoveReferences(id model.Id, referenceIds []model.Id, version model.Version) error
+	// Moves the references (keeping their relative order) to the end of the target category, whose version is bumped as well
+	MoveReferences(id model.Id, referenceIds []model.Id, targetCategoryId model.Id, version model.Version) error
+	TagReferences(id model.Id, referenceIds []model.Id, tag model.Tag, version model.Version) error
+	UntagReferences(id model.Id, referenceIds []model.Id, tag model.Tag, version model.Version) error
 }
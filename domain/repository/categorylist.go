@@ -5,10 +5,24 @@ import "github.com/VladMinzatu/reference-manager/domain/model"
 /*
 Defines operations that are performed at the level of the entire category list.
 Each operation is meant to be performed in a single transaction, with consistency enforced at the infrastructure level. (through e.g. row-level locking)
+
+Categories form a tree: each category is either top-level or the child of another category, and positions are maintained per parent.
 */
 type CategoryListRepository interface {
+	// Returns all categories in depth-first tree order (each category is followed by its subcategories)
 	GetAllCategoryRefs() ([]model.CategoryRef, error)
 	AddNewCategory(name model.Title) (model.Category, error)
+	AddNewSubcategory(parentId model.Id, name model.Title) (model.Category, error)
+	// Reorders the top-level categories
 	ReorderCategories(positions map[model.Id]int) error
+	// Reorders the direct subcategories of the given parent
+	ReorderSubcategories(parentId model.Id, positions map[model.Id]int) error
+	// Moves a single category to the given (0-based) position among its siblings, shifting the siblings in between by one
+	MoveCategoryToPosition(id model.Id, position int) error
+	// Moves a category (along with its subcategories) to the end of the new parent's children. A zero newParentId makes it top-level.
+	ReparentCategory(id model.Id, newParentId model.Id) error
+	// Renames a category and reparents it as ReparentCategory does, in one transaction, so neither is applied without the other
+	RenameAndReparentCategory(id model.Id, name model.Title, newParentId model.Id) error
+	// Deletes a category along with all its subcategories
 	DeleteCategory(id model.Id) error
 }
@@ -0,0 +1,14 @@
+package repository
+
+import "github.com/VladMinzatu/reference-manager/domain/model"
+
+// Like attachments, covers only record the images; they and their thumbnails are kept in the blob store
+type CoverRepository interface {
+	// Returns the cover of the book, or model.ErrNoCover if it has none
+	GetCover(referenceId model.Id) (*model.BookCover, error)
+	// Returns the covers of all the books that have one, by reference id
+	GetCovers() (map[model.Id]model.BookCover, error)
+	// Sets the cover of a book, replacing the one it had
+	SetCover(cover model.BookCover) error
+	RemoveCover(referenceId model.Id) error
+}
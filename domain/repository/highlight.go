@@ -0,0 +1,19 @@
+package repository
+
+import "github.com/VladMinzatu/reference-manager/domain/model"
+
+/*
+The highlights of a book are a collection of their own: they are only loaded when needed (e.g. on the detail page of the book)
+and changing them doesn't touch the category of the book.
+*/
+type HighlightRepository interface {
+	// Returns the highlights of a book, in order
+	GetHighlights(referenceId model.Id) ([]model.Highlight, error)
+	GetHighlightById(id model.Id) (*model.Highlight, error)
+	// Appends a highlight to the highlights of the book it belongs to and returns its id
+	AddHighlight(highlight model.Highlight) (model.Id, error)
+	UpdateHighlight(highlight model.Highlight) error
+	DeleteHighlight(id model.Id) error
+	// Moves a highlight to the given 0-based position among the highlights of its book
+	MoveHighlight(id model.Id, position int) error
+}
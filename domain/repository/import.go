@@ -0,0 +1,9 @@
+package repository
+
+import "github.com/VladMinzatu/reference-manager/domain/model"
+
+type ImportRepository interface {
+	// Creates the categories and adds the references of the plan in one transaction, so that an import that fails
+	// partway leaves the library as it was
+	Import(plan model.ImportPlan) error
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+/*
+The health of links is kept apart from the links themselves: it's written by the link checker in the background and
+doesn't change the categories of the links.
+*/
+type LinkHealthRepository interface {
+	// Returns the links that are due for a check, i.e. never checked, checked before the given time or edited since
+	// their last check, with the outcome of their last check if there was one
+	GetLinksToCheck(checkedBefore time.Time) ([]model.LinkHealth, error)
+	// Returns the outcome of the last check of every link that was checked, by reference id
+	GetLinkHealth() (map[model.Id]model.LinkHealth, error)
+	// Records the outcome of a check of a link, replacing the previous one
+	SaveLinkHealth(health model.LinkHealth) error
+}
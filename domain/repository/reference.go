@@ -6,5 +6,16 @@ import "github.com/VladMinzatu/reference-manager/domain/model"
 This repository is used for operations that can be performed at the level of individual references in a concurrency safe way without the need to lock the entire category.
 */
 type ReferencesRepository interface {
+	// Returns the reference (of its concrete type) along with the category it belongs to
+	GetReferenceById(id model.Id) (*model.CategorizedReference, error)
 	UpdateReference(id model.Id, reference model.Reference) error
+	// Returns the references of all categories, grouped by category and in order within each
+	GetAllReferences() ([]model.CategorizedReference, error)
+	// Saves the merged reference, including its tags, and removes the references merged into it (from whichever category they are in),
+	// moving their highlights and relations to it
+	MergeReferences(merged model.Reference, mergedIds []model.Id) error
+	// Returns the links of all categories that point to the given (canonical) URL
+	FindLinksByURL(url model.URL) ([]model.CategorizedReference, error)
+	// Replaces the reference with one of a different type, keeping its id, category and position
+	ConvertReference(id model.Id, reference model.Reference) error
 }
@@ -0,0 +1,18 @@
+package repository
+
+import "github.com/VladMinzatu/reference-manager/domain/model"
+
+/*
+Relations link references across categories, so they are managed independently of the categories of the references they link.
+*/
+type RelationRepository interface {
+	// Returns the relations the reference takes part in, in either direction
+	GetRelations(referenceId model.Id) ([]model.Relation, error)
+	// Returns all the relations, grouped by type
+	GetAllRelations() ([]model.Relation, error)
+	// Returns all the relations of the given type
+	GetRelationsOfType(relType model.RelationType) ([]model.Relation, error)
+	// Adds a relation. Prerequisite relations are checked for cycles in the same transaction.
+	AddRelation(relation model.Relation) error
+	RemoveRelation(relation model.Relation) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import "github.com/VladMinzatu/reference-manager/domain/model"
+
+/*
+The snapshots of a link only record the archived copies; their content is kept in a blob store, outside of the
+transactions of the repositories.
+*/
+type SnapshotRepository interface {
+	// Returns the snapshots of a link, most recent first
+	GetSnapshots(referenceId model.Id) ([]model.Snapshot, error)
+	GetSnapshotById(id model.Id) (*model.Snapshot, error)
+	// Records a snapshot of a link and returns its id
+	AddSnapshot(snapshot model.Snapshot) (model.Id, error)
+	// Removes the record of a snapshot. Its content is queued as orphaned if nothing else refers to it.
+	DeleteSnapshot(id model.Id) error
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/port"
+	"github.com/VladMinzatu/reference-manager/domain/repository"
+)
+
+// ArchiveService keeps copies of the pages of links, to be read offline or after the links rot
+type ArchiveService struct {
+	referenceRepo repository.ReferencesRepository
+	snapshotRepo  repository.SnapshotRepository
+	store         port.BlobStore
+	blobs         *BlobCollector
+	archiver      port.PageArchiver
+}
+
+func NewArchiveService(referenceRepo repository.ReferencesRepository, snapshotRepo repository.SnapshotRepository, store port.BlobStore, blobs *BlobCollector, archiver port.PageArchiver) *ArchiveService {
+	return &ArchiveService{referenceRepo: referenceRepo, snapshotRepo: snapshotRepo, store: store, blobs: blobs, archiver: archiver}
+}
+
+// Captures the page of the link and records the snapshot, with the page's stylesheets and images if inlineAssets is set
+func (s *ArchiveService) ArchiveLink(ctx context.Context, referenceId model.Id, inlineAssets bool) (*model.Snapshot, error) {
+	ref, err := s.referenceRepo.GetReferenceById(referenceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve reference: %w", err)
+	}
+	link, ok := ref.Reference.(model.LinkReference)
+	if !ok {
+		return nil, fmt.Errorf("reference %d is a %s, only links can be archived", referenceId, model.TypeOf(ref.Reference))
+	}
+	page, err := s.archiver.Archive(ctx, link.URL, inlineAssets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive %s: %w", link.URL, err)
+	}
+	snapshot := model.Snapshot{
+		ReferenceId:   referenceId,
+		URL:           page.URL,
+		ContentHash:   model.HashContent(page.HTML),
+		Size:          int64(len(page.HTML)),
+		InlinedAssets: page.InlinedAssets,
+		CapturedAt:    time.Now(),
+	}
+	// recorded before the copy is stored, so it's never left unreferenced for the collector to delete
+	snapshot.Id, err = s.snapshotRepo.AddSnapshot(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record snapshot: %w", err)
+	}
+	if err := s.blobs.Store(page.HTML); err != nil {
+		if err := s.snapshotRepo.DeleteSnapshot(snapshot.Id); err != nil {
+			slog.Error("failed to remove snapshot without content", "id", snapshot.Id, "error", err)
+		}
+		s.blobs.CollectAfterRemoval()
+		return nil, fmt.Errorf("failed to store the copy of %s: %w", link.URL, err)
+	}
+	return &snapshot, nil
+}
+
+func (s *ArchiveService) GetSnapshots(referenceId model.Id) ([]model.Snapshot, error) {
+	snapshots, err := s.snapshotRepo.GetSnapshots(referenceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// Returns the snapshot along with the archived HTML
+func (s *ArchiveService) GetSnapshotContent(id model.Id) (*model.Snapshot, []byte, error) {
+	snapshot, err := s.snapshotRepo.GetSnapshotById(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve snapshot: %w", err)
+	}
+	content, err := s.store.Get(snapshot.ContentHash)
+	if errors.Is(err, model.ErrBlobNotFound) {
+		return nil, nil, fmt.Errorf("the copy of snapshot %d is missing from the blob store: %w", id, err)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return snapshot, content, nil
+}
+
+// Removes the snapshot, and its content unless other snapshots have the same copy. Returns the removed snapshot.
+func (s *ArchiveService) DeleteSnapshot(id model.Id) (*model.Snapshot, error) {
+	snapshot, err := s.snapshotRepo.GetSnapshotById(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve snapshot: %w", err)
+	}
+	if err := s.snapshotRepo.DeleteSnapshot(id); err != nil {
+		return nil, fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	if _, err := s.blobs.Collect(); err != nil {
+		return nil, fmt.Errorf("failed to delete the copy of snapshot %d: %w", id, err)
+	}
+	return snapshot, nil
+}
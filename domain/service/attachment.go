@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/port"
+	"github.com/VladMinzatu/reference-manager/domain/repository"
+)
+
+// AttachmentService stores files with references, checking their type and size, and their integrity when read back
+type AttachmentService struct {
+	repo    repository.AttachmentRepository
+	store   port.BlobStore
+	blobs   *BlobCollector
+	maxSize int64
+}
+
+func NewAttachmentService(repo repository.AttachmentRepository, store port.BlobStore, blobs *BlobCollector, maxSize int64) *AttachmentService {
+	return &AttachmentService{repo: repo, store: store, blobs: blobs, maxSize: maxSize}
+}
+
+// The largest file that can be attached, in bytes
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+func (s *AttachmentService) AddAttachment(referenceId model.Id, fileName string, data []byte) (*model.Attachment, error) {
+	name, err := model.NewFileName(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s is empty", name)
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, fmt.Errorf("%s is too large (max %d bytes)", name, s.maxSize)
+	}
+	mediaType, err := model.DetectAttachmentType(name, data)
+	if err != nil {
+		return nil, err
+	}
+	attachment := model.Attachment{
+		ReferenceId: referenceId,
+		FileName:    name,
+		MediaType:   mediaType,
+		Size:        int64(len(data)),
+		ContentHash: model.HashContent(data),
+		AddedAt:     time.Now(),
+	}
+	// recorded before its content is stored, so the content is never left unreferenced for the collector to delete
+	attachment.Id, err = s.repo.AddAttachment(attachment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record attachment: %w", err)
+	}
+	if err := s.blobs.Store(data); err != nil {
+		if err := s.repo.DeleteAttachment(attachment.Id); err != nil {
+			slog.Error("failed to remove attachment without content", "id", attachment.Id, "error", err)
+		}
+		s.blobs.CollectAfterRemoval()
+		return nil, fmt.Errorf("failed to store %s: %w", name, err)
+	}
+	return &attachment, nil
+}
+
+func (s *AttachmentService) GetAttachments(referenceId model.Id) ([]model.Attachment, error) {
+	attachments, err := s.repo.GetAttachments(referenceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// Returns the attachment along with its content, which is checked against its hash
+func (s *AttachmentService) GetAttachmentContent(id model.Id) (*model.Attachment, []byte, error) {
+	attachment, err := s.repo.GetAttachmentById(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve attachment: %w", err)
+	}
+	content, err := s.readContent(*attachment)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+func (s *AttachmentService) readContent(attachment model.Attachment) ([]byte, error) {
+	content, err := s.store.Get(attachment.ContentHash)
+	if errors.Is(err, model.ErrBlobNotFound) {
+		return nil, fmt.Errorf("the content of attachment %d is missing from the blob store: %w", attachment.Id, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if model.HashContent(content) != attachment.ContentHash {
+		return nil, fmt.Errorf("the content of attachment %d is corrupted: %w", attachment.Id, model.ErrCorruptContent)
+	}
+	return content, nil
+}
+
+// Removes the attachment, and its content unless it's shared with other attachments or snapshots. Returns the
+// removed attachment.
+func (s *AttachmentService) DeleteAttachment(id model.Id) (*model.Attachment, error) {
+	attachment, err := s.repo.GetAttachmentById(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve attachment: %w", err)
+	}
+	if err := s.repo.DeleteAttachment(id); err != nil {
+		return nil, fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	if _, err := s.blobs.Collect(); err != nil {
+		return nil, fmt.Errorf("failed to delete the content of attachment %d: %w", id, err)
+	}
+	return attachment, nil
+}
+
+// Reads back the content of every attachment and returns those that are missing or corrupted
+func (s *AttachmentService) VerifyAttachments() ([]model.AttachmentProblem, error) {
+	attachments, err := s.repo.GetAllAttachments()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve attachments: %w", err)
+	}
+	problems := []model.AttachmentProblem{}
+	for _, attachment := range attachments {
+		if _, err := s.readContent(attachment); err != nil {
+			problems = append(problems, model.AttachmentProblem{Attachment: attachment, Err: err})
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/port"
+	"github.com/VladMinzatu/reference-manager/domain/repository"
+)
+
+// BlobCollector deletes the content of removed attachments, snapshots and covers from the blob store
+type BlobCollector struct {
+	repo  repository.OrphanedBlobRepository
+	store port.BlobStore
+	// held while content is written or collected, see Store
+	mu sync.Mutex
+}
+
+func NewBlobCollector(repo repository.OrphanedBlobRepository, store port.BlobStore) *BlobCollector {
+	return &BlobCollector{repo: repo, store: store}
+}
+
+/*
+Store writes content to the blob store for rows that were recorded before, referring to it by its hash.
+Recording the rows first takes the content off the orphaned queue, so it can't be claimed once it's written, and the
+lock keeps a collection that claimed it before from deleting it after (within this process, as the lock isn't shared
+with other processes using the same library). If writing fails, the rows must be removed.
+*/
+func (c *BlobCollector) Store(contents ...[]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, data := range contents {
+		if _, err := c.store.Put(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Collect deletes the content that is no longer in use and returns how much was deleted.
+Each blob is claimed before it's deleted, so content that is stored again in the meantime is kept. A blob that then
+fails to be deleted is left behind in the store, which only costs the space.
+*/
+func (c *BlobCollector) Collect() (int, error) {
+	hashes, err := c.repo.GetOrphanedBlobs()
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve orphaned blobs: %w", err)
+	}
+	deleted := 0
+	for _, hash := range hashes {
+		collected, err := c.collect(hash)
+		if err != nil {
+			return deleted, err
+		}
+		if collected {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (c *BlobCollector) collect(hash model.ContentHash) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	claimed, err := c.repo.ClaimOrphanedBlob(hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim blob %s: %w", hash, err)
+	}
+	if !claimed {
+		return false, nil
+	}
+	if err := c.store.Delete(hash); err != nil {
+		return false, fmt.Errorf("failed to delete blob %s: %w", hash, err)
+	}
+	return true, nil
+}
+
+// Collects the content left behind by removing references, attachments, snapshots or covers. They are gone by the
+// time their content is collected, so failures are only logged.
+func (c *BlobCollector) CollectAfterRemoval() {
+	if _, err := c.Collect(); err != nil {
+		slog.Error("failed to delete the content of removed references", "error", err)
+	}
+}
@@ -10,10 +10,12 @@ import (
 
 type CategoryService struct {
 	repo repository.CategoryRepository
+	// removing references removes their attachments and snapshots, whose content is then deleted from the blob store
+	blobs *BlobCollector
 }
 
-func NewCategoryService(repo repository.CategoryRepository) *CategoryService {
-	return &CategoryService{repo: repo}
+func NewCategoryService(repo repository.CategoryRepository, blobs *BlobCollector) *CategoryService {
+	return &CategoryService{repo: repo, blobs: blobs}
 }
 
 func (s *CategoryService) GetCategoryById(categoryId model.Id) (*model.Category, error) {
@@ -75,6 +77,45 @@ func (s *CategoryService) ReorderReferences(categoryId model.Id, positions map[m
 	return category, nil
 }
 
+func (s *CategoryService) MoveReferenceToPosition(categoryId model.Id, referenceId model.Id, position int) (*model.Category, error) {
+	category, err := s.GetCategoryById(categoryId)
+	if err != nil {
+		return nil, err
+	}
+
+	current := -1
+	for i, ref := range category.References {
+		if ref.GetId() == referenceId {
+			current = i
+			break
+		}
+	}
+	if current < 0 {
+		return nil, fmt.Errorf("reference with id %v not found in category %v", referenceId, categoryId)
+	}
+	if position < 0 || position >= len(category.References) {
+		return nil, fmt.Errorf("invalid position %d (must be between 0 and %d)", position, len(category.References)-1)
+	}
+	if position == current {
+		return category, nil
+	}
+
+	if err := s.repo.MoveReferenceToPosition(category.Id, referenceId, position, category.Version); err != nil {
+		return nil, err
+	}
+
+	moved := category.References[current]
+	others := append(category.References[:current:current], category.References[current+1:]...)
+	newOrder := make([]model.Reference, 0, len(category.References))
+	newOrder = append(newOrder, others[:position]...)
+	newOrder = append(newOrder, moved)
+	newOrder = append(newOrder, others[position:]...)
+
+	category.References = newOrder
+	category.Version++
+	return category, nil
+}
+
 func (s *CategoryService) AddReference(categoryId model.Id, reference model.Reference) (*model.Category, error) {
 	category, err := s.GetCategoryById(categoryId)
 	if err != nil {
@@ -98,6 +139,7 @@ func (s *CategoryService) RemoveReference(categoryId model.Id, referenceId model
 	if err := s.repo.RemoveReference(category.Id, referenceId, category.Version); err != nil {
 		return nil, err
 	}
+	s.blobs.CollectAfterRemoval()
 
 	for i, ref := range category.References {
 		if ref.GetId() == referenceId {
@@ -109,3 +151,50 @@ func (s *CategoryService) RemoveReference(categoryId model.Id, referenceId model
 	category.Version++
 	return category, nil
 }
+
+// The bulk operations change many references at once, so the updated category is read back rather than patched in memory
+
+func (s *CategoryService) SetReferencesStarred(categoryId model.Id, referenceIds []model.Id, starred bool) (*model.Category, error) {
+	return s.bulkUpdate(categoryId, func(category *model.Category) error {
+		return s.repo.SetReferencesStarred(category.Id, referenceIds, starred, category.Version)
+	})
+}
+
+func (s *CategoryService) RemoveReferences(categoryId model.Id, referenceIds []model.Id) (*model.Category, error) {
+	return s.bulkUpdate(categoryId, func(category *model.Category) error {
+		if err := s.repo.RemoveReferences(category.Id, referenceIds, category.Version); err != nil {
+			return err
+		}
+		s.blobs.CollectAfterRemoval()
+		return nil
+	})
+}
+
+func (s *CategoryService) MoveReferences(categoryId model.Id, referenceIds []model.Id, targetCategoryId model.Id) (*model.Category, error) {
+	return s.bulkUpdate(categoryId, func(category *model.Category) error {
+		return s.repo.MoveReferences(category.Id, referenceIds, targetCategoryId, category.Version)
+	})
+}
+
+func (s *CategoryService) TagReferences(categoryId model.Id, referenceIds []model.Id, tag model.Tag) (*model.Category, error) {
+	return s.bulkUpdate(categoryId, func(category *model.Category) error {
+		return s.repo.TagReferences(category.Id, referenceIds, tag, category.Version)
+	})
+}
+
+func (s *CategoryService) UntagReferences(categoryId model.Id, referenceIds []model.Id, tag model.Tag) (*model.Category, error) {
+	return s.bulkUpdate(categoryId, func(category *model.Category) error {
+		return s.repo.UntagReferences(category.Id, referenceIds, tag, category.Version)
+	})
+}
+
+func (s *CategoryService) bulkUpdate(categoryId model.Id, update func(category *model.Category) error) (*model.Category, error) {
+	category, err := s.GetCategoryById(categoryId)
+	if err != nil {
+		return nil, err
+	}
+	if err := update(category); err != nil {
+		return nil, err
+	}
+	return s.GetCategoryById(categoryId)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/port"
+	"github.com/VladMinzatu/reference-manager/domain/repository"
+)
+
+// CoverService keeps the cover images of books, uploaded or fetched from an external catalog, with their thumbnails
+type CoverService struct {
+	referenceRepo repository.ReferencesRepository
+	coverRepo     repository.CoverRepository
+	store         port.BlobStore
+	blobs         *BlobCollector
+	images        port.ImageProcessor
+	provider      port.CoverProvider
+}
+
+func NewCoverService(referenceRepo repository.ReferencesRepository, coverRepo repository.CoverRepository, store port.BlobStore, blobs *BlobCollector, images port.ImageProcessor, provider port.CoverProvider) *CoverService {
+	return &CoverService{referenceRepo: referenceRepo, coverRepo: coverRepo, store: store, blobs: blobs, images: images, provider: provider}
+}
+
+// Sets the image as the cover of the book, making its thumbnail, and deletes the cover it replaces
+func (s *CoverService) SetCover(referenceId model.Id, image []byte) (*model.BookCover, error) {
+	if _, err := s.getBook(referenceId); err != nil {
+		return nil, err
+	}
+	if len(image) > model.MaxCoverSize {
+		return nil, fmt.Errorf("cover image is too large (max %d bytes)", model.MaxCoverSize)
+	}
+	info, err := s.images.Inspect(image)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cover image: %w", err)
+	}
+	thumbnail, err := s.images.Thumbnail(image, model.ThumbnailWidth, model.ThumbnailHeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make thumbnail: %w", err)
+	}
+
+	cover := model.BookCover{
+		ReferenceId:   referenceId,
+		ImageHash:     model.HashContent(image),
+		MediaType:     info.MediaType,
+		Width:         info.Width,
+		Height:        info.Height,
+		Size:          int64(len(image)),
+		ThumbnailHash: model.HashContent(thumbnail),
+		UpdatedAt:     time.Now(),
+	}
+	// recorded before the images are stored, so they are never left unreferenced for the collector to delete
+	if err := s.coverRepo.SetCover(cover); err != nil {
+		return nil, fmt.Errorf("failed to record cover: %w", err)
+	}
+	if err := s.blobs.Store(image, thumbnail); err != nil {
+		// the book is left without a cover rather than with one whose image is missing
+		if err := s.coverRepo.RemoveCover(referenceId); err != nil {
+			slog.Error("failed to remove cover without image", "reference_id", referenceId, "error", err)
+		}
+		s.blobs.CollectAfterRemoval()
+		return nil, fmt.Errorf("failed to store cover: %w", err)
+	}
+	if _, err := s.blobs.Collect(); err != nil {
+		return nil, fmt.Errorf("failed to delete the replaced cover: %w", err)
+	}
+	return &cover, nil
+}
+
+// Fetches the cover of the book by its ISBN from the cover provider. Returns model.ErrMetadataNotFound if it has none.
+func (s *CoverService) FetchCover(referenceId model.Id) (*model.BookCover, error) {
+	book, err := s.getBook(referenceId)
+	if err != nil {
+		return nil, err
+	}
+	if book.ISBN == "" {
+		return nil, fmt.Errorf("book %d has no ISBN to look up its cover by", referenceId)
+	}
+	image, err := s.provider.FetchCover(book.ISBN)
+	if errors.Is(err, model.ErrMetadataNotFound) {
+		return nil, fmt.Errorf("no cover found for ISBN %s: %w", book.ISBN.Hyphenated(), err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cover for ISBN %s: %w", book.ISBN.Hyphenated(), err)
+	}
+	return s.SetCover(referenceId, image)
+}
+
+// Returns the cover of the book, or model.ErrNoCover if it has none
+func (s *CoverService) GetCover(referenceId model.Id) (*model.BookCover, error) {
+	cover, err := s.coverRepo.GetCover(referenceId)
+	if errors.Is(err, model.ErrNoCover) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve cover: %w", err)
+	}
+	return cover, nil
+}
+
+// Returns the covers of all the books that have one, by reference id
+func (s *CoverService) GetCovers() (map[model.Id]model.BookCover, error) {
+	covers, err := s.coverRepo.GetCovers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve covers: %w", err)
+	}
+	return covers, nil
+}
+
+// Returns the cover of the book along with its image, or its thumbnail (a JPEG) if thumbnail is set
+func (s *CoverService) GetCoverImage(referenceId model.Id, thumbnail bool) (*model.BookCover, []byte, error) {
+	cover, err := s.GetCover(referenceId)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash := cover.ImageHash
+	if thumbnail {
+		hash = cover.ThumbnailHash
+	}
+	image, err := s.store.Get(hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the cover of book %d: %w", referenceId, err)
+	}
+	if model.HashContent(image) != hash {
+		return nil, nil, fmt.Errorf("the cover of book %d is corrupted: %w", referenceId, model.ErrCorruptContent)
+	}
+	return cover, image, nil
+}
+
+// Removes the cover of the book along with its thumbnail
+func (s *CoverService) RemoveCover(referenceId model.Id) error {
+	if err := s.coverRepo.RemoveCover(referenceId); err != nil {
+		return fmt.Errorf("failed to remove cover: %w", err)
+	}
+	if _, err := s.blobs.Collect(); err != nil {
+		return fmt.Errorf("failed to delete the removed cover: %w", err)
+	}
+	return nil
+}
+
+func (s *CoverService) getBook(referenceId model.Id) (*model.BookReference, error) {
+	ref, err := s.referenceRepo.GetReferenceById(referenceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve reference: %w", err)
+	}
+	book, ok := ref.Reference.(model.BookReference)
+	if !ok {
+		return nil, fmt.Errorf("reference %d is a %s, only books have covers", referenceId, model.TypeOf(ref.Reference))
+	}
+	return &book, nil
+}
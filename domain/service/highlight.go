@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/repository"
+)
+
+type HighlightService struct {
+	repo repository.HighlightRepository
+}
+
+func NewHighlightService(repo repository.HighlightRepository) *HighlightService {
+	return &HighlightService{repo: repo}
+}
+
+func (s *HighlightService) GetHighlights(referenceId model.Id) ([]model.Highlight, error) {
+	highlights, err := s.repo.GetHighlights(referenceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve highlights: %w", err)
+	}
+	return highlights, nil
+}
+
+func (s *HighlightService) GetHighlightById(id model.Id) (*model.Highlight, error) {
+	highlight, err := s.repo.GetHighlightById(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve highlight: %w", err)
+	}
+	return highlight, nil
+}
+
+// Adds a highlight at the end of the highlights of a book and returns it with its assigned id
+func (s *HighlightService) AddHighlight(referenceId model.Id, text string, location model.Location, comment string) (*model.Highlight, error) {
+	highlight, err := model.NewHighlight(0, referenceId, text, location, comment)
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.repo.AddHighlight(highlight)
+	if err != nil {
+		return nil, err
+	}
+	highlight.Id = id
+	return &highlight, nil
+}
+
+func (s *HighlightService) UpdateHighlight(id model.Id, text string, location model.Location, comment string) (*model.Highlight, error) {
+	existing, err := s.GetHighlightById(id)
+	if err != nil {
+		return nil, err
+	}
+	highlight, err := model.NewHighlight(id, existing.ReferenceId, text, location, comment)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateHighlight(highlight); err != nil {
+		return nil, err
+	}
+	return &highlight, nil
+}
+
+// Deletes a highlight and returns it, e.g. for finding the book it belonged to
+func (s *HighlightService) DeleteHighlight(id model.Id) (*model.Highlight, error) {
+	highlight, err := s.GetHighlightById(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.DeleteHighlight(id); err != nil {
+		return nil, err
+	}
+	return highlight, nil
+}
+
+func (s *HighlightService) MoveHighlight(id model.Id, position int) (*model.Highlight, error) {
+	highlight, err := s.GetHighlightById(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.MoveHighlight(id, position); err != nil {
+		return nil, err
+	}
+	return highlight, nil
+}
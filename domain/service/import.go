@@ -0,0 +1,151 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/repository"
+)
+
+// ImportService adds references parsed from other formats (e.g. browser bookmarks) to the library
+type ImportService struct {
+	categoryListRepo repository.CategoryListRepository
+	referenceRepo    repository.ReferencesRepository
+	importRepo       repository.ImportRepository
+}
+
+func NewImportService(categoryListRepo repository.CategoryListRepository, referenceRepo repository.ReferencesRepository, importRepo repository.ImportRepository) *ImportService {
+	return &ImportService{categoryListRepo: categoryListRepo, referenceRepo: referenceRepo, importRepo: importRepo}
+}
+
+/*
+Import adds the references of the folders to the library, in order:
+  - folders are matched by name to the categories with the same parent, and missing ones are created once a reference
+    is imported into them (or one of their subcategories)
+  - references that point to the same ISBN or URL as one already in the library (or earlier in the import) are skipped
+  - the whole import is planned first and then applied at once, so an import that fails changes nothing
+  - with dryRun, nothing is changed and the report tells what would be done
+*/
+func (s *ImportService) Import(folders []model.ImportFolder, dryRun bool) (*model.ImportReport, error) {
+	categories, err := s.categoryListRepo.GetAllCategoryRefs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve categories: %w", err)
+	}
+	references, err := s.referenceRepo.GetAllReferences()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve references: %w", err)
+	}
+
+	run := &importRun{
+		categories: make(map[categoryKey]model.Id, len(categories)),
+		known:      make(map[string]string, len(references)),
+		report:     &model.ImportReport{DryRun: dryRun},
+	}
+	for _, category := range categories {
+		run.categories[categoryKey{parentId: category.ParentId, name: category.Name}] = category.Id
+	}
+	for _, ref := range references {
+		if identifier := model.IdentifierOf(ref.Reference); identifier != "" {
+			run.known[identifier] = "already in the library"
+		}
+	}
+
+	for _, folder := range folders {
+		run.importFolder(folder, nil)
+	}
+	if !dryRun {
+		if err := s.importRepo.Import(run.plan); err != nil {
+			return nil, fmt.Errorf("failed to import: %w", err)
+		}
+	}
+	return run.report, nil
+}
+
+type categoryKey struct {
+	parentId model.Id
+	name     model.Title
+}
+
+// importRun holds the state of a single import as it's planned
+type importRun struct {
+	// the categories of the library by parent and name, including the ones planned so far. Planned categories get
+	// negative ids (see model.ImportPlan), so that their subcategories can be told apart too.
+	categories  map[categoryKey]model.Id
+	lastPlanned model.Id
+	// the identifiers of the references in the library, including the ones planned so far, with the reason to skip them
+	known  map[string]string
+	plan   model.ImportPlan
+	report *model.ImportReport
+}
+
+// importCategory is the category a folder is imported into, which is only created once something is imported into it
+type importCategory struct {
+	parent *importCategory
+	name   model.Title
+	path   string
+	id     model.Id
+	exists bool
+}
+
+// Returns the category with the name under the parent (or at the top level, without a parent), which may not exist yet
+func (r *importRun) category(parent *importCategory, name model.Title) *importCategory {
+	category := &importCategory{parent: parent, name: name, path: model.CategoryPath("", name)}
+	parentId := model.Id(0)
+	if parent != nil {
+		category.path = model.CategoryPath(parent.path, name)
+		if !parent.exists {
+			return category
+		}
+		parentId = parent.id
+	}
+	category.id, category.exists = r.categories[categoryKey{parentId: parentId, name: name}]
+	return category
+}
+
+// Plans the category, and its parents, if they don't exist yet
+func (r *importRun) ensure(category *importCategory) {
+	if category.exists {
+		return
+	}
+	parentId := model.Id(0)
+	if category.parent != nil {
+		r.ensure(category.parent)
+		parentId = category.parent.id
+	}
+	r.report.CreatedCategories = append(r.report.CreatedCategories, category.path)
+	r.lastPlanned--
+	category.id, category.exists = r.lastPlanned, true
+	r.plan.Categories = append(r.plan.Categories, model.PlannedCategory{Id: category.id, ParentId: parentId, Name: category.name})
+	r.categories[categoryKey{parentId: parentId, name: category.name}] = category.id
+}
+
+// Plans adding the reference to the category, creating the category if needed
+func (r *importRun) add(category *importCategory, ref model.Reference) {
+	r.ensure(category)
+	r.plan.References = append(r.plan.References, model.PlannedReference{CategoryId: category.id, Reference: ref})
+}
+
+func (r *importRun) importFolder(folder model.ImportFolder, parent *importCategory) {
+	category := r.category(parent, folder.Name)
+	for _, ref := range folder.References {
+		item := model.ImportItem{Category: category.path, Title: string(ref.Title()), Outcome: model.Imported}
+		identifier := model.IdentifierOf(ref)
+		if reason, ok := r.known[identifier]; identifier != "" && ok {
+			item.Outcome, item.Reason = model.SkippedDuplicate, reason
+			r.report.Items = append(r.report.Items, item)
+			continue
+		}
+		r.add(category, ref)
+		if identifier != "" {
+			r.known[identifier] = "also earlier in the import"
+		}
+		r.report.Items = append(r.report.Items, item)
+	}
+	for _, rejected := range folder.Rejected {
+		r.report.Items = append(r.report.Items, model.ImportItem{Category: category.path, Title: rejected.Title, Outcome: model.SkippedInvalid, Reason: rejected.Reason})
+	}
+
+	for _, child := range folder.Folders {
+		r.importFolder(child, category)
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/repository"
+)
+
+// LibraryService provides read-only views of the whole library, e.g. for exports
+type LibraryService struct {
+	categoryListRepo repository.CategoryListRepository
+	referenceRepo    repository.ReferencesRepository
+	relationRepo     repository.RelationRepository
+}
+
+func NewLibraryService(categoryListRepo repository.CategoryListRepository, referenceRepo repository.ReferencesRepository, relationRepo repository.RelationRepository) *LibraryService {
+	return &LibraryService{categoryListRepo: categoryListRepo, referenceRepo: referenceRepo, relationRepo: relationRepo}
+}
+
+func (s *LibraryService) GetLibrary() (*model.Library, error) {
+	categories, err := s.categoryListRepo.GetAllCategoryRefs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve categories: %w", err)
+	}
+	references, err := s.referenceRepo.GetAllReferences()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve references: %w", err)
+	}
+	relations, err := s.relationRepo.GetAllRelations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve relations: %w", err)
+	}
+	return &model.Library{Categories: categories, References: references, Relations: relations}, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/port"
+	"github.com/VladMinzatu/reference-manager/domain/repository"
+)
+
+// LinkCheckOptions configure a round of link checks
+type LinkCheckOptions struct {
+	// how many links are checked at the same time
+	Concurrency int
+	// links checked more recently than this are skipped; with 0, all links are checked
+	MaxAge time.Duration
+}
+
+// LinkCheckService checks the links of the library for broken pages, on demand or periodically in the background
+type LinkCheckService struct {
+	repo    repository.LinkHealthRepository
+	checker port.LinkChecker
+}
+
+func NewLinkCheckService(repo repository.LinkHealthRepository, checker port.LinkChecker) *LinkCheckService {
+	return &LinkCheckService{repo: repo, checker: checker}
+}
+
+// Returns the outcome of the last check of every link that was checked, by reference id
+func (s *LinkCheckService) GetLinkHealth() (map[model.Id]model.LinkHealth, error) {
+	health, err := s.repo.GetLinkHealth()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve link health: %w", err)
+	}
+	return health, nil
+}
+
+/*
+CheckLinks checks the links that are due and records the outcome of each check as soon as it's done:
+  - links are due if they were never checked, were edited since their last check or were checked longer than MaxAge ago
+  - up to Concurrency links are checked at the same time, and progress (if not nil) is called after each check
+  - when the context is cancelled, the checks that are running are abandoned and the report has the finished ones
+*/
+func (s *LinkCheckService) CheckLinks(ctx context.Context, options LinkCheckOptions, progress func(model.LinkHealth)) (*model.LinkCheckReport, error) {
+	checkedBefore := time.Now()
+	if options.MaxAge > 0 {
+		checkedBefore = checkedBefore.Add(-options.MaxAge)
+	}
+	links, err := s.repo.GetLinksToCheck(checkedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve links to check: %w", err)
+	}
+
+	due := make(chan model.LinkHealth)
+	results := make(chan model.LinkHealth)
+	var workers sync.WaitGroup
+	for i := 0; i < max(options.Concurrency, 1); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for link := range due {
+				health := s.checker.CheckLink(ctx, link.URL)
+				health.ReferenceId = link.ReferenceId
+				results <- health
+			}
+		}()
+	}
+	go func() {
+		defer close(due)
+		for _, link := range links {
+			select {
+			case due <- link:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	report := &model.LinkCheckReport{}
+	var saveErr error
+	for health := range results {
+		// checks that were abandoned failed because of the cancellation, not the link
+		if ctx.Err() != nil {
+			continue
+		}
+		if err := s.repo.SaveLinkHealth(health); err != nil && saveErr == nil {
+			saveErr = fmt.Errorf("failed to save the health of link %d: %w", health.ReferenceId, err)
+		}
+		report.Checked = append(report.Checked, health)
+		if progress != nil {
+			progress(health)
+		}
+	}
+	return report, saveErr
+}
+
+// Checks the links that are due every interval, until the context is cancelled. Meant to be run in its own goroutine.
+func (s *LinkCheckService) Run(ctx context.Context, interval time.Duration, options LinkCheckOptions) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := s.CheckLinks(ctx, options, nil)
+		if err != nil {
+			slog.Error("failed to check links", "error", err)
+		} else if len(report.Checked) > 0 {
+			slog.Info("checked links", "checked", len(report.Checked), "broken", len(report.Broken()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/port"
+)
+
+// MetadataService fills in the details of books from an external catalog and of links from their pages
+type MetadataService struct {
+	provider port.MetadataProvider
+	fetcher  port.PageFetcher
+}
+
+func NewMetadataService(provider port.MetadataProvider, fetcher port.PageFetcher) *MetadataService {
+	return &MetadataService{provider: provider, fetcher: fetcher}
+}
+
+// Looks up the book with the ISBN, which is validated first. Returns model.ErrMetadataNotFound for unknown books.
+func (s *MetadataService) LookupBook(isbn string) (*model.BookMetadata, error) {
+	validISBN, err := model.NewISBN(isbn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ISBN: %w", err)
+	}
+	metadata, err := s.provider.LookupBook(validISBN)
+	if errors.Is(err, model.ErrMetadataNotFound) {
+		return nil, fmt.Errorf("no book found with ISBN %s: %w", validISBN.Hyphenated(), err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up ISBN %s: %w", validISBN.Hyphenated(), err)
+	}
+	if metadata.ISBN == "" {
+		metadata.ISBN = validISBN
+	}
+	return &metadata, nil
+}
+
+// Fetches the page at the URL, which is validated first, for its title and description
+func (s *MetadataService) FetchLink(url string) (*model.PageMetadata, error) {
+	validURL, err := model.NewURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	metadata, err := s.fetcher.FetchPage(validURL)
+	if errors.Is(err, model.ErrMetadataNotFound) {
+		return nil, fmt.Errorf("no page found at %s: %w", validURL, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", validURL, err)
+	}
+	metadata.URL = validURL
+	return &metadata, nil
+}
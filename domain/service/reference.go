@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/repository"
+)
+
+type ReferenceService struct {
+	repo repository.ReferencesRepository
+	// merging removes the other references, whose snapshots and covers may be left unused
+	blobs *BlobCollector
+}
+
+func NewReferenceService(repo repository.ReferencesRepository, blobs *BlobCollector) *ReferenceService {
+	return &ReferenceService{repo: repo, blobs: blobs}
+}
+
+func (s *ReferenceService) GetReferenceById(referenceId model.Id) (*model.CategorizedReference, error) {
+	ref, err := s.repo.GetReferenceById(referenceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve reference: %w", err)
+	}
+	return ref, nil
+}
+
+// Returns the links already in the library that point to the same page as the given URL, for warning about duplicates
+func (s *ReferenceService) FindDuplicateLinks(url model.URL) ([]model.CategorizedReference, error) {
+	links, err := s.repo.FindLinksByURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up links: %w", err)
+	}
+	return links, nil
+}
+
+// Changes the type of a reference (see model.ConvertReference for how the fields are mapped) and returns the converted reference
+func (s *ReferenceService) ConvertReference(referenceId model.Id, target model.ReferenceType, input model.ConversionInput) (*model.CategorizedReference, error) {
+	ref, err := s.GetReferenceById(referenceId)
+	if err != nil {
+		return nil, err
+	}
+
+	converted, err := model.ConvertReference(ref.Reference, target, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ConvertReference(referenceId, converted); err != nil {
+		return nil, err
+	}
+	return &model.CategorizedReference{Category: ref.Category, Reference: converted}, nil
+}
+
+// Returns the groups of references across the library that are likely duplicates of each other
+func (s *ReferenceService) FindDuplicates() ([]model.DuplicateGroup, error) {
+	refs, err := s.repo.GetAllReferences()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve references: %w", err)
+	}
+	return model.FindDuplicates(refs), nil
+}
+
+// Merges the other references into the one that is kept (see model.MergeReferences) and removes them
+func (s *ReferenceService) MergeReferences(keepId model.Id, otherIds []model.Id) (*model.CategorizedReference, error) {
+	keep, err := s.GetReferenceById(keepId)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[model.Id]bool{}
+	var ids []model.Id
+	var others []model.Reference
+	for _, id := range otherIds {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		other, err := s.GetReferenceById(id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		others = append(others, other.Reference)
+	}
+
+	merged, err := model.MergeReferences(keep.Reference, others)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.MergeReferences(merged, ids); err != nil {
+		return nil, err
+	}
+	s.blobs.CollectAfterRemoval()
+	return &model.CategorizedReference{Category: keep.Category, Reference: merged}, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/repository"
+)
+
+type RelationService struct {
+	repo          repository.RelationRepository
+	referenceRepo repository.ReferencesRepository
+	categoryRepo  repository.CategoryRepository
+}
+
+func NewRelationService(repo repository.RelationRepository, referenceRepo repository.ReferencesRepository, categoryRepo repository.CategoryRepository) *RelationService {
+	return &RelationService{repo: repo, referenceRepo: referenceRepo, categoryRepo: categoryRepo}
+}
+
+func (s *RelationService) AddRelation(from model.Id, to model.Id, relType model.RelationType) (model.Relation, error) {
+	relation, err := model.NewRelation(from, to, relType)
+	if err != nil {
+		return model.Relation{}, err
+	}
+	if err := s.repo.AddRelation(relation); err != nil {
+		return model.Relation{}, err
+	}
+	return relation, nil
+}
+
+func (s *RelationService) RemoveRelation(from model.Id, to model.Id, relType model.RelationType) error {
+	relation, err := model.NewRelation(from, to, relType)
+	if err != nil {
+		return err
+	}
+	return s.repo.RemoveRelation(relation)
+}
+
+// Returns the relations of a reference along with the references they link it to
+func (s *RelationService) GetRelatedReferences(referenceId model.Id) ([]model.RelatedReference, error) {
+	relations, err := s.repo.GetRelations(referenceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve relations: %w", err)
+	}
+	related := make([]model.RelatedReference, 0, len(relations))
+	for _, relation := range relations {
+		ref, err := s.referenceRepo.GetReferenceById(relation.Other(referenceId))
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve related reference: %w", err)
+		}
+		related = append(related, model.RelatedReference{Relation: relation, Reference: *ref})
+	}
+	return related, nil
+}
+
+// Returns the category along with its references sorted so that prerequisites come first
+func (s *RelationService) ReadingOrder(categoryId model.Id) (*model.Category, []model.Reference, error) {
+	category, err := s.categoryRepo.GetCategoryById(categoryId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve category: %w", err)
+	}
+	if category == nil {
+		return nil, nil, fmt.Errorf("category with id %v not found", categoryId)
+	}
+	prerequisites, err := s.repo.GetRelationsOfType(model.PrerequisiteRelation)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve prerequisites: %w", err)
+	}
+	ordered, err := model.ReadingOrder(category.References, prerequisites)
+	if err != nil {
+		return nil, nil, err
+	}
+	return category, ordered, nil
+}
@@ -0,0 +1,287 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+/*
+Sort keys are used to order lists (categories among their siblings, references within a category) without having to renumber the
+whole list on every change. A key is a fraction in base 62 written without the leading "0." (e.g. "V" is roughly 0.5), so keys can
+be compared byte-wise - which is exactly how SQLite compares TEXT columns by default.
+
+There is always room for a new key between any two keys, so moving or inserting an item only needs a single new key. The price is
+that keys can grow when items are repeatedly inserted in the same spot, so lists are rebalanced once a key gets too long.
+Keys never end with the zero digit, which guarantees that there is always room for a smaller key as well.
+*/
+
+const sortKeyDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// MaxSortKeyLength is the length above which a list of keys should be rebalanced
+const MaxSortKeyLength = 32
+
+// KeyedItem is an item of an ordered list, along with its current sort key
+type KeyedItem struct {
+	Id  model.Id
+	Key string
+}
+
+// SortKeyUpdate holds the new sort keys computed for a list
+type SortKeyUpdate struct {
+	Keys map[model.Id]string
+	// Rebalanced is set when every item of the list received a fresh key, in which case the new keys may collide with
+	// old keys of other items until the whole update is applied
+	Rebalanced bool
+}
+
+// SortKeyBetween returns a key that sorts strictly between before and after. An empty before or after means unbounded.
+func SortKeyBetween(before, after string) (string, error) {
+	if err := validateSortKey(before); err != nil {
+		return "", err
+	}
+	if err := validateSortKey(after); err != nil {
+		return "", err
+	}
+	if after != "" && before >= after {
+		return "", fmt.Errorf("sort key %q must be less than %q", before, after)
+	}
+	return midpoint(before, after, after != ""), nil
+}
+
+// SortKeysBetween returns n increasing keys that sort strictly between before and after, spread evenly so that they stay short
+func SortKeysBetween(before, after string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	mid, err := SortKeyBetween(before, after)
+	if err != nil {
+		return nil, err
+	}
+	leftCount := (n - 1) / 2
+	left, err := SortKeysBetween(before, mid, leftCount)
+	if err != nil {
+		return nil, err
+	}
+	right, err := SortKeysBetween(mid, after, n-1-leftCount)
+	if err != nil {
+		return nil, err
+	}
+	keys := append(left, mid)
+	return append(keys, right...), nil
+}
+
+// EvenlySpacedSortKeys returns n increasing keys of equal length spread over the whole key space
+func EvenlySpacedSortKeys(n int) []string {
+	base := len(sortKeyDigits)
+	width, space := 1, base
+	for space < 2*(n+1) {
+		width++
+		space *= base
+	}
+	step := space / (n + 1)
+
+	keys := make([]string, n)
+	for i := range keys {
+		value := (i + 1) * step
+		digits := make([]byte, width)
+		for d := width - 1; d >= 0; d-- {
+			digits[d] = sortKeyDigits[value%base]
+			value /= base
+		}
+		// trimming trailing zeros keeps the order and the no-trailing-zero invariant
+		keys[i] = strings.TrimRight(string(digits), "0")
+	}
+	return keys
+}
+
+// NeedsRebalancing reports whether a key has grown long enough that its list should be rebalanced
+func NeedsRebalancing(key string) bool {
+	return len(key) > MaxSortKeyLength
+}
+
+// ReassignSortKeys computes the sort keys needed to put the items (given in their current order) into the order described by
+// positions, which must be a permutation as checked by ValidatePositions.
+// Items along a longest run that is already in the right relative order keep their keys, so moving a single item changes a single key.
+// None of the new keys collide with any existing key, unless the list had to be rebalanced.
+func ReassignSortKeys(current []KeyedItem, positions map[model.Id]int) (SortKeyUpdate, error) {
+	ids := make([]model.Id, len(current))
+	for i, item := range current {
+		ids[i] = item.Id
+	}
+	if err := ValidatePositions(ids, positions); err != nil {
+		return SortKeyUpdate{}, err
+	}
+
+	// the items in their new order
+	reordered := make([]KeyedItem, len(current))
+	for _, item := range current {
+		reordered[positions[item.Id]] = item
+	}
+
+	// current index of each item, in the new order; the items to keep are a longest increasing subsequence of these
+	currentIndex := make(map[model.Id]int, len(current))
+	for i, item := range current {
+		currentIndex[item.Id] = i
+	}
+	sequence := make([]int, len(reordered))
+	for i, item := range reordered {
+		sequence[i] = currentIndex[item.Id]
+	}
+	keep := longestIncreasingSubsequence(sequence)
+
+	taken := make(map[string]bool, len(current))
+	for _, item := range current {
+		taken[item.Key] = true
+	}
+
+	keys := make(map[model.Id]string)
+	previousKey := ""
+	for i := 0; i < len(reordered); {
+		if keep[i] {
+			previousKey = reordered[i].Key
+			i++
+			continue
+		}
+		// a run of items that need new keys, bounded by the next item that keeps its key
+		end := i
+		for end < len(reordered) && !keep[end] {
+			end++
+		}
+		nextKey := ""
+		if end < len(reordered) {
+			nextKey = reordered[end].Key
+		}
+		runKeys, err := SortKeysBetween(previousKey, nextKey, end-i)
+		if err != nil {
+			return SortKeyUpdate{}, err
+		}
+		for j, key := range runKeys {
+			upper := nextKey
+			if j+1 < len(runKeys) {
+				upper = runKeys[j+1]
+			}
+			for taken[key] {
+				if key, err = SortKeyBetween(key, upper); err != nil {
+					return SortKeyUpdate{}, err
+				}
+			}
+			if NeedsRebalancing(key) {
+				return rebalancedUpdate(reordered), nil
+			}
+			keys[reordered[i+j].Id] = key
+			previousKey = key
+		}
+		i = end
+	}
+	return SortKeyUpdate{Keys: keys}, nil
+}
+
+// RebalanceSortKeys assigns fresh, evenly spaced keys to the items, keeping their current order
+func RebalanceSortKeys(current []KeyedItem) SortKeyUpdate {
+	return rebalancedUpdate(current)
+}
+
+func rebalancedUpdate(ordered []KeyedItem) SortKeyUpdate {
+	fresh := EvenlySpacedSortKeys(len(ordered))
+	keys := make(map[model.Id]string, len(ordered))
+	for i, item := range ordered {
+		keys[item.Id] = fresh[i]
+	}
+	return SortKeyUpdate{Keys: keys, Rebalanced: true}
+}
+
+// longestIncreasingSubsequence marks the elements of one longest strictly increasing subsequence
+func longestIncreasingSubsequence(values []int) []bool {
+	// tails[k] is the index of the smallest tail of an increasing subsequence of length k+1
+	var tails []int
+	previous := make([]int, len(values))
+	for i, value := range values {
+		k := sort.Search(len(tails), func(j int) bool { return values[tails[j]] >= value })
+		if k > 0 {
+			previous[i] = tails[k-1]
+		} else {
+			previous[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	marked := make([]bool, len(values))
+	if len(tails) == 0 {
+		return marked
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = previous[i] {
+		marked[i] = true
+	}
+	return marked
+}
+
+var errInvalidSortKey = errors.New("invalid sort key")
+
+func validateSortKey(key string) error {
+	if key == "" {
+		return nil
+	}
+	if key[len(key)-1] == '0' {
+		return fmt.Errorf("%w %q: must not end with a zero digit", errInvalidSortKey, key)
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(sortKeyDigits, key[i]) < 0 {
+			return fmt.Errorf("%w %q: unexpected character %q", errInvalidSortKey, key, key[i])
+		}
+	}
+	return nil
+}
+
+// midpoint finds a key between a and b (or between a and 1 if b is unbounded), preferring the shortest such key
+func midpoint(a, b string, bounded bool) string {
+	if bounded {
+		// strip the longest common prefix, padding a with zeros as needed
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(suffix(a, n), b[n:], true)
+		}
+	}
+
+	// the first digits (or the lack of a digit) are different
+	digitA := 0
+	if len(a) > 0 {
+		digitA = strings.IndexByte(sortKeyDigits, a[0])
+	}
+	digitB := len(sortKeyDigits)
+	if bounded {
+		digitB = strings.IndexByte(sortKeyDigits, b[0])
+	}
+	if digitB-digitA > 1 {
+		return string(sortKeyDigits[(digitA+digitB+1)/2])
+	}
+	// the first digits are consecutive
+	if bounded && len(b) > 1 {
+		return b[:1]
+	}
+	return string(sortKeyDigits[digitA]) + midpoint(suffix(a, 1), "", false)
+}
+
+func digitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return sortKeyDigits[0]
+}
+
+func suffix(key string, from int) string {
+	if from < len(key) {
+		return key[from:]
+	}
+	return ""
+}
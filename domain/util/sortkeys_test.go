@@ -0,0 +1,215 @@
+package util
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+func TestSortKeyBetween(t *testing.T) {
+	cases := []struct {
+		before, after string
+	}{
+		{"", ""},
+		{"", "V"},
+		{"V", ""},
+		{"A", "B"},
+		{"A", "A1"},
+		{"000001V", "000002V"},
+		{"", "001"},
+		{"zzz", ""},
+		{"Vz", "W"},
+	}
+	for _, c := range cases {
+		key, err := SortKeyBetween(c.before, c.after)
+		if err != nil {
+			t.Errorf("SortKeyBetween(%q, %q) failed: %v", c.before, c.after, err)
+			continue
+		}
+		if key <= c.before || (c.after != "" && key >= c.after) {
+			t.Errorf("SortKeyBetween(%q, %q) = %q is not between the bounds", c.before, c.after, key)
+		}
+		if key[len(key)-1] == '0' {
+			t.Errorf("SortKeyBetween(%q, %q) = %q ends with a zero digit", c.before, c.after, key)
+		}
+	}
+
+	t.Run("rejects unordered bounds", func(t *testing.T) {
+		if _, err := SortKeyBetween("B", "A"); err == nil {
+			t.Error("expected error for unordered bounds")
+		}
+		if _, err := SortKeyBetween("A", "A"); err == nil {
+			t.Error("expected error for equal bounds")
+		}
+	})
+
+	t.Run("rejects invalid keys", func(t *testing.T) {
+		if _, err := SortKeyBetween("A0", ""); err == nil {
+			t.Error("expected error for key with trailing zero")
+		}
+		if _, err := SortKeyBetween("", "A-"); err == nil {
+			t.Error("expected error for key with invalid character")
+		}
+	})
+}
+
+func TestRepeatedInsertionsStayOrdered(t *testing.T) {
+	keys := []string{"A", "B"}
+	// always insert right after the first key, which makes keys grow the fastest
+	for i := 0; i < 200; i++ {
+		key, err := SortKeyBetween(keys[0], keys[1])
+		if err != nil {
+			t.Fatalf("insertion %d failed: %v", i, err)
+		}
+		keys = append([]string{keys[0], key}, keys[1:]...)
+	}
+	if !sort.StringsAreSorted(keys) {
+		t.Error("keys are not sorted")
+	}
+	if !NeedsRebalancing(keys[1]) {
+		t.Errorf("expected %q to need rebalancing after many insertions in the same spot", keys[1])
+	}
+}
+
+func TestSortKeysBetween(t *testing.T) {
+	keys, err := SortKeysBetween("A", "B", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 10 {
+		t.Fatalf("expected 10 keys, got %d", len(keys))
+	}
+	if !sort.StringsAreSorted(keys) || keys[0] <= "A" || keys[9] >= "B" {
+		t.Errorf("keys not sorted within bounds: %v", keys)
+	}
+	for i := 1; i < len(keys); i++ {
+		if keys[i] == keys[i-1] {
+			t.Errorf("duplicate key %q", keys[i])
+		}
+	}
+}
+
+func TestEvenlySpacedSortKeys(t *testing.T) {
+	for _, n := range []int{0, 1, 5, 30, 31, 500} {
+		keys := EvenlySpacedSortKeys(n)
+		if len(keys) != n {
+			t.Fatalf("expected %d keys, got %d", n, len(keys))
+		}
+		for i, key := range keys {
+			if err := validateSortKey(key); err != nil || key == "" {
+				t.Errorf("invalid key %q: %v", key, err)
+			}
+			if i > 0 && keys[i-1] >= key {
+				t.Errorf("keys %q and %q out of order", keys[i-1], key)
+			}
+		}
+	}
+}
+
+func TestReassignSortKeys(t *testing.T) {
+	current := []KeyedItem{{1, "A"}, {2, "B"}, {3, "C"}, {4, "D"}, {5, "E"}}
+
+	applied := func(update SortKeyUpdate) []model.Id {
+		items := make([]KeyedItem, len(current))
+		copy(items, current)
+		for i := range items {
+			if key, ok := update.Keys[items[i].Id]; ok {
+				items[i].Key = key
+			}
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+		ids := make([]model.Id, len(items))
+		for i, item := range items {
+			ids[i] = item.Id
+		}
+		return ids
+	}
+
+	t.Run("moving one item changes one key", func(t *testing.T) {
+		update, err := ReassignSortKeys(current, map[model.Id]int{1: 1, 2: 2, 3: 3, 4: 0, 5: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(update.Keys) != 1 || update.Rebalanced {
+			t.Errorf("expected a single new key, got %v", update)
+		}
+		if got := applied(update); !equalIds(got, []model.Id{4, 1, 2, 3, 5}) {
+			t.Errorf("unexpected order %v", got)
+		}
+	})
+
+	t.Run("reversing the list", func(t *testing.T) {
+		update, err := ReassignSortKeys(current, map[model.Id]int{1: 4, 2: 3, 3: 2, 4: 1, 5: 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(update.Keys) != 4 {
+			t.Errorf("expected 4 new keys, got %v", update.Keys)
+		}
+		if got := applied(update); !equalIds(got, []model.Id{5, 4, 3, 2, 1}) {
+			t.Errorf("unexpected order %v", got)
+		}
+	})
+
+	t.Run("identity permutation changes nothing", func(t *testing.T) {
+		update, err := ReassignSortKeys(current, map[model.Id]int{1: 0, 2: 1, 3: 2, 4: 3, 5: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(update.Keys) != 0 {
+			t.Errorf("expected no new keys, got %v", update.Keys)
+		}
+	})
+
+	t.Run("new keys avoid the old keys of moved items", func(t *testing.T) {
+		// the midpoint between A and C is B, which is still taken by item 2 until it gets its new key
+		items := []KeyedItem{{1, "A"}, {2, "B"}, {3, "C"}}
+		update, err := ReassignSortKeys(items, map[model.Id]int{1: 0, 2: 2, 3: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for id, key := range update.Keys {
+			for _, item := range items {
+				if item.Id != id && item.Key == key {
+					t.Errorf("new key %q for %v collides with the key of %v", key, id, item.Id)
+				}
+			}
+		}
+	})
+
+	t.Run("rejects invalid permutations", func(t *testing.T) {
+		if _, err := ReassignSortKeys(current, map[model.Id]int{1: 0, 2: 1}); err == nil {
+			t.Error("expected error for incomplete permutation")
+		}
+	})
+
+	t.Run("rebalances keys that grew too long", func(t *testing.T) {
+		// there is no short key between "A" and "A000...01"
+		long := "A"
+		for len(long) < MaxSortKeyLength {
+			long += "0"
+		}
+		long += "1"
+		items := []KeyedItem{{1, "A"}, {3, long}, {2, "B"}}
+		update, err := ReassignSortKeys(items, map[model.Id]int{1: 0, 2: 1, 3: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !update.Rebalanced || len(update.Keys) != 3 {
+			t.Errorf("expected a rebalanced update, got %v", update)
+		}
+	})
+}
+
+func equalIds(a, b []model.Id) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
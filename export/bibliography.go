@@ -0,0 +1,54 @@
+package export
+
+import (
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// bibliographyEntry is a reference as reference managers like Zotero see it, with the authors of books split from the title
+type bibliographyEntry struct {
+	id model.Id
+	// book, webpage or note
+	kind     string
+	title    string
+	authors  []string
+	isbn     string
+	url      string
+	abstract string
+	// the text of notes
+	note     string
+	keywords []string
+}
+
+// Returns the entries of the references of the categories, in order
+func bibliographyEntries(library model.Library, categories []model.CategoryRef) []bibliographyEntry {
+	var entries []bibliographyEntry
+	for _, category := range categories {
+		for _, ref := range library.ReferencesOf(category.Id) {
+			renderer := &bibliographyRenderer{}
+			ref.Render(renderer)
+			renderer.entry.id = ref.GetId()
+			for _, tag := range ref.Tags() {
+				renderer.entry.keywords = append(renderer.entry.keywords, string(tag))
+			}
+			entries = append(entries, renderer.entry)
+		}
+	}
+	return entries
+}
+
+type bibliographyRenderer struct {
+	entry bibliographyEntry
+}
+
+func (r *bibliographyRenderer) RenderBook(ref model.BookReference) {
+	title, authors := model.SplitAuthors(ref.Title())
+	r.entry = bibliographyEntry{kind: "book", title: title, authors: authors, isbn: string(ref.ISBN), abstract: ref.Description}
+}
+
+func (r *bibliographyRenderer) RenderLink(ref model.LinkReference) {
+	r.entry = bibliographyEntry{kind: "webpage", title: string(ref.Title()), url: string(ref.URL), abstract: ref.Description}
+}
+
+func (r *bibliographyRenderer) RenderNote(ref model.NoteReference) {
+	r.entry = bibliographyEntry{kind: "note", title: string(ref.Title()), note: ref.Text}
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+func bibliographyLibrary() model.Library {
+	library := testLibrary()
+	book := model.NewBookReference(10, "Operating Systems: Three Easy Pieces (by Remzi H. Arpaci-Dusseau and Andrea C. Arpaci-Dusseau)", "9781985086593", "The OS book", true)
+	book.SetTags([]model.Tag{"os"})
+	library.References[0].Reference = book
+	return library
+}
+
+func TestWriteRIS(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRIS(&buf, bibliographyLibrary(), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ris := strings.ReplaceAll(buf.String(), "\r\n", "\n")
+	for _, expected := range []string{
+		"TY  - BOOK\nTI  - Operating Systems: Three Easy Pieces\nAU  - Arpaci-Dusseau, Remzi H.\nAU  - Arpaci-Dusseau, Andrea C.\nSN  - 9781985086593\nAB  - The OS book\nKW  - os\nER  - \n",
+		"TY  - ELEC\nTI  - Raft\nUR  - https://raft.github.io\nER  - \n",
+		"TY  - GEN\nTI  - Thoughts\nN1  - text\nER  - \n",
+	} {
+		if !strings.Contains(ris, expected) {
+			t.Errorf("expected %q in:\n%s", expected, ris)
+		}
+	}
+	if strings.Count(ris, "ER  - ") != 4 {
+		t.Errorf("expected 4 records, got:\n%s", ris)
+	}
+}
+
+func TestWriteCSLJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSLJSON(&buf, bibliographyLibrary(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var items []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &items); err != nil {
+		t.Fatalf("expected a JSON array, got %v:\n%s", err, buf.String())
+	}
+	if len(items) != 4 {
+		t.Fatalf("expected the references of the category and its subcategory, got %d", len(items))
+	}
+	book := items[0]
+	authors, _ := book["author"].([]any)
+	if book["id"] != "reference-10" || book["type"] != "book" || book["title"] != "Operating Systems: Three Easy Pieces" || book["ISBN"] != "9781985086593" || book["keyword"] != "os" || len(authors) != 2 {
+		t.Errorf("unexpected book %v", book)
+	}
+	if first, _ := authors[0].(map[string]any); first["family"] != "Arpaci-Dusseau" || first["given"] != "Remzi H." {
+		t.Errorf("unexpected author %v", authors[0])
+	}
+	if items[2]["type"] != "webpage" || items[2]["URL"] != "https://raft.github.io" {
+		t.Errorf("unexpected link %v", items[2])
+	}
+	if items[3]["type"] != "document" || items[3]["note"] != "text" {
+		t.Errorf("unexpected note %v", items[3])
+	}
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+var cslTypes = map[string]string{"book": "book", "webpage": "webpage", "note": "document"}
+
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+type cslItem struct {
+	Id       string    `json:"id"`
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Author   []cslName `json:"author,omitempty"`
+	ISBN     string    `json:"ISBN,omitempty"`
+	URL      string    `json:"URL,omitempty"`
+	Abstract string    `json:"abstract,omitempty"`
+	Note     string    `json:"note,omitempty"`
+	Keyword  string    `json:"keyword,omitempty"`
+}
+
+/*
+WriteCSLJSON exports references as CSL-JSON, the format of the citation style processors that Zotero and Mendeley use
+and can import. As with WriteCSV, categoryId 0 exports the whole library, otherwise the category and its subcategories.
+Books have their authors split from the title, notes are documents with their text as the note and tags are keywords.
+*/
+func WriteCSLJSON(w io.Writer, library model.Library, categoryId model.Id) error {
+	categories, _, err := selectCategories(library, categoryId)
+	if err != nil {
+		return err
+	}
+	entries := bibliographyEntries(library, categories)
+	items := make([]cslItem, 0, len(entries))
+	for _, entry := range entries {
+		item := cslItem{
+			Id:       fmt.Sprintf("reference-%d", entry.id),
+			Type:     cslTypes[entry.kind],
+			Title:    entry.title,
+			ISBN:     entry.isbn,
+			URL:      entry.url,
+			Abstract: entry.abstract,
+			Note:     entry.note,
+			Keyword:  strings.Join(entry.keywords, ", "),
+		}
+		for _, author := range entry.authors {
+			given, surname := model.SplitName(author)
+			if given == "" {
+				item.Author = append(item.Author, cslName{Literal: author})
+			} else {
+				item.Author = append(item.Author, cslName{Family: surname, Given: given})
+			}
+		}
+		items = append(items, item)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(items); err != nil {
+		return fmt.Errorf("error writing CSL-JSON: %v", err)
+	}
+	return nil
+}
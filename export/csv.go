@@ -0,0 +1,87 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// The columns of a CSV export, which the CSV importer reads by default
+var CSVHeader = []string{"type", "title", "isbn", "url", "description", "starred", "category", "position"}
+
+/*
+WriteCSV exports references as CSV with a header row and one row per reference, e.g. for editing in a spreadsheet:
+  - with categoryId 0, the whole library is exported, otherwise the category and its subcategories
+  - the category column has the path of the category (e.g. "Programming / Go") and the position is within the category
+  - notes have their text in the description column
+*/
+func WriteCSV(w io.Writer, library model.Library, categoryId model.Id) error {
+	categories, paths, err := selectCategories(library, categoryId)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(CSVHeader); err != nil {
+		return fmt.Errorf("error writing CSV: %v", err)
+	}
+	for _, category := range categories {
+		for i, ref := range library.ReferencesOf(category.Id) {
+			row := &csvRowRenderer{}
+			ref.Render(row)
+			record := []string{row.kind, string(ref.Title()), row.isbn, row.url, row.description,
+				strconv.FormatBool(ref.Starred()), paths[category.Id], strconv.Itoa(i + 1)}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("error writing CSV: %v", err)
+			}
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing CSV: %v", err)
+	}
+	return nil
+}
+
+// Returns the categories to export, in tree order: all of them with categoryId 0, otherwise the category and its
+// subcategories. The paths of the categories (e.g. "Programming / Go") are returned by id.
+func selectCategories(library model.Library, categoryId model.Id) ([]model.CategoryRef, map[model.Id]string, error) {
+	paths := make(map[model.Id]string, len(library.Categories))
+	included := make(map[model.Id]bool, len(library.Categories))
+	var categories []model.CategoryRef
+	// parents come before their subcategories, so their paths are known by then
+	for _, category := range library.Categories {
+		paths[category.Id] = model.CategoryPath(paths[category.ParentId], category.Name)
+		included[category.Id] = categoryId == 0 || category.Id == categoryId || included[category.ParentId]
+		if included[category.Id] {
+			categories = append(categories, category)
+		}
+	}
+	if _, ok := paths[categoryId]; categoryId != 0 && !ok {
+		return nil, nil, fmt.Errorf("category with id %d not found", categoryId)
+	}
+	return categories, paths, nil
+}
+
+// csvRowRenderer collects the type specific columns of a reference
+type csvRowRenderer struct {
+	kind        string
+	isbn        string
+	url         string
+	description string
+}
+
+func (r *csvRowRenderer) RenderBook(ref model.BookReference) {
+	r.kind, r.isbn, r.description = "book", string(ref.ISBN), ref.Description
+}
+
+func (r *csvRowRenderer) RenderLink(ref model.LinkReference) {
+	r.kind, r.url, r.description = "link", string(ref.URL), ref.Description
+}
+
+func (r *csvRowRenderer) RenderNote(ref model.NoteReference) {
+	r.kind, r.description = "note", ref.Text
+}
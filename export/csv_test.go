@@ -0,0 +1,36 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testLibrary(), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "type,title,isbn,url,description,starred,category,position\n" +
+		"book,OSTEP,9781985086593,,,true,Systems,1\n" +
+		"book,DDIA,9781449373320,,,false,\"Systems / Distributed \"\"Systems\"\"\",1\n" +
+		"link,Raft,,https://raft.github.io,,false,\"Systems / Distributed \"\"Systems\"\"\",2\n" +
+		"note,Thoughts,,,text,true,\"Systems / Distributed \"\"Systems\"\"\",3\n"
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteCSVOfCategory(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testLibrary(), 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := bytes.Count(buf.Bytes(), []byte("\n")); lines != 4 {
+		t.Errorf("expected the header and the 3 references of the category, got %d lines:\n%s", lines, buf.String())
+	}
+	if err := WriteCSV(&buf, testLibrary(), model.Id(99)); err == nil {
+		t.Error("expected error for unknown category")
+	}
+}
@@ -0,0 +1,168 @@
+// Package export turns the library into formats meant to be read outside of the app
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// Graph is the library as nodes (categories and references) and edges (containment and relations between references)
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+type Node struct {
+	Id string `json:"id"`
+	// category, book, link or note
+	Kind    string `json:"kind"`
+	Label   string `json:"label"`
+	Starred bool   `json:"starred,omitempty"`
+	// the ISBN of a book or the URL of a link
+	Identifier string `json:"identifier,omitempty"`
+}
+
+type Edge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// subcategory and contains link categories to their children, the others are relation types
+	Kind string `json:"kind"`
+}
+
+const (
+	SubcategoryEdge = "subcategory"
+	ContainsEdge    = "contains"
+)
+
+func categoryNodeId(id model.Id) string {
+	return fmt.Sprintf("category-%d", id)
+}
+
+func referenceNodeId(id model.Id) string {
+	return fmt.Sprintf("reference-%d", id)
+}
+
+// Builds the graph of the library. Categories come first, in tree order, followed by their references.
+func BuildGraph(library model.Library) Graph {
+	graph := Graph{Nodes: []Node{}, Edges: []Edge{}}
+	for _, category := range library.Categories {
+		graph.Nodes = append(graph.Nodes, Node{Id: categoryNodeId(category.Id), Kind: "category", Label: string(category.Name)})
+		if !category.IsTopLevel() {
+			graph.Edges = append(graph.Edges, Edge{Source: categoryNodeId(category.ParentId), Target: categoryNodeId(category.Id), Kind: SubcategoryEdge})
+		}
+	}
+	for _, ref := range library.References {
+		renderer := &graphNodeRenderer{}
+		ref.Reference.Render(renderer)
+		graph.Nodes = append(graph.Nodes, renderer.node)
+		graph.Edges = append(graph.Edges, Edge{Source: categoryNodeId(ref.Category.Id), Target: renderer.node.Id, Kind: ContainsEdge})
+	}
+	for _, relation := range library.Relations {
+		graph.Edges = append(graph.Edges, Edge{Source: referenceNodeId(relation.From), Target: referenceNodeId(relation.To), Kind: string(relation.Type)})
+	}
+	return graph
+}
+
+// Writes the graph in the given format, dot or json
+func WriteGraph(w io.Writer, graph Graph, format string) error {
+	switch strings.ToLower(format) {
+	case "dot":
+		return WriteDOT(w, graph)
+	case "json":
+		return WriteJSON(w, graph)
+	}
+	return fmt.Errorf("unknown graph format %q (must be dot or json)", format)
+}
+
+func WriteJSON(w io.Writer, graph Graph) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(graph)
+}
+
+/*
+WriteDOT writes the graph in the Graphviz DOT language, e.g. for `dot -Tsvg`:
+  - categories are folders, notes are notes and books and links are rounded boxes, with starred references filled in gold
+  - containment edges are plain, relation edges are dashed and labelled with their type; related edges have no direction
+*/
+func WriteDOT(w io.Writer, graph Graph) error {
+	var b strings.Builder
+	b.WriteString("digraph library {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [fontname=\"Helvetica\"];\n")
+	for _, node := range graph.Nodes {
+		shape, styles := "box", []string{"rounded"}
+		switch node.Kind {
+		case "category":
+			shape, styles = "folder", nil
+		case string(model.NoteType):
+			shape, styles = "note", nil
+		}
+		attrs := []string{"label=" + dotQuote(nodeLabel(node)), "shape=" + shape}
+		if node.Starred {
+			styles = append(styles, "filled")
+			attrs = append(attrs, "fillcolor=gold")
+		}
+		if len(styles) > 0 {
+			attrs = append(attrs, "style="+dotQuote(strings.Join(styles, ",")))
+		}
+		fmt.Fprintf(&b, "\t%s [%s];\n", dotQuote(node.Id), strings.Join(attrs, ", "))
+	}
+	for _, edge := range graph.Edges {
+		var attrs []string
+		switch edge.Kind {
+		case ContainsEdge:
+		case SubcategoryEdge:
+			attrs = append(attrs, "style=bold")
+		default:
+			attrs = append(attrs, "style=dashed", "label="+dotQuote(edge.Kind))
+			if edge.Kind == string(model.RelatedRelation) {
+				attrs = append(attrs, "dir=none")
+			}
+		}
+		fmt.Fprintf(&b, "\t%s -> %s", dotQuote(edge.Source), dotQuote(edge.Target))
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func nodeLabel(node Node) string {
+	if node.Kind == "category" {
+		return node.Label
+	}
+	return fmt.Sprintf("%s\n(%s)", node.Label, node.Kind)
+}
+
+// Quotes a DOT ID, escaping quotes and backslashes and turning newlines into DOT's centered line breaks
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// graphNodeRenderer turns a reference into a node of the graph
+type graphNodeRenderer struct {
+	node Node
+}
+
+func (r *graphNodeRenderer) RenderBook(ref model.BookReference) {
+	r.node = Node{Id: referenceNodeId(ref.GetId()), Kind: string(model.BookType), Label: string(ref.Title()), Starred: ref.Starred(), Identifier: ref.ISBN.Hyphenated()}
+}
+
+func (r *graphNodeRenderer) RenderLink(ref model.LinkReference) {
+	r.node = Node{Id: referenceNodeId(ref.GetId()), Kind: string(model.LinkType), Label: string(ref.Title()), Starred: ref.Starred(), Identifier: string(ref.URL)}
+}
+
+func (r *graphNodeRenderer) RenderNote(ref model.NoteReference) {
+	r.node = Node{Id: referenceNodeId(ref.GetId()), Kind: string(model.NoteType), Label: string(ref.Title()), Starred: ref.Starred()}
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+func testLibrary() model.Library {
+	parent := model.CategoryRef{Id: 1, Name: "Systems"}
+	child := model.CategoryRef{Id: 2, Name: "Distributed \"Systems\"", ParentId: 1}
+	return model.Library{
+		Categories: []model.CategoryRef{parent, child},
+		References: []model.CategorizedReference{
+			{Category: parent, Reference: model.NewBookReference(10, "OSTEP", "9781985086593", "", true)},
+			{Category: child, Reference: model.NewBookReference(11, "DDIA", "9781449373320", "", false)},
+			{Category: child, Reference: model.NewLinkReference(12, "Raft", "https://raft.github.io", "", false)},
+			{Category: child, Reference: model.NewNoteReference(13, "Thoughts", "text", true)},
+		},
+		Relations: []model.Relation{
+			{From: 10, To: 11, Type: model.PrerequisiteRelation},
+			{From: 11, To: 12, Type: model.RelatedRelation},
+		},
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	graph := BuildGraph(testLibrary())
+	if len(graph.Nodes) != 6 {
+		t.Fatalf("expected 6 nodes, got %d", len(graph.Nodes))
+	}
+	book := graph.Nodes[2]
+	if book.Id != "reference-10" || book.Kind != "book" || !book.Starred || !strings.HasPrefix(book.Identifier, "978-") {
+		t.Errorf("unexpected book node %+v", book)
+	}
+	if graph.Nodes[4].Identifier != "https://raft.github.io" {
+		t.Errorf("expected the URL as the identifier of the link, got %+v", graph.Nodes[4])
+	}
+	expected := []Edge{
+		{Source: "category-1", Target: "category-2", Kind: SubcategoryEdge},
+		{Source: "category-1", Target: "reference-10", Kind: ContainsEdge},
+		{Source: "category-2", Target: "reference-11", Kind: ContainsEdge},
+		{Source: "category-2", Target: "reference-12", Kind: ContainsEdge},
+		{Source: "category-2", Target: "reference-13", Kind: ContainsEdge},
+		{Source: "reference-10", Target: "reference-11", Kind: "prerequisite"},
+		{Source: "reference-11", Target: "reference-12", Kind: "related"},
+	}
+	if len(graph.Edges) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, graph.Edges)
+	}
+	for i := range expected {
+		if graph.Edges[i] != expected[i] {
+			t.Errorf("expected edge %d to be %+v, got %+v", i, expected[i], graph.Edges[i])
+		}
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraph(&buf, BuildGraph(testLibrary()), "dot"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dot := buf.String()
+	for _, expected := range []string{
+		"digraph library {",
+		`"category-2" [label="Distributed \"Systems\"", shape=folder];`,
+		`"reference-10" [label="OSTEP\n(book)", shape=box, fillcolor=gold, style="rounded,filled"];`,
+		`"reference-13" [label="Thoughts\n(note)", shape=note, fillcolor=gold, style="filled"];`,
+		`"category-1" -> "category-2" [style=bold];`,
+		`"category-2" -> "reference-11";`,
+		`"reference-10" -> "reference-11" [style=dashed, label="prerequisite"];`,
+		`"reference-11" -> "reference-12" [style=dashed, label="related", dir=none];`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("expected %q in:\n%s", expected, dot)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraph(&buf, BuildGraph(testLibrary()), "JSON"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded Graph
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if len(decoded.Nodes) != 6 || len(decoded.Edges) != 7 || !decoded.Nodes[2].Starred {
+		t.Errorf("unexpected decoded graph %+v", decoded)
+	}
+	if !strings.Contains(buf.String(), `"kind": "category"`) || strings.Contains(buf.String(), `"starred": false`) {
+		t.Errorf("unexpected JSON %s", buf.String())
+	}
+
+	if err := WriteGraph(&buf, Graph{}, "svg"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
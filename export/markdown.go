@@ -0,0 +1,160 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// File is a file of a multi-file export, named relative to the export directory
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// Writes the files into the directory, creating it if needed. Existing files with the same names are overwritten.
+func WriteFiles(dir string, files []File) error {
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("error creating directory for %s: %v", file.Name, err)
+		}
+		if err := os.WriteFile(path, file.Content, 0o644); err != nil {
+			return fmt.Errorf("error writing %s: %v", file.Name, err)
+		}
+	}
+	return nil
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Returns the name (without extension) of the exported file of a category. The id keeps categories with the same name apart.
+func CategoryFileName(category model.CategoryRef) string {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(string(category.Name)), "-"), "-")
+	if slug == "" {
+		return fmt.Sprintf("%d", category.Id)
+	}
+	return fmt.Sprintf("%d-%s", category.Id, slug)
+}
+
+/*
+MarkdownFiles exports the library as one Markdown file per category, e.g. for a wiki. Each file has:
+  - YAML frontmatter with the title and id of the category, its parent and the number of (starred) references
+  - the references as an ordered list, in the order of the category, with starred ones marked with ★
+  - links to the subcategories
+*/
+func MarkdownFiles(library model.Library) []File {
+	byId := make(map[model.Id]model.CategoryRef, len(library.Categories))
+	for _, category := range library.Categories {
+		byId[category.Id] = category
+	}
+
+	files := make([]File, 0, len(library.Categories))
+	for _, category := range library.Categories {
+		refs := library.ReferencesOf(category.Id)
+		starred := 0
+		for _, ref := range refs {
+			if ref.Starred() {
+				starred++
+			}
+		}
+
+		var b strings.Builder
+		b.WriteString("---\n")
+		fmt.Fprintf(&b, "title: %s\n", strconv.Quote(string(category.Name)))
+		fmt.Fprintf(&b, "category_id: %d\n", category.Id)
+		if parent, ok := byId[category.ParentId]; ok {
+			fmt.Fprintf(&b, "parent: %s\n", strconv.Quote(string(parent.Name)))
+		}
+		fmt.Fprintf(&b, "references: %d\n", len(refs))
+		fmt.Fprintf(&b, "starred: %d\n", starred)
+		b.WriteString("---\n\n")
+		fmt.Fprintf(&b, "# %s\n", escapeMarkdown(string(category.Name)))
+		if parent, ok := byId[category.ParentId]; ok {
+			fmt.Fprintf(&b, "\nPart of [%s](%s.md).\n", escapeMarkdown(string(parent.Name)), CategoryFileName(parent))
+		}
+
+		if len(refs) == 0 {
+			b.WriteString("\nNo references yet.\n")
+		}
+		for i, ref := range refs {
+			renderer := &markdownRenderer{builder: &b, position: i + 1}
+			b.WriteString("\n")
+			ref.Render(renderer)
+		}
+
+		var children []model.CategoryRef
+		for _, child := range library.Categories {
+			if child.ParentId == category.Id {
+				children = append(children, child)
+			}
+		}
+		if len(children) > 0 {
+			b.WriteString("\n## Subcategories\n\n")
+			for _, child := range children {
+				fmt.Fprintf(&b, "- [%s](%s.md)\n", escapeMarkdown(string(child.Name)), CategoryFileName(child))
+			}
+		}
+
+		files = append(files, File{Name: CategoryFileName(category) + ".md", Content: []byte(b.String())})
+	}
+	return files
+}
+
+// markdownRenderer writes a reference as an item of the ordered list of its category
+type markdownRenderer struct {
+	builder  *strings.Builder
+	position int
+}
+
+func (r *markdownRenderer) RenderBook(ref model.BookReference) {
+	r.item(ref, fmt.Sprintf("**%s** (book, ISBN %s)", escapeMarkdown(string(ref.Title())), ref.ISBN.Hyphenated()), ref.Description)
+}
+
+func (r *markdownRenderer) RenderLink(ref model.LinkReference) {
+	r.item(ref, fmt.Sprintf("[%s](<%s>)", escapeMarkdown(string(ref.Title())), ref.URL), ref.Description)
+}
+
+func (r *markdownRenderer) RenderNote(ref model.NoteReference) {
+	r.item(ref, fmt.Sprintf("**%s** (note)", escapeMarkdown(string(ref.Title()))), ref.Text)
+}
+
+// Writes the item's heading line, followed by its text (which is Markdown already) and tags, indented to belong to the item
+func (r *markdownRenderer) item(ref model.Reference, heading string, text string) {
+	marker := fmt.Sprintf("%d. ", r.position)
+	indent := strings.Repeat(" ", len(marker))
+	star := ""
+	if ref.Starred() {
+		star = "★ "
+	}
+	fmt.Fprintf(r.builder, "%s%s%s\n", marker, star, heading)
+	if text = strings.TrimSpace(text); text != "" {
+		r.builder.WriteString("\n")
+		for _, line := range strings.Split(text, "\n") {
+			if strings.TrimSpace(line) == "" {
+				r.builder.WriteString("\n")
+			} else {
+				fmt.Fprintf(r.builder, "%s%s\n", indent, line)
+			}
+		}
+	}
+	if tags := ref.Tags(); len(tags) > 0 {
+		names := make([]string, len(tags))
+		for i, tag := range tags {
+			names[i] = "`" + string(tag) + "`"
+		}
+		fmt.Fprintf(r.builder, "\n%sTags: %s\n", indent, strings.Join(names, ", "))
+	}
+}
+
+var markdownSpecialChars = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, "#", `\#`)
+
+// Escapes the characters of plain text (e.g. titles) that Markdown would otherwise interpret
+func escapeMarkdown(text string) string {
+	return markdownSpecialChars.Replace(text)
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+func TestCategoryFileName(t *testing.T) {
+	if name := CategoryFileName(model.CategoryRef{Id: 3, Name: "OS & Low-Level!"}); name != "3-os-low-level" {
+		t.Errorf("expected 3-os-low-level, got %q", name)
+	}
+	if name := CategoryFileName(model.CategoryRef{Id: 4, Name: "日本語"}); name != "4" {
+		t.Errorf("expected 4, got %q", name)
+	}
+}
+
+func TestMarkdownFiles(t *testing.T) {
+	library := testLibrary()
+	link := model.NewLinkReference(12, "Raft [paper]", "https://raft.github.io", "The *Raft* site\n\nwith two paragraphs", false)
+	link.SetTags([]model.Tag{"consensus", "papers"})
+	library.References[2].Reference = link
+
+	files := MarkdownFiles(library)
+	if len(files) != 2 {
+		t.Fatalf("expected one file per category, got %d", len(files))
+	}
+	if files[0].Name != "1-systems.md" || files[1].Name != "2-distributed-systems.md" {
+		t.Errorf("unexpected file names %q, %q", files[0].Name, files[1].Name)
+	}
+
+	parent := string(files[0].Content)
+	for _, expected := range []string{
+		"---\ntitle: \"Systems\"\ncategory_id: 1\nreferences: 1\nstarred: 1\n---\n\n# Systems\n",
+		"1. ★ **OSTEP** (book, ISBN 978-1-985086-59-3)\n",
+		"## Subcategories\n\n- [Distributed \"Systems\"](2-distributed-systems.md)\n",
+	} {
+		if !strings.Contains(parent, expected) {
+			t.Errorf("expected %q in:\n%s", expected, parent)
+		}
+	}
+
+	child := string(files[1].Content)
+	for _, expected := range []string{
+		"title: \"Distributed \\\"Systems\\\"\"\ncategory_id: 2\nparent: \"Systems\"\nreferences: 3\nstarred: 1\n",
+		"Part of [Systems](1-systems.md).\n",
+		"1. **DDIA** (book, ISBN 978-1-4493-7332-0)\n",
+		"2. [Raft \\[paper\\]](<https://raft.github.io>)\n\n   The *Raft* site\n\n   with two paragraphs\n\n   Tags: `consensus`, `papers`\n",
+		"3. ★ **Thoughts** (note)\n\n   text\n",
+	} {
+		if !strings.Contains(child, expected) {
+			t.Errorf("expected %q in:\n%s", expected, child)
+		}
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	if err := WriteFiles(dir, []File{{Name: "a.md", Content: []byte("a")}, {Name: "sub/b.md", Content: []byte("b")}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "sub", "b.md"))
+	if err != nil || string(content) != "b" {
+		t.Errorf("expected sub/b.md to contain b, got %q, err=%v", content, err)
+	}
+}
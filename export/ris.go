@@ -0,0 +1,53 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+var risTypes = map[string]string{"book": "BOOK", "webpage": "ELEC", "note": "GEN"}
+
+/*
+WriteRIS exports references in the RIS format read by Zotero, Mendeley and most other reference managers. As with
+WriteCSV, categoryId 0 exports the whole library, otherwise the category and its subcategories. Books are BOOK records
+with their authors (AU) split from the title, links ELEC records and notes GEN records with their text as a note (N1).
+Tags become keywords (KW).
+*/
+func WriteRIS(w io.Writer, library model.Library, categoryId model.Id) error {
+	categories, _, err := selectCategories(library, categoryId)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(w)
+	field := func(tag string, value string) {
+		if value != "" {
+			fmt.Fprintf(out, "%s  - %s\r\n", tag, value)
+		}
+	}
+	for _, entry := range bibliographyEntries(library, categories) {
+		fmt.Fprintf(out, "TY  - %s\r\n", risTypes[entry.kind])
+		field("TI", entry.title)
+		for _, author := range entry.authors {
+			given, surname := model.SplitName(author)
+			if given != "" {
+				author = surname + ", " + given
+			}
+			field("AU", author)
+		}
+		field("SN", entry.isbn)
+		field("UR", entry.url)
+		field("AB", entry.abstract)
+		field("N1", entry.note)
+		for _, keyword := range entry.keywords {
+			field("KW", keyword)
+		}
+		out.WriteString("ER  - \r\n\r\n")
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("error writing RIS: %v", err)
+	}
+	return nil
+}
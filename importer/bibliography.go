@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+type entryKind int
+
+const (
+	// an entry of another type, e.g. a journal article, imported as a link if it has a URL or DOI
+	otherEntry entryKind = iota
+	bookEntry
+	webPageEntry
+	// an entry without a specific type, which is a note unless it has an ISBN or URL
+	genericEntry
+)
+
+// bibliographyEntry is a record of a reference manager like Zotero, read from RIS or CSL-JSON
+type bibliographyEntry struct {
+	kind entryKind
+	// the type as the source names it, for error messages
+	typeName string
+	title    string
+	// given names first
+	authors  []string
+	isbns    []string
+	url      string
+	doi      string
+	abstract string
+	note     string
+	keywords []string
+}
+
+// Returns the reference the entry becomes: books for books, links for web pages and other entries with a URL, and
+// notes for generic entries without either
+func (e bibliographyEntry) reference() (model.Reference, error) {
+	kind := e.kind
+	switch {
+	case kind == genericEntry && len(e.isbns) > 0:
+		kind = bookEntry
+	case kind == genericEntry && (e.url != "" || e.doi != ""):
+		kind = webPageEntry
+	case kind == otherEntry:
+		if e.url == "" && e.doi == "" {
+			return nil, fmt.Errorf("%s entries need a URL or DOI to be imported as links", e.typeName)
+		}
+		kind = webPageEntry
+	}
+
+	title := e.title
+	if kind == bookEntry {
+		title = model.TitleWithAuthors(title, e.authors)
+	}
+	validTitle, err := model.NewTitle(model.TruncateTitle(title))
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []model.Tag
+	for _, keyword := range e.keywords {
+		if tag, err := model.NewTag(keyword); err == nil && !containsTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	var ref model.Reference
+	switch kind {
+	case bookEntry:
+		isbn, err := e.isbn()
+		if err != nil {
+			return nil, err
+		}
+		book := model.NewBookReference(0, validTitle, isbn, e.abstract, false)
+		book.SetTags(tags)
+		ref = book
+	case webPageEntry:
+		address := e.url
+		if address == "" {
+			address = "https://doi.org/" + strings.TrimPrefix(strings.TrimPrefix(e.doi, "https://doi.org/"), "doi:")
+		}
+		url, err := model.NewURL(address)
+		if err != nil {
+			return nil, err
+		}
+		link := model.NewLinkReference(0, validTitle, url, e.abstract, false)
+		link.SetTags(tags)
+		ref = link
+	default:
+		text := e.note
+		if text == "" {
+			text = e.abstract
+		}
+		note := model.NewNoteReference(0, validTitle, text, false)
+		note.SetTags(tags)
+		ref = note
+	}
+	return ref, nil
+}
+
+// Returns the first valid ISBN, as sources often list several (e.g. "9780262510875 (pbk.) 0262011530")
+func (e bibliographyEntry) isbn() (model.ISBN, error) {
+	var firstErr error
+	for _, val := range e.isbns {
+		for _, candidate := range strings.FieldsFunc(val, func(r rune) bool { return r == ' ' || r == ',' || r == ';' }) {
+			isbn, err := model.NewISBN(candidate)
+			if err == nil {
+				return isbn, nil
+			}
+			if firstErr == nil && strings.ContainsAny(candidate, "0123456789") {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return "", errors.New("no ISBN (books are identified by their ISBN)")
+}
+
+// Parses the entries into a folder, rejecting the ones that can't be imported
+func bibliographyFolder(name model.Title, entries []bibliographyEntry) model.ImportFolder {
+	folder := model.ImportFolder{Name: name}
+	for i, entry := range entries {
+		ref, err := entry.reference()
+		if err != nil {
+			title := entry.title
+			if title == "" {
+				title = fmt.Sprintf("entry %d", i+1)
+			}
+			folder.Rejected = append(folder.Rejected, model.RejectedEntry{Title: model.TruncateTitle(title), Reason: fmt.Sprintf("entry %d: %v", i+1, err)})
+			continue
+		}
+		folder.References = append(folder.References, ref)
+	}
+	return folder
+}
@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+const risExport = "\ufeffTY  - BOOK\r\n" +
+	"TI  - Operating Systems: Three Easy Pieces\r\n" +
+	"AU  - Arpaci-Dusseau, Remzi H.\r\n" +
+	"AU  - Arpaci-Dusseau, Andrea C.\r\n" +
+	"SN  - 978-1-985086-59-3 (pbk.)\r\n" +
+	"AB  - The OS book\r\n" +
+	"with a second line\r\n" +
+	"KW  - os\r\n" +
+	"ER  -\r\n" +
+	"\r\n" +
+	"TY  - JOUR\r\n" +
+	"TI  - In Search of an Understandable Consensus Algorithm\r\n" +
+	"DO  - 10.5555/2643634.2643666\r\n" +
+	"ER  - \r\n" +
+	"TY  - GEN\r\n" +
+	"TI  - Thoughts\r\n" +
+	"N1  - text\r\n" +
+	"ER  - \r\n" +
+	"TY  - JOUR\r\n" +
+	"TI  - Paper without a link\r\n" +
+	"ER  - \r\n" +
+	"TY  - BOOK\r\n" +
+	"TI  - Book without an ISBN\r\n" +
+	"ER  - \r\n"
+
+func TestParseRIS(t *testing.T) {
+	folders, err := ParseRIS(strings.NewReader(risExport), "Zotero")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(folders) != 1 || folders[0].Name != "Zotero" || len(folders[0].References) != 3 {
+		t.Fatalf("expected 3 references in the Zotero folder, got %+v", folders)
+	}
+	refs := folders[0].References
+
+	book, ok := refs[0].(model.BookReference)
+	if !ok || book.Title() != "Operating Systems: Three Easy Pieces (by Remzi H. Arpaci-Dusseau and Andrea C. Arpaci-Dusseau)" ||
+		book.ISBN != "9781985086593" || book.Description != "The OS book\nwith a second line" {
+		t.Errorf("unexpected book %+v", refs[0])
+	}
+	if tags := refs[0].Tags(); len(tags) != 1 || tags[0] != "os" {
+		t.Errorf("expected the keywords as tags, got %v", tags)
+	}
+	if link, ok := refs[1].(model.LinkReference); !ok || link.URL != "https://doi.org/10.5555/2643634.2643666" {
+		t.Errorf("expected an article with a DOI to become a link, got %+v", refs[1])
+	}
+	if note, ok := refs[2].(model.NoteReference); !ok || note.Text != "text" {
+		t.Errorf("expected a generic record to become a note, got %+v", refs[2])
+	}
+
+	rejected := folders[0].Rejected
+	if len(rejected) != 2 || !strings.Contains(rejected[0].Reason, "JOUR entries need a URL or DOI") || !strings.Contains(rejected[1].Reason, "no ISBN") {
+		t.Errorf("unexpected rejected entries %+v", rejected)
+	}
+
+	if _, err := ParseRIS(strings.NewReader("not a RIS file"), "Zotero"); err == nil {
+		t.Error("expected error for a file without records")
+	}
+}
+
+func TestParseCSLJSON(t *testing.T) {
+	input := `[
+		{"id": "a", "type": "book", "title": "Code", "author": [{"family": "Petzold", "given": "Charles"}], "ISBN": "9780735611313", "keyword": "classics, hardware"},
+		{"id": "b", "type": "webpage", "title": "Raft", "URL": "https://raft.github.io", "abstract": "The Raft site"},
+		{"id": "c", "type": "document", "title": "Thoughts", "note": "text", "author": [{"literal": "Me"}]},
+		{"id": "d", "type": "article-journal", "title": "No link"}
+	]`
+	folders, err := ParseCSLJSON(strings.NewReader(input), "Mendeley")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	refs := folders[0].References
+	if len(refs) != 3 || len(folders[0].Rejected) != 1 {
+		t.Fatalf("expected 3 references and 1 rejected item, got %+v", folders[0])
+	}
+	if book, ok := refs[0].(model.BookReference); !ok || book.Title() != "Code (by Charles Petzold)" || len(book.Tags()) != 2 {
+		t.Errorf("unexpected book %+v", refs[0])
+	}
+	if link, ok := refs[1].(model.LinkReference); !ok || link.Description != "The Raft site" {
+		t.Errorf("unexpected link %+v", refs[1])
+	}
+	if note, ok := refs[2].(model.NoteReference); !ok || note.Title() != "Thoughts" || note.Text != "text" {
+		t.Errorf("expected a document without a URL to become a note without authors in the title, got %+v", refs[2])
+	}
+
+	if _, err := ParseCSLJSON(strings.NewReader(`{"title": "not an array"}`), "Mendeley"); err == nil {
+		t.Error("expected error for a JSON object")
+	}
+}
@@ -0,0 +1,180 @@
+// Package importer parses references exported by other tools into folders that can be imported into the library.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Bookmarks that aren't in any folder are imported into a folder with this name
+const LooseBookmarksFolder = "Imported bookmarks"
+
+type bookmarkFolder struct {
+	name      string
+	bookmarks []*bookmark
+	folders   []*bookmarkFolder
+}
+
+type bookmark struct {
+	title       string
+	url         string
+	description string
+}
+
+/*
+ParseBookmarks parses a bookmarks file in the Netscape bookmark format, which all major browsers export. The format nests
+<DL> lists of <DT> entries, each either a folder (<H3>, followed by its own <DL>) or a bookmark (<A HREF>, optionally
+followed by a <DD> description). Bookmark folders become folders, bookmarks become links in the same order:
+  - bookmarks without a title are titled with their URL, and titles that are too long are truncated
+  - bookmarks that aren't http(s) web pages (e.g. javascript: bookmarklets) are rejected
+  - folders without any bookmarks, at any depth, are left out
+*/
+func ParseBookmarks(r io.Reader) ([]model.ImportFolder, error) {
+	root := &bookmarkFolder{name: LooseBookmarksFolder}
+	// the folders whose lists are open, innermost last
+	stack := []*bookmarkFolder{}
+	current := func() *bookmarkFolder {
+		if len(stack) == 0 {
+			return root
+		}
+		return stack[len(stack)-1]
+	}
+
+	var pendingFolder *bookmarkFolder
+	var text *strings.Builder
+	// the bookmark a <DD> describes, until the next entry starts
+	var described *bookmark
+	var inDescription bool
+
+	tokenizer := html.NewTokenizer(r)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return nil, fmt.Errorf("error reading bookmarks: %v", err)
+			}
+			if root.bookmarks == nil && len(root.folders) == 0 {
+				return nil, fmt.Errorf("no bookmarks found (is this a bookmarks file exported from a browser?)")
+			}
+			return toImportFolders(root), nil
+		case html.TextToken:
+			if text != nil {
+				text.Write(tokenizer.Text())
+			} else if inDescription && described != nil {
+				described.description += string(tokenizer.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch atom.Lookup(name) {
+			case atom.Dt, atom.Dl:
+				inDescription = false
+				if atom.Lookup(name) == atom.Dl {
+					folder := pendingFolder
+					if folder == nil {
+						// the top-level list, or a list without a heading, belongs to the enclosing folder
+						folder = current()
+					} else {
+						current().folders = append(current().folders, folder)
+					}
+					stack = append(stack, folder)
+					pendingFolder = nil
+				}
+			case atom.H3:
+				// folders can have descriptions too, which aren't imported
+				described = nil
+				text = &strings.Builder{}
+			case atom.A:
+				href := ""
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = tokenizer.TagAttr()
+					if strings.EqualFold(string(key), "href") {
+						href = string(val)
+					}
+				}
+				described = &bookmark{url: href}
+				current().bookmarks = append(current().bookmarks, described)
+				text = &strings.Builder{}
+			case atom.Dd:
+				inDescription = true
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch atom.Lookup(name) {
+			case atom.H3:
+				if text != nil {
+					pendingFolder = &bookmarkFolder{name: strings.TrimSpace(text.String())}
+					text = nil
+				}
+			case atom.A:
+				if text != nil && described != nil {
+					described.title = text.String()
+					text = nil
+				}
+			case atom.Dl:
+				inDescription = false
+				described = nil
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+			}
+		}
+	}
+}
+
+func toImportFolders(root *bookmarkFolder) []model.ImportFolder {
+	var folders []model.ImportFolder
+	// loose bookmarks go first, like they are usually shown before the folders
+	if len(root.bookmarks) > 0 {
+		folders = append(folders, toImportFolder(&bookmarkFolder{name: root.name, bookmarks: root.bookmarks}))
+	}
+	for _, folder := range root.folders {
+		if hasBookmarks(folder) {
+			folders = append(folders, toImportFolder(folder))
+		}
+	}
+	return folders
+}
+
+func toImportFolder(folder *bookmarkFolder) model.ImportFolder {
+	name := model.TruncateTitle(folder.name)
+	if name == "" {
+		name = "Untitled folder"
+	}
+	imported := model.ImportFolder{Name: model.Title(name)}
+	for _, b := range folder.bookmarks {
+		title := model.TruncateTitle(strings.Join(strings.Fields(b.title), " "))
+		if title == "" {
+			title = model.TruncateTitle(b.url)
+		}
+		url, err := model.NewURL(b.url)
+		if err != nil {
+			imported.Rejected = append(imported.Rejected, model.RejectedEntry{Title: title, Reason: fmt.Sprintf("invalid URL %q: %v", b.url, err)})
+			continue
+		}
+		imported.References = append(imported.References, model.NewLinkReference(0, model.Title(title), url, strings.TrimSpace(b.description), false))
+	}
+	for _, child := range folder.folders {
+		if hasBookmarks(child) {
+			imported.Folders = append(imported.Folders, toImportFolder(child))
+		}
+	}
+	return imported
+}
+
+func hasBookmarks(folder *bookmarkFolder) bool {
+	if len(folder.bookmarks) > 0 {
+		return true
+	}
+	for _, child := range folder.folders {
+		if hasBookmarks(child) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+const bookmarksFile = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file. -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><A HREF="https://example.com/loose" ADD_DATE="1700000000">Loose &amp; free</A>
+    <DT><H3 ADD_DATE="1700000000" PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DD>The folder description
+    <DL><p>
+        <DT><A HREF="https://go.dev/?utm_source=x">The Go
+            Programming Language</A>
+        <DD>Go's home page
+        <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+        <DT><H3>Papers</H3>
+        <DL><p>
+            <DT><A HREF="https://raft.github.io/raft.pdf"></A>
+        </DL><p>
+        <DT><H3>Empty</H3>
+        <DL><p>
+        </DL><p>
+        <DT><A HREF="https://en.wikipedia.org/wiki/Go">Go (Wikipedia)</A>
+    </DL><p>
+</DL><p>
+`
+
+func TestParseBookmarks(t *testing.T) {
+	folders, err := ParseBookmarks(strings.NewReader(bookmarksFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(folders) != 2 || folders[0].Name != LooseBookmarksFolder || folders[1].Name != "Bookmarks bar" {
+		t.Fatalf("expected the loose bookmarks folder and the bookmarks bar, got %+v", folders)
+	}
+	loose := folders[0].References
+	if len(loose) != 1 || loose[0].Title() != "Loose & free" {
+		t.Errorf("unexpected loose bookmarks %+v", loose)
+	}
+
+	bar := folders[1]
+	if len(bar.References) != 2 {
+		t.Fatalf("expected 2 bookmarks in the bar, got %+v", bar.References)
+	}
+	golang := bar.References[0].(model.LinkReference)
+	if golang.Title() != "The Go Programming Language" || golang.URL != "https://go.dev" || golang.Description != "Go's home page" {
+		t.Errorf("unexpected bookmark %+v", golang)
+	}
+	wiki := bar.References[1].(model.LinkReference)
+	if wiki.Title() != "Go (Wikipedia)" || wiki.Description != "" {
+		t.Errorf("expected the folder description not to be attached to a bookmark, got %+v", wiki)
+	}
+	if len(bar.Rejected) != 1 || bar.Rejected[0].Title != "Bookmarklet" {
+		t.Errorf("expected the bookmarklet to be rejected, got %+v", bar.Rejected)
+	}
+
+	if len(bar.Folders) != 1 || bar.Folders[0].Name != "Papers" {
+		t.Fatalf("expected only the non-empty subfolder, got %+v", bar.Folders)
+	}
+	paper := bar.Folders[0].References[0]
+	if paper.Title() != "https://raft.github.io/raft.pdf" {
+		t.Errorf("expected untitled bookmark to be titled with its URL, got %q", paper.Title())
+	}
+}
+
+func TestParseBookmarksRejectsOtherFiles(t *testing.T) {
+	if _, err := ParseBookmarks(strings.NewReader("just some text")); err == nil {
+		t.Error("expected error for a file without bookmarks")
+	}
+}
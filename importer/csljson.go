@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+var cslTypes = map[string]entryKind{
+	"book": bookEntry, "chapter": bookEntry,
+	"webpage": webPageEntry, "post": webPageEntry, "post-weblog": webPageEntry,
+	"document": genericEntry,
+}
+
+type cslName struct {
+	Family  string `json:"family"`
+	Given   string `json:"given"`
+	Literal string `json:"literal"`
+}
+
+type cslItem struct {
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Author   []cslName `json:"author"`
+	ISBN     string    `json:"ISBN"`
+	URL      string    `json:"URL"`
+	DOI      string    `json:"DOI"`
+	Abstract string    `json:"abstract"`
+	Note     string    `json:"note"`
+	Keyword  string    `json:"keyword"`
+}
+
+/*
+ParseCSLJSON parses a CSL-JSON file (an array of items), as exported by Zotero and Mendeley, into a folder with the
+given name. Items are imported like RIS records: books (book, chapter) need an ISBN, web pages (webpage, post) and other
+items with a URL or DOI become links, and documents without either become notes. Keywords become tags.
+*/
+func ParseCSLJSON(r io.Reader, name model.Title) ([]model.ImportFolder, error) {
+	var items []cslItem
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("error reading CSL-JSON (expected an array of items): %v", err)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("no CSL-JSON items found")
+	}
+	entries := make([]bibliographyEntry, len(items))
+	for i, item := range items {
+		kind, ok := cslTypes[item.Type]
+		if !ok {
+			kind = otherEntry
+		}
+		entry := bibliographyEntry{
+			kind:     kind,
+			typeName: item.Type,
+			title:    strings.TrimSpace(item.Title),
+			url:      strings.TrimSpace(item.URL),
+			doi:      strings.TrimSpace(item.DOI),
+			abstract: strings.TrimSpace(item.Abstract),
+			note:     strings.TrimSpace(item.Note),
+		}
+		if item.ISBN != "" {
+			entry.isbns = []string{item.ISBN}
+		}
+		for _, author := range item.Author {
+			fullName := strings.TrimSpace(author.Literal)
+			if fullName == "" {
+				fullName = strings.TrimSpace(author.Given + " " + author.Family)
+			}
+			if fullName != "" {
+				entry.authors = append(entry.authors, fullName)
+			}
+		}
+		for _, keyword := range strings.FieldsFunc(item.Keyword, func(r rune) bool { return r == ',' || r == ';' }) {
+			entry.keywords = append(entry.keywords, strings.TrimSpace(keyword))
+		}
+		entries[i] = entry
+	}
+	return []model.ImportFolder{bibliographyFolder(name, entries)}, nil
+}
@@ -0,0 +1,258 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// Rows of a CSV file without a category are imported into a folder with this name
+const UncategorizedFolder = "Imported from CSV"
+
+// CSVField is a property of a reference that is read from a column of a CSV file
+type CSVField string
+
+const (
+	TypeField        CSVField = "type"
+	TitleField       CSVField = "title"
+	ISBNField        CSVField = "isbn"
+	URLField         CSVField = "url"
+	DescriptionField CSVField = "description"
+	StarredField     CSVField = "starred"
+	CategoryField    CSVField = "category"
+)
+
+var csvFields = []CSVField{TypeField, TitleField, ISBNField, URLField, DescriptionField, StarredField, CategoryField}
+
+// ColumnMapping maps the fields to the names of the columns (in the header row) they are read from
+type ColumnMapping map[CSVField]string
+
+// Returns the mapping that reads each field from the column with the same name, as in the CSV export
+func DefaultColumnMapping() ColumnMapping {
+	mapping := make(ColumnMapping, len(csvFields))
+	for _, field := range csvFields {
+		mapping[field] = string(field)
+	}
+	return mapping
+}
+
+// Parses a mapping like "title=Name,url=Link" into the default mapping with these fields overridden
+func ParseColumnMapping(spec string) (ColumnMapping, error) {
+	mapping := DefaultColumnMapping()
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid column mapping %q: expected field=column", pair)
+		}
+		field, column = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(column)
+		if _, known := mapping[CSVField(field)]; !known {
+			return nil, fmt.Errorf("unknown field %q in column mapping", field)
+		}
+		if column == "" {
+			return nil, fmt.Errorf("missing column for field %q in column mapping", field)
+		}
+		mapping[CSVField(field)] = column
+	}
+	return mapping, nil
+}
+
+type csvFolder struct {
+	name     model.Title
+	refs     []model.Reference
+	rejected []model.RejectedEntry
+	folders  []*csvFolder
+}
+
+func (f *csvFolder) folder(name model.Title) *csvFolder {
+	for _, child := range f.folders {
+		if child.name == name {
+			return child
+		}
+	}
+	child := &csvFolder{name: name}
+	f.folders = append(f.folders, child)
+	return child
+}
+
+/*
+ParseCSV parses a CSV file with a header row into folders, reading the columns given by the mapping (matched to the
+header case-insensitively). Only the title column is required, and rows are imported in the order of the file:
+  - the type (book, link or note) is read from the type column or, when it's empty, follows from the ISBN or URL
+  - books need an ISBN, links a URL and notes take their text from the description column
+  - the category column has a category path like "Programming / Go", and empty categories go to UncategorizedFolder
+  - rows that fail validation are rejected with their line number and the reason
+*/
+func ParseCSV(r io.Reader, mapping ColumnMapping) ([]model.ImportFolder, error) {
+	reader, columns, err := readCSVHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	indexes := make(map[CSVField]int, len(mapping))
+	for _, field := range csvFields {
+		column, mapped := mapping[field]
+		if !mapped {
+			continue
+		}
+		index, ok := columns[strings.ToLower(column)]
+		if ok {
+			indexes[field] = index
+		} else if field == TitleField || column != string(field) {
+			// the default columns are optional, but the title and columns that were asked for aren't
+			return nil, fmt.Errorf("column %q for the %s is missing from the header", column, field)
+		}
+	}
+
+	root := &csvFolder{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV: %v", err)
+		}
+		// the line the row starts on, which is the row number unless fields span lines
+		line, _ := reader.FieldPos(0)
+		value := func(field CSVField) string {
+			if index, ok := indexes[field]; ok && index < len(record) {
+				return strings.TrimSpace(record[index])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		path, err := parseCategoryPath(value(CategoryField))
+		if len(path) == 0 {
+			path = []model.Title{UncategorizedFolder}
+		}
+		folder := root
+		for _, name := range path {
+			folder = folder.folder(name)
+		}
+		if err == nil {
+			var ref model.Reference
+			ref, err = parseCSVReference(value)
+			if err == nil {
+				folder.refs = append(folder.refs, ref)
+				continue
+			}
+		}
+		title := value(TitleField)
+		if title == "" {
+			title = fmt.Sprintf("line %d", line)
+		}
+		folder.rejected = append(folder.rejected, model.RejectedEntry{Title: model.TruncateTitle(title), Reason: fmt.Sprintf("line %d: %v", line, err)})
+	}
+
+	folders := make([]model.ImportFolder, 0, len(root.folders))
+	for _, folder := range root.folders {
+		folders = append(folders, folder.toImportFolder())
+	}
+	return folders, nil
+}
+
+// Reads the header row of a CSV file, returning the index of each column by its lowercased name
+func readCSVHeader(r io.Reader) (*csv.Reader, map[string]int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("the CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading CSV: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// spreadsheet programs often start the file with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return reader, columns, nil
+}
+
+func (f *csvFolder) toImportFolder() model.ImportFolder {
+	folder := model.ImportFolder{Name: f.name, References: f.refs, Rejected: f.rejected}
+	for _, child := range f.folders {
+		folder.Folders = append(folder.Folders, child.toImportFolder())
+	}
+	return folder
+}
+
+func parseCategoryPath(val string) ([]model.Title, error) {
+	if val == "" {
+		return nil, nil
+	}
+	var path []model.Title
+	for _, part := range strings.Split(val, "/") {
+		name, err := model.NewTitle(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid category %q: %v", val, err)
+		}
+		path = append(path, name)
+	}
+	return path, nil
+}
+
+func parseCSVReference(value func(CSVField) string) (model.Reference, error) {
+	title, err := model.NewTitle(value(TitleField))
+	if err != nil {
+		return nil, err
+	}
+	starred, err := parseStarred(value(StarredField))
+	if err != nil {
+		return nil, err
+	}
+
+	kind := strings.ToLower(value(TypeField))
+	if kind == "" {
+		switch {
+		case value(ISBNField) != "":
+			kind = "book"
+		case value(URLField) != "":
+			kind = "link"
+		default:
+			kind = "note"
+		}
+	}
+	switch kind {
+	case "book":
+		isbn, err := model.NewISBN(value(ISBNField))
+		if err != nil {
+			return nil, err
+		}
+		return model.NewBookReference(0, title, isbn, value(DescriptionField), starred), nil
+	case "link":
+		url, err := model.NewURL(value(URLField))
+		if err != nil {
+			return nil, err
+		}
+		return model.NewLinkReference(0, title, url, value(DescriptionField), starred), nil
+	case "note":
+		return model.NewNoteReference(0, title, value(DescriptionField), starred), nil
+	default:
+		return nil, fmt.Errorf("unknown type %q (expected book, link or note)", kind)
+	}
+}
+
+func parseStarred(val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case "", "false", "no", "n", "0":
+		return false, nil
+	case "true", "yes", "y", "1", "x", "★":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid starred value %q (expected true or false)", val)
+	}
+}
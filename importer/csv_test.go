@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+func TestParseColumnMapping(t *testing.T) {
+	mapping, err := ParseColumnMapping("Title=Name, url = Link")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mapping[TitleField] != "Name" || mapping[URLField] != "Link" || mapping[ISBNField] != "isbn" {
+		t.Errorf("unexpected mapping %v", mapping)
+	}
+	for _, spec := range []string{"title", "author=Author", "title="} {
+		if _, err := ParseColumnMapping(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	input := "\ufefftype,title,isbn,url,description,starred,category,position\n" +
+		"book,OSTEP,978-1-985086-59-3,,Operating systems,true,Systems,1\n" +
+		",Raft,,https://raft.github.io,,,Systems / Distributed,1\n" +
+		"note,Thoughts,,,some text,no,,1\n" +
+		",,,,,,,\n" +
+		"book,Bad ISBN,123,,,,Systems,2\n" +
+		"link,,,https://example.com,,,Systems,3\n" +
+		"video,Talk,,,,,Systems,4\n" +
+		"link,Starred?,,https://example.com,,maybe,,2\n"
+	folders, err := ParseCSV(strings.NewReader(input), DefaultColumnMapping())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(folders) != 2 || folders[0].Name != "Systems" || folders[1].Name != UncategorizedFolder {
+		t.Fatalf("expected the Systems and uncategorized folders, got %+v", folders)
+	}
+
+	systems := folders[0]
+	book, ok := systems.References[0].(model.BookReference)
+	if len(systems.References) != 1 || !ok || book.ISBN != "9781985086593" || !book.Starred() || book.Description != "Operating systems" {
+		t.Errorf("unexpected references %+v", systems.References)
+	}
+	if len(systems.Folders) != 1 || systems.Folders[0].Name != "Distributed" {
+		t.Fatalf("expected the Distributed subfolder, got %+v", systems.Folders)
+	}
+	if link, ok := systems.Folders[0].References[0].(model.LinkReference); !ok || link.URL != "https://raft.github.io" {
+		t.Errorf("expected the type of a row with a URL to be link, got %+v", systems.Folders[0].References)
+	}
+
+	expectedRejected := []model.RejectedEntry{
+		{Title: "Bad ISBN", Reason: "line 6: "},
+		{Title: "line 7", Reason: "line 7: title cannot be empty"},
+		{Title: "Talk", Reason: "line 8: unknown type \"video\" (expected book, link or note)"},
+	}
+	if len(systems.Rejected) != len(expectedRejected) {
+		t.Fatalf("expected %d rejected rows, got %+v", len(expectedRejected), systems.Rejected)
+	}
+	for i, expected := range expectedRejected {
+		rejected := systems.Rejected[i]
+		if rejected.Title != expected.Title || !strings.HasPrefix(rejected.Reason, expected.Reason) {
+			t.Errorf("expected rejected row %+v, got %+v", expected, rejected)
+		}
+	}
+
+	uncategorized := folders[1]
+	if note, ok := uncategorized.References[0].(model.NoteReference); len(uncategorized.References) != 1 || !ok || note.Text != "some text" {
+		t.Errorf("unexpected uncategorized references %+v", uncategorized.References)
+	}
+	if len(uncategorized.Rejected) != 1 || !strings.Contains(uncategorized.Rejected[0].Reason, "invalid starred value") {
+		t.Errorf("expected the row with an invalid starred value to be rejected, got %+v", uncategorized.Rejected)
+	}
+}
+
+func TestParseCSVWithColumnMapping(t *testing.T) {
+	mapping, _ := ParseColumnMapping("title=Name,url=Link,category=List")
+	input := "Name,Link,List,Notes\nRaft,https://raft.github.io,Papers,ignored\n"
+	folders, err := ParseCSV(strings.NewReader(input), mapping)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(folders) != 1 || folders[0].Name != "Papers" || len(folders[0].References) != 1 || folders[0].References[0].Title() != "Raft" {
+		t.Errorf("unexpected folders %+v", folders)
+	}
+
+	if _, err := ParseCSV(strings.NewReader("Name,Link\n"), DefaultColumnMapping()); err == nil {
+		t.Error("expected error for a missing title column")
+	}
+	if _, err := ParseCSV(strings.NewReader("Name,Link\n"), mapping); err == nil {
+		t.Error("expected error for a missing mapped column")
+	}
+	if _, err := ParseCSV(strings.NewReader(""), mapping); err == nil {
+		t.Error("expected error for an empty file")
+	}
+}
@@ -0,0 +1,240 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+// ReadingLogFormat describes the CSV export of a reading tracker, by the names its columns can have
+type ReadingLogFormat struct {
+	// the name of the folder the books are imported into
+	Name              model.Title
+	title             []string
+	authors           []string
+	additionalAuthors []string
+	// in order of preference
+	isbns []string
+	// the shelf that tells the reading status of the book, e.g. to-read
+	shelf []string
+	// the other shelves of the book, separated by commas
+	shelves []string
+	rating  []string
+	review  []string
+}
+
+// The library export of Goodreads (My Books → Import and export)
+var Goodreads = ReadingLogFormat{
+	Name:              "Goodreads",
+	title:             []string{"Title"},
+	authors:           []string{"Author"},
+	additionalAuthors: []string{"Additional Authors"},
+	isbns:             []string{"ISBN13", "ISBN"},
+	shelf:             []string{"Exclusive Shelf"},
+	shelves:           []string{"Bookshelves"},
+	rating:            []string{"My Rating"},
+	review:            []string{"My Review"},
+}
+
+// The reading log export of Open Library (Settings → Import and Export)
+var OpenLibrary = ReadingLogFormat{
+	Name:    "Open Library",
+	title:   []string{"Title"},
+	authors: []string{"Author(s)", "Authors", "Author"},
+	isbns:   []string{"ISBN 13", "ISBN13", "ISBN 10", "ISBN10", "ISBN"},
+	shelf:   []string{"Bookshelf", "Reading Log", "Shelf"},
+	rating:  []string{"My Ratings", "My Rating", "Rating"},
+}
+
+// Returns the format with the given name: goodreads or openlibrary
+func NewReadingLogFormat(val string) (ReadingLogFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "goodreads":
+		return Goodreads, nil
+	case "openlibrary", "open-library":
+		return OpenLibrary, nil
+	default:
+		return ReadingLogFormat{}, fmt.Errorf("unknown reading log format %q (expected goodreads or openlibrary)", val)
+	}
+}
+
+// ShelfMapping tells what the reading status shelf of a book (e.g. to-read) becomes
+type ShelfMapping string
+
+const (
+	// each reading status shelf becomes a category, e.g. Goodreads / to-read
+	ShelvesAsCategories ShelfMapping = "categories"
+	// all books go into one category, tagged with their reading status
+	ShelvesAsTags ShelfMapping = "tags"
+)
+
+func NewShelfMapping(val string) (ShelfMapping, error) {
+	switch mapping := ShelfMapping(strings.ToLower(strings.TrimSpace(val))); mapping {
+	case ShelvesAsCategories, ShelvesAsTags:
+		return mapping, nil
+	default:
+		return "", fmt.Errorf("unknown shelf mapping %q (expected categories or tags)", val)
+	}
+}
+
+type ReadingLogOptions struct {
+	Shelves ShelfMapping
+	// books rated with at least this many stars (out of 5) are starred; with 0, none are
+	StarThreshold int
+}
+
+/*
+ParseReadingLog parses the CSV export of a reading tracker into a folder of books named after the format:
+  - the title gets the authors appended as "(by Author and Other Author)", like books added by hand
+  - the ISBN-13 is used if there is one, otherwise the ISBN-10, and books without an ISBN (e.g. e-books) are rejected
+  - the reading status shelf becomes a subfolder or a tag, as the options say, and the other shelves become tags
+  - the rating stars the book if it reaches the threshold, and the review becomes the description
+*/
+func ParseReadingLog(r io.Reader, format ReadingLogFormat, options ReadingLogOptions) ([]model.ImportFolder, error) {
+	reader, columns, err := readCSVHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := findColumn(columns, format.title); !ok {
+		return nil, fmt.Errorf("no title column found (is this a %s export?)", format.Name)
+	}
+	if _, ok := findColumn(columns, format.isbns); !ok {
+		return nil, fmt.Errorf("no ISBN column found (is this a %s export?)", format.Name)
+	}
+
+	root := &csvFolder{name: format.Name}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		value := func(names []string) string {
+			if index, ok := findColumn(columns, names); ok && index < len(record) {
+				return strings.TrimSpace(record[index])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		shelf := value(format.shelf)
+		folder := root
+		if options.Shelves == ShelvesAsCategories && shelf != "" {
+			if name, err := model.NewTitle(model.TruncateTitle(shelf)); err == nil {
+				folder = root.folder(name)
+			}
+		}
+		ref, err := parseReadingLogBook(format, options, value, shelf)
+		if err != nil {
+			title := value(format.title)
+			if title == "" {
+				title = fmt.Sprintf("line %d", line)
+			}
+			folder.rejected = append(folder.rejected, model.RejectedEntry{Title: model.TruncateTitle(title), Reason: fmt.Sprintf("line %d: %v", line, err)})
+			continue
+		}
+		folder.refs = append(folder.refs, ref)
+	}
+	return []model.ImportFolder{root.toImportFolder()}, nil
+}
+
+func findColumn(columns map[string]int, names []string) (int, bool) {
+	for _, name := range names {
+		if index, ok := columns[strings.ToLower(name)]; ok {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+var reviewLineBreaks = regexp.MustCompile(`(?i)<br\s*/?>`)
+
+func parseReadingLogBook(format ReadingLogFormat, options ReadingLogOptions, value func([]string) string, shelf string) (model.Reference, error) {
+	title := value(format.title)
+	if title == "" {
+		return nil, errors.New("title cannot be empty")
+	}
+	var authors []string
+	// Open Library lists all authors in one column, Goodreads lists the others in a second one
+	for _, author := range strings.Split(value(format.authors)+","+value(format.additionalAuthors), ",") {
+		if author = strings.Join(strings.Fields(author), " "); author != "" {
+			authors = append(authors, author)
+		}
+	}
+	title = model.TitleWithAuthors(title, authors)
+
+	isbn, err := readingLogISBN(format, value)
+	if err != nil {
+		return nil, err
+	}
+
+	starred := false
+	if rating := value(format.rating); rating != "" && options.StarThreshold > 0 {
+		stars, err := strconv.ParseFloat(rating, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rating %q", rating)
+		}
+		starred = stars >= float64(options.StarThreshold)
+	}
+
+	var tags []model.Tag
+	shelves := strings.Split(value(format.shelves), ",")
+	if options.Shelves == ShelvesAsTags {
+		shelves = append([]string{shelf}, shelves...)
+	}
+	for _, name := range shelves {
+		name = strings.TrimSpace(name)
+		// shelves have names like "Want to Read", which become tags like want-to-read
+		tag, err := model.NewTag(strings.Join(strings.Fields(name), "-"))
+		if err == nil && !containsTag(tags, tag) && !(options.Shelves == ShelvesAsCategories && strings.EqualFold(name, shelf)) {
+			tags = append(tags, tag)
+		}
+	}
+
+	description := strings.TrimSpace(reviewLineBreaks.ReplaceAllString(value(format.review), "\n"))
+	book := model.NewBookReference(0, model.Title(model.TruncateTitle(title)), isbn, description, starred)
+	book.SetTags(tags)
+	return book, nil
+}
+
+// Goodreads writes ISBNs as formulas like ="9780735611313", so that spreadsheets keep them as text.
+// The columns are tried in order, so a mistyped ISBN-13 falls back to the ISBN-10; the first error is returned if none is valid
+func readingLogISBN(format ReadingLogFormat, value func([]string) string) (model.ISBN, error) {
+	var firstErr error
+	for _, name := range format.isbns {
+		val := strings.Trim(strings.TrimPrefix(value([]string{name}), "="), `"`)
+		if val == "" {
+			continue
+		}
+		isbn, err := model.NewISBN(val)
+		if err == nil {
+			return isbn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return "", errors.New("no ISBN (books are identified by their ISBN)")
+}
+
+func containsTag(tags []model.Tag, tag model.Tag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
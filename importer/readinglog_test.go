@@ -0,0 +1,107 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+const goodreadsExport = `Book Id,Title,Author,Author l-f,Additional Authors,ISBN,ISBN13,My Rating,Average Rating,Bookshelves,Exclusive Shelf,My Review
+1,Code,Charles Petzold,"Petzold, Charles",,"=""0735611319""","=""9780735611313""",5,4.4,"favorites, read",read,Great<br/>book
+2,Operating Systems: Three Easy Pieces,Remzi H. Arpaci-Dusseau,"Arpaci-Dusseau, Remzi H.",Andrea C. Arpaci-Dusseau,"=""198508659X""","=""""",3,4.6,,read,
+3,Designing Data-Intensive Applications,Martin Kleppmann,"Kleppmann, Martin",,"=""""","=""9781449373320""",0,4.7,to-read,to-read,
+4,Some Kindle Book,Someone,"Someone",,"=""""","=""""",4,3.9,,read,
+`
+
+func TestParseGoodreadsWithShelvesAsCategories(t *testing.T) {
+	folders, err := ParseReadingLog(strings.NewReader(goodreadsExport), Goodreads, ReadingLogOptions{Shelves: ShelvesAsCategories, StarThreshold: 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(folders) != 1 || folders[0].Name != "Goodreads" || len(folders[0].Folders) != 2 {
+		t.Fatalf("expected the Goodreads folder with a folder per shelf, got %+v", folders)
+	}
+	read, toRead := folders[0].Folders[0], folders[0].Folders[1]
+	if read.Name != "read" || toRead.Name != "to-read" {
+		t.Errorf("unexpected shelf folders %q, %q", read.Name, toRead.Name)
+	}
+
+	if len(read.References) != 2 {
+		t.Fatalf("expected 2 books on the read shelf, got %+v", read.References)
+	}
+	code := read.References[0].(model.BookReference)
+	if code.Title() != "Code (by Charles Petzold)" || code.ISBN != "9780735611313" || !code.Starred() || code.Description != "Great\nbook" {
+		t.Errorf("unexpected book %+v", code)
+	}
+	if tags := code.Tags(); len(tags) != 1 || tags[0] != "favorites" {
+		t.Errorf("expected the other shelves as tags, got %v", tags)
+	}
+	ostep := read.References[1].(model.BookReference)
+	if ostep.Title() != "Operating Systems: Three Easy Pieces (by Remzi H. Arpaci-Dusseau and Andrea C. Arpaci-Dusseau)" || ostep.ISBN != "9781985086593" || ostep.Starred() {
+		t.Errorf("expected the ISBN-10 to be used without an ISBN-13 and a rating below the threshold not to star, got %+v", ostep)
+	}
+	if len(read.Rejected) != 1 || !strings.Contains(read.Rejected[0].Reason, "no ISBN") {
+		t.Errorf("expected the book without an ISBN to be rejected, got %+v", read.Rejected)
+	}
+	if ddia := toRead.References[0]; ddia.Starred() || len(ddia.Tags()) != 0 {
+		t.Errorf("expected an unrated book without other shelves, got %+v", ddia)
+	}
+}
+
+func TestParseGoodreadsWithShelvesAsTags(t *testing.T) {
+	folders, err := ParseReadingLog(strings.NewReader(goodreadsExport), Goodreads, ReadingLogOptions{Shelves: ShelvesAsTags})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(folders) != 1 || len(folders[0].Folders) != 0 || len(folders[0].References) != 3 {
+		t.Fatalf("expected all books in one folder, got %+v", folders)
+	}
+	code := folders[0].References[0]
+	if tags := code.Tags(); len(tags) != 2 || tags[0] != "read" || tags[1] != "favorites" || code.Starred() {
+		t.Errorf("expected the shelves as tags and no stars without a threshold, got %+v", code)
+	}
+}
+
+func TestParseGoodreadsFallsBackToAValidISBN(t *testing.T) {
+	input := `Book Id,Title,Author,ISBN,ISBN13,My Rating,Bookshelves,Exclusive Shelf
+1,Code,Charles Petzold,"=""0735611319""","=""9780735611314""",0,,read
+2,Typos,Someone,"=""0735611310""","=""9780735611314""",0,,read
+`
+	folders, err := ParseReadingLog(strings.NewReader(input), Goodreads, ReadingLogOptions{Shelves: ShelvesAsTags})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(folders) != 1 || len(folders[0].References) != 1 {
+		t.Fatalf("expected one book, got %+v", folders)
+	}
+	if code := folders[0].References[0].(model.BookReference); code.ISBN != "9780735611313" {
+		t.Errorf("expected the ISBN-10 to be used when the ISBN-13 is invalid, got %+v", code)
+	}
+	if len(folders[0].Rejected) != 1 || strings.Contains(folders[0].Rejected[0].Reason, "no ISBN") {
+		t.Errorf("expected the book without a valid ISBN to be rejected for its invalid ISBN, got %+v", folders[0].Rejected)
+	}
+}
+
+func TestParseOpenLibraryReadingLog(t *testing.T) {
+	input := "Work Id,Edition Id,Title,Author(s),ISBN 13,Bookshelf,My Ratings\n" +
+		"OL1W,OL1M,The Pragmatic Programmer,\"Andrew Hunt, David Thomas\",9780201616224,Want to Read,\n"
+	folders, err := ParseReadingLog(strings.NewReader(input), OpenLibrary, ReadingLogOptions{Shelves: ShelvesAsTags, StarThreshold: 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	book := folders[0].References[0]
+	if book.Title() != "The Pragmatic Programmer (by Andrew Hunt and David Thomas)" || len(book.Tags()) != 1 || book.Tags()[0] != "want-to-read" {
+		t.Errorf("unexpected book %+v", book)
+	}
+
+	if _, err := ParseReadingLog(strings.NewReader("Work Id,Title\nOL1W,Book\n"), OpenLibrary, ReadingLogOptions{}); err == nil {
+		t.Error("expected error for an export without ISBNs")
+	}
+	if _, err := NewReadingLogFormat("librarything"); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+	if _, err := NewShelfMapping("folders"); err == nil {
+		t.Error("expected error for an unknown shelf mapping")
+	}
+}
@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+)
+
+var risTypes = map[string]entryKind{
+	"BOOK": bookEntry, "EBOOK": bookEntry, "EDBOOK": bookEntry, "CHAP": bookEntry,
+	"ELEC": webPageEntry, "WEB": webPageEntry, "BLOG": webPageEntry, "ICOMM": webPageEntry,
+	"GEN": genericEntry,
+}
+
+// a tag line like "TI  - Title"; the space after the dash is missing on empty lines like "ER  -"
+var risLine = regexp.MustCompile(`^([A-Z][A-Z0-9])  -(?: (.*))?$`)
+
+/*
+ParseRIS parses a RIS file, as exported by Zotero, Mendeley and most other reference managers, into a folder with the
+given name:
+  - books (BOOK, CHAP, ...) need an ISBN (SN) and get their authors (AU) appended to the title
+  - web pages (ELEC, WEB, ...) become links, and so do other records (e.g. JOUR) with a URL (UR) or DOI (DO)
+  - generic records (GEN) without an ISBN or URL become notes, with the note (N1) or abstract (AB) as the text
+  - keywords (KW) become tags
+*/
+func ParseRIS(r io.Reader, name model.Title) ([]model.ImportFolder, error) {
+	var entries []bibliographyEntry
+	var entry *bibliographyEntry
+	// the field that lines without a tag continue
+	var last *string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), "\r")
+		match := risLine.FindStringSubmatch(line)
+		if match == nil {
+			if last != nil {
+				*last += "\n" + line
+			}
+			continue
+		}
+		tag, value := match[1], strings.TrimSpace(match[2])
+		if tag == "TY" {
+			kind, ok := risTypes[value]
+			if !ok {
+				kind = otherEntry
+			}
+			entry, last = &bibliographyEntry{kind: kind, typeName: value}, nil
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+		last = nil
+		switch tag {
+		case "ER":
+			entries = append(entries, *entry)
+			entry = nil
+		case "TI", "T1":
+			if entry.title == "" {
+				entry.title = value
+				last = &entry.title
+			}
+		case "AU", "A1":
+			// authors are written "Surname, Given names"
+			if surname, given, ok := strings.Cut(value, ","); ok {
+				value = strings.TrimSpace(given) + " " + strings.TrimSpace(surname)
+			}
+			entry.authors = append(entry.authors, value)
+		case "SN":
+			entry.isbns = append(entry.isbns, value)
+		case "UR":
+			if entry.url == "" {
+				entry.url = value
+			}
+		case "DO":
+			entry.doi = value
+		case "AB", "N2":
+			entry.abstract = value
+			last = &entry.abstract
+		case "N1":
+			entry.note = value
+			last = &entry.note
+		case "KW":
+			entry.keywords = append(entry.keywords, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading RIS: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no RIS records found (is this a RIS file?)")
+	}
+	for i := range entries {
+		entries[i].abstract = strings.TrimSpace(entries[i].abstract)
+		entries[i].note = strings.TrimSpace(entries[i].note)
+	}
+	return []model.ImportFolder{bibliographyFolder(name, entries)}, nil
+}
@@ -1,32 +1,88 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"log"
+	"time"
 
 	"github.com/VladMinzatu/reference-manager/adapters"
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/port"
 	"github.com/VladMinzatu/reference-manager/domain/service"
 	"github.com/VladMinzatu/reference-manager/web"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
-	db, err := sql.Open("sqlite3", "db/backup/vlad.db")
+	linkCheckInterval := flag.Duration("link-check-interval", time.Hour, "how often to look for links that are due for a check, 0 disables the link checker")
+	linkCheckMaxAge := flag.Duration("link-check-max-age", 7*24*time.Hour, "how long a link check is good for before the link is checked again")
+	linkCheckConcurrency := flag.Int("link-check-concurrency", 4, "number of links to check at the same time")
+	linkCheckHostInterval := flag.Duration("link-check-host-interval", adapters.DefaultHostInterval, "minimum time between requests to the same host")
+	blobDir := flag.String("blob-dir", "", "directory to keep archived pages and attachments in (by default they are kept in the database)")
+	openLibraryURL := flag.String("openlibrary-url", adapters.DefaultOpenLibraryURL, "base URL of the Open Library API, to look up books by ISBN")
+	coverURL := flag.String("cover-url", adapters.DefaultCoverURL, "URL of the covers of books by ISBN, with {isbn} in place of the ISBN")
+	maxAttachmentSize := flag.Int64("max-attachment-size", model.DefaultMaxAttachmentSize, "largest file that can be attached, in bytes")
+	flag.Parse()
+
+	// foreign keys are enabled in the DSN, so that every pooled connection enforces them, not just the first
+	db, err := sql.Open("sqlite3", "db/backup/vlad.db?_foreign_keys=on")
 	if err != nil {
 		log.Fatal("Failed to open database:", err)
 	}
 
-	// Enable foreign key constraints
-	_, err = db.Exec("PRAGMA foreign_keys = ON")
-	if err != nil {
-		log.Fatal("Failed to enable foreign keys:", err)
+	var blobStore port.BlobStore = adapters.NewSQLiteBlobStore(db)
+	if *blobDir != "" {
+		blobStore = adapters.NewFileBlobStore(*blobDir)
+	}
+	blobCollector := service.NewBlobCollector(adapters.NewSQLiteOrphanedBlobRepository(db), blobStore)
+	// content is collected as it's removed; this catches up on collections that failed or were interrupted
+	if _, err := blobCollector.Collect(); err != nil {
+		log.Println("Failed to delete the content of removed references:", err)
 	}
 
 	categoryRepo := adapters.NewSQLiteCategoryRepository(db)
-	categoryService := service.NewCategoryService(categoryRepo)
+	categoryService := service.NewCategoryService(categoryRepo, blobCollector)
 	categoryListRepository := adapters.NewSQLiteCategoryListRepository(db)
 	referenceRepo := adapters.NewSQLiteReferencesRepository(db)
 
-	handler := web.NewHandler(categoryService, categoryListRepository, referenceRepo)
+	referenceService := service.NewReferenceService(referenceRepo, blobCollector)
+	highlightService := service.NewHighlightService(adapters.NewSQLiteHighlightRepository(db))
+	relationRepo := adapters.NewSQLiteRelationRepository(db)
+	relationService := service.NewRelationService(relationRepo, referenceRepo, categoryRepo)
+	libraryService := service.NewLibraryService(categoryListRepository, referenceRepo, relationRepo)
+	importService := service.NewImportService(categoryListRepository, referenceRepo, adapters.NewSQLiteImportRepository(db))
+	metadataService := service.NewMetadataService(adapters.NewOpenLibraryProvider(*openLibraryURL), adapters.NewHTTPPageFetcher(adapters.DefaultPageFetchTimeout, adapters.DefaultMaxPageSize))
+
+	linkChecker := adapters.NewHTTPLinkChecker(adapters.DefaultLinkCheckTimeout, *linkCheckHostInterval)
+	linkCheckService := service.NewLinkCheckService(adapters.NewSQLiteLinkHealthRepository(db), linkChecker)
+	if *linkCheckInterval > 0 {
+		go linkCheckService.Run(context.Background(), *linkCheckInterval, service.LinkCheckOptions{Concurrency: *linkCheckConcurrency, MaxAge: *linkCheckMaxAge})
+	}
+
+	archiver := adapters.NewHTTPPageArchiver(adapters.DefaultArchiveTimeout, adapters.DefaultMaxArchiveSize, adapters.DefaultMaxAssetsSize)
+	archiveService := service.NewArchiveService(referenceRepo, adapters.NewSQLiteSnapshotRepository(db), blobStore, blobCollector, archiver)
+
+	attachmentService := service.NewAttachmentService(adapters.NewSQLiteAttachmentRepository(db), blobStore, blobCollector, *maxAttachmentSize)
+	coverProvider := adapters.NewHTTPCoverProvider(*coverURL, adapters.DefaultCoverFetchTimeout, model.MaxCoverSize)
+	coverService := service.NewCoverService(referenceRepo, adapters.NewSQLiteCoverRepository(db), blobStore, blobCollector, adapters.NewStdlibImageProcessor(adapters.DefaultMaxImagePixels), coverProvider)
+
+	handler := web.NewHandler(web.HandlerDeps{
+		CategoryService:        categoryService,
+		CategoryListRepository: categoryListRepository,
+		ReferenceRepo:          referenceRepo,
+		ReferenceService:       referenceService,
+		HighlightService:       highlightService,
+		RelationService:        relationService,
+		LibraryService:         libraryService,
+		ImportService:          importService,
+		MetadataService:        metadataService,
+		LinkCheckService:       linkCheckService,
+		ArchiveService:         archiveService,
+		AttachmentService:      attachmentService,
+		CoverService:           coverService,
+		BlobCollector:          blobCollector,
+	})
 	web.StartServer(handler)
 }
@@ -0,0 +1,152 @@
+// Package markdown renders the Markdown of note texts and descriptions, as sanitized HTML for the web UI and as plain text for the CLI.
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+var (
+	// GFM adds tables, strikethrough, task lists and autolinks. Raw HTML in the source is dropped by goldmark's default (safe) renderer.
+	md = goldmark.New(goldmark.WithExtensions(extension.GFM))
+	// The output is sanitized on top of that, since links can still carry e.g. javascript: URLs
+	policy = bluemonday.UGCPolicy().AddTargetBlankToFullyQualifiedLinks(true)
+)
+
+// ToHTML renders Markdown as HTML that is safe to embed in a page
+func ToHTML(source string) template.HTML {
+	if strings.TrimSpace(source) == "" {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(source), &buf); err != nil {
+		// goldmark only fails on writer errors, which a bytes.Buffer doesn't have, but fall back to escaped text just in case
+		return template.HTML(template.HTMLEscapeString(source))
+	}
+	return template.HTML(policy.SanitizeBytes(buf.Bytes()))
+}
+
+// ToPlainText renders Markdown as text for the terminal: markup is dropped, list items get a "- " (or "1. ") marker,
+// code blocks are indented and links are followed by their URL in parentheses.
+func ToPlainText(source string) string {
+	src := []byte(source)
+	doc := md.Parser().Parse(text.NewReader(src))
+	var buf strings.Builder
+	writeBlocks(&buf, doc, src, "")
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+func writeBlocks(buf *strings.Builder, parent ast.Node, src []byte, indent string) {
+	for node := parent.FirstChild(); node != nil; node = node.NextSibling() {
+		switch n := node.(type) {
+		case *ast.Heading, *ast.Paragraph, *ast.TextBlock:
+			writeLine(buf, indent, inlineText(n, src))
+			if _, tight := n.(*ast.TextBlock); !tight {
+				buf.WriteString("\n")
+			}
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				line := lines.At(i)
+				buf.WriteString(indent + "    " + strings.TrimRight(string(line.Value(src)), "\n") + "\n")
+			}
+			buf.WriteString("\n")
+		case *ast.List:
+			number := n.Start
+			for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+				marker := "- "
+				if n.IsOrdered() {
+					marker = strconv.Itoa(number) + ". "
+					number++
+				}
+				var itemBuf strings.Builder
+				writeBlocks(&itemBuf, item, src, "")
+				lines := strings.Split(strings.TrimRight(itemBuf.String(), "\n"), "\n")
+				for i, line := range lines {
+					prefix := strings.Repeat(" ", len(marker))
+					if i == 0 {
+						prefix = marker
+					}
+					writeLine(buf, indent, prefix+line)
+				}
+			}
+			buf.WriteString("\n")
+		case *ast.Blockquote:
+			var quoteBuf strings.Builder
+			writeBlocks(&quoteBuf, n, src, "")
+			for _, line := range strings.Split(strings.TrimRight(quoteBuf.String(), "\n"), "\n") {
+				writeLine(buf, indent, strings.TrimRight("> "+line, " "))
+			}
+			buf.WriteString("\n")
+		case *east.Table:
+			for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+				var cells []string
+				for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+					cells = append(cells, inlineText(cell, src))
+				}
+				writeLine(buf, indent, strings.Join(cells, " | "))
+			}
+			buf.WriteString("\n")
+		case *ast.ThematicBreak:
+			writeLine(buf, indent, "---")
+			buf.WriteString("\n")
+		case *ast.HTMLBlock:
+			// raw HTML is not shown in the web UI either
+		default:
+			writeBlocks(buf, n, src, indent)
+		}
+	}
+}
+
+func writeLine(buf *strings.Builder, indent, line string) {
+	if line == "" {
+		buf.WriteString("\n")
+		return
+	}
+	buf.WriteString(indent + line + "\n")
+}
+
+func inlineText(parent ast.Node, src []byte) string {
+	var buf strings.Builder
+	for node := parent.FirstChild(); node != nil; node = node.NextSibling() {
+		switch n := node.(type) {
+		case *ast.Text:
+			buf.Write(n.Segment.Value(src))
+			if n.HardLineBreak() || n.SoftLineBreak() {
+				buf.WriteString("\n")
+			}
+		case *ast.String:
+			buf.Write(n.Value)
+		case *ast.Link:
+			label := inlineText(n, src)
+			buf.WriteString(label)
+			if url := string(n.Destination); url != label {
+				buf.WriteString(" (" + url + ")")
+			}
+		case *ast.AutoLink:
+			buf.Write(n.URL(src))
+		case *ast.Image:
+			buf.WriteString(inlineText(n, src))
+		case *ast.RawHTML:
+			// dropped, as in the HTML output
+		case *east.TaskCheckBox:
+			if n.IsChecked {
+				buf.WriteString("[x] ")
+			} else {
+				buf.WriteString("[ ] ")
+			}
+		default:
+			buf.WriteString(inlineText(n, src))
+		}
+	}
+	return buf.String()
+}
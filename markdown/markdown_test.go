@@ -0,0 +1,38 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToHTML(t *testing.T) {
+	html := string(ToHTML("# Title\n\nSome *emphasis* and a [link](https://example.com).\n\n- one\n- two\n\n```go\nfmt.Println()\n```"))
+	for _, expected := range []string{"<h1", "<em>emphasis</em>", `href="https://example.com"`, "<li>one</li>", "<code", "fmt.Println()"} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected %q in %q", expected, html)
+		}
+	}
+}
+
+func TestToHTMLIsSanitized(t *testing.T) {
+	html := string(ToHTML("<script>alert(1)</script>\n\n[click](javascript:alert(1)) <img src=x onerror=alert(1)>"))
+	for _, unexpected := range []string{"<script", "javascript:", "onerror"} {
+		if strings.Contains(html, unexpected) {
+			t.Errorf("expected %q to be removed from %q", unexpected, html)
+		}
+	}
+	if ToHTML("  ") != "" {
+		t.Error("expected empty output for blank input")
+	}
+}
+
+func TestToPlainText(t *testing.T) {
+	source := "# Title\n\nSome *emphasis* and a [link](https://example.com).\n\n1. one\n2. two\n   - nested\n\n> quoted\n\n```\ncode\n```\n\n| a | b |\n|---|---|\n| 1 | 2 |"
+	expected := "Title\n\nSome emphasis and a link (https://example.com).\n\n1. one\n2. two\n   - nested\n\n> quoted\n\n    code\n\na | b\n1 | 2"
+	if got := ToPlainText(source); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if got := ToPlainText("plain text"); got != "plain text" {
+		t.Errorf("expected plain text to be unchanged, got %q", got)
+	}
+}
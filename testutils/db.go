@@ -10,11 +10,8 @@ import (
 )
 
 func SetupTestDB(t *testing.T) (*sql.DB, func()) {
-	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
-	require.NoError(t, err)
-
-	// Enable foreign key constraints
-	_, err = db.Exec("PRAGMA foreign_keys = ON")
+	// foreign keys are enabled in the DSN, so that every pooled connection enforces them
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared&_foreign_keys=on")
 	require.NoError(t, err)
 
 	goose.SetDialect("sqlite3")
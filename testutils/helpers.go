@@ -5,11 +5,18 @@ import (
 	"testing"
 
 	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/domain/util"
 	"github.com/stretchr/testify/require"
 )
 
 func CreateTestCategory(t *testing.T, db *sql.DB, name string) (model.Id, model.Version) {
-	res, err := db.Exec(`INSERT INTO categories (name, position, version) VALUES (?, 0, 1)`, name)
+	var lastKey string
+	err := db.QueryRow(`SELECT COALESCE(MAX(sort_key), '') FROM categories WHERE parent_id IS NULL`).Scan(&lastKey)
+	require.NoError(t, err)
+	sortKey, err := util.SortKeyBetween(lastKey, "")
+	require.NoError(t, err)
+
+	res, err := db.Exec(`INSERT INTO categories (name, sort_key, version) VALUES (?, ?, 1)`, name, sortKey)
 	require.NoError(t, err)
 	id, err := res.LastInsertId()
 	require.NoError(t, err)
@@ -18,52 +25,43 @@ func CreateTestCategory(t *testing.T, db *sql.DB, name string) (model.Id, model.
 }
 
 func CreateTestBookReference(t *testing.T, db *sql.DB, categoryId model.Id, title, isbn, description string, starred bool) model.Id {
-	// Get the next position for this category
-	var position int
-	err := db.QueryRow(`SELECT COALESCE(MAX(position) + 1, 0) FROM base_references WHERE category_id = ?`, categoryId).Scan(&position)
-	require.NoError(t, err)
+	refId := createTestBaseReference(t, db, categoryId, title, starred)
 
-	res, err := db.Exec(`INSERT INTO base_references (category_id, title, position, is_starred) VALUES (?, ?, ?, ?)`, categoryId, title, position, starred)
-	require.NoError(t, err)
-	refId, err := res.LastInsertId()
+	_, err := db.Exec(`INSERT INTO book_references (reference_id, isbn, description) VALUES (?, ?, ?)`, int64(refId), isbn, description)
 	require.NoError(t, err)
 
-	_, err = db.Exec(`INSERT INTO book_references (reference_id, isbn, description) VALUES (?, ?, ?)`, refId, isbn, description)
-	require.NoError(t, err)
-
-	return model.Id(refId)
+	return refId
 }
 
 func CreateTestLinkReference(t *testing.T, db *sql.DB, categoryId model.Id, title, url, description string, starred bool) model.Id {
-	// Get the next position for this category
-	var position int
-	err := db.QueryRow(`SELECT COALESCE(MAX(position) + 1, 0) FROM base_references WHERE category_id = ?`, categoryId).Scan(&position)
-	require.NoError(t, err)
-
-	res, err := db.Exec(`INSERT INTO base_references (category_id, title, position, is_starred) VALUES (?, ?, ?, ?)`, categoryId, title, position, starred)
-	require.NoError(t, err)
-	refId, err := res.LastInsertId()
-	require.NoError(t, err)
+	refId := createTestBaseReference(t, db, categoryId, title, starred)
 
-	_, err = db.Exec(`INSERT INTO link_references (reference_id, url, description) VALUES (?, ?, ?)`, refId, url, description)
+	_, err := db.Exec(`INSERT INTO link_references (reference_id, url, description) VALUES (?, ?, ?)`, int64(refId), url, description)
 	require.NoError(t, err)
 
-	return model.Id(refId)
+	return refId
 }
 
 func CreateTestNoteReference(t *testing.T, db *sql.DB, categoryId model.Id, title, text string, starred bool) model.Id {
-	// Get the next position for this category
-	var position int
-	err := db.QueryRow(`SELECT COALESCE(MAX(position) + 1, 0) FROM base_references WHERE category_id = ?`, categoryId).Scan(&position)
+	refId := createTestBaseReference(t, db, categoryId, title, starred)
+
+	_, err := db.Exec(`INSERT INTO note_references (reference_id, text) VALUES (?, ?)`, int64(refId), text)
 	require.NoError(t, err)
 
-	res, err := db.Exec(`INSERT INTO base_references (category_id, title, position, is_starred) VALUES (?, ?, ?, ?)`, categoryId, title, position, starred)
+	return refId
+}
+
+// Appends a base reference at the end of the category
+func createTestBaseReference(t *testing.T, db *sql.DB, categoryId model.Id, title string, starred bool) model.Id {
+	var lastKey string
+	err := db.QueryRow(`SELECT COALESCE(MAX(sort_key), '') FROM base_references WHERE category_id = ?`, categoryId).Scan(&lastKey)
 	require.NoError(t, err)
-	refId, err := res.LastInsertId()
+	sortKey, err := util.SortKeyBetween(lastKey, "")
 	require.NoError(t, err)
 
-	_, err = db.Exec(`INSERT INTO note_references (reference_id, text) VALUES (?, ?)`, refId, text)
+	res, err := db.Exec(`INSERT INTO base_references (category_id, title, sort_key, is_starred) VALUES (?, ?, ?, ?)`, categoryId, title, sortKey, starred)
+	require.NoError(t, err)
+	refId, err := res.LastInsertId()
 	require.NoError(t, err)
-
 	return model.Id(refId)
 }
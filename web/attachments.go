@@ -0,0 +1,160 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/gin-gonic/gin"
+)
+
+// AttachmentsData is the attached files panel of the detail page of a reference
+type AttachmentsData struct {
+	ReferenceId model.Id
+	Attachments []AttachmentDTO
+	// the file types that can be attached, for the file picker
+	Accept  string
+	MaxSize string
+	// why the last upload failed
+	Error string
+}
+
+type AttachmentDTO struct {
+	Id          model.Id
+	FileName    model.FileName
+	MediaType   string
+	Size        string
+	ContentHash model.ContentHash
+	AddedAt     string
+}
+
+func (h *Handler) attachmentsData(referenceId model.Id) (*AttachmentsData, error) {
+	attachments, err := h.attachmentService.GetAttachments(referenceId)
+	if err != nil {
+		return nil, err
+	}
+	data := &AttachmentsData{
+		ReferenceId: referenceId,
+		Attachments: make([]AttachmentDTO, 0, len(attachments)),
+		Accept:      strings.Join(model.AttachmentExtensions(), ","),
+		MaxSize:     formatSize(h.attachmentService.MaxSize()),
+	}
+	for _, attachment := range attachments {
+		data.Attachments = append(data.Attachments, AttachmentDTO{
+			Id:          attachment.Id,
+			FileName:    attachment.FileName,
+			MediaType:   attachment.MediaType,
+			Size:        formatSize(attachment.Size),
+			ContentHash: attachment.ContentHash,
+			AddedAt:     attachment.AddedAt.Format("2006-01-02 15:04"),
+		})
+	}
+	return data, nil
+}
+
+func (h *Handler) renderAttachments(c *gin.Context, referenceId model.Id, uploadErr error) {
+	data, err := h.attachmentsData(referenceId)
+	if err != nil {
+		slog.Error("failed to load attachments", "error", err, "referenceId", referenceId)
+		c.String(http.StatusInternalServerError, "Failed to load attachments")
+		return
+	}
+	if uploadErr != nil {
+		data.Error = uploadErr.Error()
+	}
+	c.HTML(http.StatusOK, "_attachments", data)
+}
+
+// AddAttachment stores the uploaded file with the reference and returns the updated attachments panel, with the error
+// if the file was rejected
+func (h *Handler) AddAttachment(c *gin.Context) {
+	referenceId, ok := idParam(c, "Invalid reference id")
+	if !ok {
+		return
+	}
+	maxSize := h.attachmentService.MaxSize()
+	// leaves room for the rest of the multipart form, so that only the file decides whether the upload is too large
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+	fileHeader, err := c.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		h.renderAttachments(c, referenceId, fmt.Errorf("the file is too large (max %s)", formatSize(maxSize)))
+		return
+	}
+	if err != nil {
+		h.renderAttachments(c, referenceId, errors.New("choose a file to attach"))
+		return
+	}
+	if fileHeader.Size > maxSize {
+		h.renderAttachments(c, referenceId, fmt.Errorf("%s is too large (max %s)", fileHeader.Filename, formatSize(maxSize)))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		slog.Error("failed to open uploaded file", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		slog.Error("failed to read uploaded file", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+
+	_, err = h.attachmentService.AddAttachment(referenceId, fileHeader.Filename, data)
+	if err != nil {
+		slog.Error("failed to add attachment", "error", err, "referenceId", referenceId)
+	}
+	h.renderAttachments(c, referenceId, err)
+}
+
+/*
+Attachment downloads an attached file. Its content is checked against its hash before it's served, and the hash is
+sent along (as the ETag and the Repr-Digest of RFC 9530) so that clients can check the download too.
+*/
+func (h *Handler) Attachment(c *gin.Context) {
+	id, ok := idParam(c, "Invalid attachment id")
+	if !ok {
+		return
+	}
+	attachment, content, err := h.attachmentService.GetAttachmentContent(id)
+	if errors.Is(err, model.ErrCorruptContent) {
+		slog.Error("attachment is corrupted", "error", err, "id", id)
+		c.String(http.StatusInternalServerError, "The attachment is corrupted")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load attachment", "error", err, "id", id)
+		c.String(http.StatusNotFound, "Attachment not found")
+		return
+	}
+	digest, _ := hex.DecodeString(string(attachment.ContentHash))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": string(attachment.FileName)}))
+	c.Header("ETag", `"`+string(attachment.ContentHash)+`"`)
+	c.Header("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(digest)+":")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, attachment.MediaType, content)
+}
+
+func (h *Handler) DeleteAttachment(c *gin.Context) {
+	id, ok := idParam(c, "Invalid attachment id")
+	if !ok {
+		return
+	}
+	attachment, err := h.attachmentService.DeleteAttachment(id)
+	if err != nil {
+		slog.Error("failed to delete attachment", "error", err, "id", id)
+		c.String(http.StatusInternalServerError, "Failed to delete attachment")
+		return
+	}
+	h.renderAttachments(c, attachment.ReferenceId, nil)
+}
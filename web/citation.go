@@ -0,0 +1,28 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/citation"
+	"github.com/gin-gonic/gin"
+)
+
+// Citation returns the plain-text citation of a book or link in the style given by the style query parameter (apa by default)
+func (h *Handler) Citation(c *gin.Context) {
+	style, err := citation.NewStyle(c.DefaultQuery("style", string(citation.APA)))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid citation style")
+		return
+	}
+	ref, ok := h.loadReference(c)
+	if !ok {
+		return
+	}
+	text, err := citation.Cite(ref.Reference, style, time.Now())
+	if err != nil {
+		c.String(http.StatusBadRequest, "Failed to cite reference: %v", err)
+		return
+	}
+	c.String(http.StatusOK, text)
+}
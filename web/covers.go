@@ -0,0 +1,180 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/gin-gonic/gin"
+)
+
+// CoverData is the cover panel of the detail page of a book
+type CoverData struct {
+	ReferenceId model.Id
+	// the URLs of the cover and its thumbnail, empty if the book has none
+	ImageURL     string
+	ThumbnailURL string
+	// e.g. "JPEG, 600x900, 120.5 KB"
+	Info string
+	// covers can only be fetched by ISBN
+	HasISBN bool
+	// why the last attempt to set the cover failed
+	Error string
+}
+
+// Returns the URL of the cover image or its thumbnail. The URL changes with the cover, so browsers don't show a
+// cached image of the one it replaced.
+func coverURL(cover model.BookCover, thumbnail bool) string {
+	path := fmt.Sprintf("/books/%d/cover", cover.ReferenceId)
+	hash := cover.ImageHash
+	if thumbnail {
+		path += "/thumbnail"
+		hash = cover.ThumbnailHash
+	}
+	return path + "?v=" + string(hash[:12])
+}
+
+func (h *Handler) coverData(referenceId model.Id) (*CoverData, error) {
+	ref, err := h.referenceRepo.GetReferenceById(referenceId)
+	if err != nil {
+		return nil, err
+	}
+	book, ok := ref.Reference.(model.BookReference)
+	if !ok {
+		return nil, fmt.Errorf("reference %d is not a book", referenceId)
+	}
+	data := &CoverData{ReferenceId: referenceId, HasISBN: book.ISBN != ""}
+	cover, err := h.coverService.GetCover(referenceId)
+	if errors.Is(err, model.ErrNoCover) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data.ImageURL = coverURL(*cover, false)
+	data.ThumbnailURL = coverURL(*cover, true)
+	format := strings.ToUpper(strings.TrimPrefix(cover.MediaType, "image/"))
+	data.Info = fmt.Sprintf("%s, %dx%d, %s", format, cover.Width, cover.Height, formatSize(cover.Size))
+	return data, nil
+}
+
+func (h *Handler) renderCover(c *gin.Context, referenceId model.Id, coverErr error) {
+	data, err := h.coverData(referenceId)
+	if err != nil {
+		slog.Error("failed to load cover", "error", err, "referenceId", referenceId)
+		c.String(http.StatusInternalServerError, "Failed to load cover")
+		return
+	}
+	if coverErr != nil {
+		data.Error = coverErr.Error()
+	}
+	c.HTML(http.StatusOK, "_cover", data)
+}
+
+// UploadCover sets the uploaded image as the cover of the book and returns the updated cover panel, with the error if
+// the image was rejected
+func (h *Handler) UploadCover(c *gin.Context) {
+	referenceId, ok := idParam(c, "Invalid book id")
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, model.MaxCoverSize+1<<20)
+	fileHeader, err := c.FormFile("image")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || (err == nil && fileHeader.Size > model.MaxCoverSize) {
+		h.renderCover(c, referenceId, fmt.Errorf("the image is too large (max %s)", formatSize(model.MaxCoverSize)))
+		return
+	}
+	if err != nil {
+		h.renderCover(c, referenceId, errors.New("choose an image to upload"))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		slog.Error("failed to open uploaded file", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+	defer file.Close()
+	image, err := io.ReadAll(file)
+	if err != nil {
+		slog.Error("failed to read uploaded file", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+
+	_, err = h.coverService.SetCover(referenceId, image)
+	if err != nil {
+		slog.Error("failed to set cover", "error", err, "referenceId", referenceId)
+	}
+	h.renderCover(c, referenceId, err)
+}
+
+// FetchCover fetches the cover of the book by its ISBN and returns the updated cover panel, with the error if it failed
+func (h *Handler) FetchCover(c *gin.Context) {
+	referenceId, ok := idParam(c, "Invalid book id")
+	if !ok {
+		return
+	}
+	_, err := h.coverService.FetchCover(referenceId)
+	if err != nil {
+		slog.Error("failed to fetch cover", "error", err, "referenceId", referenceId)
+	}
+	h.renderCover(c, referenceId, err)
+}
+
+func (h *Handler) RemoveCover(c *gin.Context) {
+	referenceId, ok := idParam(c, "Invalid book id")
+	if !ok {
+		return
+	}
+	if err := h.coverService.RemoveCover(referenceId); err != nil {
+		slog.Error("failed to remove cover", "error", err, "referenceId", referenceId)
+		c.String(http.StatusInternalServerError, "Failed to remove cover")
+		return
+	}
+	h.renderCover(c, referenceId, nil)
+}
+
+func (h *Handler) CoverImage(c *gin.Context) {
+	h.serveCover(c, false)
+}
+
+func (h *Handler) CoverThumbnail(c *gin.Context) {
+	h.serveCover(c, true)
+}
+
+// Serves the cover or its thumbnail, tagged with its hash so that browsers can revalidate their cached copy cheaply
+func (h *Handler) serveCover(c *gin.Context, thumbnail bool) {
+	referenceId, ok := idParam(c, "Invalid book id")
+	if !ok {
+		return
+	}
+	cover, image, err := h.coverService.GetCoverImage(referenceId, thumbnail)
+	if errors.Is(err, model.ErrNoCover) {
+		c.String(http.StatusNotFound, "Cover not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load cover", "error", err, "referenceId", referenceId)
+		c.String(http.StatusInternalServerError, "Failed to load cover")
+		return
+	}
+	mediaType, hash := cover.MediaType, cover.ImageHash
+	if thumbnail {
+		mediaType, hash = model.ThumbnailMediaType, cover.ThumbnailHash
+	}
+	etag := `"` + string(hash) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Content-Type-Options", "nosniff")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, mediaType, image)
+}
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/export"
+	"github.com/gin-gonic/gin"
+)
+
+var graphContentTypes = map[string]string{
+	"dot":  "text/vnd.graphviz; charset=utf-8",
+	"json": "application/json; charset=utf-8",
+}
+
+// ExportGraph downloads the graph of the library in the format given by the format query parameter (dot by default)
+func (h *Handler) ExportGraph(c *gin.Context) {
+	format := c.DefaultQuery("format", "dot")
+	contentType, ok := graphContentTypes[format]
+	if !ok {
+		c.String(http.StatusBadRequest, "Invalid format")
+		return
+	}
+	library, err := h.libraryService.GetLibrary()
+	if err != nil {
+		slog.Error("failed to load library", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to export graph")
+		return
+	}
+	var buf bytes.Buffer
+	if err := export.WriteGraph(&buf, export.BuildGraph(*library), format); err != nil {
+		slog.Error("failed to export graph", "error", err, "format", format)
+		c.String(http.StatusInternalServerError, "Failed to export graph")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="library.%s"`, format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+type referencesFormat struct {
+	write       func(io.Writer, model.Library, model.Id) error
+	contentType string
+	extension   string
+}
+
+var referencesFormats = map[string]referencesFormat{
+	"csv":     {export.WriteCSV, "text/csv; charset=utf-8", "csv"},
+	"ris":     {export.WriteRIS, "application/x-research-info-systems; charset=utf-8", "ris"},
+	"csljson": {export.WriteCSLJSON, "application/vnd.citationstyles.csl+json; charset=utf-8", "json"},
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^\p{L}\p{N} ._-]+`)
+
+// ExportReferences downloads the references in the format given by the format query parameter (csv, ris or csljson),
+// of the whole library or of the category given by the category query parameter. The file is named after the
+// category, which is the name the category gets when the file is imported again.
+func (h *Handler) ExportReferences(c *gin.Context) {
+	format, ok := referencesFormats[c.Query("format")]
+	if !ok {
+		c.String(http.StatusBadRequest, "Invalid format")
+		return
+	}
+	var categoryId model.Id
+	if categoryStr := c.Query("category"); categoryStr != "" {
+		categoryInt, err := strconv.ParseInt(categoryStr, 10, 64)
+		if err == nil {
+			categoryId, err = model.NewId(categoryInt)
+		}
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid category id")
+			return
+		}
+	}
+	library, err := h.libraryService.GetLibrary()
+	if err != nil {
+		slog.Error("failed to load library", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to export references")
+		return
+	}
+	var buf bytes.Buffer
+	if err := format.write(&buf, *library, categoryId); err != nil {
+		slog.Error("failed to export references", "error", err, "format", c.Query("format"), "categoryId", categoryId)
+		c.String(http.StatusNotFound, "Category not found")
+		return
+	}
+	filename := "library"
+	for _, category := range library.Categories {
+		if category.Id == categoryId {
+			if name := strings.Join(strings.Fields(unsafeFileNameChars.ReplaceAllString(string(category.Name), "")), " "); name != "" {
+				filename = name
+			}
+		}
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format.extension))
+	c.Data(http.StatusOK, format.contentType, buf.Bytes())
+}
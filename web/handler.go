@@ -3,14 +3,17 @@ package web
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"html/template"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/VladMinzatu/reference-manager/domain/model"
 	"github.com/VladMinzatu/reference-manager/domain/repository"
 	"github.com/VladMinzatu/reference-manager/domain/service"
+	"github.com/VladMinzatu/reference-manager/markdown"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,27 +21,116 @@ type Handler struct {
 	categoryService        *service.CategoryService
 	categoryListRepository repository.CategoryListRepository
 	referenceRepo          repository.ReferencesRepository
+	referenceService       *service.ReferenceService
+	highlightService       *service.HighlightService
+	relationService        *service.RelationService
+	libraryService         *service.LibraryService
+	importService          *service.ImportService
+	metadataService        *service.MetadataService
+	linkCheckService       *service.LinkCheckService
+	archiveService         *service.ArchiveService
+	attachmentService      *service.AttachmentService
+	coverService           *service.CoverService
+	blobCollector          *service.BlobCollector
 	template               *template.Template
 }
 
 type SidebarData struct {
-	Categories       []model.CategoryRef
+	Categories       []CategoryTreeNode
 	ActiveCategoryId model.Id
 }
 
+// CategoryTreeNode is a category in the sidebar tree. Expanded is set for the active category and its ancestors.
+type CategoryTreeNode struct {
+	Id       model.Id
+	Name     model.Title
+	ParentId model.Id
+	Active   bool
+	Expanded bool
+	Children []CategoryTreeNode
+}
+
+func newSidebarData(categories []model.CategoryRef, activeCategoryId model.Id) SidebarData {
+	return SidebarData{
+		Categories:       buildCategoryTreeNodes(model.BuildCategoryTree(categories), activeCategoryId),
+		ActiveCategoryId: activeCategoryId,
+	}
+}
+
+func buildCategoryTreeNodes(nodes []*model.CategoryNode, activeCategoryId model.Id) []CategoryTreeNode {
+	result := make([]CategoryTreeNode, 0, len(nodes))
+	for _, node := range nodes {
+		children := buildCategoryTreeNodes(node.Children, activeCategoryId)
+		treeNode := CategoryTreeNode{
+			Id:       node.Id,
+			Name:     node.Name,
+			ParentId: node.ParentId,
+			Active:   node.Id == activeCategoryId,
+			Children: children,
+		}
+		treeNode.Expanded = treeNode.Active
+		for _, child := range children {
+			if child.Expanded {
+				treeNode.Expanded = true
+			}
+		}
+		result = append(result, treeNode)
+	}
+	return result
+}
+
 type ReferencesData struct {
 	CategoryId   model.Id
 	CategoryName model.Title
 	References   []template.HTML
+	// The categories that selected references can be moved to in multi-select mode
+	MoveTargets []ParentCategoryOption
 }
 
 type AddReferenceFormData struct {
 	CategoryId int64
+	Book       BookFormFields
+	Link       LinkFormFields
+}
+
+// HandlerDeps holds the services and repositories the handler is built from. The fields are named, so that
+// dependencies of the same type can't be swapped by mistake
+type HandlerDeps struct {
+	CategoryService        *service.CategoryService
+	CategoryListRepository repository.CategoryListRepository
+	ReferenceRepo          repository.ReferencesRepository
+	ReferenceService       *service.ReferenceService
+	HighlightService       *service.HighlightService
+	RelationService        *service.RelationService
+	LibraryService         *service.LibraryService
+	ImportService          *service.ImportService
+	MetadataService        *service.MetadataService
+	LinkCheckService       *service.LinkCheckService
+	ArchiveService         *service.ArchiveService
+	AttachmentService      *service.AttachmentService
+	CoverService           *service.CoverService
+	BlobCollector          *service.BlobCollector
 }
 
-func NewHandler(categoryService *service.CategoryService, categoryListRepository repository.CategoryListRepository, referenceRepo repository.ReferencesRepository) *Handler {
+func NewHandler(deps HandlerDeps) *Handler {
 	tmpl := template.Must(template.ParseGlob("web/templates/*.html"))
-	return &Handler{categoryService: categoryService, categoryListRepository: categoryListRepository, referenceRepo: referenceRepo, template: tmpl}
+	return &Handler{
+		categoryService:        deps.CategoryService,
+		categoryListRepository: deps.CategoryListRepository,
+		referenceRepo:          deps.ReferenceRepo,
+		referenceService:       deps.ReferenceService,
+		highlightService:       deps.HighlightService,
+		relationService:        deps.RelationService,
+		libraryService:         deps.LibraryService,
+		importService:          deps.ImportService,
+		metadataService:        deps.MetadataService,
+		linkCheckService:       deps.LinkCheckService,
+		archiveService:         deps.ArchiveService,
+		attachmentService:      deps.AttachmentService,
+		coverService:           deps.CoverService,
+		blobCollector:          deps.BlobCollector,
+		template:               tmpl,
+	}
 }
 
 func (h *Handler) Index(c *gin.Context) {
@@ -50,19 +142,26 @@ func (h *Handler) Index(c *gin.Context) {
 	if len(categories) > 0 {
 		activeCategoryId = categories[0].Id
 		activeCategoryName = categories[0].Name
+		// permalinks link back to the category of the reference
+		if requested, err := strconv.ParseInt(c.Query("category"), 10, 64); err == nil {
+			for _, cat := range categories {
+				if cat.Id == model.Id(requested) {
+					activeCategoryId = cat.Id
+					activeCategoryName = cat.Name
+				}
+			}
+		}
 		references = h.renderReferences(activeCategoryId)
 	}
 
 	// Render the full page with both components
 	c.HTML(http.StatusOK, "index.html", gin.H{
-		"sidebar": SidebarData{
-			Categories:       categories,
-			ActiveCategoryId: activeCategoryId,
-		},
+		"sidebar": newSidebarData(categories, activeCategoryId),
 		"references": ReferencesData{
 			CategoryId:   activeCategoryId,
 			CategoryName: activeCategoryName,
 			References:   references,
+			MoveTargets:  moveTargetOptions(categories, activeCategoryId),
 		},
 	})
 }
@@ -103,21 +202,32 @@ func (h *Handler) CategoryReferences(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "body-fragment", gin.H{
-		"sidebar": SidebarData{
-			Categories:       categories,
-			ActiveCategoryId: catId,
-		},
+		"sidebar": newSidebarData(categories, catId),
 		"references": ReferencesData{
 			CategoryId:   catId,
 			CategoryName: categoryName,
 			References:   references,
+			MoveTargets:  moveTargetOptions(categories, catId),
 		},
 	})
 }
 
 func (h *Handler) AddCategoryForm(c *gin.Context) {
+	data := struct {
+		ParentId   int64
+		ParentName string
+	}{}
+	if parentIdStr := c.Query("parentId"); parentIdStr != "" {
+		parentId, err := strconv.ParseInt(parentIdStr, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid parent category id")
+			return
+		}
+		data.ParentId = parentId
+		data.ParentName = c.Query("parentName")
+	}
 	c.Status(http.StatusOK)
-	h.template.ExecuteTemplate(c.Writer, "add-category-form.html", nil)
+	h.template.ExecuteTemplate(c.Writer, "add-category-form.html", data)
 }
 
 func (h *Handler) CreateCategory(c *gin.Context) {
@@ -126,7 +236,17 @@ func (h *Handler) CreateCategory(c *gin.Context) {
 		c.String(http.StatusBadRequest, "Category name required")
 		return
 	}
-	category, err := h.categoryListRepository.AddNewCategory(name)
+	parentId, err := parseParentId(c.PostForm("parentId"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid parent category id")
+		return
+	}
+	var category model.Category
+	if parentId != 0 {
+		category, err = h.categoryListRepository.AddNewSubcategory(parentId, name)
+	} else {
+		category, err = h.categoryListRepository.AddNewCategory(name)
+	}
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Failed to create category")
 		return
@@ -135,10 +255,7 @@ func (h *Handler) CreateCategory(c *gin.Context) {
 
 	// Alternative here would be to just return c.HTML(.., "sidebar") and use custom client-side JS and HTMX event (triggered on both delete and add-form-sumbmit) to update the the references when the sidebar is updated.
 	c.HTML(http.StatusOK, "body-fragment", gin.H{
-		"sidebar": SidebarData{
-			Categories:       categories,
-			ActiveCategoryId: category.Id,
-		},
+		"sidebar": newSidebarData(categories, category.Id),
 		"references": ReferencesData{
 			CategoryId:   category.Id,
 			CategoryName: category.Name,
@@ -167,6 +284,8 @@ func (h *Handler) DeleteCategory(c *gin.Context) {
 		c.String(http.StatusInternalServerError, "Failed to delete category")
 		return
 	}
+	// the references of the category went with it, along with their attachments, snapshots and covers
+	h.blobCollector.CollectAfterRemoval()
 
 	// Get updated categories list
 	// TODO: error handling
@@ -180,17 +299,14 @@ func (h *Handler) DeleteCategory(c *gin.Context) {
 
 	// TODO: error handling
 	category, _ := h.categoryService.GetCategoryById(activeCategoryId)
-	renderer := NewHTMLReferenceRenderer(h.template)
+	renderer := h.referenceRenderer()
 	for _, ref := range category.References {
 		ref.Render(renderer)
 	}
 
 	// Alternative here would be to just return c.HTML(.., "sidebar") and use custom client-side JS and HTMX event (triggered on both delete and add-form-sumbmit) to update the the references when the sidebar is updated.
 	c.HTML(http.StatusOK, "body-fragment", gin.H{
-		"sidebar": SidebarData{
-			Categories:       categories,
-			ActiveCategoryId: activeCategoryId,
-		},
+		"sidebar": newSidebarData(categories, activeCategoryId),
 		"references": ReferencesData{
 			CategoryId:   activeCategoryId,
 			CategoryName: activeCategoryName,
@@ -201,76 +317,179 @@ func (h *Handler) DeleteCategory(c *gin.Context) {
 
 func (h *Handler) renderReferences(categoryId model.Id) []template.HTML {
 	category, _ := h.categoryService.GetCategoryById(categoryId)
-	renderer := NewHTMLReferenceRenderer(h.template)
+	renderer := h.referenceRenderer()
 	for _, ref := range category.References {
 		ref.Render(renderer)
 	}
 	return renderer.collected
 }
 
+// Returns a renderer of the list rows of the references, with the links flagged by their last check and the books
+// with their covers
+func (h *Handler) referenceRenderer() *HTMLReferenceRenderer {
+	health, err := h.linkCheckService.GetLinkHealth()
+	if err != nil {
+		// the references are still worth showing without it
+		slog.Error("failed to load link health", "error", err)
+	}
+	covers, err := h.coverService.GetCovers()
+	if err != nil {
+		slog.Error("failed to load covers", "error", err)
+	}
+	return NewHTMLReferenceRenderer(h.template).WithLinkHealth(health).WithCovers(covers)
+}
+
+// HTMLReferenceRenderer renders each reference with the template for its type
 type HTMLReferenceRenderer struct {
-	tmpl      *template.Template
-	collected []template.HTML
+	tmpl       *template.Template
+	templates  referenceTemplates
+	static     bool
+	linkHealth map[model.Id]model.LinkHealth
+	covers     map[model.Id]model.BookCover
+	collected  []template.HTML
+}
+
+type referenceTemplates struct {
+	book, link, note string
 }
 
+var (
+	listItemTemplates = referenceTemplates{book: "_book", link: "_link", note: "_note"}
+	editFormTemplates = referenceTemplates{book: "_edit_book_form", link: "_edit_link_form", note: "_edit_note_form"}
+)
+
 // DTOs for template rendering
 type BookReferenceDTO struct {
 	Id          int64
 	Title       string
 	ISBN        string
+	ISBN10      string
 	Description string
-	Starred     bool
+	// the description rendered from Markdown, for the list rows (the edit forms use the raw Description)
+	DescriptionHTML template.HTML
+	Starred         bool
+	Tags            []string
+	// rendered for the static site export, without the controls
+	Static bool
+	// the thumbnail of the cover, empty if the book has none
+	CoverURL string
 }
 
 type LinkReferenceDTO struct {
-	Id          int64
-	Title       string
-	URL         string
-	Description string
-	Starred     bool
+	Id              int64
+	Title           string
+	URL             string
+	Description     string
+	DescriptionHTML template.HTML
+	Starred         bool
+	Tags            []string
+	Static          bool
+	// the outcome of the last check of the link, nil if it wasn't checked since it was added or edited
+	Health *LinkHealthDTO
+}
+
+type LinkHealthDTO struct {
+	Broken           bool
+	MovedToOtherSite bool
+	Summary          string
+	CheckedAt        string
 }
 
 type NoteReferenceDTO struct {
-	Id      int64
-	Title   string
-	Text    string
-	Starred bool
+	Id       int64
+	Title    string
+	Text     string
+	TextHTML template.HTML
+	Starred  bool
+	Tags     []string
+	Static   bool
 }
 
 func NewHTMLReferenceRenderer(tmpl *template.Template) *HTMLReferenceRenderer {
-	return &HTMLReferenceRenderer{tmpl: tmpl, collected: make([]template.HTML, 0)}
+	return &HTMLReferenceRenderer{tmpl: tmpl, templates: listItemTemplates, collected: make([]template.HTML, 0)}
+}
+
+// NewHTMLEditFormRenderer renders the edit forms of the references instead of their list rows
+func NewHTMLEditFormRenderer(tmpl *template.Template) *HTMLReferenceRenderer {
+	return &HTMLReferenceRenderer{tmpl: tmpl, templates: editFormTemplates, collected: make([]template.HTML, 0)}
+}
+
+// NewStaticHTMLReferenceRenderer renders the list rows of the references without the controls, for the static site export
+func NewStaticHTMLReferenceRenderer(tmpl *template.Template) *HTMLReferenceRenderer {
+	return &HTMLReferenceRenderer{tmpl: tmpl, templates: listItemTemplates, static: true, collected: make([]template.HTML, 0)}
+}
+
+// WithLinkHealth has the links rendered with the outcome of their last check
+func (r *HTMLReferenceRenderer) WithLinkHealth(health map[model.Id]model.LinkHealth) *HTMLReferenceRenderer {
+	r.linkHealth = health
+	return r
+}
+
+// WithCovers has the books rendered with the thumbnails of their covers
+func (r *HTMLReferenceRenderer) WithCovers(covers map[model.Id]model.BookCover) *HTMLReferenceRenderer {
+	r.covers = covers
+	return r
 }
 
 func (r *HTMLReferenceRenderer) RenderBook(ref model.BookReference) {
 	dto := BookReferenceDTO{
-		Id:          int64(ref.GetId()),
-		Title:       string(ref.Title()),
-		ISBN:        string(ref.ISBN),
-		Description: ref.Description,
-		Starred:     ref.Starred(),
-	}
-	r.Render("_book", dto)
+		Id:              int64(ref.GetId()),
+		Title:           string(ref.Title()),
+		ISBN:            ref.ISBN.Hyphenated(),
+		ISBN10:          ref.ISBN.ISBN10(),
+		Description:     ref.Description,
+		DescriptionHTML: markdown.ToHTML(ref.Description),
+		Starred:         ref.Starred(),
+		Tags:            tagNames(ref.Tags()),
+		Static:          r.static,
+	}
+	if cover, ok := r.covers[ref.GetId()]; ok {
+		dto.CoverURL = coverURL(cover, true)
+	}
+	r.Render(r.templates.book, dto)
 }
 
 func (r *HTMLReferenceRenderer) RenderLink(ref model.LinkReference) {
 	dto := LinkReferenceDTO{
-		Id:          int64(ref.GetId()),
-		Title:       string(ref.Title()),
-		URL:         string(ref.URL),
-		Description: ref.Description,
-		Starred:     ref.Starred(),
+		Id:              int64(ref.GetId()),
+		Title:           string(ref.Title()),
+		URL:             string(ref.URL),
+		Description:     ref.Description,
+		DescriptionHTML: markdown.ToHTML(ref.Description),
+		Starred:         ref.Starred(),
+		Tags:            tagNames(ref.Tags()),
+		Static:          r.static,
+	}
+	if health, ok := r.linkHealth[ref.GetId()]; ok && health.AppliesTo(ref.URL) {
+		dto.Health = &LinkHealthDTO{
+			Broken:           health.Broken(),
+			MovedToOtherSite: health.MovedToOtherSite(),
+			Summary:          health.Summary(),
+			CheckedAt:        health.CheckedAt.Format("2006-01-02"),
+		}
 	}
-	r.Render("_link", dto)
+	r.Render(r.templates.link, dto)
 }
 
 func (r *HTMLReferenceRenderer) RenderNote(ref model.NoteReference) {
 	dto := NoteReferenceDTO{
-		Id:      int64(ref.GetId()),
-		Title:   string(ref.Title()),
-		Text:    ref.Text,
-		Starred: ref.Starred(),
+		Id:       int64(ref.GetId()),
+		Title:    string(ref.Title()),
+		Text:     ref.Text,
+		TextHTML: markdown.ToHTML(ref.Text),
+		Starred:  ref.Starred(),
+		Tags:     tagNames(ref.Tags()),
+		Static:   r.static,
+	}
+	r.Render(r.templates.note, dto)
+}
+
+func tagNames(tags []model.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = string(tag)
 	}
-	r.Render("_note", dto)
+	return names
 }
 
 func (r *HTMLReferenceRenderer) Render(rendererName string, data interface{}) {
@@ -364,13 +583,28 @@ func (h *Handler) CreateReference(c *gin.Context) {
 		}
 
 	case "link":
-		url := model.URL(c.PostForm("url"))
-		if url == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "url is required for links"})
+		url, err := model.NewURL(c.PostForm("url"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid url: " + err.Error()})
 			return
 		}
 		description := c.PostForm("description")
 
+		if c.PostForm("allow_duplicate") != "on" {
+			duplicates, err := h.referenceService.FindDuplicateLinks(url)
+			if err != nil {
+				slog.Error("failed to look up duplicate links", "error", err, "url", url)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check for duplicate links"})
+				return
+			}
+			if len(duplicates) > 0 {
+				// the warning goes into the form (which stays open), where the user can confirm adding the link anyway
+				c.Header("HX-Retarget", "#link-duplicate-warning")
+				c.HTML(http.StatusConflict, "_duplicate_links", duplicateLinksData(url, duplicates))
+				return
+			}
+		}
+
 		linkTitle, err := model.NewTitle(title)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid title: " + err.Error()})
@@ -422,37 +656,139 @@ func (h *Handler) CreateReference(c *gin.Context) {
 	c.HTML(http.StatusOK, "_references_list", references)
 }
 
-func (h *Handler) DeleteReference(c *gin.Context) {
-	categoryIdStr := c.Param("categoryId") // TODO: pass the categoryId in here
-	idStr := c.Param("id")
-	if categoryIdStr == "" || idStr == "" {
-		c.String(http.StatusBadRequest, "Invalid path")
+// DuplicateGroupDTO is a group of likely duplicates on the duplicates report
+type DuplicateGroupDTO struct {
+	Reasons    string
+	References []DuplicateReferenceDTO
+}
+
+type DuplicateReferenceDTO struct {
+	Id           model.Id
+	Title        model.Title
+	Type         model.ReferenceType
+	Detail       string
+	Starred      bool
+	CategoryId   model.Id
+	CategoryName model.Title
+}
+
+// Duplicates is the report of likely duplicates across the whole library
+func (h *Handler) Duplicates(c *gin.Context) {
+	groups, ok := h.duplicateGroups(c)
+	if !ok {
 		return
 	}
-	categoryId, err := strconv.ParseInt(categoryIdStr, 10, 64)
+	c.HTML(http.StatusOK, "duplicates.html", gin.H{"Groups": groups})
+}
+
+// MergeDuplicates merges a group of the duplicates report into the selected reference and returns the updated report
+func (h *Handler) MergeDuplicates(c *gin.Context) {
+	keepInt, err := strconv.ParseInt(c.PostForm("keep"), 10, 64)
 	if err != nil {
-		c.String(http.StatusBadRequest, "Invalid category id")
+		c.String(http.StatusBadRequest, "Invalid reference id")
 		return
 	}
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	keepId, err := model.NewId(keepInt)
 	if err != nil {
 		c.String(http.StatusBadRequest, "Invalid reference id")
 		return
 	}
+	var otherIds []model.Id
+	for _, idStr := range c.PostFormArray("ids") {
+		idInt, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid reference id")
+			return
+		}
+		refId, err := model.NewId(idInt)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid reference id")
+			return
+		}
+		if refId != keepId {
+			otherIds = append(otherIds, refId)
+		}
+	}
 
-	catId, err := model.NewId(categoryId)
-	if err != nil {
-		c.String(http.StatusBadRequest, "Invalid category id")
+	if _, err := h.referenceService.MergeReferences(keepId, otherIds); err != nil {
+		slog.Error("failed to merge references", "error", err, "keep", keepId, "others", otherIds)
+		c.String(http.StatusInternalServerError, "Failed to merge references")
 		return
 	}
-	refId, err := model.NewId(id)
+	groups, ok := h.duplicateGroups(c)
+	if !ok {
+		return
+	}
+	c.HTML(http.StatusOK, "_duplicates", gin.H{"Groups": groups})
+}
+
+func (h *Handler) duplicateGroups(c *gin.Context) ([]DuplicateGroupDTO, bool) {
+	groups, err := h.referenceService.FindDuplicates()
 	if err != nil {
-		c.String(http.StatusBadRequest, "Invalid reference id")
+		slog.Error("failed to find duplicates", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to find duplicates")
+		return nil, false
+	}
+	dtos := make([]DuplicateGroupDTO, 0, len(groups))
+	for _, group := range groups {
+		reasons := make([]string, 0, len(group.Reasons))
+		for _, reason := range group.Reasons {
+			reasons = append(reasons, string(reason))
+		}
+		dto := DuplicateGroupDTO{Reasons: strings.Join(reasons, ", ")}
+		for _, ref := range group.References {
+			var detail string
+			switch r := ref.Reference.(type) {
+			case model.BookReference:
+				detail = "ISBN: " + r.ISBN.Hyphenated()
+			case model.LinkReference:
+				detail = string(r.URL)
+			}
+			dto.References = append(dto.References, DuplicateReferenceDTO{
+				Id:           ref.Reference.GetId(),
+				Title:        ref.Reference.Title(),
+				Type:         model.TypeOf(ref.Reference),
+				Detail:       detail,
+				Starred:      ref.Reference.Starred(),
+				CategoryId:   ref.Category.Id,
+				CategoryName: ref.Category.Name,
+			})
+		}
+		dtos = append(dtos, dto)
+	}
+	return dtos, true
+}
+
+type DuplicateLink struct {
+	Id           model.Id
+	Title        model.Title
+	CategoryId   model.Id
+	CategoryName model.Title
+}
+
+func duplicateLinksData(url model.URL, duplicates []model.CategorizedReference) gin.H {
+	links := make([]DuplicateLink, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		links = append(links, DuplicateLink{
+			Id:           duplicate.Reference.GetId(),
+			Title:        duplicate.Reference.Title(),
+			CategoryId:   duplicate.Category.Id,
+			CategoryName: duplicate.Category.Name,
+		})
+	}
+	return gin.H{"URL": url, "Links": links}
+}
+
+func (h *Handler) DeleteReference(c *gin.Context) {
+	// the category (whose version guards the removal) is looked up from the reference itself
+	ref, ok := h.loadReference(c)
+	if !ok {
 		return
 	}
-	_, err = h.categoryService.RemoveReference(catId, refId)
+	refId := ref.Reference.GetId()
+	_, err := h.categoryService.RemoveReference(ref.Category.Id, refId)
 	if err != nil {
-		slog.Error("failed to delete reference", "error", err, "categoryId", categoryId, "id", id)
+		slog.Error("failed to delete reference", "error", err, "categoryId", ref.Category.Id, "id", refId)
 		c.String(http.StatusInternalServerError, "Failed to delete reference")
 		return
 	}
@@ -461,38 +797,190 @@ func (h *Handler) DeleteReference(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
-// Helper for rendering edit reference forms
-func (h *Handler) EditBookForm(c *gin.Context) {
-	renderEditReferenceForm(c, "_edit_book_form", map[string]interface{}{
-		"Title":       c.Query("title"),
-		"ISBN":        c.Query("isbn"),
-		"Description": c.Query("description"),
-		"Starred":     c.Query("starred") == "true" || c.Query("starred") == "1",
-	})
+// EditReferenceForm renders the edit form matching the type of the reference, filled in with the stored values
+func (h *Handler) EditReferenceForm(c *gin.Context) {
+	ref, ok := h.loadReference(c)
+	if !ok {
+		return
+	}
+	renderer := NewHTMLEditFormRenderer(h.template)
+	ref.Reference.Render(renderer)
+	writeRendered(c, renderer.Collect())
 }
 
-func (h *Handler) EditLinkForm(c *gin.Context) {
-	renderEditReferenceForm(c, "_edit_link_form", map[string]interface{}{
-		"Title":       c.Query("title"),
-		"URL":         c.Query("url"),
-		"Description": c.Query("description"),
-		"Starred":     c.Query("starred") == "true" || c.Query("starred") == "1",
+// ReferenceDetail is the permalink page of a single reference
+func (h *Handler) ReferenceDetail(c *gin.Context) {
+	ref, ok := h.loadReference(c)
+	if !ok {
+		return
+	}
+	renderer := h.referenceRenderer()
+	ref.Reference.Render(renderer)
+
+	// only books have highlights
+	var highlights *HighlightsData
+	if model.TypeOf(ref.Reference) == model.BookType {
+		data, err := h.highlightsData(ref.Reference.GetId())
+		if err != nil {
+			slog.Error("failed to load highlights", "error", err, "id", ref.Reference.GetId())
+			c.String(http.StatusInternalServerError, "Failed to load highlights")
+			return
+		}
+		highlights = data
+	}
+
+	var cover *CoverData
+	if model.TypeOf(ref.Reference) == model.BookType {
+		data, err := h.coverData(ref.Reference.GetId())
+		if err != nil {
+			slog.Error("failed to load cover", "error", err, "id", ref.Reference.GetId())
+			c.String(http.StatusInternalServerError, "Failed to load cover")
+			return
+		}
+		cover = data
+	}
+
+	// only links are archived
+	var snapshots *SnapshotsData
+	if model.TypeOf(ref.Reference) == model.LinkType {
+		data, err := h.snapshotsData(ref.Reference.GetId())
+		if err != nil {
+			slog.Error("failed to load snapshots", "error", err, "id", ref.Reference.GetId())
+			c.String(http.StatusInternalServerError, "Failed to load snapshots")
+			return
+		}
+		snapshots = data
+	}
+
+	relations, err := h.relationsData(ref.Reference.GetId())
+	if err != nil {
+		slog.Error("failed to load relations", "error", err, "id", ref.Reference.GetId())
+		c.String(http.StatusInternalServerError, "Failed to load relations")
+		return
+	}
+
+	attachments, err := h.attachmentsData(ref.Reference.GetId())
+	if err != nil {
+		slog.Error("failed to load attachments", "error", err, "id", ref.Reference.GetId())
+		c.String(http.StatusInternalServerError, "Failed to load attachments")
+		return
+	}
+
+	c.HTML(http.StatusOK, "reference.html", gin.H{
+		"CategoryId":   ref.Category.Id,
+		"CategoryName": ref.Category.Name,
+		"Reference":    renderer.Collect(),
+		"Highlights":   highlights,
+		"Cover":        cover,
+		"Snapshots":    snapshots,
+		"Relations":    relations,
+		"Attachments":  attachments,
 	})
 }
 
-func (h *Handler) EditNoteForm(c *gin.Context) {
-	renderEditReferenceForm(c, "_edit_note_form", map[string]interface{}{
-		"Title":   c.Query("title"),
-		"Text":    c.Query("content"),
-		"Starred": c.Query("starred") == "true" || c.Query("starred") == "1",
-	})
+// ConvertReferenceForm lets the user pick the new type of a reference and fill in the field that type requires
+func (h *Handler) ConvertReferenceForm(c *gin.Context) {
+	ref, ok := h.loadReference(c)
+	if !ok {
+		return
+	}
+	current := model.TypeOf(ref.Reference)
+	var targets []model.ReferenceType
+	for _, t := range []model.ReferenceType{model.BookType, model.LinkType, model.NoteType} {
+		if t != current {
+			targets = append(targets, t)
+		}
+	}
+
+	data := struct {
+		Id      model.Id
+		Title   model.Title
+		Type    model.ReferenceType
+		Targets []model.ReferenceType
+	}{
+		Id:      ref.Reference.GetId(),
+		Title:   ref.Reference.Title(),
+		Type:    current,
+		Targets: targets,
+	}
+	c.HTML(http.StatusOK, "_convert_reference_form", data)
 }
 
-func renderEditReferenceForm(c *gin.Context, tmpl string, fields map[string]interface{}) {
-	idStr := c.Param("id")
-	id, _ := strconv.ParseInt(idStr, 10, 64)
-	fields["Id"] = id
-	c.HTML(http.StatusOK, tmpl, fields)
+func (h *Handler) ConvertReference(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid reference id")
+		return
+	}
+	refId, err := model.NewId(id)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid reference id")
+		return
+	}
+	target, err := model.NewReferenceType(c.PostForm("type"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid type")
+		return
+	}
+	input := model.ConversionInput{ISBN: model.ISBN(c.PostForm("isbn")), URL: model.URL(c.PostForm("url"))}
+
+	if _, err := h.referenceService.ConvertReference(refId, target, input); err != nil {
+		slog.Error("failed to convert reference", "error", err, "id", id, "type", target)
+		c.String(http.StatusBadRequest, "Failed to convert reference: %v", err)
+		return
+	}
+	h.renderReference(c, refId)
+}
+
+// MarkdownPreview renders the content of a note form the way the note will be shown
+func (h *Handler) MarkdownPreview(c *gin.Context) {
+	preview := markdown.ToHTML(c.PostForm("content"))
+	if preview == "" {
+		preview = `<p class="text-gray-400">Nothing to preview</p>`
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(preview))
+}
+
+// Loads the reference given by the id path parameter, writing the error response if that fails
+func (h *Handler) loadReference(c *gin.Context) (*model.CategorizedReference, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid reference id")
+		return nil, false
+	}
+	refId, err := model.NewId(id)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid reference id")
+		return nil, false
+	}
+	ref, err := h.referenceRepo.GetReferenceById(refId)
+	if err != nil {
+		slog.Error("failed to load reference", "error", err, "id", id)
+		c.String(http.StatusNotFound, "Reference not found")
+		return nil, false
+	}
+	return ref, true
+}
+
+// Renders the list row of a single reference as stored in the db
+func (h *Handler) renderReference(c *gin.Context, refId model.Id) {
+	ref, err := h.referenceRepo.GetReferenceById(refId)
+	if err != nil {
+		slog.Error("failed to load reference", "error", err, "id", refId)
+		c.String(http.StatusInternalServerError, "Failed to load reference")
+		return
+	}
+	renderer := h.referenceRenderer()
+	ref.Reference.Render(renderer)
+	writeRendered(c, renderer.Collect())
+}
+
+func writeRendered(c *gin.Context, fragments []template.HTML) {
+	var buf bytes.Buffer
+	for _, fragment := range fragments {
+		buf.WriteString(string(fragment))
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
 }
 
 func (h *Handler) UpdateBook(c *gin.Context) {
@@ -519,7 +1007,7 @@ func (h *Handler) UpdateBook(c *gin.Context) {
 	}
 	bookISBN, err := model.NewISBN(isbn)
 	if err != nil {
-		c.String(http.StatusBadRequest, "Invalid ISBN")
+		c.String(http.StatusBadRequest, "Invalid ISBN: %v", err)
 		return
 	}
 	book := model.NewBookReference(
@@ -535,20 +1023,8 @@ func (h *Handler) UpdateBook(c *gin.Context) {
 		return
 	}
 
-	data := struct {
-		Id          int64
-		Title       string
-		ISBN        string
-		Description string
-		Starred     bool
-	}{
-		Id:          id,
-		Title:       title,
-		ISBN:        isbn,
-		Description: description,
-		Starred:     starred,
-	}
-	c.HTML(http.StatusOK, "_book", data)
+	// the reference is read back, so that the row shows exactly what was stored (including the fields not on the form, like tags)
+	h.renderReference(c, refId)
 }
 
 func (h *Handler) UpdateLink(c *gin.Context) {
@@ -591,20 +1067,8 @@ func (h *Handler) UpdateLink(c *gin.Context) {
 		return
 	}
 
-	data := struct {
-		Id          int64
-		Title       string
-		URL         string
-		Description string
-		Starred     bool
-	}{
-		Id:          id,
-		Title:       title,
-		URL:         url,
-		Description: description,
-		Starred:     starred,
-	}
-	c.HTML(http.StatusOK, "_link", data)
+	// the reference is read back, so that the row shows exactly what was stored (including the fields not on the form, like tags)
+	h.renderReference(c, refId)
 }
 
 func (h *Handler) UpdateNote(c *gin.Context) {
@@ -640,34 +1104,101 @@ func (h *Handler) UpdateNote(c *gin.Context) {
 		return
 	}
 
-	data := struct {
-		Id      int64
-		Title   string
-		Text    string
-		Starred bool
-	}{
-		Id:      id,
-		Title:   title,
-		Text:    content,
-		Starred: starred,
-	}
-	c.HTML(http.StatusOK, "_note", data)
+	// the reference is read back, so that the row shows exactly what was stored (including the fields not on the form, like tags)
+	h.renderReference(c, refId)
+}
+
+type ParentCategoryOption struct {
+	Id    model.Id
+	Label string
 }
 
 func (h *Handler) EditCategoryForm(c *gin.Context) {
 	idStr := c.Param("id")
-	id, _ := strconv.ParseInt(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid category id")
+		return
+	}
+	catId, err := model.NewId(id)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid category id")
+		return
+	}
 	name := c.Query("name")
+
+	categories, err := h.categoryListRepository.GetAllCategoryRefs()
+	if err != nil {
+		slog.Error("failed to load categories", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to load categories")
+		return
+	}
+	var parentId model.Id
+	var options []ParentCategoryOption
+	for _, root := range model.BuildCategoryTree(categories) {
+		root.Walk(func(node *model.CategoryNode, depth int) {
+			if node.Id == catId {
+				parentId = node.ParentId
+			}
+		})
+		collectParentOptions(root, catId, 0, &options)
+	}
+
 	data := struct {
-		Id   int64
-		Name string
+		Id            int64
+		Name          string
+		ParentId      model.Id
+		TopLevel      string
+		ParentOptions []ParentCategoryOption
 	}{
-		Id:   id,
-		Name: name,
+		Id:            id,
+		Name:          name,
+		ParentId:      parentId,
+		TopLevel:      topLevelParentId,
+		ParentOptions: options,
 	}
 	c.HTML(http.StatusOK, "_edit_category_form", data)
 }
 
+// collectParentOptions lists the categories that the category being edited can be moved under (i.e. excluding its own subtree)
+func collectParentOptions(node *model.CategoryNode, editedId model.Id, depth int, options *[]ParentCategoryOption) {
+	if node.Id == editedId {
+		return
+	}
+	*options = append(*options, ParentCategoryOption{Id: node.Id, Label: strings.Repeat("— ", depth) + string(node.Name)})
+	for _, child := range node.Children {
+		collectParentOptions(child, editedId, depth+1, options)
+	}
+}
+
+// moveTargetOptions lists all the categories except the current one, indented by depth
+func moveTargetOptions(categories []model.CategoryRef, currentId model.Id) []ParentCategoryOption {
+	var options []ParentCategoryOption
+	for _, root := range model.BuildCategoryTree(categories) {
+		root.Walk(func(node *model.CategoryNode, depth int) {
+			if node.Id != currentId {
+				options = append(options, ParentCategoryOption{Id: node.Id, Label: strings.Repeat("— ", depth) + string(node.Name)})
+			}
+		})
+	}
+	return options
+}
+
+// The parent category fields of the forms hold this for the top level, and the id of the parent otherwise
+const topLevelParentId = "0"
+
+// Returns the parent category id of a form field, or the zero Id for the top level (also when the field is empty)
+func parseParentId(value string) (model.Id, error) {
+	if value == "" || value == topLevelParentId {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return model.NewId(id)
+}
+
 func (h *Handler) UpdateCategory(c *gin.Context) {
 	idStr := c.Param("id")
 	idInt, err := strconv.ParseInt(idStr, 10, 64)
@@ -690,17 +1221,42 @@ func (h *Handler) UpdateCategory(c *gin.Context) {
 		c.String(http.StatusBadRequest, "Invalid category name")
 		return
 	}
-	_, err = h.categoryService.UpdateTitle(catId, title)
+	// without a parent in the form, the category stays where it is
+	parentIdStr := c.PostForm("parentId")
+	reparent := parentIdStr != ""
+	parentId, err := parseParentId(parentIdStr)
 	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid parent category id")
+		return
+	}
+	if reparent {
+		// checked up front for a clear error; the rename and the move are then saved together, so that a move
+		// rejected in the meantime doesn't leave the rename applied
+		categories, err := h.categoryListRepository.GetAllCategoryRefs()
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Failed to load categories")
+			return
+		}
+		if err := model.ValidateParent(categories, catId, parentId); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := h.categoryListRepository.RenameAndReparentCategory(catId, title, parentId); err != nil {
+			if errors.Is(err, model.ErrCategoryCycle) {
+				c.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			c.String(http.StatusInternalServerError, "Failed to update category")
+			return
+		}
+	} else if _, err := h.categoryService.UpdateTitle(catId, title); err != nil {
 		c.String(http.StatusInternalServerError, "Failed to update category")
 		return
 	}
+
 	// Return updated sidebar
 	categories, _ := h.categoryListRepository.GetAllCategoryRefs()
-	c.HTML(http.StatusOK, "sidebar", SidebarData{
-		Categories:       categories,
-		ActiveCategoryId: catId,
-	})
+	c.HTML(http.StatusOK, "sidebar", newSidebarData(categories, catId))
 }
 
 func (h *Handler) ReorderCategories(c *gin.Context) {
@@ -714,10 +1270,49 @@ func (h *Handler) ReorderCategories(c *gin.Context) {
 		c.String(http.StatusBadRequest, "Invalid positions format")
 		return
 	}
-	if err := h.categoryListRepository.ReorderCategories(positions); err != nil {
+	parentId, err := parseParentId(c.PostForm("parentId"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid parentId")
+		return
+	}
+	if parentId != 0 {
+		err = h.categoryListRepository.ReorderSubcategories(parentId, positions)
+	} else {
+		err = h.categoryListRepository.ReorderCategories(positions)
+	}
+	if err != nil {
 		c.String(http.StatusInternalServerError, "Failed to reorder categories")
 		return
 	}
+	h.renderSidebarAfterReorder(c)
+}
+
+func (h *Handler) MoveCategory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid category id")
+		return
+	}
+	catId, err := model.NewId(id)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid category id")
+		return
+	}
+	position, err := strconv.Atoi(c.PostForm("position"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid position")
+		return
+	}
+	if err := h.categoryListRepository.MoveCategoryToPosition(catId, position); err != nil {
+		slog.Error("failed to move category", "error", err, "id", id, "position", position)
+		c.String(http.StatusInternalServerError, "Failed to move category")
+		return
+	}
+	h.renderSidebarAfterReorder(c)
+}
+
+// Renders the sidebar, keeping the active category sent by the client (or using the first one)
+func (h *Handler) renderSidebarAfterReorder(c *gin.Context) {
 	categories, _ := h.categoryListRepository.GetAllCategoryRefs()
 
 	// Find the currently active category from the request or use the first one
@@ -739,10 +1334,7 @@ func (h *Handler) ReorderCategories(c *gin.Context) {
 		activeCategoryId = categories[0].Id
 	}
 
-	c.HTML(http.StatusOK, "sidebar", SidebarData{
-		Categories:       categories,
-		ActiveCategoryId: activeCategoryId,
-	})
+	c.HTML(http.StatusOK, "sidebar", newSidebarData(categories, activeCategoryId))
 }
 
 func (h *Handler) ReorderReferences(c *gin.Context) {
@@ -792,3 +1384,113 @@ func (h *Handler) ReorderReferences(c *gin.Context) {
 	references := h.renderReferences(catId)
 	c.HTML(http.StatusOK, "_references_list", references)
 }
+
+func (h *Handler) MoveReference(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid reference id")
+		return
+	}
+	refId, err := model.NewId(id)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid reference id")
+		return
+	}
+	categoryIdInt, err := strconv.ParseInt(c.PostForm("categoryId"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid categoryId")
+		return
+	}
+	catId, err := model.NewId(categoryIdInt)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid categoryId")
+		return
+	}
+	position, err := strconv.Atoi(c.PostForm("position"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid position")
+		return
+	}
+
+	_, err = h.categoryService.MoveReferenceToPosition(catId, refId, position)
+	if err != nil {
+		slog.Error("failed to move reference", "error", err, "categoryId", categoryIdInt, "id", id, "position", position)
+		c.String(http.StatusInternalServerError, "Failed to move reference")
+		return
+	}
+	references := h.renderReferences(catId)
+	c.HTML(http.StatusOK, "_references_list", references)
+}
+
+// BulkReferences applies one action to all the references selected in multi-select mode
+func (h *Handler) BulkReferences(c *gin.Context) {
+	categoryIdInt, err := strconv.ParseInt(c.PostForm("categoryId"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid categoryId")
+		return
+	}
+	catId, err := model.NewId(categoryIdInt)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid categoryId")
+		return
+	}
+	var refIds []model.Id
+	for _, idStr := range c.PostFormArray("ids") {
+		idInt, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid reference id")
+			return
+		}
+		refId, err := model.NewId(idInt)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid reference id")
+			return
+		}
+		refIds = append(refIds, refId)
+	}
+	if len(refIds) == 0 {
+		c.String(http.StatusBadRequest, "No references selected")
+		return
+	}
+
+	action := c.PostForm("action")
+	switch action {
+	case "star", "unstar":
+		_, err = h.categoryService.SetReferencesStarred(catId, refIds, action == "star")
+	case "delete":
+		_, err = h.categoryService.RemoveReferences(catId, refIds)
+	case "move":
+		targetIdInt, parseErr := strconv.ParseInt(c.PostForm("targetCategoryId"), 10, 64)
+		if parseErr != nil {
+			c.String(http.StatusBadRequest, "Invalid targetCategoryId")
+			return
+		}
+		targetId, idErr := model.NewId(targetIdInt)
+		if idErr != nil {
+			c.String(http.StatusBadRequest, "Invalid targetCategoryId")
+			return
+		}
+		_, err = h.categoryService.MoveReferences(catId, refIds, targetId)
+	case "tag", "untag":
+		tag, tagErr := model.NewTag(c.PostForm("tag"))
+		if tagErr != nil {
+			c.String(http.StatusBadRequest, "Invalid tag: %v", tagErr)
+			return
+		}
+		if action == "tag" {
+			_, err = h.categoryService.TagReferences(catId, refIds, tag)
+		} else {
+			_, err = h.categoryService.UntagReferences(catId, refIds, tag)
+		}
+	default:
+		c.String(http.StatusBadRequest, "Unknown action")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to apply bulk action", "error", err, "action", action, "categoryId", categoryIdInt, "ids", refIds)
+		c.String(http.StatusInternalServerError, "Failed to update references")
+		return
+	}
+	references := h.renderReferences(catId)
+	c.HTML(http.StatusOK, "_references_list", references)
+}
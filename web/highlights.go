@@ -0,0 +1,177 @@
+package web
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/markdown"
+	"github.com/gin-gonic/gin"
+)
+
+// HighlightsData is the highlights panel of the detail page of a book
+type HighlightsData struct {
+	ReferenceId model.Id
+	Highlights  []HighlightDTO
+}
+
+type HighlightDTO struct {
+	Id          model.Id
+	ReferenceId model.Id
+	Text        string
+	Location    string
+	Comment     string
+	CommentHTML template.HTML
+	// for the move up/down buttons
+	First bool
+	Last  bool
+	Up    int
+	Down  int
+}
+
+func newHighlightDTO(highlight model.Highlight) HighlightDTO {
+	return HighlightDTO{
+		Id:          highlight.Id,
+		ReferenceId: highlight.ReferenceId,
+		Text:        highlight.Text,
+		Location:    string(highlight.Location),
+		Comment:     highlight.Comment,
+		CommentHTML: markdown.ToHTML(highlight.Comment),
+	}
+}
+
+func (h *Handler) highlightsData(referenceId model.Id) (*HighlightsData, error) {
+	highlights, err := h.highlightService.GetHighlights(referenceId)
+	if err != nil {
+		return nil, err
+	}
+	data := &HighlightsData{ReferenceId: referenceId, Highlights: make([]HighlightDTO, 0, len(highlights))}
+	for i, highlight := range highlights {
+		dto := newHighlightDTO(highlight)
+		dto.First = i == 0
+		dto.Last = i == len(highlights)-1
+		dto.Up = i - 1
+		dto.Down = i + 1
+		data.Highlights = append(data.Highlights, dto)
+	}
+	return data, nil
+}
+
+func (h *Handler) renderHighlights(c *gin.Context, referenceId model.Id) {
+	data, err := h.highlightsData(referenceId)
+	if err != nil {
+		slog.Error("failed to load highlights", "error", err, "referenceId", referenceId)
+		c.String(http.StatusInternalServerError, "Failed to load highlights")
+		return
+	}
+	c.HTML(http.StatusOK, "_highlights", data)
+}
+
+func (h *Handler) Highlights(c *gin.Context) {
+	referenceId, ok := idParam(c, "Invalid reference id")
+	if !ok {
+		return
+	}
+	h.renderHighlights(c, referenceId)
+}
+
+func (h *Handler) AddHighlight(c *gin.Context) {
+	referenceId, ok := idParam(c, "Invalid reference id")
+	if !ok {
+		return
+	}
+	location, err := model.NewLocation(c.PostForm("location"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid location: %v", err)
+		return
+	}
+	if _, err := h.highlightService.AddHighlight(referenceId, c.PostForm("text"), location, c.PostForm("comment")); err != nil {
+		slog.Error("failed to add highlight", "error", err, "referenceId", referenceId)
+		c.String(http.StatusBadRequest, "Failed to add highlight: %v", err)
+		return
+	}
+	h.renderHighlights(c, referenceId)
+}
+
+func (h *Handler) EditHighlightForm(c *gin.Context) {
+	id, ok := idParam(c, "Invalid highlight id")
+	if !ok {
+		return
+	}
+	highlight, err := h.highlightService.GetHighlightById(id)
+	if err != nil {
+		slog.Error("failed to load highlight", "error", err, "id", id)
+		c.String(http.StatusNotFound, "Highlight not found")
+		return
+	}
+	c.HTML(http.StatusOK, "_edit_highlight_form", newHighlightDTO(*highlight))
+}
+
+func (h *Handler) UpdateHighlight(c *gin.Context) {
+	id, ok := idParam(c, "Invalid highlight id")
+	if !ok {
+		return
+	}
+	location, err := model.NewLocation(c.PostForm("location"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid location: %v", err)
+		return
+	}
+	highlight, err := h.highlightService.UpdateHighlight(id, c.PostForm("text"), location, c.PostForm("comment"))
+	if err != nil {
+		slog.Error("failed to update highlight", "error", err, "id", id)
+		c.String(http.StatusBadRequest, "Failed to update highlight: %v", err)
+		return
+	}
+	h.renderHighlights(c, highlight.ReferenceId)
+}
+
+func (h *Handler) DeleteHighlight(c *gin.Context) {
+	id, ok := idParam(c, "Invalid highlight id")
+	if !ok {
+		return
+	}
+	highlight, err := h.highlightService.DeleteHighlight(id)
+	if err != nil {
+		slog.Error("failed to delete highlight", "error", err, "id", id)
+		c.String(http.StatusInternalServerError, "Failed to delete highlight")
+		return
+	}
+	h.renderHighlights(c, highlight.ReferenceId)
+}
+
+func (h *Handler) MoveHighlight(c *gin.Context) {
+	id, ok := idParam(c, "Invalid highlight id")
+	if !ok {
+		return
+	}
+	position, err := strconv.Atoi(c.PostForm("position"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid position")
+		return
+	}
+	highlight, err := h.highlightService.MoveHighlight(id, position)
+	if err != nil {
+		slog.Error("failed to move highlight", "error", err, "id", id, "position", position)
+		c.String(http.StatusBadRequest, "Failed to move highlight: %v", err)
+		return
+	}
+	h.renderHighlights(c, highlight.ReferenceId)
+}
+
+// Parses the id path parameter, writing the error response if it isn't a valid id
+func idParam(c *gin.Context, message string) (model.Id, bool) {
+	idInt, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, message)
+		return 0, false
+	}
+	id, err := model.NewId(idInt)
+	if err != nil {
+		c.String(http.StatusBadRequest, message)
+		return 0, false
+	}
+	return id, true
+}
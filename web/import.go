@@ -0,0 +1,135 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/importer"
+	"github.com/gin-gonic/gin"
+)
+
+type ImportReportData struct {
+	Report model.ImportReport
+	Error  string
+}
+
+func (d ImportReportData) Imported() int   { return d.Report.Count(model.Imported) }
+func (d ImportReportData) Duplicates() int { return d.Report.Count(model.SkippedDuplicate) }
+func (d ImportReportData) Invalid() int    { return d.Report.Count(model.SkippedInvalid) }
+
+// ImportPage shows the forms for importing references from other tools
+func (h *Handler) ImportPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "import.html", nil)
+}
+
+// ImportBookmarks imports an uploaded browser bookmarks file, or only previews the import if dry_run is set, and
+// returns the report
+func (h *Handler) ImportBookmarks(c *gin.Context) {
+	h.importUpload(c, importer.ParseBookmarks)
+}
+
+// ImportCSV imports an uploaded CSV file, reading the columns given by the mapping field (e.g. "title=Name,url=Link"),
+// or only previews the import if dry_run is set, and returns the report
+func (h *Handler) ImportCSV(c *gin.Context) {
+	mapping, err := importer.ParseColumnMapping(c.PostForm("mapping"))
+	if err != nil {
+		h.renderImportReport(c, ImportReportData{Error: err.Error()})
+		return
+	}
+	h.importUpload(c, func(r io.Reader) ([]model.ImportFolder, error) {
+		return importer.ParseCSV(r, mapping)
+	})
+}
+
+// ImportReadingLog imports an uploaded Goodreads or Open Library export (given by the format field), with the shelves
+// mapped as the shelves field says and books starred from the star_threshold rating, or only previews the import if
+// dry_run is set, and returns the report
+func (h *Handler) ImportReadingLog(c *gin.Context) {
+	format, err := importer.NewReadingLogFormat(c.PostForm("format"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid format")
+		return
+	}
+	shelves, err := importer.NewShelfMapping(c.PostForm("shelves"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid shelf mapping")
+		return
+	}
+	threshold, err := strconv.Atoi(c.DefaultPostForm("star_threshold", "0"))
+	if err != nil || threshold < 0 || threshold > 5 {
+		c.String(http.StatusBadRequest, "Invalid star threshold")
+		return
+	}
+	options := importer.ReadingLogOptions{Shelves: shelves, StarThreshold: threshold}
+	h.importUpload(c, func(r io.Reader) ([]model.ImportFolder, error) {
+		return importer.ParseReadingLog(r, format, options)
+	})
+}
+
+var bibliographyParsers = map[string]func(io.Reader, model.Title) ([]model.ImportFolder, error){
+	"ris":     importer.ParseRIS,
+	"csljson": importer.ParseCSLJSON,
+}
+
+// ImportBibliography imports an uploaded RIS or CSL-JSON file (given by the format field) into the top-level category
+// named by the category field, or after the file if it's empty, or only previews the import if dry_run is set, and
+// returns the report
+func (h *Handler) ImportBibliography(c *gin.Context) {
+	parse, ok := bibliographyParsers[c.PostForm("format")]
+	if !ok {
+		c.String(http.StatusBadRequest, "Invalid format")
+		return
+	}
+	categoryName := strings.TrimSpace(c.PostForm("category"))
+	if categoryName == "" {
+		if fileHeader, err := c.FormFile("file"); err == nil {
+			categoryName = strings.TrimSuffix(filepath.Base(fileHeader.Filename), filepath.Ext(fileHeader.Filename))
+		}
+	}
+	h.importUpload(c, func(r io.Reader) ([]model.ImportFolder, error) {
+		category, err := model.NewTitle(categoryName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid category name: %v", err)
+		}
+		return parse(r, category)
+	})
+}
+
+// Parses the uploaded file with the given parser and imports the folders
+func (h *Handler) importUpload(c *gin.Context, parse func(io.Reader) ([]model.ImportFolder, error)) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		h.renderImportReport(c, ImportReportData{Error: "Choose a file to import"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		slog.Error("failed to open uploaded file", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+	defer file.Close()
+
+	folders, err := parse(file)
+	if err != nil {
+		h.renderImportReport(c, ImportReportData{Error: err.Error()})
+		return
+	}
+	report, err := h.importService.Import(folders, c.PostForm("dry_run") != "")
+	if err != nil {
+		slog.Error("failed to import", "error", err, "file", fileHeader.Filename)
+		h.renderImportReport(c, ImportReportData{Error: "The import failed, so nothing was imported"})
+		return
+	}
+	h.renderImportReport(c, ImportReportData{Report: *report})
+}
+
+func (h *Handler) renderImportReport(c *gin.Context, data ImportReportData) {
+	c.HTML(http.StatusOK, "_import_report", data)
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/gin-gonic/gin"
+)
+
+type LinkProblemDTO struct {
+	Id           model.Id
+	Title        model.Title
+	URL          model.URL
+	CategoryId   model.Id
+	CategoryName model.Title
+	Health       LinkHealthDTO
+}
+
+type LinkProblemsDTO struct {
+	Heading string
+	Links   []LinkProblemDTO
+}
+
+// LinkHealth is the report of the links that were broken or had moved to another site when they were last checked
+func (h *Handler) LinkHealth(c *gin.Context) {
+	health, err := h.linkCheckService.GetLinkHealth()
+	if err != nil {
+		slog.Error("failed to load link health", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to load link health")
+		return
+	}
+	refs, err := h.referenceRepo.GetAllReferences()
+	if err != nil {
+		slog.Error("failed to load references", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to load references")
+		return
+	}
+
+	var broken, moved []LinkProblemDTO
+	checked := 0
+	for _, ref := range refs {
+		link, ok := ref.Reference.(model.LinkReference)
+		if !ok {
+			continue
+		}
+		linkHealth, ok := health[link.GetId()]
+		if !ok || !linkHealth.AppliesTo(link.URL) {
+			continue
+		}
+		checked++
+		problem := LinkProblemDTO{
+			Id:           link.GetId(),
+			Title:        link.Title(),
+			URL:          link.URL,
+			CategoryId:   ref.Category.Id,
+			CategoryName: ref.Category.Name,
+			Health:       LinkHealthDTO{Broken: linkHealth.Broken(), MovedToOtherSite: linkHealth.MovedToOtherSite(), Summary: linkHealth.Summary(), CheckedAt: linkHealth.CheckedAt.Format("2006-01-02")},
+		}
+		if problem.Health.Broken {
+			broken = append(broken, problem)
+		} else if problem.Health.MovedToOtherSite {
+			moved = append(moved, problem)
+		}
+	}
+	c.HTML(http.StatusOK, "link_health.html", gin.H{
+		"Checked":  checked,
+		"Sections": []LinkProblemsDTO{{Heading: "Broken", Links: broken}, {Heading: "Moved to another site", Links: moved}},
+	})
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/gin-gonic/gin"
+)
+
+// BookFormFields are the fields of the add book form, which can be filled in by an ISBN lookup
+type BookFormFields struct {
+	Title       string
+	ISBN        string
+	Description string
+	Error       string
+}
+
+// Fills in the title and description of the book form from the catalog by the ISBN in the form. Fields that were
+// already filled in are kept if the catalog has nothing for them, and failures are shown in the form.
+func (h *Handler) LookupBook(c *gin.Context) {
+	fields := BookFormFields{Title: c.Query("title"), ISBN: c.Query("isbn"), Description: c.Query("description")}
+	metadata, err := h.metadataService.LookupBook(fields.ISBN)
+	if err != nil {
+		slog.Error("Failed to look up book", "isbn", fields.ISBN, "error", err)
+		fields.Error = err.Error()
+		c.HTML(http.StatusOK, "book-form-fields", fields)
+		return
+	}
+	fields.ISBN = metadata.ISBN.Hyphenated()
+	if title := metadata.FullTitle(); title != "" {
+		fields.Title = string(title)
+	}
+	if metadata.Description != "" {
+		fields.Description = metadata.Description
+	}
+	c.HTML(http.StatusOK, "book-form-fields", fields)
+}
+
+// LinkFormFields are the fields of the add link form, which can be filled in from the page at the URL
+type LinkFormFields struct {
+	URL         string
+	Title       string
+	Description string
+	Error       string
+}
+
+// Fills in the title and description of the link form from the page at the URL in the form. Only empty fields are
+// filled in, unless overwrite is set, and failures are shown in the form.
+func (h *Handler) FetchLink(c *gin.Context) {
+	fields := LinkFormFields{URL: c.Query("url"), Title: c.Query("title"), Description: c.Query("description")}
+	overwrite := c.Query("overwrite") != ""
+	if fields.URL == "" || (!overwrite && fields.Title != "" && fields.Description != "") {
+		c.HTML(http.StatusOK, "link-form-fields", fields)
+		return
+	}
+	page, err := h.metadataService.FetchLink(fields.URL)
+	if err != nil {
+		slog.Error("Failed to fetch link", "url", fields.URL, "error", err)
+		fields.Error = err.Error()
+		c.HTML(http.StatusOK, "link-form-fields", fields)
+		return
+	}
+	fields.URL = string(page.URL)
+	if page.Title != "" && (overwrite || fields.Title == "") {
+		fields.Title = model.TruncateTitle(page.Title)
+	}
+	if page.Description != "" && (overwrite || fields.Description == "") {
+		fields.Description = page.Description
+	}
+	c.HTML(http.StatusOK, "link-form-fields", fields)
+}
@@ -0,0 +1,210 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/gin-gonic/gin"
+)
+
+// RelationsData is the relations panel of the detail page of a reference
+type RelationsData struct {
+	ReferenceId model.Id
+	Related     []RelatedReferenceDTO
+	// the references that can be picked when adding a relation
+	Options []RelationOptionDTO
+	Error   string
+}
+
+type RelatedReferenceDTO struct {
+	Label        string
+	Id           model.Id
+	Title        string
+	Type         string
+	CategoryId   model.Id
+	CategoryName string
+	// identify the relation for removing it
+	From         model.Id
+	To           model.Id
+	RelationType string
+}
+
+type RelationOptionDTO struct {
+	Id           model.Id
+	Title        string
+	CategoryName string
+}
+
+func (h *Handler) relationsData(referenceId model.Id) (*RelationsData, error) {
+	related, err := h.relationService.GetRelatedReferences(referenceId)
+	if err != nil {
+		return nil, err
+	}
+	all, err := h.referenceRepo.GetAllReferences()
+	if err != nil {
+		return nil, err
+	}
+	data := &RelationsData{ReferenceId: referenceId}
+	for _, rel := range related {
+		data.Related = append(data.Related, RelatedReferenceDTO{
+			Label:        rel.Relation.Describe(referenceId),
+			Id:           rel.Reference.Reference.GetId(),
+			Title:        string(rel.Reference.Reference.Title()),
+			Type:         string(model.TypeOf(rel.Reference.Reference)),
+			CategoryId:   rel.Reference.Category.Id,
+			CategoryName: string(rel.Reference.Category.Name),
+			From:         rel.Relation.From,
+			To:           rel.Relation.To,
+			RelationType: string(rel.Relation.Type),
+		})
+	}
+	for _, ref := range all {
+		if ref.Reference.GetId() == referenceId {
+			continue
+		}
+		data.Options = append(data.Options, RelationOptionDTO{
+			Id:           ref.Reference.GetId(),
+			Title:        string(ref.Reference.Title()),
+			CategoryName: string(ref.Category.Name),
+		})
+	}
+	return data, nil
+}
+
+// Renders the relations panel, with an error message to show above it if the last change failed
+func (h *Handler) renderRelations(c *gin.Context, referenceId model.Id, errorMessage string) {
+	data, err := h.relationsData(referenceId)
+	if err != nil {
+		slog.Error("failed to load relations", "error", err, "referenceId", referenceId)
+		c.String(http.StatusInternalServerError, "Failed to load relations")
+		return
+	}
+	data.Error = errorMessage
+	c.HTML(http.StatusOK, "_relations", data)
+}
+
+// AddRelation relates the reference to the picked one. The relation is chosen from the point of view of the reference,
+// e.g. "requires" makes the picked reference a prerequisite of it.
+func (h *Handler) AddRelation(c *gin.Context) {
+	referenceId, ok := idParam(c, "Invalid reference id")
+	if !ok {
+		return
+	}
+	otherInt, err := strconv.ParseInt(c.PostForm("other"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid reference id")
+		return
+	}
+	otherId, err := model.NewId(otherInt)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid reference id")
+		return
+	}
+
+	from, to := referenceId, otherId
+	var relType model.RelationType
+	switch c.PostForm("relation") {
+	case "requires":
+		from, to, relType = otherId, referenceId, model.PrerequisiteRelation
+	case "prerequisite-for":
+		relType = model.PrerequisiteRelation
+	case "supersedes":
+		relType = model.SupersedesRelation
+	case "superseded-by":
+		from, to, relType = otherId, referenceId, model.SupersedesRelation
+	case "related":
+		relType = model.RelatedRelation
+	default:
+		c.String(http.StatusBadRequest, "Invalid relation")
+		return
+	}
+
+	// errors such as prerequisite cycles are shown in the panel
+	errorMessage := ""
+	if _, err := h.relationService.AddRelation(from, to, relType); err != nil {
+		slog.Error("failed to add relation", "error", err, "from", from, "to", to, "type", relType)
+		errorMessage = err.Error()
+	}
+	h.renderRelations(c, referenceId, errorMessage)
+}
+
+// RemoveRelation removes the relation given by the from, to and type query parameters and returns the relations
+// panel of the reference given by the path
+func (h *Handler) RemoveRelation(c *gin.Context) {
+	referenceId, ok := idParam(c, "Invalid reference id")
+	if !ok {
+		return
+	}
+	var ids []model.Id
+	for _, param := range []string{"from", "to"} {
+		idInt, err := strconv.ParseInt(c.Query(param), 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid reference id")
+			return
+		}
+		id, err := model.NewId(idInt)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid reference id")
+			return
+		}
+		ids = append(ids, id)
+	}
+	relType, err := model.NewRelationType(c.Query("type"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid relation type")
+		return
+	}
+	if err := h.relationService.RemoveRelation(ids[0], ids[1], relType); err != nil {
+		slog.Error("failed to remove relation", "error", err, "from", ids[0], "to", ids[1], "type", relType)
+		c.String(http.StatusInternalServerError, "Failed to remove relation")
+		return
+	}
+	h.renderRelations(c, referenceId, "")
+}
+
+type ReadingOrderItemDTO struct {
+	Id    model.Id
+	Title string
+	Type  string
+	// titles of the direct prerequisites, wherever they are
+	Prerequisites []string
+}
+
+// ReadingOrder lists the references of a category so that prerequisites come first
+func (h *Handler) ReadingOrder(c *gin.Context) {
+	categoryId, ok := idParam(c, "Invalid category id")
+	if !ok {
+		return
+	}
+	category, ordered, err := h.relationService.ReadingOrder(categoryId)
+	if err != nil {
+		slog.Error("failed to compute reading order", "error", err, "categoryId", categoryId)
+		c.String(http.StatusInternalServerError, "Failed to compute reading order")
+		return
+	}
+
+	items := make([]ReadingOrderItemDTO, 0, len(ordered))
+	for _, ref := range ordered {
+		related, err := h.relationService.GetRelatedReferences(ref.GetId())
+		if err != nil {
+			slog.Error("failed to load relations", "error", err, "referenceId", ref.GetId())
+			c.String(http.StatusInternalServerError, "Failed to load relations")
+			return
+		}
+		item := ReadingOrderItemDTO{Id: ref.GetId(), Title: string(ref.Title()), Type: string(model.TypeOf(ref))}
+		for _, rel := range related {
+			if rel.Relation.Type == model.PrerequisiteRelation && rel.Relation.To == ref.GetId() {
+				item.Prerequisites = append(item.Prerequisites, string(rel.Reference.Reference.Title()))
+			}
+		}
+		items = append(items, item)
+	}
+
+	c.HTML(http.StatusOK, "reading_order.html", gin.H{
+		"CategoryId":   category.Id,
+		"CategoryName": category.Name,
+		"Items":        items,
+	})
+}
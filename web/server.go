@@ -30,17 +30,57 @@ func StartServer(handler *Handler) error {
 	r.DELETE("/categories/:id", handler.DeleteCategory)
 	r.GET("/add-reference-form", handler.AddReferenceForm)
 	r.POST("/references", handler.CreateReference)
+	r.GET("/books/lookup", handler.LookupBook)
+	r.GET("/links/fetch", handler.FetchLink)
 	r.DELETE("/references/:id", handler.DeleteReference)
-	r.GET("/books/:id/edit", handler.EditBookForm)
+	r.GET("/references/:id", handler.ReferenceDetail)
+	r.GET("/references/:id/citation", handler.Citation)
+	r.GET("/references/:id/convert", handler.ConvertReferenceForm)
+	r.POST("/references/:id/convert", handler.ConvertReference)
+	r.GET("/books/:id/edit", handler.EditReferenceForm)
 	r.PUT("/books/:id", handler.UpdateBook)
-	r.GET("/links/:id/edit", handler.EditLinkForm)
+	r.GET("/links/:id/edit", handler.EditReferenceForm)
 	r.PUT("/links/:id", handler.UpdateLink)
-	r.GET("/notes/:id/edit", handler.EditNoteForm)
+	r.GET("/notes/:id/edit", handler.EditReferenceForm)
 	r.PUT("/notes/:id", handler.UpdateNote)
 	r.GET("/categories/:id/edit", handler.EditCategoryForm)
 	r.POST("/categories/:id", handler.UpdateCategory)
 	r.PUT("/categories/reorder", handler.ReorderCategories)
 	r.PUT("/references/reorder", handler.ReorderReferences)
+	r.PUT("/categories/:id/move", handler.MoveCategory)
+	r.PUT("/references/:id/move", handler.MoveReference)
+	r.POST("/references/bulk", handler.BulkReferences)
+	r.GET("/references/:id/highlights", handler.Highlights)
+	r.POST("/references/:id/highlights", handler.AddHighlight)
+	r.GET("/highlights/:id/edit", handler.EditHighlightForm)
+	r.PUT("/highlights/:id", handler.UpdateHighlight)
+	r.DELETE("/highlights/:id", handler.DeleteHighlight)
+	r.PUT("/highlights/:id/move", handler.MoveHighlight)
+	r.POST("/references/:id/relations", handler.AddRelation)
+	r.DELETE("/references/:id/relations", handler.RemoveRelation)
+	r.GET("/categories/:id/reading-order", handler.ReadingOrder)
+	r.POST("/markdown/preview", handler.MarkdownPreview)
+	r.GET("/export/graph", handler.ExportGraph)
+	r.GET("/export/references", handler.ExportReferences)
+	r.GET("/import", handler.ImportPage)
+	r.POST("/import/bookmarks", handler.ImportBookmarks)
+	r.POST("/import/csv", handler.ImportCSV)
+	r.POST("/import/reading-log", handler.ImportReadingLog)
+	r.POST("/import/bibliography", handler.ImportBibliography)
+	r.GET("/duplicates", handler.Duplicates)
+	r.GET("/links/health", handler.LinkHealth)
+	r.POST("/references/:id/snapshots", handler.ArchiveLink)
+	r.GET("/snapshots/:id", handler.Snapshot)
+	r.DELETE("/snapshots/:id", handler.DeleteSnapshot)
+	r.POST("/references/:id/attachments", handler.AddAttachment)
+	r.GET("/attachments/:id", handler.Attachment)
+	r.DELETE("/attachments/:id", handler.DeleteAttachment)
+	r.GET("/books/:id/cover", handler.CoverImage)
+	r.GET("/books/:id/cover/thumbnail", handler.CoverThumbnail)
+	r.POST("/books/:id/cover", handler.UploadCover)
+	r.POST("/books/:id/cover/fetch", handler.FetchCover)
+	r.DELETE("/books/:id/cover", handler.RemoveCover)
+	r.POST("/duplicates/merge", handler.MergeDuplicates)
 
 	return r.Run(":8080")
 }
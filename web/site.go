@@ -0,0 +1,91 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/VladMinzatu/reference-manager/export"
+)
+
+type SiteCategoryDTO struct {
+	Name       string
+	File       string
+	Indent     int
+	References int
+	Starred    int
+}
+
+/*
+SiteFiles exports the library as a static HTML site: an index of the categories and a page per category.
+The pages reuse the templates of the web UI, with the reference rows rendered without their controls, so the site can be
+served from anywhere (or opened from disk) without the app. Like the web UI, they are styled with Tailwind from its CDN.
+*/
+func SiteFiles(library model.Library, exported time.Time) ([]export.File, error) {
+	tmpl, err := template.ParseGlob("web/templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing templates: %v", err)
+	}
+
+	categories := make([]SiteCategoryDTO, 0, len(library.Categories))
+	byId := make(map[model.Id]SiteCategoryDTO, len(library.Categories))
+	depths := make(map[model.Id]int, len(library.Categories))
+	for _, category := range library.Categories {
+		// categories come in tree order, so parents are seen first
+		depth := 0
+		if !category.IsTopLevel() {
+			depth = depths[category.ParentId] + 1
+		}
+		depths[category.Id] = depth
+		dto := SiteCategoryDTO{Name: string(category.Name), File: export.CategoryFileName(category) + ".html", Indent: depth * 2}
+		for _, ref := range library.ReferencesOf(category.Id) {
+			dto.References++
+			if ref.Starred() {
+				dto.Starred++
+			}
+		}
+		categories = append(categories, dto)
+		byId[category.Id] = dto
+	}
+
+	var files []export.File
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "site_index", map[string]interface{}{
+		"Categories": categories,
+		"Exported":   exported.Format("January 2, 2006"),
+	}); err != nil {
+		return nil, fmt.Errorf("error rendering index: %v", err)
+	}
+	files = append(files, export.File{Name: "index.html", Content: buf.Bytes()})
+
+	for _, category := range library.Categories {
+		renderer := NewStaticHTMLReferenceRenderer(tmpl)
+		for _, ref := range library.ReferencesOf(category.Id) {
+			ref.Render(renderer)
+		}
+		var subcategories []SiteCategoryDTO
+		for _, child := range library.Categories {
+			if child.ParentId == category.Id {
+				subcategories = append(subcategories, byId[child.Id])
+			}
+		}
+		var parent *SiteCategoryDTO
+		if dto, ok := byId[category.ParentId]; ok {
+			parent = &dto
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, "site_category", map[string]interface{}{
+			"Name":          string(category.Name),
+			"Parent":        parent,
+			"References":    renderer.Collect(),
+			"Subcategories": subcategories,
+		}); err != nil {
+			return nil, fmt.Errorf("error rendering category %d: %v", category.Id, err)
+		}
+		files = append(files, export.File{Name: byId[category.Id].File, Content: buf.Bytes()})
+	}
+	return files, nil
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/VladMinzatu/reference-manager/domain/model"
+	"github.com/gin-gonic/gin"
+)
+
+// SnapshotsData is the archived copies panel of the detail page of a link
+type SnapshotsData struct {
+	ReferenceId model.Id
+	Snapshots   []SnapshotDTO
+	// why the last attempt to archive the link failed
+	Error string
+}
+
+type SnapshotDTO struct {
+	Id            model.Id
+	URL           model.URL
+	CapturedAt    string
+	Size          string
+	InlinedAssets bool
+}
+
+func (h *Handler) snapshotsData(referenceId model.Id) (*SnapshotsData, error) {
+	snapshots, err := h.archiveService.GetSnapshots(referenceId)
+	if err != nil {
+		return nil, err
+	}
+	data := &SnapshotsData{ReferenceId: referenceId, Snapshots: make([]SnapshotDTO, 0, len(snapshots))}
+	for _, snapshot := range snapshots {
+		data.Snapshots = append(data.Snapshots, SnapshotDTO{
+			Id:            snapshot.Id,
+			URL:           snapshot.URL,
+			CapturedAt:    snapshot.CapturedAt.Format("2006-01-02 15:04"),
+			Size:          formatSize(snapshot.Size),
+			InlinedAssets: snapshot.InlinedAssets,
+		})
+	}
+	return data, nil
+}
+
+func (h *Handler) renderSnapshots(c *gin.Context, referenceId model.Id, archiveErr error) {
+	data, err := h.snapshotsData(referenceId)
+	if err != nil {
+		slog.Error("failed to load snapshots", "error", err, "referenceId", referenceId)
+		c.String(http.StatusInternalServerError, "Failed to load snapshots")
+		return
+	}
+	if archiveErr != nil {
+		data.Error = archiveErr.Error()
+	}
+	c.HTML(http.StatusOK, "_snapshots", data)
+}
+
+// ArchiveLink captures a copy of the page of the link and returns the updated snapshots panel, with the error if it failed
+func (h *Handler) ArchiveLink(c *gin.Context) {
+	referenceId, ok := idParam(c, "Invalid reference id")
+	if !ok {
+		return
+	}
+	_, err := h.archiveService.ArchiveLink(c.Request.Context(), referenceId, c.PostForm("inline_assets") != "")
+	if err != nil {
+		slog.Error("failed to archive link", "error", err, "referenceId", referenceId)
+	}
+	h.renderSnapshots(c, referenceId, err)
+}
+
+/*
+Snapshot serves an archived copy. The copies are pages of other sites, so they are served sandboxed: they can't run
+scripts or reach the app as its origin, and only load the images, styles and fonts that weren't inlined.
+*/
+func (h *Handler) Snapshot(c *gin.Context) {
+	id, ok := idParam(c, "Invalid snapshot id")
+	if !ok {
+		return
+	}
+	_, content, err := h.archiveService.GetSnapshotContent(id)
+	if err != nil {
+		slog.Error("failed to load snapshot", "error", err, "id", id)
+		c.String(http.StatusNotFound, "Snapshot not found")
+		return
+	}
+	c.Header("Content-Security-Policy", "sandbox; default-src 'none'; img-src * data:; style-src * 'unsafe-inline'; font-src * data:")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", content)
+}
+
+func (h *Handler) DeleteSnapshot(c *gin.Context) {
+	id, ok := idParam(c, "Invalid snapshot id")
+	if !ok {
+		return
+	}
+	snapshot, err := h.archiveService.DeleteSnapshot(id)
+	if err != nil {
+		slog.Error("failed to delete snapshot", "error", err, "id", id)
+		c.String(http.StatusInternalServerError, "Failed to delete snapshot")
+		return
+	}
+	h.renderSnapshots(c, snapshot.ReferenceId, nil)
+}
+
+// Formats a size in bytes for people, e.g. 1.5 MB
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}